@@ -16,8 +16,15 @@ import (
 	"github.com/Vighnesh-V-H/async/internal/orchestrator"
 	"github.com/Vighnesh-V-H/async/internal/repositories"
 	"github.com/Vighnesh-V-H/async/internal/router"
+	"github.com/Vighnesh-V-H/async/internal/scheduler"
 	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/internal/transport"
+	"github.com/Vighnesh-V-H/async/internal/triggers"
+	"github.com/Vighnesh-V-H/async/internal/webhooks"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+	"github.com/Vighnesh-V-H/async/pkg/cache"
 	"github.com/Vighnesh-V-H/async/pkg/database"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
 	"github.com/Vighnesh-V-H/async/pkg/kafka"
 	"github.com/gin-gonic/gin"
 )
@@ -48,6 +55,22 @@ func main() {
 		Str("service", cfg.Primary.ServiceName).
 		Msg("Starting orchestrator service")
 
+	// Load workflow definitions
+	definitions, err := dsl.LoadDir(cfg.Workflows.Dir)
+	if err != nil {
+		appLog.Fatal().Err(err).Str("dir", cfg.Workflows.Dir).Msg("Failed to load workflow definitions")
+	}
+
+	taskRouter, err := events.NewTopicRouterFromRegistry(cfg.Kafka.TasksTopic, definitions)
+	if err != nil {
+		appLog.Fatal().Err(err).Msg("Invalid task topic routing")
+	}
+
+	appLog.Info().
+		Int("workflows", len(definitions.All())).
+		Strs("task_topics", taskRouter.Topics()).
+		Msg("Workflow definitions loaded")
+
 	// Initialize database with config
 	appLog.Info().Msg("Initializing database connection")
 	db, err := database.InitDB(ctx, &cfg.Database, logCfg)
@@ -63,57 +86,174 @@ func main() {
 
 	appLog.Info().Msg("Database initialized successfully")
 
-	// Initialize Kafka producer with config
-	appLog.Info().Msg("Initializing Kafka producer")
-	kafkaProducer, err := kafka.InitProducer(&cfg.Kafka, appLog)
+	// Initialize event transport (Kafka or Redis Streams)
+	appLog.Info().Str("backend", cfg.Transport.Backend).Msg("Initializing event transport")
+	eventTransport, err := transport.Open(ctx, cfg, appLog, logCfg)
 	if err != nil {
-		appLog.Fatal().Err(err).Msg("Failed to initialize Kafka producer")
+		appLog.Fatal().Err(err).Msg("Failed to initialize event transport")
 	}
-	defer kafka.Close()
+	defer eventTransport.Close()
+	eventTransport.HandleInTransactions()
 
-	appLog.Info().Msg("Kafka producer initialized successfully")
+	appLog.Info().Str("backend", eventTransport.Backend).Msg("Event transport initialized successfully")
 
-	// Initialize Kafka consumer with config
-	appLog.Info().Msg("Initializing Kafka consumer for task completions")
-	kafkaConsumer, err := kafka.InitConsumer(&cfg.Kafka, appLog)
+	// Initialize Redis for completion dedupe and live execution events; the
+	// database remains the source of truth
+	appLog.Info().Msg("Initializing Redis connection")
+	var dedupe *cache.Deduper
+	var feed *service.ExecutionFeed
+	redisClient, err := cache.InitRedis(ctx, &cfg.Redis, appLog)
 	if err != nil {
-		appLog.Fatal().Err(err).Msg("Failed to initialize Kafka consumer")
+		appLog.Warn().Err(err).Msg("Redis unavailable, completion dedupe falls back to database checks and event streaming is disabled")
+	} else {
+		defer cache.CloseRedis()
+		dedupe = cache.NewDeduper(redisClient, "completion", cfg.Redis.DedupeTTL)
+		feed = service.NewExecutionFeed(cache.NewPubSub(redisClient, "execution-events"))
+		appLog.Info().Msg("Redis initialized successfully")
 	}
 
-	appLog.Info().Msg("Kafka consumer initialized successfully")
-
 	// Initialize event handlers
-	eventProducer := events.NewEventProducer(kafkaProducer, logCfg)
-	eventConsumer := events.NewEventConsumer(kafkaConsumer, logCfg)
+	eventProducer := events.NewEventProducer(eventTransport.Publisher, cfg.Kafka.PublishTimeout, logCfg)
+	eventProducer.SetEncoding(cfg.Kafka.EventEncoding)
+	for _, topic := range cfg.Kafka.ProtobufTopics {
+		eventProducer.SetCodec(topic, events.ProtobufCodec)
+	}
+	eventConsumer := events.NewEventConsumer(eventTransport.Subscriber, logCfg)
+	eventConsumer.SetDeadLetterQueue(eventProducer, cfg.Kafka.DLQTopic)
 	appLog.Info().Msg("Event producer and consumer initialized")
 
+	// Initialize blob store for claim checks
+	blobStore, err := blob.NewStore(&cfg.Blob)
+	if err != nil {
+		appLog.Fatal().Err(err).Str("backend", cfg.Blob.Backend).Msg("Failed to initialize blob store")
+	}
+	claimCheck := blob.NewClaimCheck(blobStore, cfg.Blob.Threshold)
+	appLog.Info().Str("backend", cfg.Blob.Backend).Int("threshold", cfg.Blob.Threshold).Msg("Blob store initialized")
+
 	// Initialize repositories
 	workflowRepo := repositories.NewWorkflowRepository(db)
 	instanceRepo := repositories.NewInstanceRepository(db)
+	taskRepo := repositories.NewTaskRepository(db)
+	artifactRepo := repositories.NewArtifactRepository(db)
+	webhookRepo := repositories.NewWebhookRepository(db)
+	signalRepo := repositories.NewSignalRepository(db)
+	approvalRepo := repositories.NewApprovalRepository(db)
+	scheduleRepo := repositories.NewScheduleRepository(db)
+	leaseRepo := repositories.NewLeaseRepository(db)
 	appLog.Info().Msg("Repositories initialized")
 
 	// Initialize services
-	workflowService := service.NewWorkflowService(workflowRepo)
+	workflowService := service.NewWorkflowService(workflowRepo, definitions)
 	instanceService := service.NewInstanceService(instanceRepo)
+	taskService := service.NewTaskService(taskRepo)
+	statusService := service.NewStatusService(instanceRepo, taskRepo)
+	webhookService := service.NewWebhookService(webhookRepo)
+	signalService := service.NewSignalService(signalRepo)
+	approvalService := service.NewApprovalService(approvalRepo, webhookService)
+	scheduleService := service.NewScheduleService(scheduleRepo, leaseRepo)
+	if cfg.Artifacts.SigningKey == "" {
+		appLog.Warn().Msg("No artifact signing key configured, signed URLs are only valid on this replica")
+	}
+	artifactService, err := service.NewArtifactService(artifactRepo, blobStore, cfg.Artifacts.SigningKey)
+	if err != nil {
+		appLog.Fatal().Err(err).Msg("Failed to initialize artifact service")
+	}
 	appLog.Info().Msg("Services initialized")
 
 	// Initialize orchestrator
-	orch := orchestrator.NewOrchestrator(workflowService, instanceService, eventProducer, logCfg)
+	orch := orchestrator.NewOrchestrator(workflowService, instanceService, taskService, eventProducer, taskRouter, dedupe, logCfg)
+	orch.SetClaimCheck(claimCheck)
+	orch.SetWebhooks(webhookService)
+	orch.SetSignals(signalService)
+	orch.SetApprovals(approvalService)
+	if feed != nil {
+		orch.SetFeed(feed)
+	}
 	appLog.Info().Msg("Orchestrator state machine initialized")
 
+	// Initialize scheduler for schedule triggers
+	workflowScheduler := scheduler.NewScheduler(scheduleService, instanceService, orch, definitions, cfg.Scheduler, logCfg)
+
+	// Initialize kafka triggers; their consumer group is separate from the
+	// completions consumer so every trigger topic is read once per cluster
+	var kafkaTriggers *triggers.KafkaTriggers
+	if hasSubscriptions(definitions) {
+		triggerConsumer, err := kafka.NewConsumer(&cfg.Kafka, cfg.Kafka.TriggerGroupID, appLog)
+		if err != nil {
+			appLog.Fatal().Err(err).Msg("Failed to create kafka trigger consumer")
+		}
+		defer triggerConsumer.Close()
+
+		triggerSubscriber := events.NewKafkaSubscriber(triggerConsumer, cfg.Kafka.ConsumerConcurrency, logCfg)
+		triggerSubscriber.SetDeadLetterQueue(eventProducer, cfg.Kafka.DLQTopic, cfg.Kafka.TriggerAttempts)
+		kafkaTriggers, err = triggers.NewKafkaTriggers(triggerSubscriber, orch, definitions, logCfg)
+		if err != nil {
+			appLog.Fatal().Err(err).Msg("Failed to initialize kafka triggers")
+		}
+		appLog.Info().Strs("topics", kafkaTriggers.Topics()).Msg("Kafka triggers initialized")
+	}
+
 	// Initialize handlers
 	workflowHandler := handler.NewWorkflowHandler(workflowService)
-	audioHandler := handler.NewAudioHandler(workflowService, instanceService, eventProducer)
+	audioHandler := handler.NewAudioHandler(workflowService, orch)
+	executionHandler := handler.NewExecutionHandler(statusService)
+	executionHandler.SetCanceller(orch)
+	executionHandler.SetSignaller(orch)
+	if feed != nil {
+		executionHandler.SetFeed(feed)
+	}
+	webhookHandler := handler.NewWebhookHandler(workflowService, webhookService)
+	approvalHandler := handler.NewApprovalHandler(approvalService)
+	approvalHandler.SetDecider(orch)
+	scheduleHandler := handler.NewScheduleHandler(scheduleService)
+	scheduleHandler.SetTriggerer(workflowScheduler)
+	artifactHandler := handler.NewArtifactHandler(instanceService, artifactService, cfg.Artifacts)
 	appLog.Info().Msg("Handlers initialized")
 
-	// Start Kafka consumer in background
+	// Start completion consumer in background
+	go func() {
+		appLog.Info().Msg("Starting consumer for task completions")
+		if err := eventConsumer.ConsumeCompletions(ctx, []string{cfg.Kafka.CompletionsTopic}, orch.ProcessCompletion); err != nil {
+			appLog.Error().Err(err).Msg("Completion consumer stopped")
+		}
+	}()
+
+	// Start webhook dispatcher in background
+	dispatcher := webhooks.NewDispatcher(webhookService, cfg.Webhooks, logCfg)
+	go func() {
+		appLog.Info().Msg("Starting webhook dispatcher")
+		if err := dispatcher.Run(ctx); err != nil && err != context.Canceled {
+			appLog.Error().Err(err).Msg("Webhook dispatcher stopped")
+		}
+	}()
+
+	// Start timers for waiting tasks in background
 	go func() {
-		appLog.Info().Msg("Starting Kafka consumer for task completions")
-		if err := eventConsumer.ConsumeCompletions(ctx, orch.ProcessCompletion); err != nil {
-			appLog.Error().Err(err).Msg("Kafka consumer stopped")
+		appLog.Info().Msg("Starting timers")
+		if err := orch.RunTimers(ctx, cfg.Timers.PollInterval, cfg.Timers.BatchSize); err != nil && err != context.Canceled {
+			appLog.Error().Err(err).Msg("Timers stopped")
 		}
 	}()
 
+	// Start scheduler in background; only the replica holding its lease
+	// fires schedules
+	go func() {
+		appLog.Info().Msg("Starting scheduler")
+		if err := workflowScheduler.Run(ctx); err != nil && err != context.Canceled {
+			appLog.Error().Err(err).Msg("Scheduler stopped")
+		}
+	}()
+
+	// Start kafka triggers in background
+	if kafkaTriggers != nil {
+		go func() {
+			appLog.Info().Msg("Starting kafka triggers")
+			if err := kafkaTriggers.Run(ctx); err != nil && err != context.Canceled {
+				appLog.Error().Err(err).Msg("Kafka triggers stopped")
+			}
+		}()
+	}
+
 	// Setup Gin router
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
@@ -130,7 +270,11 @@ func main() {
 	})
 
 	router.SetupWorkflowRoutes(ginRouter, workflowHandler)
-	router.SetupAudioRoutes(ginRouter, audioHandler)
+	router.SetupAudioRoutes(ginRouter, audioHandler, executionHandler)
+	router.SetupExecutionRoutes(ginRouter, executionHandler, artifactHandler)
+	router.SetupWebhookRoutes(ginRouter, webhookHandler)
+	router.SetupApprovalRoutes(ginRouter, approvalHandler)
+	router.SetupScheduleRoutes(ginRouter, scheduleHandler)
 	appLog.Info().Msg("Routes configured")
 
 	// Setup HTTP server with config
@@ -170,3 +314,13 @@ func main() {
 	cancel()
 	appLog.Info().Msg("Orchestrator stopped")
 }
+
+// hasSubscriptions reports whether any workflow has a kafka trigger.
+func hasSubscriptions(definitions *dsl.Registry) bool {
+	for _, wf := range definitions.All() {
+		if len(wf.Subscriptions()) > 0 {
+			return true
+		}
+	}
+	return false
+}
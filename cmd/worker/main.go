@@ -1 +1,122 @@
-package worker
\ No newline at end of file
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/executors/aitask"
+	"github.com/Vighnesh-V-H/async/internal/executors/audio"
+	"github.com/Vighnesh-V-H/async/internal/executors/notification"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/transport"
+	"github.com/Vighnesh-V-H/async/internal/workers"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+)
+
+func main() {
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Initialize logger from config
+	logCfg := logger.Config{
+		Level:       cfg.Logger.Level,
+		Format:      cfg.Logger.Format,
+		ServiceName: cfg.Logger.ServiceName,
+		Environment: cfg.Logger.Environment,
+		IsProd:      cfg.Logger.IsProd,
+	}
+
+	appLog := logger.New(logCfg)
+
+	appLog.Info().
+		Str("environment", cfg.Primary.Environment).
+		Str("service", cfg.Primary.ServiceName).
+		Strs("topics", cfg.Worker.Topics).
+		Msg("Starting worker service")
+
+	// Initialize event transport (Kafka or Redis Streams)
+	appLog.Info().Str("backend", cfg.Transport.Backend).Msg("Initializing event transport")
+	eventTransport, err := transport.Open(ctx, cfg, appLog, logCfg)
+	if err != nil {
+		appLog.Fatal().Err(err).Msg("Failed to initialize event transport")
+	}
+	defer eventTransport.Close()
+
+	// Initialize event handlers
+	eventProducer := events.NewEventProducer(eventTransport.Publisher, cfg.Kafka.PublishTimeout, logCfg)
+	eventProducer.SetEncoding(cfg.Kafka.EventEncoding)
+	for _, topic := range cfg.Kafka.ProtobufTopics {
+		eventProducer.SetCodec(topic, events.ProtobufCodec)
+	}
+	eventConsumer := events.NewEventConsumer(eventTransport.Subscriber, logCfg)
+	eventConsumer.SetDeadLetterQueue(eventProducer, cfg.Kafka.DLQTopic)
+
+	// Initialize blob store for claim checks
+	blobStore, err := blob.NewStore(&cfg.Blob)
+	if err != nil {
+		appLog.Fatal().Err(err).Str("backend", cfg.Blob.Backend).Msg("Failed to initialize blob store")
+	}
+	claimCheck := blob.NewClaimCheck(blobStore, cfg.Blob.Threshold)
+
+	// Register executors
+	registry := workers.NewRegistry()
+
+	notifier := notification.NewExecutor()
+	notifier.SetDeliveryLog(notification.NewStoreDeliveryLog(blobStore))
+	if cfg.Notify.SMTPHost != "" {
+		notifier.SetChannel("email", notification.NewSMTPChannel(
+			cfg.Notify.SMTPHost, cfg.Notify.SMTPPort,
+			cfg.Notify.SMTPUsername, cfg.Notify.SMTPPassword,
+			cfg.Notify.SMTPFrom, cfg.Notify.Timeout,
+		))
+	}
+	notifier.SetChannel("slack", notification.NewWebhookChannel(cfg.Notify.SlackWebhookURL, cfg.Notify.Timeout))
+	registry.Register(notification.TaskType, notifier)
+	appLog.Info().Strs("channels", notifier.Kinds()).Msg("Notification channels configured")
+
+	provider, err := aitask.NewProvider(&cfg.AI)
+	if err != nil {
+		appLog.Fatal().Err(err).Str("provider", cfg.AI.Provider).Msg("Failed to initialize AI provider")
+	}
+	registry.Register(aitask.TaskType, aitask.NewExecutor(provider, cfg.AI.DefaultModel, cfg.AI.MaxTokens))
+
+	registry.Register(audio.TaskExtractText, audio.NewTextExecutor(audio.DefaultChunkSize))
+	registry.Register(audio.TaskGenerateAudio, audio.NewGenerateExecutor(audio.NewToneSynthesizer()))
+	registry.Register(audio.TaskStoreAudio, audio.NewStoreExecutor())
+
+	worker := workers.NewWorker(registry, eventProducer, cfg.Kafka.CompletionsTopic, logCfg)
+	worker.SetClaimCheck(claimCheck)
+	appLog.Info().Strs("task_types", registry.TaskTypes()).Msg("Executors registered")
+
+	// Start consuming tasks in background
+	consumerDone := make(chan struct{})
+	go func() {
+		defer close(consumerDone)
+		if err := eventConsumer.ConsumeTasks(ctx, cfg.Worker.Topics, worker.HandleTask); err != nil && err != context.Canceled {
+			appLog.Error().Err(err).Msg("Task consumer stopped")
+		}
+	}()
+
+	// Wait for shutdown signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	appLog.Info().Msg("Worker started, waiting for shutdown signal")
+	<-sigChan
+
+	appLog.Info().Msg("Shutting down gracefully...")
+	cancel()
+	<-consumerDone // let in-flight tasks finish and offsets commit
+	appLog.Info().Msg("Worker stopped")
+}
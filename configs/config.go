@@ -13,14 +13,23 @@ import (
 	"github.com/rs/zerolog"
 )
 
-
 type Config struct {
-	Primary  PrimaryConfig  `koanf:"primary" validate:"required"`
-	Server   ServerConfig   `koanf:"server" validate:"required"`
-	Database DatabaseConfig `koanf:"database" validate:"required"`
-	Redis    RedisConfig    `koanf:"redis" validate:"required"`
-	Kafka    KafkaConfig    `koanf:"kafka" validate:"required"`
-	Logger   LoggerConfig   `koanf:"logger" validate:"required"`
+	Primary   PrimaryConfig   `koanf:"primary" validate:"required"`
+	Server    ServerConfig    `koanf:"server" validate:"required"`
+	Database  DatabaseConfig  `koanf:"database" validate:"required"`
+	Redis     RedisConfig     `koanf:"redis" validate:"required"`
+	Kafka     KafkaConfig     `koanf:"kafka" validate:"required"`
+	Logger    LoggerConfig    `koanf:"logger" validate:"required"`
+	Workflows WorkflowsConfig `koanf:"workflows" validate:"required"`
+	Worker    WorkerConfig    `koanf:"worker" validate:"required"`
+	Transport TransportConfig `koanf:"transport" validate:"required"`
+	Blob      BlobConfig      `koanf:"blob" validate:"required"`
+	Artifacts ArtifactsConfig `koanf:"artifacts" validate:"required"`
+	Webhooks  WebhooksConfig  `koanf:"webhooks" validate:"required"`
+	Notify    NotifyConfig    `koanf:"notify" validate:"required"`
+	AI        AIConfig        `koanf:"ai" validate:"required"`
+	Timers    TimersConfig    `koanf:"timers" validate:"required"`
+	Scheduler SchedulerConfig `koanf:"scheduler" validate:"required"`
 }
 
 type PrimaryConfig struct {
@@ -41,6 +50,9 @@ type DatabaseConfig struct {
 	MaxIdleConns    int           `koanf:"max_idle_conns" validate:"required,min=1"`
 	ConnMaxLifetime time.Duration `koanf:"conn_max_lifetime" validate:"required"`
 	ConnMaxIdleTime time.Duration `koanf:"conn_max_idle_time" validate:"required"`
+	// Queue settings, used when the transport backend is postgres.
+	QueueVisibilityTimeout time.Duration `koanf:"queue_visibility_timeout" validate:"required"`
+	QueuePollInterval      time.Duration `koanf:"queue_poll_interval" validate:"required"`
 }
 
 type RedisConfig struct {
@@ -52,22 +64,41 @@ type RedisConfig struct {
 	DialTimeout  time.Duration `koanf:"dial_timeout" validate:"required"`
 	ReadTimeout  time.Duration `koanf:"read_timeout" validate:"required"`
 	WriteTimeout time.Duration `koanf:"write_timeout" validate:"required"`
+	DedupeTTL    time.Duration `koanf:"dedupe_ttl" validate:"required"`
+	// Stream settings, used when the transport backend is redis.
+	StreamMaxLen       int64         `koanf:"stream_max_len" validate:"min=0"`
+	StreamBlockTimeout time.Duration `koanf:"stream_block_timeout" validate:"required"`
+	StreamClaimMinIdle time.Duration `koanf:"stream_claim_min_idle" validate:"required"`
 }
 
 type KafkaConfig struct {
-	Brokers            []string      `koanf:"brokers" validate:"required,min=1"`
-	ProducerAcks       string        `koanf:"producer_acks" validate:"required"`
-	ProducerRetries    int           `koanf:"producer_retries" validate:"required,min=0"`
-	ProducerLingerMs   int           `koanf:"producer_linger_ms" validate:"required,min=0"`
-	CompressionType    string        `koanf:"compression_type" validate:"required"`
-	RequestTimeoutMs   int           `koanf:"request_timeout_ms" validate:"required,min=1000"`
-	DeliveryTimeoutMs  int           `koanf:"delivery_timeout_ms" validate:"required,min=1000"`
-	ConsumerGroupID    string        `koanf:"consumer_group_id" validate:"required"`
-	ConsumerTopic      string        `koanf:"consumer_topic" validate:"required"`
-	AutoOffsetReset    string        `koanf:"auto_offset_reset" validate:"required,oneof=earliest latest none"`
-	EnableAutoCommit   bool          `koanf:"enable_auto_commit"`
-	SessionTimeoutMs   int           `koanf:"session_timeout_ms" validate:"required,min=1000"`
-	HeartbeatInterval  time.Duration `koanf:"heartbeat_interval" validate:"required"`
+	Brokers             []string `koanf:"brokers" validate:"required,min=1"`
+	ProducerAcks        string   `koanf:"producer_acks" validate:"required"`
+	ProducerRetries     int      `koanf:"producer_retries" validate:"required,min=0"`
+	ProducerLingerMs    int      `koanf:"producer_linger_ms" validate:"required,min=0"`
+	CompressionType     string   `koanf:"compression_type" validate:"required"`
+	RequestTimeoutMs    int      `koanf:"request_timeout_ms" validate:"required,min=1000"`
+	DeliveryTimeoutMs   int      `koanf:"delivery_timeout_ms" validate:"required,min=1000"`
+	ConsumerGroupID     string   `koanf:"consumer_group_id" validate:"required"`
+	ConsumerConcurrency int      `koanf:"consumer_concurrency" validate:"required,min=1"`
+	TriggerGroupID      string   `koanf:"trigger_group_id" validate:"required"`
+	// TriggerAttempts is how often a trigger message is redelivered before
+	// it goes to the DLQ topic.
+	TriggerAttempts   int           `koanf:"trigger_attempts" validate:"required,min=1"`
+	TasksTopic        string        `koanf:"tasks_topic" validate:"required"`
+	CompletionsTopic  string        `koanf:"completions_topic" validate:"required"`
+	DLQTopic          string        `koanf:"dlq_topic" validate:"required"`
+	ControlTopic      string        `koanf:"control_topic" validate:"required"`
+	AutoOffsetReset   string        `koanf:"auto_offset_reset" validate:"required,oneof=earliest latest none"`
+	EnableAutoCommit  bool          `koanf:"enable_auto_commit"`
+	SessionTimeoutMs  int           `koanf:"session_timeout_ms" validate:"required,min=1000"`
+	HeartbeatInterval time.Duration `koanf:"heartbeat_interval" validate:"required"`
+	PublishTimeout    time.Duration `koanf:"publish_timeout" validate:"required"`
+	// EventEncoding is how events are written; every encoding is accepted
+	// when reading.
+	EventEncoding string `koanf:"event_encoding" validate:"required,oneof=envelope cloudevents-binary cloudevents-structured"`
+	// ProtobufTopics are written with the protobuf codec instead of JSON.
+	ProtobufTopics []string `koanf:"protobuf_topics"`
 }
 
 type LoggerConfig struct {
@@ -78,6 +109,110 @@ type LoggerConfig struct {
 	IsProd      bool   `koanf:"is_prod"`
 }
 
+// WorkflowsConfig points at the directory holding the YAML workflow definitions.
+type WorkflowsConfig struct {
+	Dir string `koanf:"dir" validate:"required"`
+}
+
+// WorkerConfig selects the task topics a worker pool consumes.
+type WorkerConfig struct {
+	Topics []string `koanf:"topics" validate:"required,min=1"`
+}
+
+// TransportConfig selects the message broker carrying task and completion
+// events. Topic names and the consumer group come from KafkaConfig whichever
+// backend is used.
+type TransportConfig struct {
+	Backend      string `koanf:"backend" validate:"required,oneof=kafka redis postgres"`
+	ConsumerName string `koanf:"consumer_name" validate:"required"`
+}
+
+// BlobConfig configures the store for payloads too large to travel inside
+// events. Values longer than Threshold bytes are offloaded.
+type BlobConfig struct {
+	Backend     string `koanf:"backend" validate:"required,oneof=filesystem s3"`
+	Threshold   int    `koanf:"threshold" validate:"required,min=1"`
+	Dir         string `koanf:"dir"`
+	S3Endpoint  string `koanf:"s3_endpoint" validate:"required_if=Backend s3"`
+	S3Region    string `koanf:"s3_region"`
+	S3Bucket    string `koanf:"s3_bucket" validate:"required_if=Backend s3"`
+	S3AccessKey string `koanf:"s3_access_key"`
+	S3SecretKey string `koanf:"s3_secret_key"`
+}
+
+// ArtifactsConfig configures artifact downloads. Download URLs are signed
+// with SigningKey and expire after URLTTL unless the client asks for a
+// different lifetime, capped at MaxURLTTL.
+type ArtifactsConfig struct {
+	SigningKey        string        `koanf:"signing_key"`
+	URLTTL            time.Duration `koanf:"url_ttl" validate:"required"`
+	MaxURLTTL         time.Duration `koanf:"max_url_ttl" validate:"required,gtefield=URLTTL"`
+	BaseURL           string        `koanf:"base_url"`
+	RequireSignedURLs bool          `koanf:"require_signed_urls"`
+}
+
+// WebhooksConfig configures delivery of lifecycle events to webhook
+// subscribers. Failed attempts are retried with exponential backoff from
+// InitialBackoff up to MaxBackoff until MaxAttempts is reached.
+type WebhooksConfig struct {
+	MaxAttempts    int           `koanf:"max_attempts" validate:"required,min=1"`
+	InitialBackoff time.Duration `koanf:"initial_backoff" validate:"required"`
+	MaxBackoff     time.Duration `koanf:"max_backoff" validate:"required,gtefield=InitialBackoff"`
+	RequestTimeout time.Duration `koanf:"request_timeout" validate:"required"`
+	PollInterval   time.Duration `koanf:"poll_interval" validate:"required"`
+	BatchSize      int           `koanf:"batch_size" validate:"required,min=1"`
+	Concurrency    int           `koanf:"concurrency" validate:"required,min=1"`
+}
+
+// TimersConfig configures how often the orchestrator looks for waiting
+// tasks, such as wait_for_signal states, whose deadline has passed.
+type TimersConfig struct {
+	PollInterval time.Duration `koanf:"poll_interval" validate:"required"`
+	BatchSize    int           `koanf:"batch_size" validate:"required,min=1"`
+}
+
+// SchedulerConfig configures the scheduler firing the schedule triggers of
+// workflows. Only the orchestrator replica holding the scheduler lease fires
+// them; it renews the lease every PollInterval and another replica takes
+// over once it went LeaseTTL without renewal. Runs more than
+// MisfireThreshold plus their jitter late count as missed and are handled
+// by the catch-up policy of their schedule, which makes up for at most
+// MaxCatchUp of them. A schedule buffers at most MaxBuffered runs.
+type SchedulerConfig struct {
+	PollInterval     time.Duration `koanf:"poll_interval" validate:"required"`
+	LeaseTTL         time.Duration `koanf:"lease_ttl" validate:"required,gtfield=PollInterval"`
+	BatchSize        int           `koanf:"batch_size" validate:"required,min=1"`
+	MisfireThreshold time.Duration `koanf:"misfire_threshold" validate:"required"`
+	MaxCatchUp       int           `koanf:"max_catch_up" validate:"required,min=1"`
+	MaxBuffered      int           `koanf:"max_buffered" validate:"required,min=1"`
+}
+
+// NotifyConfig configures the channels of notification states. The email
+// channel is only available once SMTPHost is set; the slack channel posts to
+// SlackWebhookURL unless a workflow names a webhook URL as its target.
+type NotifyConfig struct {
+	SMTPHost        string        `koanf:"smtp_host"`
+	SMTPPort        int           `koanf:"smtp_port" validate:"required,min=1,max=65535"`
+	SMTPUsername    string        `koanf:"smtp_username"`
+	SMTPPassword    string        `koanf:"smtp_password"`
+	SMTPFrom        string        `koanf:"smtp_from" validate:"required_with=SMTPHost"`
+	SlackWebhookURL string        `koanf:"slack_webhook_url" validate:"omitempty,url"`
+	Timeout         time.Duration `koanf:"timeout" validate:"required"`
+}
+
+// AIConfig configures the model provider of ai_task states. The openai
+// provider speaks the OpenAI chat completions API, which Ollama, vLLM and
+// LocalAI serve as well; fake returns canned completions without a network.
+// States without a model use DefaultModel.
+type AIConfig struct {
+	Provider     string        `koanf:"provider" validate:"required,oneof=openai fake"`
+	BaseURL      string        `koanf:"base_url" validate:"required_if=Provider openai,omitempty,url"`
+	APIKey       string        `koanf:"api_key"`
+	DefaultModel string        `koanf:"default_model"`
+	MaxTokens    int           `koanf:"max_tokens" validate:"min=0"`
+	Timeout      time.Duration `koanf:"timeout" validate:"required"`
+}
+
 func LoadConfig() (*Config, error) {
 	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
 
@@ -146,6 +281,12 @@ func applyDefaults(cfg *Config) {
 	if cfg.Database.ConnMaxIdleTime == 0 {
 		cfg.Database.ConnMaxIdleTime = 5 * time.Minute
 	}
+	if cfg.Database.QueueVisibilityTimeout == 0 {
+		cfg.Database.QueueVisibilityTimeout = 5 * time.Minute
+	}
+	if cfg.Database.QueuePollInterval == 0 {
+		cfg.Database.QueuePollInterval = 2 * time.Second
+	}
 
 	if cfg.Redis.PoolSize == 0 {
 		cfg.Redis.PoolSize = 20
@@ -162,6 +303,15 @@ func applyDefaults(cfg *Config) {
 	if cfg.Redis.WriteTimeout == 0 {
 		cfg.Redis.WriteTimeout = 2 * time.Second
 	}
+	if cfg.Redis.DedupeTTL == 0 {
+		cfg.Redis.DedupeTTL = 24 * time.Hour
+	}
+	if cfg.Redis.StreamBlockTimeout == 0 {
+		cfg.Redis.StreamBlockTimeout = time.Second
+	}
+	if cfg.Redis.StreamClaimMinIdle == 0 {
+		cfg.Redis.StreamClaimMinIdle = time.Minute
+	}
 
 	if cfg.Kafka.ProducerAcks == "" {
 		cfg.Kafka.ProducerAcks = "all"
@@ -181,6 +331,27 @@ func applyDefaults(cfg *Config) {
 	if cfg.Kafka.DeliveryTimeoutMs == 0 {
 		cfg.Kafka.DeliveryTimeoutMs = 120000
 	}
+	if cfg.Kafka.ConsumerConcurrency == 0 {
+		cfg.Kafka.ConsumerConcurrency = 8
+	}
+	if cfg.Kafka.TriggerGroupID == "" {
+		cfg.Kafka.TriggerGroupID = "workflow-triggers"
+	}
+	if cfg.Kafka.TriggerAttempts == 0 {
+		cfg.Kafka.TriggerAttempts = 5
+	}
+	if cfg.Kafka.TasksTopic == "" {
+		cfg.Kafka.TasksTopic = "task-queue"
+	}
+	if cfg.Kafka.CompletionsTopic == "" {
+		cfg.Kafka.CompletionsTopic = "task-completions"
+	}
+	if cfg.Kafka.DLQTopic == "" {
+		cfg.Kafka.DLQTopic = "task-dlq"
+	}
+	if cfg.Kafka.ControlTopic == "" {
+		cfg.Kafka.ControlTopic = "workflow-control"
+	}
 	if cfg.Kafka.AutoOffsetReset == "" {
 		cfg.Kafka.AutoOffsetReset = "earliest"
 	}
@@ -190,6 +361,12 @@ func applyDefaults(cfg *Config) {
 	if cfg.Kafka.HeartbeatInterval == 0 {
 		cfg.Kafka.HeartbeatInterval = 3 * time.Second
 	}
+	if cfg.Kafka.PublishTimeout == 0 {
+		cfg.Kafka.PublishTimeout = 10 * time.Second
+	}
+	if cfg.Kafka.EventEncoding == "" {
+		cfg.Kafka.EventEncoding = "envelope"
+	}
 
 	if cfg.Logger.Level == "" {
 		cfg.Logger.Level = "info"
@@ -197,13 +374,113 @@ func applyDefaults(cfg *Config) {
 	if cfg.Logger.Format == "" {
 		cfg.Logger.Format = "console"
 	}
+
+	if cfg.Workflows.Dir == "" {
+		cfg.Workflows.Dir = "workflows"
+	}
+
+	if len(cfg.Worker.Topics) == 0 {
+		cfg.Worker.Topics = []string{cfg.Kafka.TasksTopic}
+	}
+
+	if cfg.Blob.Backend == "" {
+		cfg.Blob.Backend = "filesystem"
+	}
+	if cfg.Blob.Threshold == 0 {
+		cfg.Blob.Threshold = 256 * 1024
+	}
+	if cfg.Blob.Dir == "" {
+		cfg.Blob.Dir = "data/blobs"
+	}
+	if cfg.Blob.S3Region == "" {
+		cfg.Blob.S3Region = "us-east-1"
+	}
+
+	if cfg.Artifacts.URLTTL == 0 {
+		cfg.Artifacts.URLTTL = 15 * time.Minute
+	}
+	if cfg.Artifacts.MaxURLTTL == 0 {
+		cfg.Artifacts.MaxURLTTL = 24 * time.Hour
+	}
+
+	if cfg.Webhooks.MaxAttempts == 0 {
+		cfg.Webhooks.MaxAttempts = 8
+	}
+	if cfg.Webhooks.InitialBackoff == 0 {
+		cfg.Webhooks.InitialBackoff = 10 * time.Second
+	}
+	if cfg.Webhooks.MaxBackoff == 0 {
+		cfg.Webhooks.MaxBackoff = time.Hour
+	}
+	if cfg.Webhooks.RequestTimeout == 0 {
+		cfg.Webhooks.RequestTimeout = 10 * time.Second
+	}
+	if cfg.Webhooks.PollInterval == 0 {
+		cfg.Webhooks.PollInterval = 2 * time.Second
+	}
+	if cfg.Webhooks.BatchSize == 0 {
+		cfg.Webhooks.BatchSize = 50
+	}
+	if cfg.Webhooks.Concurrency == 0 {
+		cfg.Webhooks.Concurrency = 8
+	}
+
+	if cfg.Notify.SMTPPort == 0 {
+		cfg.Notify.SMTPPort = 587
+	}
+	if cfg.Notify.Timeout == 0 {
+		cfg.Notify.Timeout = 10 * time.Second
+	}
+
+	if cfg.AI.Provider == "" {
+		cfg.AI.Provider = "openai"
+	}
+	if cfg.AI.BaseURL == "" {
+		cfg.AI.BaseURL = "http://localhost:11434/v1"
+	}
+	if cfg.AI.Timeout == 0 {
+		cfg.AI.Timeout = 2 * time.Minute
+	}
+
+	if cfg.Timers.PollInterval == 0 {
+		cfg.Timers.PollInterval = 5 * time.Second
+	}
+	if cfg.Timers.BatchSize == 0 {
+		cfg.Timers.BatchSize = 100
+	}
+
+	if cfg.Scheduler.PollInterval == 0 {
+		cfg.Scheduler.PollInterval = time.Second
+	}
+	if cfg.Scheduler.LeaseTTL == 0 {
+		cfg.Scheduler.LeaseTTL = 15 * time.Second
+	}
+	if cfg.Scheduler.BatchSize == 0 {
+		cfg.Scheduler.BatchSize = 100
+	}
+	if cfg.Scheduler.MisfireThreshold == 0 {
+		cfg.Scheduler.MisfireThreshold = time.Minute
+	}
+	if cfg.Scheduler.MaxCatchUp == 0 {
+		cfg.Scheduler.MaxCatchUp = 100
+	}
+	if cfg.Scheduler.MaxBuffered == 0 {
+		cfg.Scheduler.MaxBuffered = 10
+	}
+
+	if cfg.Transport.Backend == "" {
+		cfg.Transport.Backend = "kafka"
+	}
+	if cfg.Transport.ConsumerName == "" {
+		host, _ := os.Hostname()
+		cfg.Transport.ConsumerName = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
 }
 
 func (c *Config) IsDevelopment() bool {
 	return c.Primary.Environment == "development"
 }
 
-
 func (c *Config) IsProduction() bool {
 	return c.Primary.Environment == "production"
 }
@@ -0,0 +1,241 @@
+package events
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/events/eventspb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Event encodings selectable with KafkaConfig.EventEncoding.
+const (
+	// EncodingEnvelope is the JSON Envelope as the message value.
+	EncodingEnvelope = "envelope"
+	// EncodingCloudEventsBinary puts the CloudEvents attributes in ce_
+	// headers and the event data in the message value.
+	EncodingCloudEventsBinary = "cloudevents-binary"
+	// EncodingCloudEventsStructured puts the whole CloudEvent, attributes and
+	// data, in the message value as application/cloudevents+json.
+	EncodingCloudEventsStructured = "cloudevents-structured"
+)
+
+const (
+	cloudEventsSpecVersion      = "1.0"
+	cloudEventsHeaderPrefix     = "ce_"
+	contentTypeHeader           = "content-type"
+	contentTypeCloudEventsJSON  = "application/cloudevents+json"
+	ceExtensionSchemaVersion    = "schemaversion"
+	ceExtensionCorrelationID    = "correlationid"
+	ceExtensionCausationID      = "causationid"
+	ceAttributeSpecVersion      = "specversion"
+	ceAttributeDataContentType  = "datacontenttype"
+	ceAttributeID               = "id"
+	ceAttributeSource           = "source"
+	ceAttributeType             = "type"
+	ceAttributeTime             = "time"
+	ceAttributeSubject          = "subject"
+	ceStructuredDataField       = "data"
+	ceStructuredDataBase64Field = "data_base64"
+)
+
+// encodeEnvelope renders env in the given encoding and returns the message
+// value and the headers to send with it.
+func encodeEnvelope(env *Envelope, encoding string) ([]byte, map[string]string, error) {
+	switch encoding {
+	case "", EncodingEnvelope:
+		if env.dataContentType == ContentTypeProtobuf {
+			value, err := proto.Marshal(&eventspb.Envelope{
+				Id:            env.ID,
+				Type:          env.Type,
+				SchemaVersion: uint32(env.SchemaVersion),
+				Source:        env.Source,
+				Time:          timestamppb.New(env.Time),
+				CorrelationId: env.CorrelationID,
+				CausationId:   env.CausationID,
+				Data:          env.Data,
+			})
+			return value, map[string]string{contentTypeHeader: contentTypeProtobufEnvelope}, err
+		}
+		value, err := json.Marshal(env)
+		return value, nil, err
+
+	case EncodingCloudEventsBinary:
+		headers := map[string]string{contentTypeHeader: env.dataContentType}
+		for name, value := range cloudEventAttributes(env) {
+			headers[cloudEventsHeaderPrefix+name] = value
+		}
+		return env.Data, headers, nil
+
+	case EncodingCloudEventsStructured:
+		event := make(map[string]interface{})
+		for name, value := range cloudEventAttributes(env) {
+			event[name] = value
+		}
+		event[ceAttributeDataContentType] = env.dataContentType
+		if env.dataContentType == ContentTypeJSON {
+			event[ceStructuredDataField] = env.Data
+		} else {
+			event[ceStructuredDataBase64Field] = base64.StdEncoding.EncodeToString(env.Data)
+		}
+		value, err := json.Marshal(event)
+		return value, map[string]string{contentTypeHeader: contentTypeCloudEventsJSON}, err
+
+	default:
+		return nil, nil, fmt.Errorf("unknown event encoding %q", encoding)
+	}
+}
+
+// cloudEventAttributes maps the envelope onto CloudEvents context attributes.
+// Fields without a CloudEvents counterpart become extensions; the execution
+// ID doubles as the subject.
+func cloudEventAttributes(env *Envelope) map[string]string {
+	attrs := map[string]string{
+		ceAttributeSpecVersion:   cloudEventsSpecVersion,
+		ceAttributeID:            env.ID,
+		ceAttributeSource:        env.Source,
+		ceAttributeType:          env.Type,
+		ceAttributeTime:          env.Time.Format(time.RFC3339Nano),
+		ceExtensionSchemaVersion: strconv.Itoa(env.SchemaVersion),
+	}
+	if env.CorrelationID != "" {
+		attrs[ceAttributeSubject] = env.CorrelationID
+		attrs[ceExtensionCorrelationID] = env.CorrelationID
+	}
+	if env.CausationID != "" {
+		attrs[ceExtensionCausationID] = env.CausationID
+	}
+	return attrs
+}
+
+// parseMessage recognises every format a producer may have used: CloudEvents
+// binary and structured mode, the JSON envelope, and the bare legacy JSON.
+// Legacy payloads come back with SchemaVersion 0.
+func parseMessage(msg *Message) (*Envelope, error) {
+	if _, ok := msg.Headers[cloudEventsHeaderPrefix+ceAttributeSpecVersion]; ok {
+		attrs := make(map[string]string)
+		for name, value := range msg.Headers {
+			if strings.HasPrefix(name, cloudEventsHeaderPrefix) {
+				attrs[strings.TrimPrefix(name, cloudEventsHeaderPrefix)] = value
+			}
+		}
+		env, err := envelopeFromCloudEvent(attrs, msg.Value)
+		if err != nil {
+			return nil, err
+		}
+		env.dataContentType = msg.Headers[contentTypeHeader]
+		return env, nil
+	}
+
+	if msg.Headers[contentTypeHeader] == contentTypeProtobufEnvelope {
+		var pb eventspb.Envelope
+		if err := proto.Unmarshal(msg.Value, &pb); err != nil {
+			return nil, err
+		}
+		return &Envelope{
+			ID:            pb.Id,
+			Type:          pb.Type,
+			SchemaVersion: int(pb.SchemaVersion),
+			Source:        pb.Source,
+			Time:          pb.Time.AsTime(),
+			CorrelationID: pb.CorrelationId,
+			CausationID:   pb.CausationId,
+			Data:          pb.Data,
+
+			dataContentType: ContentTypeProtobuf,
+		}, nil
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Value, &probe); err != nil {
+		return nil, err
+	}
+
+	if _, ok := probe[ceAttributeSpecVersion]; ok {
+		attrs := make(map[string]string)
+		for name, raw := range probe {
+			if name == ceStructuredDataField || name == ceStructuredDataBase64Field {
+				continue
+			}
+			var value string
+			if err := json.Unmarshal(raw, &value); err != nil {
+				// Extensions may be numbers or booleans.
+				value = string(raw)
+			}
+			attrs[name] = value
+		}
+		var data []byte
+		if raw, ok := probe[ceStructuredDataBase64Field]; ok {
+			var encoded string
+			if err := json.Unmarshal(raw, &encoded); err != nil {
+				return nil, fmt.Errorf("invalid data_base64: %w", err)
+			}
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid data_base64: %w", err)
+			}
+			data = decoded
+		} else if raw, ok := probe[ceStructuredDataField]; ok {
+			data = raw
+		} else {
+			return nil, fmt.Errorf("cloudevent has no data")
+		}
+
+		env, err := envelopeFromCloudEvent(attrs, data)
+		if err != nil {
+			return nil, err
+		}
+		env.dataContentType = attrs[ceAttributeDataContentType]
+		return env, nil
+	}
+
+	env := &Envelope{}
+	if err := json.Unmarshal(msg.Value, env); err != nil {
+		return nil, err
+	}
+	if env.SchemaVersion == 0 {
+		return &Envelope{Data: msg.Value}, nil
+	}
+	return env, nil
+}
+
+func envelopeFromCloudEvent(attrs map[string]string, data []byte) (*Envelope, error) {
+	if v := attrs[ceAttributeSpecVersion]; v != cloudEventsSpecVersion {
+		return nil, fmt.Errorf("unsupported cloudevents specversion %q", v)
+	}
+
+	env := &Envelope{
+		ID:            attrs[ceAttributeID],
+		Type:          attrs[ceAttributeType],
+		Source:        attrs[ceAttributeSource],
+		CorrelationID: attrs[ceExtensionCorrelationID],
+		CausationID:   attrs[ceExtensionCausationID],
+		Data:          data,
+		// CloudEvents from producers that predate the extension carry the
+		// first enveloped version.
+		SchemaVersion: 2,
+	}
+	if env.CorrelationID == "" {
+		env.CorrelationID = attrs[ceAttributeSubject]
+	}
+	if v, ok := attrs[ceExtensionSchemaVersion]; ok {
+		version, err := strconv.Atoi(v)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid schemaversion %q", v)
+		}
+		env.SchemaVersion = version
+	}
+	if v, ok := attrs[ceAttributeTime]; ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloudevent time %q: %w", v, err)
+		}
+		env.Time = t
+	}
+	return env, nil
+}
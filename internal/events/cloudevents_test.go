@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// viaCodec returns event as it reads back after a round trip through codec.
+func viaCodec(t *testing.T, codec Codec, event *CompletionEvent) *CompletionEvent {
+	t.Helper()
+	data, err := codec.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &CompletionEvent{}
+	if err := codec.Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestCloudEventsRoundTrip(t *testing.T) {
+	for _, encoding := range []string{EncodingCloudEventsBinary, EncodingCloudEventsStructured} {
+		for _, codec := range []Codec{JSONCodec, ProtobufCodec} {
+			t.Run(encoding+"/"+codec.ContentType(), func(t *testing.T) {
+				logCfg := testLog
+				logCfg.ServiceName = "worker"
+				ep := NewEventProducer(NewMemoryBroker(1), time.Second, logCfg)
+				ep.SetEncoding(encoding)
+				ep.SetCodec("completions", codec)
+				ctx := ContextWithEnvelope(context.Background(), &Envelope{ID: "evt-task"})
+
+				msg, err := ep.completionMessage(ctx, "completions", sampleCompletion())
+				if err != nil {
+					t.Fatal(err)
+				}
+				switch encoding {
+				case EncodingCloudEventsBinary:
+					if msg.Headers["ce_specversion"] != "1.0" || msg.Headers["ce_type"] != EventTypeCompletion || msg.Headers[contentTypeHeader] != codec.ContentType() {
+						t.Errorf("headers = %v, want the attributes and the data content type", msg.Headers)
+					}
+				case EncodingCloudEventsStructured:
+					if msg.Headers[contentTypeHeader] != contentTypeCloudEventsJSON {
+						t.Errorf("content type = %q, want %q", msg.Headers[contentTypeHeader], contentTypeCloudEventsJSON)
+					}
+				}
+
+				var completion CompletionEvent
+				env, err := decodeEnvelope(msg, EventTypeCompletion, &completion)
+				if err != nil {
+					t.Fatalf("decodeEnvelope: %v", err)
+				}
+				if want := viaCodec(t, codec, sampleCompletion()); !reflect.DeepEqual(&completion, want) {
+					t.Errorf("completion = %+v, want %+v", completion, *want)
+				}
+				if env.ID == "" || env.Source != "worker" || env.SchemaVersion != CompletionEventVersion || env.Time.IsZero() {
+					t.Errorf("envelope = %s from %q version %d at %s, want its attributes kept", env.ID, env.Source, env.SchemaVersion, env.Time)
+				}
+				if env.CorrelationID != sampleCompletion().ExecutionID || env.CausationID != "evt-task" {
+					t.Errorf("envelope correlated with %q, caused by %q; want the execution and evt-task", env.CorrelationID, env.CausationID)
+				}
+			})
+		}
+	}
+}
+
+// structuredEvent hand-writes a structured mode CloudEvent the way a producer
+// outside this repo might.
+func structuredEvent(t *testing.T, dataContentType string, data []byte) *Message {
+	t.Helper()
+	event := map[string]interface{}{
+		"specversion":   "1.0",
+		"id":            "evt-1",
+		"source":        "external",
+		"type":          EventTypeCompletion,
+		"time":          "2026-10-19T10:00:00.25Z",
+		"subject":       "exec-1",
+		"schemaversion": strconv.Itoa(CompletionEventVersion),
+	}
+	if dataContentType != "" {
+		event["datacontenttype"] = dataContentType
+	}
+	if codec, err := codecFor(dataContentType); err == nil && codec == JSONCodec {
+		event["data"] = json.RawMessage(data)
+	} else {
+		event["data_base64"] = base64.StdEncoding.EncodeToString(data)
+	}
+	value, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Message{Topic: "completions", Value: value, Headers: map[string]string{contentTypeHeader: contentTypeCloudEventsJSON}}
+}
+
+func TestCloudEventsPickCodecFromDataContentType(t *testing.T) {
+	tests := []struct {
+		dataContentType string
+		codec           Codec
+	}{
+		{"", JSONCodec},
+		{"application/json; charset=utf-8", JSONCodec},
+		{ContentTypeProtobuf, ProtobufCodec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dataContentType, func(t *testing.T) {
+			data, err := tt.codec.Marshal(sampleCompletion())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var completion CompletionEvent
+			env, err := decodeEnvelope(structuredEvent(t, tt.dataContentType, data), EventTypeCompletion, &completion)
+			if err != nil {
+				t.Fatalf("decodeEnvelope: %v", err)
+			}
+			if want := viaCodec(t, tt.codec, sampleCompletion()); !reflect.DeepEqual(&completion, want) {
+				t.Errorf("completion = %+v, want %+v", completion, *want)
+			}
+			// Without the correlationid extension the subject is the execution
+			if env.CorrelationID != "exec-1" || !env.Time.Equal(time.Date(2026, 10, 19, 10, 0, 0, 250e6, time.UTC)) {
+				t.Errorf("envelope correlated with %q at %s, want exec-1 at the event time", env.CorrelationID, env.Time)
+			}
+		})
+	}
+}
+
+func TestCloudEventsRejectUnknownDataContentType(t *testing.T) {
+	msg := structuredEvent(t, "application/xml", []byte("<completion/>"))
+	if _, err := decodeEnvelope(msg, EventTypeCompletion, &CompletionEvent{}); err == nil {
+		t.Error("decoded data of an unknown content type")
+	}
+}
@@ -0,0 +1,327 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Vighnesh-V-H/async/internal/events/eventspb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Content types of event data.
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/protobuf"
+
+	// contentTypeProtobufEnvelope marks a message whose whole value is an
+	// eventspb.Envelope.
+	contentTypeProtobufEnvelope = "application/vnd.async.envelope+protobuf"
+)
+
+// Codec serializes the data of task and completion events.
+type Codec interface {
+	ContentType() string
+	Marshal(event interface{}) ([]byte, error)
+	Unmarshal(data []byte, out interface{}) error
+}
+
+var (
+	// JSONCodec is the default codec and the only one older event versions
+	// were written with.
+	JSONCodec Codec = jsonCodec{}
+	// ProtobufCodec writes the eventspb messages, keeping integer types that
+	// JSON turns into float64.
+	ProtobufCodec Codec = protobufCodec{}
+)
+
+// codecFor returns the codec for a content type; an empty content type is
+// JSON. Parameters such as charset are ignored.
+func codecFor(contentType string) (Codec, error) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "", ContentTypeJSON:
+		return JSONCodec, nil
+	case ContentTypeProtobuf:
+		return ProtobufCodec, nil
+	default:
+		return nil, fmt.Errorf("unsupported data content type %q", contentType)
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return ContentTypeJSON }
+
+func (jsonCodec) Marshal(event interface{}) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func (jsonCodec) Unmarshal(data []byte, out interface{}) error {
+	return json.Unmarshal(data, out)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string { return ContentTypeProtobuf }
+
+func (protobufCodec) Marshal(event interface{}) ([]byte, error) {
+	switch e := event.(type) {
+	case *TaskEvent:
+		input, err := toPBMap(e.Input)
+		if err != nil {
+			return nil, fmt.Errorf("encoding input: %w", err)
+		}
+		params, err := toPBMap(e.Params)
+		if err != nil {
+			return nil, fmt.Errorf("encoding params: %w", err)
+		}
+		context, err := toPBMap(e.Context)
+		if err != nil {
+			return nil, fmt.Errorf("encoding context: %w", err)
+		}
+		return proto.Marshal(&eventspb.TaskEvent{
+			ExecutionId: e.ExecutionID,
+			WorkflowId:  uint64(e.WorkflowID),
+			TaskId:      e.TaskID,
+			Attempt:     uint32(e.Attempt),
+			TaskType:    e.TaskType,
+			Step:        uint32(e.Step),
+			Input:       input,
+			Params:      params,
+			Context:     context,
+		})
+	case *CompletionEvent:
+		output, err := toPBMap(e.Output)
+		if err != nil {
+			return nil, fmt.Errorf("encoding output: %w", err)
+		}
+		return proto.Marshal(&eventspb.CompletionEvent{
+			ExecutionId: e.ExecutionID,
+			WorkflowId:  uint64(e.WorkflowID),
+			TaskId:      e.TaskID,
+			Attempt:     uint32(e.Attempt),
+			TaskType:    e.TaskType,
+			Step:        uint32(e.Step),
+			Status:      e.Status,
+			Output:      output,
+			Error:       e.Error,
+			Artifacts:   toPBArtifacts(e.Artifacts),
+		})
+	default:
+		return nil, fmt.Errorf("protobuf codec cannot encode %T", event)
+	}
+}
+
+func (protobufCodec) Unmarshal(data []byte, out interface{}) error {
+	switch e := out.(type) {
+	case *TaskEvent:
+		var pb eventspb.TaskEvent
+		if err := proto.Unmarshal(data, &pb); err != nil {
+			return err
+		}
+		*e = TaskEvent{
+			ExecutionID: pb.ExecutionId,
+			WorkflowID:  uint(pb.WorkflowId),
+			TaskID:      pb.TaskId,
+			Attempt:     uint8(pb.Attempt),
+			TaskType:    pb.TaskType,
+			Step:        uint8(pb.Step),
+			Input:       fromPBMap(pb.Input),
+			Params:      fromPBMap(pb.Params),
+			Context:     fromPBMap(pb.Context),
+		}
+		return nil
+	case *CompletionEvent:
+		var pb eventspb.CompletionEvent
+		if err := proto.Unmarshal(data, &pb); err != nil {
+			return err
+		}
+		*e = CompletionEvent{
+			ExecutionID: pb.ExecutionId,
+			WorkflowID:  uint(pb.WorkflowId),
+			TaskID:      pb.TaskId,
+			Attempt:     uint8(pb.Attempt),
+			TaskType:    pb.TaskType,
+			Step:        uint8(pb.Step),
+			Status:      pb.Status,
+			Output:      fromPBMap(pb.Output),
+			Error:       pb.Error,
+			Artifacts:   fromPBArtifacts(pb.Artifacts),
+		}
+		return nil
+	default:
+		return fmt.Errorf("protobuf codec cannot decode into %T", out)
+	}
+}
+
+func toPBArtifacts(artifacts []Artifact) []*eventspb.Artifact {
+	if len(artifacts) == 0 {
+		return nil
+	}
+	out := make([]*eventspb.Artifact, len(artifacts))
+	for i, a := range artifacts {
+		out[i] = &eventspb.Artifact{
+			Name:        a.Name,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			Sha256:      a.SHA256,
+			Key:         a.Key,
+		}
+	}
+	return out
+}
+
+func fromPBArtifacts(artifacts []*eventspb.Artifact) []Artifact {
+	if len(artifacts) == 0 {
+		return nil
+	}
+	out := make([]Artifact, len(artifacts))
+	for i, a := range artifacts {
+		out[i] = Artifact{
+			Name:        a.Name,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			SHA256:      a.Sha256,
+			Key:         a.Key,
+		}
+	}
+	return out
+}
+
+func toPBMap(m map[string]interface{}) (map[string]*eventspb.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	out := make(map[string]*eventspb.Value, len(m))
+	for k, v := range m {
+		pv, err := toPBValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		out[k] = pv
+	}
+	return out, nil
+}
+
+func toPBValue(v interface{}) (*eventspb.Value, error) {
+	switch x := v.(type) {
+	case nil:
+		return &eventspb.Value{Kind: &eventspb.Value_NullValue{NullValue: true}}, nil
+	case bool:
+		return &eventspb.Value{Kind: &eventspb.Value_BoolValue{BoolValue: x}}, nil
+	case int:
+		return intValue(int64(x)), nil
+	case int8:
+		return intValue(int64(x)), nil
+	case int16:
+		return intValue(int64(x)), nil
+	case int32:
+		return intValue(int64(x)), nil
+	case int64:
+		return intValue(x), nil
+	case uint:
+		return uintValue(uint64(x)), nil
+	case uint8:
+		return uintValue(uint64(x)), nil
+	case uint16:
+		return uintValue(uint64(x)), nil
+	case uint32:
+		return uintValue(uint64(x)), nil
+	case uint64:
+		return uintValue(x), nil
+	case float32:
+		return &eventspb.Value{Kind: &eventspb.Value_DoubleValue{DoubleValue: float64(x)}}, nil
+	case float64:
+		return &eventspb.Value{Kind: &eventspb.Value_DoubleValue{DoubleValue: x}}, nil
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return intValue(i), nil
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &eventspb.Value{Kind: &eventspb.Value_DoubleValue{DoubleValue: f}}, nil
+	case string:
+		return &eventspb.Value{Kind: &eventspb.Value_StringValue{StringValue: x}}, nil
+	case []byte:
+		return &eventspb.Value{Kind: &eventspb.Value_BytesValue{BytesValue: x}}, nil
+	case []interface{}:
+		list := &eventspb.ListValue{Values: make([]*eventspb.Value, 0, len(x))}
+		for _, item := range x {
+			pv, err := toPBValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list.Values = append(list.Values, pv)
+		}
+		return &eventspb.Value{Kind: &eventspb.Value_ListValue{ListValue: list}}, nil
+	case map[string]interface{}:
+		fields, err := toPBMap(x)
+		if err != nil {
+			return nil, err
+		}
+		return &eventspb.Value{Kind: &eventspb.Value_MapValue{MapValue: &eventspb.MapValue{Fields: fields}}}, nil
+	default:
+		// Structs and typed slices: fall back to their JSON shape.
+		raw, err := json.Marshal(x)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported value of type %T: %w", v, err)
+		}
+		var generic interface{}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&generic); err != nil {
+			return nil, err
+		}
+		return toPBValue(generic)
+	}
+}
+
+func intValue(i int64) *eventspb.Value {
+	return &eventspb.Value{Kind: &eventspb.Value_IntValue{IntValue: i}}
+}
+
+func uintValue(u uint64) *eventspb.Value {
+	return &eventspb.Value{Kind: &eventspb.Value_UintValue{UintValue: u}}
+}
+
+func fromPBMap(m map[string]*eventspb.Value) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = fromPBValue(v)
+	}
+	return out
+}
+
+func fromPBValue(v *eventspb.Value) interface{} {
+	switch x := v.GetKind().(type) {
+	case *eventspb.Value_BoolValue:
+		return x.BoolValue
+	case *eventspb.Value_IntValue:
+		return x.IntValue
+	case *eventspb.Value_UintValue:
+		return x.UintValue
+	case *eventspb.Value_DoubleValue:
+		return x.DoubleValue
+	case *eventspb.Value_StringValue:
+		return x.StringValue
+	case *eventspb.Value_BytesValue:
+		return x.BytesValue
+	case *eventspb.Value_ListValue:
+		list := make([]interface{}, 0, len(x.ListValue.GetValues()))
+		for _, item := range x.ListValue.GetValues() {
+			list = append(list, fromPBValue(item))
+		}
+		return list
+	case *eventspb.Value_MapValue:
+		return fromPBMap(x.MapValue.GetFields())
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sampleCompletion() *CompletionEvent {
+	return &CompletionEvent{
+		ExecutionID: "4f1c2b9e-5d3a-4c8e-9b7f-2a6d1e0c3b5a",
+		WorkflowID:  7,
+		TaskID:      "9a8b7c6d-5e4f-4a3b-2c1d-0e9f8a7b6c5d",
+		Attempt:     1,
+		TaskType:    "generate_audio",
+		Step:        2,
+		Status:      CompletionStatusCompleted,
+		Output: map[string]interface{}{
+			"duration_ms": int64(183250),
+			"sample_rate": uint64(22050),
+			"format":      "wav",
+			"chunks":      []interface{}{"Hello there.", "This is a transcript.", "It goes on for a while."},
+			"usage":       map[string]interface{}{"prompt_tokens": int64(512), "completion_tokens": int64(128)},
+			"score":       0.875,
+		},
+		Artifacts: []Artifact{{
+			Name:        "audio",
+			ContentType: "audio/wav",
+			Size:        8084044,
+			SHA256:      "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+			Key:         "artifacts/4f1c2b9e/audio.wav",
+		}},
+	}
+}
+
+func sampleTask() *TaskEvent {
+	return &TaskEvent{
+		ExecutionID: "4f1c2b9e-5d3a-4c8e-9b7f-2a6d1e0c3b5a",
+		WorkflowID:  7,
+		TaskID:      "0f1e2d3c-4b5a-4968-8776-655443322110",
+		Attempt:     1,
+		TaskType:    "store_audio",
+		Step:        3,
+		Input:       map[string]interface{}{"audio_key": "artifacts/4f1c2b9e/audio.wav", "size": int64(8084044)},
+		Params:      map[string]interface{}{"state": "upload_audio", "retries": int64(3)},
+		Context:     map[string]interface{}{"execution_id": "4f1c2b9e-5d3a-4c8e-9b7f-2a6d1e0c3b5a", "trigger": map[string]interface{}{"file_url": "s3://uploads/talk.mp3"}},
+	}
+}
+
+func TestProtobufCodecKeepsIntegerTypes(t *testing.T) {
+	in := sampleCompletion()
+	data, err := ProtobufCodec.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out CompletionEvent
+	if err := ProtobufCodec.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, *in)
+	}
+
+	// JSON, the fallback, turns every number into float64
+	if data, err = JSONCodec.Marshal(in); err != nil {
+		t.Fatal(err)
+	}
+	var viaJSON CompletionEvent
+	if err := JSONCodec.Unmarshal(data, &viaJSON); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := viaJSON.Output["sample_rate"].(float64); !ok {
+		t.Errorf("JSON sample_rate = %T, want float64", viaJSON.Output["sample_rate"])
+	}
+}
+
+func TestCodecFor(t *testing.T) {
+	for contentType, want := range map[string]Codec{
+		"":                                JSONCodec,
+		"application/json":                JSONCodec,
+		"application/json; charset=utf-8": JSONCodec,
+		"application/protobuf":            ProtobufCodec,
+	} {
+		got, err := codecFor(contentType)
+		if err != nil || got != want {
+			t.Errorf("codecFor(%q) = %v, %v; want %v", contentType, got, err, want)
+		}
+	}
+	if _, err := codecFor("text/xml"); err == nil {
+		t.Error("codecFor(text/xml) succeeded, want an error")
+	}
+}
+
+var benchmarkCodecs = []struct {
+	name  string
+	codec Codec
+}{
+	{"json", JSONCodec},
+	{"protobuf", ProtobufCodec},
+}
+
+var benchmarkEvents = []struct {
+	name  string
+	event interface{}
+	empty func() interface{}
+}{
+	{"task", sampleTask(), func() interface{} { return new(TaskEvent) }},
+	{"completion", sampleCompletion(), func() interface{} { return new(CompletionEvent) }},
+}
+
+// BenchmarkCodecEncode reports the encoded size of each event as
+// bytes/event next to the encoding cost.
+func BenchmarkCodecEncode(b *testing.B) {
+	for _, c := range benchmarkCodecs {
+		for _, e := range benchmarkEvents {
+			b.Run(c.name+"/"+e.name, func(b *testing.B) {
+				var size int
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					data, err := c.codec.Marshal(e.event)
+					if err != nil {
+						b.Fatal(err)
+					}
+					size = len(data)
+				}
+				b.ReportMetric(float64(size), "bytes/event")
+			})
+		}
+	}
+}
+
+func BenchmarkCodecDecode(b *testing.B) {
+	for _, c := range benchmarkCodecs {
+		for _, e := range benchmarkEvents {
+			b.Run(c.name+"/"+e.name, func(b *testing.B) {
+				data, err := c.codec.Marshal(e.event)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := c.codec.Unmarshal(data, e.empty()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
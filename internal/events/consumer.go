@@ -2,125 +2,122 @@ package events
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 
 	"github.com/Vighnesh-V-H/async/internal/logger"
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/rs/zerolog"
 )
 
+// errUndecodable marks messages that can never be processed, such as those of
+// an unsupported schema version, so they go straight to the DLQ.
+var errUndecodable = errors.New("undecodable message")
+
+// EventConsumer decodes events from a Subscriber and runs the typed handlers.
+// Handler errors leave the message unacknowledged so the transport delivers
+// it again with backoff; only messages that cannot be decoded are forwarded
+// to the DLQ and acknowledged so they do not block their partition.
 type EventConsumer struct {
-	consumer *kafka.Consumer
-	logger   zerolog.Logger
+	subscriber Subscriber
+	dlq        *EventProducer
+	dlqTopic   string
+	logger     zerolog.Logger
 }
 
 type CompletionHandler func(ctx context.Context, event *CompletionEvent) error
 
-func NewEventConsumer(consumer *kafka.Consumer, logCfg logger.Config) *EventConsumer {
+type TaskHandler func(ctx context.Context, event *TaskEvent) error
+
+func NewEventConsumer(subscriber Subscriber, logCfg logger.Config) *EventConsumer {
 	return &EventConsumer{
-		consumer: consumer,
-		logger:   logger.New(logCfg),
+		subscriber: subscriber,
+		logger:     logger.New(logCfg),
 	}
 }
 
-func (ec *EventConsumer) ConsumeCompletions(ctx context.Context, handler CompletionHandler) error {
-	ec.logger.Info().Msg("Starting to consume completion events")
-
-	for {
-		select {
-		case <-ctx.Done():
-			ec.logger.Info().Msg("Context cancelled, stopping consumer")
-			return ctx.Err()
-		default:
-			msg, err := ec.consumer.ReadMessage(-1)
-			if err != nil {
-				ec.logger.Error().Err(err).Msg("Error reading message from Kafka")
-				continue
-			}
-
-			var completion CompletionEvent
-			if err := json.Unmarshal(msg.Value, &completion); err != nil {
-				ec.logger.Error().
-					Err(err).
-					Str("message", string(msg.Value)).
-					Msg("Failed to unmarshal completion event")
-				continue
-			}
-
-			ec.logger.Info().
-				Str("execution_id", completion.ExecutionID).
-				Str("task_type", completion.TaskType).
-				Str("status", completion.Status).
-				Uint8("step", completion.Step).
-				Msg("Received completion event")
-
-			if err := handler(ctx, &completion); err != nil {
-				ec.logger.Error().
-					Err(err).
-					Str("execution_id", completion.ExecutionID).
-					Msg("Failed to process completion event")
-				continue
-			}
-
-			if _, err := ec.consumer.CommitMessage(msg); err != nil {
-				ec.logger.Error().Err(err).Msg("Failed to commit offset")
-			}
-		}
-	}
+// SetDeadLetterQueue makes the consumer forward messages that cannot be
+// decoded to topic. Without one they stay unacknowledged.
+func (ec *EventConsumer) SetDeadLetterQueue(producer *EventProducer, topic string) {
+	ec.dlq = producer
+	ec.dlqTopic = topic
 }
 
-type TaskHandler func(ctx context.Context, event *TaskEvent) error
+func (ec *EventConsumer) ConsumeCompletions(ctx context.Context, topics []string, handler CompletionHandler) error {
+	ec.logger.Info().Strs("topics", topics).Msg("Starting to consume completion events")
+
+	return ec.subscriber.Subscribe(ctx, topics, ec.withDeadLetters(func(ctx context.Context, msg *Message) error {
+		var completion CompletionEvent
+		env, err := decodeEnvelope(msg, EventTypeCompletion, &completion)
+		if err != nil {
+			ec.logger.Error().
+				Err(err).
+				Str("message", string(msg.Value)).
+				Msg("Failed to decode completion event")
+			return fmt.Errorf("%w: %v", errUndecodable, err)
+		}
 
+		ec.logger.Info().
+			Str("execution_id", completion.ExecutionID).
+			Str("task_id", completion.TaskID).
+			Str("task_type", completion.TaskType).
+			Str("status", completion.Status).
+			Uint8("step", completion.Step).
+			Int("schema_version", env.SchemaVersion).
+			Msg("Received completion event")
+
+		return handler(ContextWithEnvelope(ctx, env), &completion)
+	}))
+}
 
-func (ec *EventConsumer) ConsumeTasks(ctx context.Context, handler TaskHandler) error {
-	ec.logger.Info().Msg("Starting to consume task events")
-
-	for {
-		select {
-		case <-ctx.Done():
-			ec.logger.Info().Msg("Context cancelled, stopping task consumer")
-			return ctx.Err()
-		default:
-			msg, err := ec.consumer.ReadMessage(-1)
-			if err != nil {
-				ec.logger.Error().Err(err).Msg("Error reading message from Kafka")
-				continue
-			}
-
-			var task TaskEvent
-			if err := json.Unmarshal(msg.Value, &task); err != nil {
-				ec.logger.Error().
-					Err(err).
-					Str("message", string(msg.Value)).
-					Msg("Failed to unmarshal task event")
-				continue
-			}
-
-			ec.logger.Info().
-				Str("execution_id", task.ExecutionID).
-				Str("task_type", task.TaskType).
-				Uint8("step", task.Step).
-				Msg("Received task event")
-
-			if err := handler(ctx, &task); err != nil {
-				ec.logger.Error().
-					Err(err).
-					Str("execution_id", task.ExecutionID).
-					Msg("Failed to process task event")
-				continue
-			}
-
-			if _, err := ec.consumer.CommitMessage(msg); err != nil {
-				ec.logger.Error().Err(err).Msg("Failed to commit offset")
-			}
+func (ec *EventConsumer) ConsumeTasks(ctx context.Context, topics []string, handler TaskHandler) error {
+	ec.logger.Info().Strs("topics", topics).Msg("Starting to consume task events")
+
+	return ec.subscriber.Subscribe(ctx, topics, ec.withDeadLetters(func(ctx context.Context, msg *Message) error {
+		var task TaskEvent
+		env, err := decodeEnvelope(msg, EventTypeTask, &task)
+		if err != nil {
+			ec.logger.Error().
+				Err(err).
+				Str("message", string(msg.Value)).
+				Msg("Failed to decode task event")
+			return fmt.Errorf("%w: %v", errUndecodable, err)
 		}
-	}
+
+		ec.logger.Info().
+			Str("execution_id", task.ExecutionID).
+			Str("task_id", task.TaskID).
+			Str("task_type", task.TaskType).
+			Uint8("step", task.Step).
+			Int("schema_version", env.SchemaVersion).
+			Msg("Received task event")
+
+		return handler(ContextWithEnvelope(ctx, env), &task)
+	}))
 }
 
-// Close closes the consumer
-func (ec *EventConsumer) Close() error {
-	if ec.consumer != nil {
-		return ec.consumer.Close()
+// withDeadLetters wraps process so messages that cannot be decoded are
+// forwarded to the DLQ and acknowledged. Any other error is returned for the
+// transport to redeliver the message, as is an undecodable message when
+// there is no DLQ or it is unreachable, so nothing is acknowledged unless it
+// was processed or dead lettered.
+func (ec *EventConsumer) withDeadLetters(process MessageHandler) MessageHandler {
+	return func(ctx context.Context, msg *Message) error {
+		err := process(ctx, msg)
+		if err == nil || !errors.Is(err, errUndecodable) {
+			return err
+		}
+
+		if ec.dlq == nil {
+			ec.logger.Error().
+				Err(err).
+				Str("key", string(msg.Key)).
+				Msg("Undecodable event left unacknowledged, no DLQ configured")
+			return err
+		}
+		ec.logger.Error().
+			Err(err).
+			Str("key", string(msg.Key)).
+			Msg("Forwarding undecodable event to DLQ")
+		return ec.dlq.PublishDeadLetter(ctx, ec.dlqTopic, msg, err.Error())
 	}
-	return nil
 }
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/logger"
+)
+
+var testLog = logger.Config{Level: "error"}
+
+// handlerSubscriber keeps the handler it is given so tests can deliver
+// messages to it directly.
+type handlerSubscriber struct {
+	handler MessageHandler
+}
+
+func (s *handlerSubscriber) Subscribe(ctx context.Context, topics []string, handler MessageHandler) error {
+	s.handler = handler
+	return nil
+}
+
+// newTestConsumer subscribes handler to completions, with a DLQ in broker
+// unless broker is nil.
+func newTestConsumer(t *testing.T, broker *MemoryBroker, handler CompletionHandler) MessageHandler {
+	t.Helper()
+	sub := &handlerSubscriber{}
+	consumer := NewEventConsumer(sub, testLog)
+	if broker != nil {
+		consumer.SetDeadLetterQueue(NewEventProducer(broker, time.Second, testLog), "dlq")
+	}
+	if err := consumer.ConsumeCompletions(context.Background(), []string{"completions"}, handler); err != nil {
+		t.Fatal(err)
+	}
+	return sub.handler
+}
+
+func completionMessage(t *testing.T) *Message {
+	t.Helper()
+	msg, err := NewEventProducer(NewMemoryBroker(1), time.Second, testLog).completionMessage(context.Background(), "completions", sampleCompletion())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestConsumerReturnsHandlerErrors(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	calls := 0
+	handle := newTestConsumer(t, broker, func(ctx context.Context, event *CompletionEvent) error {
+		calls++
+		return errors.New("database unavailable")
+	})
+
+	if err := handle(context.Background(), completionMessage(t)); err == nil {
+		t.Error("handle acknowledged a message its handler failed, want it redelivered")
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want once per delivery", calls)
+	}
+	if dead := broker.Messages("dlq"); len(dead) != 0 {
+		t.Errorf("dead lettered %d messages, want none", len(dead))
+	}
+}
+
+func TestConsumerHandlesDecodedEvents(t *testing.T) {
+	var got *CompletionEvent
+	handle := newTestConsumer(t, NewMemoryBroker(1), func(ctx context.Context, event *CompletionEvent) error {
+		got = event
+		if _, ok := EnvelopeFromContext(ctx); !ok {
+			t.Error("handler context carries no envelope")
+		}
+		return nil
+	})
+
+	if err := handle(context.Background(), completionMessage(t)); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if got == nil || got.TaskID != sampleCompletion().TaskID {
+		t.Errorf("handler got %+v, want the sample completion", got)
+	}
+}
+
+func TestConsumerDeadLettersUndecodableMessages(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	handle := newTestConsumer(t, broker, func(ctx context.Context, event *CompletionEvent) error {
+		t.Error("handler ran for an undecodable message")
+		return nil
+	})
+
+	msg := &Message{Topic: "completions", Key: []byte("exec-1"), Value: []byte("not an event")}
+	if err := handle(context.Background(), msg); err != nil {
+		t.Fatalf("handle = %v, want the message acknowledged once dead lettered", err)
+	}
+	dead := broker.Messages("dlq")
+	if len(dead) != 1 || string(dead[0].Value) != "not an event" || dead[0].Headers["dlq-source-topic"] != "completions" {
+		t.Fatalf("dead letters = %+v, want the message from completions", dead)
+	}
+}
+
+func TestConsumerWithoutDLQKeepsUndecodableMessages(t *testing.T) {
+	handle := newTestConsumer(t, nil, func(ctx context.Context, event *CompletionEvent) error {
+		return nil
+	})
+
+	msg := &Message{Topic: "completions", Value: []byte("not an event")}
+	if err := handle(context.Background(), msg); !errors.Is(err, errUndecodable) {
+		t.Errorf("handle = %v, want the message left unacknowledged", err)
+	}
+}
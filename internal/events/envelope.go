@@ -0,0 +1,184 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types carried in the envelope.
+const (
+	EventTypeTask       = "async.task.dispatched"
+	EventTypeCompletion = "async.task.completed"
+)
+
+// Current schema versions. Version 1 is the bare JSON payload that was
+// published before events had an envelope.
+const (
+	TaskEventVersion       = 2
+	CompletionEventVersion = 2
+)
+
+var currentVersions = map[string]int{
+	EventTypeTask:       TaskEventVersion,
+	EventTypeCompletion: CompletionEventVersion,
+}
+
+// ErrUnsupportedVersion is returned for events written by a newer producer
+// than this binary understands. Such events are dead lettered instead of
+// being decoded into structs that do not match them.
+var ErrUnsupportedVersion = errors.New("unsupported event schema version")
+
+// Envelope wraps every event with the metadata needed to evolve its schema
+// and trace it. CorrelationID is the execution ID; CausationID is the ID of
+// the event whose handling produced this one.
+type Envelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	Source        string          `json:"source"`
+	Time          time.Time       `json:"time"`
+	CorrelationID string          `json:"correlation_id"`
+	CausationID   string          `json:"causation_id,omitempty"`
+	Data          json.RawMessage `json:"data"`
+
+	// dataContentType is the codec Data is written with. Only JSON data can
+	// be embedded in a JSON envelope.
+	dataContentType string
+}
+
+// Upcaster rewrites an envelope from one schema version to the next. It may
+// change Data and fill in envelope fields the older version did not have.
+type Upcaster func(env *Envelope) error
+
+// upcasters is keyed by event type and the version an upcaster reads.
+var upcasters = map[string]map[int]Upcaster{
+	EventTypeTask:       {1: upcastLegacy},
+	EventTypeCompletion: {1: upcastLegacy},
+}
+
+// upcastLegacy turns a bare version 1 payload into version 2: the string
+// timestamp moves to the envelope time and the execution ID becomes the
+// correlation ID. Legacy events had no ID, so one is derived from the payload
+// to stay stable across redeliveries.
+func upcastLegacy(env *Envelope) error {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(env.Data, &data); err != nil {
+		return err
+	}
+
+	if raw, ok := data["timestamp"]; ok {
+		var ts string
+		if err := json.Unmarshal(raw, &ts); err == nil {
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				env.Time = t
+			}
+		}
+		delete(data, "timestamp")
+	}
+	if raw, ok := data["execution_id"]; ok {
+		_ = json.Unmarshal(raw, &env.CorrelationID)
+	}
+	if env.ID == "" {
+		env.ID = uuid.NewSHA1(uuid.NameSpaceOID, env.Data).String()
+	}
+
+	upgraded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	env.Data = upgraded
+	return nil
+}
+
+// newEnvelope wraps data as the current version of eventType. The event that
+// is being handled in ctx, if any, becomes the cause.
+func newEnvelope(ctx context.Context, eventType, source, correlationID string, codec Codec, data interface{}) (*Envelope, error) {
+	payload, err := codec.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &Envelope{
+		ID:            uuid.NewString(),
+		Type:          eventType,
+		SchemaVersion: currentVersions[eventType],
+		Source:        source,
+		Time:          time.Now().UTC(),
+		CorrelationID: correlationID,
+		Data:          payload,
+
+		dataContentType: codec.ContentType(),
+	}
+	if cause, ok := EnvelopeFromContext(ctx); ok {
+		env.CausationID = cause.ID
+	}
+	return env, nil
+}
+
+// decodeEnvelope reads msg as an event of eventType in any supported
+// encoding, upcasting older versions and unwrapping legacy payloads, and
+// decodes its data into out.
+func decodeEnvelope(msg *Message, eventType string, out interface{}) (*Envelope, error) {
+	env, err := parseMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	if env.SchemaVersion == 0 {
+		// No envelope: a version 1 payload.
+		env.Type = eventType
+		env.SchemaVersion = 1
+	}
+
+	if env.Type != eventType {
+		return nil, fmt.Errorf("unexpected event type %q, want %q", env.Type, eventType)
+	}
+
+	current := currentVersions[eventType]
+	if env.SchemaVersion > current {
+		return nil, fmt.Errorf("%w: %s version %d, newest known is %d", ErrUnsupportedVersion, eventType, env.SchemaVersion, current)
+	}
+	codec, err := codecFor(env.dataContentType)
+	if err != nil {
+		return nil, err
+	}
+	if env.SchemaVersion < current && codec != JSONCodec {
+		// Upcasters rewrite JSON; binary codecs arrived with the current
+		// version.
+		return nil, fmt.Errorf("cannot upcast %s version %d written as %s", eventType, env.SchemaVersion, env.dataContentType)
+	}
+	for env.SchemaVersion < current {
+		upcast, ok := upcasters[eventType][env.SchemaVersion]
+		if !ok {
+			return nil, fmt.Errorf("no upcaster for %s version %d", eventType, env.SchemaVersion)
+		}
+		if err := upcast(env); err != nil {
+			return nil, fmt.Errorf("upcasting %s version %d: %w", eventType, env.SchemaVersion, err)
+		}
+		env.SchemaVersion++
+	}
+
+	if err := codec.Unmarshal(env.Data, out); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+type envelopeKey struct{}
+
+// ContextWithEnvelope returns a context carrying the envelope of the event
+// being handled, so events published while handling it record it as their
+// cause.
+func ContextWithEnvelope(ctx context.Context, env *Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, env)
+}
+
+// EnvelopeFromContext returns the envelope of the event being handled.
+func EnvelopeFromContext(ctx context.Context) (*Envelope, bool) {
+	env, ok := ctx.Value(envelopeKey{}).(*Envelope)
+	return env, ok
+}
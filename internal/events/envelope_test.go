@@ -0,0 +1,118 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// legacyCompletion is a completion as published before events had an
+// envelope.
+const legacyCompletion = `{
+	"execution_id": "exec-1",
+	"workflow_id": 7,
+	"task_id": "task-1",
+	"attempt": 1,
+	"task_type": "generate_audio",
+	"step": 2,
+	"status": "completed",
+	"output": {"format": "wav"},
+	"timestamp": "2026-10-19T10:00:00Z"
+}`
+
+func TestDecodeEnvelopeUpcastsLegacyPayloads(t *testing.T) {
+	msg := &Message{Topic: "completions", Value: []byte(legacyCompletion)}
+
+	var completion CompletionEvent
+	env, err := decodeEnvelope(msg, EventTypeCompletion, &completion)
+	if err != nil {
+		t.Fatalf("decodeEnvelope: %v", err)
+	}
+	if env.SchemaVersion != CompletionEventVersion || env.Type != EventTypeCompletion {
+		t.Errorf("envelope = %s version %d, want %s version %d", env.Type, env.SchemaVersion, EventTypeCompletion, CompletionEventVersion)
+	}
+	if !env.Time.Equal(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)) || env.CorrelationID != "exec-1" {
+		t.Errorf("envelope time %s correlation %q, want the timestamp and execution ID", env.Time, env.CorrelationID)
+	}
+	if completion.TaskID != "task-1" || completion.Step != 2 || completion.Output["format"] != "wav" {
+		t.Errorf("completion = %+v, want the legacy payload", completion)
+	}
+
+	// Redeliveries of a legacy event keep its ID
+	again, err := decodeEnvelope(msg, EventTypeCompletion, &CompletionEvent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.ID == "" || again.ID != env.ID {
+		t.Errorf("IDs %q and %q, want one stable ID", env.ID, again.ID)
+	}
+}
+
+func TestDecodeEnvelopeUpcastsVersion1Envelopes(t *testing.T) {
+	value := `{"id": "evt-1", "type": "` + EventTypeCompletion + `", "schema_version": 1, "source": "worker", "data": ` + legacyCompletion + `}`
+	msg := &Message{Topic: "completions", Value: []byte(value)}
+
+	var completion CompletionEvent
+	env, err := decodeEnvelope(msg, EventTypeCompletion, &completion)
+	if err != nil {
+		t.Fatalf("decodeEnvelope: %v", err)
+	}
+	if env.ID != "evt-1" || env.Source != "worker" || env.SchemaVersion != CompletionEventVersion {
+		t.Errorf("envelope = %s from %q version %d, want evt-1 from worker upcast to %d", env.ID, env.Source, env.SchemaVersion, CompletionEventVersion)
+	}
+	if !env.Time.Equal(time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)) || env.CorrelationID != "exec-1" {
+		t.Errorf("envelope time %s correlation %q, want them moved out of the data", env.Time, env.CorrelationID)
+	}
+	if completion.TaskID != "task-1" {
+		t.Errorf("completion = %+v, want the enveloped payload", completion)
+	}
+}
+
+func TestDecodeEnvelopeRejectsFutureVersions(t *testing.T) {
+	value := `{"id": "evt-1", "type": "` + EventTypeCompletion + `", "schema_version": 3, "data": {"task_id": "task-1"}}`
+	msg := &Message{Topic: "completions", Value: []byte(value)}
+
+	if _, err := decodeEnvelope(msg, EventTypeCompletion, &CompletionEvent{}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("decodeEnvelope = %v, want %v", err, ErrUnsupportedVersion)
+	}
+}
+
+func TestDecodeEnvelopeRejectsOtherEventTypes(t *testing.T) {
+	msg, err := NewEventProducer(NewMemoryBroker(1), time.Second, testLog).completionMessage(context.Background(), "completions", sampleCompletion())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeEnvelope(msg, EventTypeTask, &TaskEvent{}); err == nil {
+		t.Error("decoded a completion as a task")
+	}
+}
+
+func TestConsumerDeadLettersFutureVersions(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	handle := newTestConsumer(t, broker, func(ctx context.Context, event *CompletionEvent) error {
+		t.Error("handler ran for an event of a newer schema")
+		return nil
+	})
+
+	value := `{"id": "evt-1", "type": "` + EventTypeCompletion + `", "schema_version": 3, "data": {"task_id": "task-1"}}`
+	if err := handle(context.Background(), &Message{Topic: "completions", Key: []byte("exec-1"), Value: []byte(value)}); err != nil {
+		t.Fatalf("handle = %v, want the event acknowledged once dead lettered", err)
+	}
+	if dead := broker.Messages("dlq"); len(dead) != 1 || string(dead[0].Value) != value {
+		t.Errorf("dead letters = %+v, want the event of the newer schema", dead)
+	}
+}
+
+func TestNewEnvelopeRecordsCause(t *testing.T) {
+	cause := &Envelope{ID: "evt-cause"}
+	ctx := ContextWithEnvelope(context.Background(), cause)
+
+	env, err := newEnvelope(ctx, EventTypeTask, "orchestrator", "exec-1", JSONCodec, sampleTask())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.CausationID != "evt-cause" || env.CorrelationID != "exec-1" || env.SchemaVersion != TaskEventVersion {
+		t.Errorf("envelope = caused by %q, correlated with %q, version %d; want evt-cause, exec-1, %d", env.CausationID, env.CorrelationID, env.SchemaVersion, TaskEventVersion)
+	}
+}
@@ -0,0 +1,434 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/rs/zerolog"
+)
+
+const (
+	pollTimeoutMs        = 100
+	commitInterval       = time.Second
+	workerQueueSize      = 64
+	revokeDrainTimeout   = 10 * time.Second
+	redeliveryBackoff    = 200 * time.Millisecond
+	maxRedeliveryBackoff = 30 * time.Second
+)
+
+// KafkaPublisher is the Publisher backed by a confluent-kafka-go producer.
+type KafkaPublisher struct {
+	producer *kafka.Producer
+	logger   zerolog.Logger
+}
+
+func NewKafkaPublisher(producer *kafka.Producer, logCfg logger.Config) *KafkaPublisher {
+	return &KafkaPublisher{
+		producer: producer,
+		logger:   logger.New(logCfg),
+	}
+}
+
+// Publish hands msg to librdkafka with a private delivery channel and blocks
+// on its report. The channel is buffered so a report that arrives after we
+// gave up does not block the producer.
+func (p *KafkaPublisher) Publish(ctx context.Context, msg *Message) (DeliveryReport, error) {
+	deliveryChan := make(chan kafka.Event, 1)
+	if err := p.producer.Produce(toKafkaMessage(msg), deliveryChan); err != nil {
+		return DeliveryReport{}, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return DeliveryReport{}, fmt.Errorf("waiting for delivery report: %w", ctx.Err())
+	case ev := <-deliveryChan:
+		report := toDeliveryReport(ev)
+		return report, report.Err
+	}
+}
+
+func (p *KafkaPublisher) PublishAsync(msg *Message, done DeliveryCallback) error {
+	deliveryChan := make(chan kafka.Event, 1)
+	if err := p.producer.Produce(toKafkaMessage(msg), deliveryChan); err != nil {
+		return err
+	}
+
+	go func() {
+		report := toDeliveryReport(<-deliveryChan)
+		if report.Err != nil {
+			p.logger.Error().Err(report.Err).Str("topic", report.Topic).Msg("Failed to deliver message")
+		}
+		if done != nil {
+			done(report)
+		}
+	}()
+	return nil
+}
+
+// KafkaSubscriber polls Kafka and fans messages out to a pool of workers.
+// Messages with the same key (the execution ID) always land on the same
+// worker, so events of one execution are handled in order while different
+// executions proceed in parallel. Offsets are committed per partition up to
+// the last contiguous finished message, so messages the handler rejects are
+// redelivered in place until it accepts them.
+type KafkaSubscriber struct {
+	consumer    *kafka.Consumer
+	concurrency int
+	redelivery  redelivery
+	logger      zerolog.Logger
+
+	mu       sync.Mutex
+	trackers map[partitionKey]*offsetTracker
+}
+
+// NewKafkaSubscriber creates a subscriber that processes up to concurrency
+// messages at a time. The consumer must be created with
+// go.application.rebalance.enable so rebalances arrive through Poll.
+func NewKafkaSubscriber(consumer *kafka.Consumer, concurrency int, logCfg logger.Config) *KafkaSubscriber {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	log := logger.New(logCfg)
+	return &KafkaSubscriber{
+		consumer:    consumer,
+		concurrency: concurrency,
+		redelivery: redelivery{
+			backoff:    redeliveryBackoff,
+			maxBackoff: maxRedeliveryBackoff,
+			logger:     log,
+		},
+		logger:   log,
+		trackers: make(map[partitionKey]*offsetTracker),
+	}
+}
+
+// SetDeadLetterQueue makes the subscriber forward messages the handler
+// rejected attempts times in a row to topic and acknowledge them, instead of
+// redelivering them until they are accepted.
+func (s *KafkaSubscriber) SetDeadLetterQueue(producer *EventProducer, topic string, attempts int) {
+	s.redelivery.dlq = producer
+	s.redelivery.dlqTopic = topic
+	s.redelivery.attempts = attempts
+}
+
+// Subscribe is the poll loop. It returns when ctx is cancelled or Kafka
+// reports a fatal error, after the workers have drained and the finished
+// offsets have been committed.
+func (s *KafkaSubscriber) Subscribe(ctx context.Context, topics []string, handler MessageHandler) error {
+	if err := s.consumer.SubscribeTopics(topics, nil); err != nil {
+		return fmt.Errorf("failed to subscribe to topics: %w", err)
+	}
+
+	s.mu.Lock()
+	s.trackers = make(map[partitionKey]*offsetTracker)
+	s.mu.Unlock()
+
+	pool := newKeyedPool(s.concurrency, workerQueueSize)
+	shutdown := func() {
+		pool.close()
+		s.commit()
+	}
+
+	ticker := time.NewTicker(commitInterval)
+	defer ticker.Stop()
+
+	s.logger.Info().Strs("topics", topics).Int("concurrency", s.concurrency).Msg("Kafka subscriber started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info().Msg("Context cancelled, stopping consumer")
+			shutdown()
+			return ctx.Err()
+		case <-ticker.C:
+			s.commit()
+		default:
+		}
+
+		switch ev := s.consumer.Poll(pollTimeoutMs).(type) {
+		case nil:
+			// poll timed out, loop to check ctx and commits
+		case *kafka.Message:
+			s.track(ev)
+			km := ev
+			pool.submit(ctx, shardKey(km), func() {
+				// Work interrupted by shutdown or a revocation is left
+				// uncommitted so it is redelivered.
+				owned := func() bool { return s.tracked(km) }
+				if s.redelivery.run(ctx, handler, fromKafkaMessage(km), owned) {
+					s.complete(km)
+				}
+			})
+		case kafka.AssignedPartitions:
+			s.assign(ev.Partitions)
+		case kafka.RevokedPartitions:
+			s.revoke(ev.Partitions)
+		case kafka.Error:
+			s.logger.Error().Err(ev).Msg("Error reading message from Kafka")
+			if ev.IsFatal() {
+				shutdown()
+				return ev
+			}
+		default:
+			s.logger.Debug().Str("event", ev.String()).Msg("Ignoring Kafka event")
+		}
+	}
+}
+
+// shardKey orders messages by key, falling back to the partition for
+// messages without one.
+func shardKey(msg *kafka.Message) []byte {
+	if len(msg.Key) > 0 {
+		return msg.Key
+	}
+	return []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))
+}
+
+func (s *KafkaSubscriber) track(msg *kafka.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := keyOf(msg.TopicPartition)
+	tracker, ok := s.trackers[key]
+	if !ok {
+		tracker = newOffsetTracker()
+		s.trackers[key] = tracker
+	}
+	tracker.add(int64(msg.TopicPartition.Offset))
+}
+
+// tracked reports whether the partition of msg is still assigned to us.
+func (s *KafkaSubscriber) tracked(msg *kafka.Message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.trackers[keyOf(msg.TopicPartition)]
+	return ok
+}
+
+func (s *KafkaSubscriber) complete(msg *kafka.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The partition may have been revoked while the message was in flight.
+	if tracker, ok := s.trackers[keyOf(msg.TopicPartition)]; ok {
+		tracker.complete(int64(msg.TopicPartition.Offset))
+	}
+}
+
+// commit stores the committable offset of every partition that advanced.
+func (s *KafkaSubscriber) commit() {
+	s.mu.Lock()
+	var offsets []kafka.TopicPartition
+	for key, tracker := range s.trackers {
+		if !tracker.dirty {
+			continue
+		}
+		topic := key.topic
+		offsets = append(offsets, kafka.TopicPartition{
+			Topic:     &topic,
+			Partition: key.partition,
+			Offset:    tracker.next,
+		})
+		tracker.dirty = false
+	}
+	s.mu.Unlock()
+
+	if len(offsets) == 0 {
+		return
+	}
+
+	if _, err := s.consumer.CommitOffsets(offsets); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to commit offsets")
+
+		s.mu.Lock()
+		for _, tp := range offsets {
+			if tracker, ok := s.trackers[keyOf(tp)]; ok {
+				tracker.dirty = true
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *KafkaSubscriber) assign(partitions []kafka.TopicPartition) {
+	s.logger.Info().Int("partitions", len(partitions)).Msg("Partitions assigned")
+
+	s.mu.Lock()
+	for _, tp := range partitions {
+		s.trackers[keyOf(tp)] = newOffsetTracker()
+	}
+	s.mu.Unlock()
+
+	var err error
+	if s.consumer.GetRebalanceProtocol() == "COOPERATIVE" {
+		err = s.consumer.IncrementalAssign(partitions)
+	} else {
+		err = s.consumer.Assign(partitions)
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to assign partitions")
+	}
+}
+
+// revoke gives in-flight messages of the revoked partitions a chance to
+// finish, commits what completed and forgets the partitions. Anything still
+// running afterwards will be redelivered to the new owner.
+func (s *KafkaSubscriber) revoke(partitions []kafka.TopicPartition) {
+	s.logger.Info().Int("partitions", len(partitions)).Msg("Partitions revoked, draining in-flight events")
+
+	deadline := time.Now().Add(revokeDrainTimeout)
+	for time.Now().Before(deadline) && s.pending(partitions) > 0 {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if n := s.pending(partitions); n > 0 {
+		s.logger.Warn().Int("pending", n).Msg("Revoking partitions with unfinished events")
+	}
+
+	s.commit()
+
+	s.mu.Lock()
+	for _, tp := range partitions {
+		delete(s.trackers, keyOf(tp))
+	}
+	s.mu.Unlock()
+
+	var err error
+	if s.consumer.GetRebalanceProtocol() == "COOPERATIVE" {
+		err = s.consumer.IncrementalUnassign(partitions)
+	} else {
+		err = s.consumer.Unassign()
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Failed to unassign partitions")
+	}
+}
+
+func (s *KafkaSubscriber) pending(partitions []kafka.TopicPartition) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, tp := range partitions {
+		if tracker, ok := s.trackers[keyOf(tp)]; ok {
+			n += tracker.pending()
+		}
+	}
+	return n
+}
+
+// redelivery hands a message to a handler until it is acknowledged.
+// Failures are retried with exponential backoff and, with a dead letter queue,
+// forwarded to it after attempts failures.
+type redelivery struct {
+	backoff    time.Duration
+	maxBackoff time.Duration
+	dlq        *EventProducer
+	dlqTopic   string
+	attempts   int
+	logger     zerolog.Logger
+}
+
+// run reports whether msg was acknowledged, by the handler or the dead letter
+// queue. It gives up when ctx is done or owned reports that the message
+// belongs to another consumer now.
+func (r *redelivery) run(ctx context.Context, handler MessageHandler, msg *Message, owned func() bool) bool {
+	backoff := r.backoff
+	for attempt := 1; ; attempt++ {
+		delivered := *msg
+		err := handler(ctx, &delivered)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		r.logger.Warn().
+			Err(err).
+			Str("topic", msg.Topic).
+			Str("key", string(msg.Key)).
+			Int64("offset", msg.Offset).
+			Int("attempt", attempt).
+			Msg("Event rejected by handler, redelivering")
+
+		if r.dlq != nil && attempt >= r.attempts {
+			if r.dlq.PublishDeadLetter(ctx, r.dlqTopic, msg, err.Error()) == nil {
+				return true
+			}
+			// Keep the message until the DLQ is reachable again
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return false
+		}
+		backoff = min(2*backoff, r.maxBackoff)
+
+		if !owned() {
+			r.logger.Info().
+				Str("topic", msg.Topic).
+				Int64("offset", msg.Offset).
+				Msg("Partition revoked, leaving event to its new owner")
+			return false
+		}
+	}
+}
+
+func toKafkaMessage(msg *Message) *kafka.Message {
+	topic := msg.Topic
+	km := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{
+			Topic:     &topic,
+			Partition: kafka.PartitionAny,
+		},
+		Key:   msg.Key,
+		Value: msg.Value,
+	}
+	for k, v := range msg.Headers {
+		km.Headers = append(km.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return km
+}
+
+func fromKafkaMessage(km *kafka.Message) *Message {
+	msg := &Message{
+		Key:       km.Key,
+		Value:     km.Value,
+		Partition: km.TopicPartition.Partition,
+		Offset:    int64(km.TopicPartition.Offset),
+	}
+	if km.TopicPartition.Topic != nil {
+		msg.Topic = *km.TopicPartition.Topic
+	}
+	if len(km.Headers) > 0 {
+		msg.Headers = make(map[string]string, len(km.Headers))
+		for _, h := range km.Headers {
+			msg.Headers[h.Key] = string(h.Value)
+		}
+	}
+	return msg
+}
+
+func toDeliveryReport(ev kafka.Event) DeliveryReport {
+	m, ok := ev.(*kafka.Message)
+	if !ok {
+		return DeliveryReport{Err: fmt.Errorf("unexpected delivery event: %v", ev)}
+	}
+
+	report := DeliveryReport{
+		Partition: m.TopicPartition.Partition,
+		Offset:    int64(m.TopicPartition.Offset),
+		Err:       m.TopicPartition.Error,
+	}
+	if m.TopicPartition.Topic != nil {
+		report.Topic = *m.TopicPartition.Topic
+	}
+	return report
+}
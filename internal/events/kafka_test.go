@@ -0,0 +1,144 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/rs/zerolog"
+)
+
+var quietLog = logger.Config{Level: "error"}
+
+func testRedelivery() redelivery {
+	return redelivery{
+		backoff:    time.Millisecond,
+		maxBackoff: 4 * time.Millisecond,
+		logger:     zerolog.Nop(),
+	}
+}
+
+func owned() bool { return true }
+
+func TestRedeliveryRetriesUntilAccepted(t *testing.T) {
+	r := testRedelivery()
+	msg := &Message{Topic: "completions", Key: []byte("exec-1"), Value: []byte("{}")}
+
+	calls := 0
+	handler := func(ctx context.Context, m *Message) error {
+		calls++
+		if string(m.Key) != "exec-1" {
+			t.Errorf("handler got key %q", m.Key)
+		}
+		if calls < 4 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	}
+
+	if !r.run(context.Background(), handler, msg, owned) {
+		t.Fatal("run = false, want the message acknowledged once the handler accepts it")
+	}
+	if calls != 4 {
+		t.Errorf("handler called %d times, want 4", calls)
+	}
+}
+
+func TestRedeliveryDeadLetters(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	r := testRedelivery()
+	r.dlq = NewEventProducer(broker, time.Second, quietLog)
+	r.dlqTopic = "dlq"
+	r.attempts = 3
+
+	msg := &Message{
+		Topic:   "file.uploaded",
+		Key:     []byte("f1"),
+		Value:   []byte(`{"url":"s3://x"}`),
+		Headers: map[string]string{"ce_type": "file.uploaded"},
+	}
+	calls := 0
+	handler := func(ctx context.Context, m *Message) error {
+		calls++
+		return errors.New("poison")
+	}
+
+	if !r.run(context.Background(), handler, msg, owned) {
+		t.Fatal("run = false, want the message acknowledged after dead lettering")
+	}
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+
+	dead := broker.Messages("dlq")
+	if len(dead) != 1 {
+		t.Fatalf("%d dead letters, want 1", len(dead))
+	}
+	got := dead[0]
+	if string(got.Key) != "f1" || string(got.Value) != `{"url":"s3://x"}` {
+		t.Errorf("dead letter = %q/%q, want the original key and value", got.Key, got.Value)
+	}
+	for header, want := range map[string]string{
+		"dlq-reason":       "poison",
+		"dlq-source-topic": "file.uploaded",
+		"ce_type":          "file.uploaded",
+	} {
+		if got.Headers[header] != want {
+			t.Errorf("header %s = %q, want %q", header, got.Headers[header], want)
+		}
+	}
+}
+
+func TestRedeliveryGivesUp(t *testing.T) {
+	failing := func(ctx context.Context, m *Message) error { return errors.New("boom") }
+	msg := &Message{Topic: "completions", Key: []byte("exec-1")}
+
+	t.Run("cancelled", func(t *testing.T) {
+		r := testRedelivery()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if r.run(ctx, failing, msg, owned) {
+			t.Error("run = true after the context was cancelled, want the message left unacknowledged")
+		}
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		r := testRedelivery()
+		calls := 0
+		handler := func(ctx context.Context, m *Message) error {
+			calls++
+			return errors.New("boom")
+		}
+		if r.run(context.Background(), handler, msg, func() bool { return false }) {
+			t.Error("run = true after the partition was revoked, want the message left to its new owner")
+		}
+		if calls != 1 {
+			t.Errorf("handler called %d times, want 1", calls)
+		}
+	})
+
+	t.Run("dead letter queue unreachable", func(t *testing.T) {
+		r := testRedelivery()
+		r.dlq = NewEventProducer(failingPublisher{}, time.Second, quietLog)
+		r.dlqTopic = "dlq"
+		r.attempts = 1
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if r.run(ctx, failing, msg, owned) {
+			t.Error("run = true although the dead letter was not published")
+		}
+	})
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, msg *Message) (DeliveryReport, error) {
+	return DeliveryReport{}, errors.New("broker down")
+}
+
+func (failingPublisher) PublishAsync(msg *Message, done DeliveryCallback) error {
+	return errors.New("broker down")
+}
@@ -0,0 +1,244 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// MemoryBroker is an in-process Publisher and Subscriber source with Kafka
+// like semantics: topics are split into partitions by key, every message gets
+// an offset, and consumer groups commit offsets per partition. A handler error
+// redelivers the message, as does a subscriber stopping before it
+// acknowledged. It is meant for tests and single-binary demos.
+type MemoryBroker struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	partitions int
+	topics     map[string]*memoryTopic
+}
+
+type memoryTopic struct {
+	partitions [][]*Message
+	// committed and owners are keyed by consumer group, one entry per partition
+	committed map[string][]int64
+	owners    map[string][]bool
+}
+
+// NewMemoryBroker creates a broker whose topics have the given number of
+// partitions.
+func NewMemoryBroker(partitions int) *MemoryBroker {
+	if partitions < 1 {
+		partitions = 1
+	}
+	b := &MemoryBroker{
+		partitions: partitions,
+		topics:     make(map[string]*memoryTopic),
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// topic returns the named topic, creating it on first use. Callers hold b.mu.
+func (b *MemoryBroker) topic(name string) *memoryTopic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &memoryTopic{
+			partitions: make([][]*Message, b.partitions),
+			committed:  make(map[string][]int64),
+			owners:     make(map[string][]bool),
+		}
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (t *memoryTopic) group(name string) ([]int64, []bool) {
+	if _, ok := t.committed[name]; !ok {
+		t.committed[name] = make([]int64, len(t.partitions))
+		t.owners[name] = make([]bool, len(t.partitions))
+	}
+	return t.committed[name], t.owners[name]
+}
+
+func (b *MemoryBroker) partitionFor(key []byte) int32 {
+	if len(key) == 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	return int32(h.Sum32() % uint32(b.partitions))
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, msg *Message) (DeliveryReport, error) {
+	if err := ctx.Err(); err != nil {
+		return DeliveryReport{}, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(msg.Topic)
+	partition := b.partitionFor(msg.Key)
+
+	stored := *msg
+	stored.Partition = partition
+	stored.Offset = int64(len(t.partitions[partition]))
+	t.partitions[partition] = append(t.partitions[partition], &stored)
+	b.cond.Broadcast()
+
+	return DeliveryReport{
+		Topic:     msg.Topic,
+		Partition: partition,
+		Offset:    stored.Offset,
+	}, nil
+}
+
+func (b *MemoryBroker) PublishAsync(msg *Message, done DeliveryCallback) error {
+	report, err := b.Publish(context.Background(), msg)
+	if err != nil {
+		return err
+	}
+	if done != nil {
+		go done(report)
+	}
+	return nil
+}
+
+// Messages returns a copy of everything published to topic, in partition and
+// offset order.
+func (b *MemoryBroker) Messages(topic string) []*Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var all []*Message
+	if t, ok := b.topics[topic]; ok {
+		for _, p := range t.partitions {
+			all = append(all, p...)
+		}
+	}
+	return all
+}
+
+// Committed returns the next offset group will read from a partition.
+func (b *MemoryBroker) Committed(group, topic string, partition int32) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	committed, _ := b.topic(topic).group(group)
+	return committed[partition]
+}
+
+// Lag returns how many messages of topic group has not acknowledged yet.
+func (b *MemoryBroker) Lag(group, topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topic)
+	committed, _ := t.group(group)
+
+	var lag int64
+	for i, p := range t.partitions {
+		lag += int64(len(p)) - committed[i]
+	}
+	return lag
+}
+
+// Subscriber returns a member of the given consumer group. Members of the same
+// group share partitions: each partition is consumed by one member at a time
+// and taken over by another when its owner stops.
+func (b *MemoryBroker) Subscriber(group string) *MemorySubscriber {
+	return &MemorySubscriber{
+		broker:          b,
+		group:           group,
+		redeliveryDelay: 50 * time.Millisecond,
+	}
+}
+
+type MemorySubscriber struct {
+	broker          *MemoryBroker
+	group           string
+	redeliveryDelay time.Duration
+}
+
+// Subscribe consumes every partition of topics with one goroutine per
+// partition, so ordering holds per key as it does on Kafka.
+func (s *MemorySubscriber) Subscribe(ctx context.Context, topics []string, handler MessageHandler) error {
+	if len(topics) == 0 {
+		return fmt.Errorf("no topics to subscribe to")
+	}
+
+	// sync.Cond cannot wait on a context, so wake everybody up on cancel.
+	stop := context.AfterFunc(ctx, func() {
+		s.broker.mu.Lock()
+		s.broker.cond.Broadcast()
+		s.broker.mu.Unlock()
+	})
+	defer stop()
+
+	var wg sync.WaitGroup
+	for _, topic := range topics {
+		for p := 0; p < s.broker.partitions; p++ {
+			wg.Add(1)
+			go func(topic string, partition int) {
+				defer wg.Done()
+				s.consumePartition(ctx, topic, partition, handler)
+			}(topic, p)
+		}
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (s *MemorySubscriber) consumePartition(ctx context.Context, topic string, partition int, handler MessageHandler) {
+	b := s.broker
+
+	b.mu.Lock()
+	t := b.topic(topic)
+	committed, owners := t.group(s.group)
+	for owners[partition] && ctx.Err() == nil {
+		b.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		b.mu.Unlock()
+		return
+	}
+	owners[partition] = true
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		owners[partition] = false
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	}()
+
+	for {
+		b.mu.Lock()
+		for committed[partition] >= int64(len(t.partitions[partition])) && ctx.Err() == nil {
+			b.cond.Wait()
+		}
+		if ctx.Err() != nil {
+			b.mu.Unlock()
+			return
+		}
+		stored := *t.partitions[partition][committed[partition]]
+		b.mu.Unlock()
+
+		if err := handler(ctx, &stored); err != nil {
+			select {
+			case <-time.After(s.redeliveryDelay):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		b.mu.Lock()
+		committed[partition] = stored.Offset + 1
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	}
+}
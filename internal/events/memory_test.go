@@ -0,0 +1,149 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collect subscribes to topic until want messages were acknowledged and
+// returns them in the order the handler accepted them.
+func collect(t *testing.T, sub *MemorySubscriber, topic string, want int, handler MessageHandler) []*Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		seen []*Message
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- sub.Subscribe(ctx, []string{topic}, func(ctx context.Context, msg *Message) error {
+			if handler != nil {
+				if err := handler(ctx, msg); err != nil {
+					return err
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append(seen, msg)
+			if len(seen) == want {
+				cancel()
+			}
+			return nil
+		})
+	}()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Subscribe = %v, want it to stop on cancel", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != want {
+		t.Fatalf("got %d messages, want %d", len(seen), want)
+	}
+	return seen
+}
+
+func publish(t *testing.T, broker *MemoryBroker, topic, key string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		msg := &Message{Topic: topic, Key: []byte(key), Value: []byte(fmt.Sprintf("%s-%d", key, i))}
+		if _, err := broker.Publish(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMemoryBrokerPublishAssignsOffsetsPerPartition(t *testing.T) {
+	broker := NewMemoryBroker(4)
+	ctx := context.Background()
+
+	first, err := broker.Publish(ctx, &Message{Topic: "tasks", Key: []byte("exec-1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := broker.Publish(ctx, &Message{Topic: "tasks", Key: []byte("exec-1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Partition != second.Partition {
+		t.Errorf("same key went to partitions %d and %d", first.Partition, second.Partition)
+	}
+	if first.Offset != 0 || second.Offset != 1 {
+		t.Errorf("offsets = %d, %d, want 0, 1", first.Offset, second.Offset)
+	}
+	if got := broker.Lag("workers", "tasks"); got != 2 {
+		t.Errorf("lag = %d, want 2", got)
+	}
+}
+
+func TestMemoryBrokerDeliversInOrderAndCommits(t *testing.T) {
+	broker := NewMemoryBroker(4)
+	publish(t, broker, "tasks", "exec-1", 5)
+	publish(t, broker, "tasks", "exec-2", 5)
+
+	seen := collect(t, broker.Subscriber("workers"), "tasks", 10, nil)
+
+	next := map[string]int{}
+	for _, msg := range seen {
+		key := string(msg.Key)
+		if want := fmt.Sprintf("%s-%d", key, next[key]); string(msg.Value) != want {
+			t.Errorf("got %s, want %s: messages of a key must arrive in order", msg.Value, want)
+		}
+		next[key]++
+	}
+	if got := broker.Lag("workers", "tasks"); got != 0 {
+		t.Errorf("lag = %d after acknowledging everything, want 0", got)
+	}
+
+	// Another group reads the topic from the start
+	if got := broker.Lag("auditors", "tasks"); got != 10 {
+		t.Errorf("lag of a new group = %d, want 10", got)
+	}
+}
+
+func TestMemoryBrokerRedeliversRejectedMessages(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	publish(t, broker, "completions", "exec-1", 2)
+
+	attempts := map[string]int{}
+	var mu sync.Mutex
+	seen := collect(t, broker.Subscriber("orchestrator"), "completions", 2, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[string(msg.Value)]++
+		if string(msg.Value) == "exec-1-0" && attempts["exec-1-0"] == 1 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+
+	if attempts["exec-1-0"] != 2 {
+		t.Errorf("rejected message delivered %d times, want 2", attempts["exec-1-0"])
+	}
+	if string(seen[0].Value) != "exec-1-0" || string(seen[1].Value) != "exec-1-1" {
+		t.Errorf("got %s, %s: a rejected message must block those behind it", seen[0].Value, seen[1].Value)
+	}
+	if got := broker.Committed("orchestrator", "completions", 0); got != 2 {
+		t.Errorf("committed = %d, want 2", got)
+	}
+}
+
+func TestMemoryBrokerGroupResumesFromCommittedOffset(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	publish(t, broker, "tasks", "exec-1", 3)
+	collect(t, broker.Subscriber("workers"), "tasks", 3, nil)
+
+	publish(t, broker, "tasks", "exec-1", 2)
+
+	// A new member of the group only sees what was not acknowledged yet
+	seen := collect(t, broker.Subscriber("workers"), "tasks", 2, nil)
+	if seen[0].Offset != 3 || seen[1].Offset != 4 {
+		t.Errorf("offsets = %d, %d, want 3, 4", seen[0].Offset, seen[1].Offset)
+	}
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+func keyOf(tp kafka.TopicPartition) partitionKey {
+	var topic string
+	if tp.Topic != nil {
+		topic = *tp.Topic
+	}
+	return partitionKey{topic: topic, partition: tp.Partition}
+}
+
+// offsetTracker follows the in-flight messages of one partition. Messages may
+// finish out of order, but only the contiguous prefix of finished offsets is
+// safe to commit, otherwise a crash would skip unfinished messages.
+type offsetTracker struct {
+	inflight []int64 // in arrival order, which is offset order within a partition
+	done     map[int64]bool
+	next     kafka.Offset // offset to commit: one past the last finished prefix
+	dirty    bool
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		done: make(map[int64]bool),
+		next: kafka.OffsetInvalid,
+	}
+}
+
+func (t *offsetTracker) add(offset int64) {
+	t.inflight = append(t.inflight, offset)
+}
+
+func (t *offsetTracker) complete(offset int64) {
+	t.done[offset] = true
+
+	for len(t.inflight) > 0 && t.done[t.inflight[0]] {
+		head := t.inflight[0]
+		delete(t.done, head)
+		t.inflight = t.inflight[1:]
+		t.next = kafka.Offset(head + 1)
+		t.dirty = true
+	}
+}
+
+func (t *offsetTracker) pending() int {
+	return len(t.inflight)
+}
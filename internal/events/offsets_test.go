@@ -0,0 +1,38 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func TestOffsetTrackerCommitsContiguousPrefix(t *testing.T) {
+	tracker := newOffsetTracker()
+	for offset := int64(10); offset < 14; offset++ {
+		tracker.add(offset)
+	}
+
+	steps := []struct {
+		complete int64
+		next     kafka.Offset
+		pending  int
+	}{
+		// Finishing out of order does not move past unfinished messages
+		{complete: 12, next: kafka.OffsetInvalid, pending: 4},
+		{complete: 10, next: 11, pending: 3},
+		{complete: 11, next: 13, pending: 1},
+		{complete: 13, next: 14, pending: 0},
+	}
+	for _, step := range steps {
+		tracker.complete(step.complete)
+		if tracker.next != step.next {
+			t.Errorf("after completing %d: next = %v, want %v", step.complete, tracker.next, step.next)
+		}
+		if got := tracker.pending(); got != step.pending {
+			t.Errorf("after completing %d: pending = %d, want %d", step.complete, got, step.pending)
+		}
+	}
+	if !tracker.dirty {
+		t.Error("tracker not dirty after advancing")
+	}
+}
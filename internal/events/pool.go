@@ -0,0 +1,56 @@
+package events
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// keyedPool runs jobs on a fixed set of goroutines. Jobs submitted with the
+// same key always run on the same goroutine, so they execute in submission
+// order while jobs with different keys run in parallel.
+type keyedPool struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+}
+
+func newKeyedPool(size, queueSize int) *keyedPool {
+	if size < 1 {
+		size = 1
+	}
+	p := &keyedPool{queues: make([]chan func(), size)}
+	for i := range p.queues {
+		p.queues[i] = make(chan func(), queueSize)
+		p.wg.Add(1)
+		go func(queue <-chan func()) {
+			defer p.wg.Done()
+			for job := range queue {
+				job()
+			}
+		}(p.queues[i])
+	}
+	return p
+}
+
+// submit queues job behind earlier jobs with the same key. It blocks while
+// that goroutine's queue is full and gives up, returning false, if ctx ends.
+func (p *keyedPool) submit(ctx context.Context, key []byte, job func()) bool {
+	h := fnv.New32a()
+	h.Write(key)
+	queue := p.queues[h.Sum32()%uint32(len(p.queues))]
+
+	select {
+	case queue <- job:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// close stops accepting jobs and waits for the queued ones to finish.
+func (p *keyedPool) close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
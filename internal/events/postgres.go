@@ -0,0 +1,556 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// queueChannel is the LISTEN/NOTIFY channel; the payload is the topic that
+// received a message.
+const queueChannel = "async_queue"
+
+const (
+	queueTableTasks    = "tasks"
+	queueTableMessages = "queue_messages"
+)
+
+// PostgresPublisher is the Publisher of the postgres transport. Task events
+// are written onto their existing row in the tasks table, so a task and its
+// queue entry are the same record; every other event is inserted into
+// queue_messages. Both wake listening subscribers with NOTIFY on commit.
+type PostgresPublisher struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewPostgresPublisher(db *gorm.DB, logCfg logger.Config) *PostgresPublisher {
+	return &PostgresPublisher{
+		db:     db,
+		logger: logger.New(logCfg),
+	}
+}
+
+// Publish stores msg and reports the row ID as its offset. The message is
+// durable once the transaction commits; published from a handler of a
+// transactional subscriber, that is the transaction of the handler.
+func (p *PostgresPublisher) Publish(ctx context.Context, msg *Message) (DeliveryReport, error) {
+	var headers []byte
+	if len(msg.Headers) > 0 {
+		var err error
+		if headers, err = json.Marshal(msg.Headers); err != nil {
+			return DeliveryReport{}, fmt.Errorf("failed to encode headers: %w", err)
+		}
+	}
+
+	var id uint64
+	err := database.Conn(ctx, p.db).Transaction(func(tx *gorm.DB) error {
+		if taskID := msg.Headers[HeaderTaskID]; taskID != "" {
+			var ids []uint64
+			err := tx.Raw(`
+				UPDATE tasks
+				SET topic = ?, message_key = ?, message = ?, message_headers = ?,
+				    timeout_at = NULL, claimed_by = NULL, updated_at = now()
+				WHERE task_id = ? AND status IN ?
+				RETURNING id`,
+				msg.Topic, msg.Key, msg.Value, headers,
+				taskID, []string{models.TaskStatusPending, models.TaskStatusDispatched},
+			).Scan(&ids).Error
+			if err != nil {
+				return err
+			}
+			if len(ids) == 1 {
+				id = ids[0]
+				return notify(tx, msg.Topic)
+			}
+		}
+
+		err := tx.Raw(`
+			INSERT INTO queue_messages (topic, message_key, message, message_headers)
+			VALUES (?, ?, ?, ?)
+			RETURNING id`,
+			msg.Topic, msg.Key, msg.Value, headers,
+		).Scan(&id).Error
+		if err != nil {
+			return err
+		}
+		return notify(tx, msg.Topic)
+	})
+
+	report := DeliveryReport{Topic: msg.Topic, Offset: int64(id), Err: err}
+	return report, err
+}
+
+func (p *PostgresPublisher) PublishAsync(msg *Message, done DeliveryCallback) error {
+	go func() {
+		report, err := p.Publish(context.Background(), msg)
+		if err != nil {
+			p.logger.Error().Err(err).Str("topic", msg.Topic).Msg("Failed to deliver message")
+		}
+		if done != nil {
+			done(report)
+		}
+	}()
+	return nil
+}
+
+func notify(tx *gorm.DB, topic string) error {
+	return tx.Exec("SELECT pg_notify(?, ?)", queueChannel, topic).Error
+}
+
+// queueRef identifies a claimed row and the delivery that claimed it, so a
+// late acknowledgement cannot touch a row somebody else has claimed since.
+type queueRef struct {
+	table      string
+	id         uint64
+	deliveries uint
+}
+
+type claimedRow struct {
+	ID             uint64
+	Topic          string
+	MessageKey     []byte
+	Message        []byte
+	MessageHeaders []byte
+	Deliveries     uint
+}
+
+// PostgresSubscriber claims messages with SELECT ... FOR UPDATE SKIP LOCKED,
+// so any number of consumers can share topics without handing out a row
+// twice. A claim hides the row for visibilityTimeout; the lease is extended
+// while the handler runs and the row becomes visible again if the consumer
+// dies. Consumers wake up on NOTIFY and fall back to polling every
+// pollInterval.
+//
+// Delivery is at least once: by default a message is acknowledged in a
+// statement of its own after the handler returned, so a crash in between
+// hands it out again. A transactional subscriber instead runs the handler in
+// the transaction that acknowledges the message, so the state the handler
+// changes through the database commits together with the acknowledgement,
+// or not at all.
+//
+// As on the redis transport, a message left unacknowledged is handed out
+// again after later messages of its key may have run.
+type PostgresSubscriber struct {
+	db                *gorm.DB
+	dsn               string
+	consumer          string
+	concurrency       int
+	visibilityTimeout time.Duration
+	pollInterval      time.Duration
+	transactional     bool
+	logger            zerolog.Logger
+
+	mu       sync.Mutex
+	inflight map[queueRef]struct{}
+	released chan struct{}
+}
+
+// NewPostgresSubscriber creates a subscriber claiming up to concurrency
+// messages at a time. dsn is used for a dedicated LISTEN connection.
+func NewPostgresSubscriber(db *gorm.DB, dsn, consumer string, concurrency int, visibilityTimeout, pollInterval time.Duration, logCfg logger.Config) *PostgresSubscriber {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &PostgresSubscriber{
+		db:                db,
+		dsn:               dsn,
+		consumer:          consumer,
+		concurrency:       concurrency,
+		visibilityTimeout: visibilityTimeout,
+		pollInterval:      pollInterval,
+		logger:            logger.New(logCfg),
+		inflight:          make(map[queueRef]struct{}),
+		released:          make(chan struct{}, 1),
+	}
+}
+
+// SetTransactional runs handlers in the transaction that acknowledges their
+// message. Handlers reach it through database.Conn and defer effects outside
+// the database with database.AfterCommit. The transaction stays open while
+// the handler runs, so it suits short handlers.
+func (s *PostgresSubscriber) SetTransactional(transactional bool) {
+	s.transactional = transactional
+}
+
+// Subscribe claims and handles messages until ctx is cancelled and returns
+// once the workers have drained.
+func (s *PostgresSubscriber) Subscribe(ctx context.Context, topics []string, handler MessageHandler) error {
+	if len(topics) == 0 {
+		return fmt.Errorf("no topics to subscribe to")
+	}
+
+	pool := newKeyedPool(s.concurrency, workerQueueSize)
+	defer pool.close()
+
+	wake := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.listen(ctx, topics, wake)
+	}()
+	go func() {
+		defer wg.Done()
+		s.extendLeases(ctx)
+	}()
+	defer wg.Wait()
+
+	s.logger.Info().
+		Strs("topics", topics).
+		Str("consumer", s.consumer).
+		Int("concurrency", s.concurrency).
+		Msg("Postgres queue subscriber started")
+
+	for {
+		if ctx.Err() != nil {
+			s.logger.Info().Msg("Context cancelled, stopping consumer")
+			return ctx.Err()
+		}
+
+		free := s.concurrency - s.inflightCount()
+		if free <= 0 {
+			s.wait(ctx, wake)
+			continue
+		}
+
+		rows, err := s.claim(ctx, topics, free)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error().Err(err).Msg("Failed to claim queued messages")
+			}
+			s.wait(ctx, wake)
+			continue
+		}
+		if len(rows) == 0 {
+			s.wait(ctx, wake)
+			continue
+		}
+
+		for _, row := range rows {
+			s.dispatch(ctx, pool, row, handler)
+		}
+	}
+}
+
+// wait blocks until a NOTIFY arrives, a handler frees a slot, the poll
+// interval passes or ctx is cancelled.
+func (s *PostgresSubscriber) wait(ctx context.Context, wake <-chan struct{}) {
+	timer := time.NewTimer(s.pollInterval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-wake:
+	case <-s.released:
+	case <-timer.C:
+	}
+}
+
+// claim leases up to limit visible rows, tasks first.
+func (s *PostgresSubscriber) claim(ctx context.Context, topics []string, limit int) ([]queueRow, error) {
+	lease := s.visibilityTimeout.Seconds()
+	db := s.db.WithContext(ctx)
+
+	var tasks []claimedRow
+	err := db.Raw(`
+		UPDATE tasks
+		SET timeout_at = now() + make_interval(secs => ?), deliveries = deliveries + 1, claimed_by = ?
+		WHERE id IN (
+			SELECT id FROM tasks
+			WHERE message IS NOT NULL
+			  AND topic IN ?
+			  AND status IN ?
+			  AND (timeout_at IS NULL OR timeout_at <= now())
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, message_key, message, message_headers, deliveries`,
+		lease, s.consumer,
+		topics, []string{models.TaskStatusPending, models.TaskStatusDispatched},
+		limit,
+	).Scan(&tasks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim tasks: %w", err)
+	}
+
+	rows := make([]queueRow, 0, len(tasks))
+	for _, t := range tasks {
+		rows = append(rows, queueRow{table: queueTableTasks, claimedRow: t})
+	}
+	if limit -= len(tasks); limit <= 0 {
+		return rows, nil
+	}
+
+	var messages []claimedRow
+	err = db.Raw(`
+		UPDATE queue_messages
+		SET visible_at = now() + make_interval(secs => ?), deliveries = deliveries + 1, claimed_by = ?
+		WHERE id IN (
+			SELECT id FROM queue_messages
+			WHERE topic IN ? AND visible_at <= now()
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, message_key, message, message_headers, deliveries`,
+		lease, s.consumer, topics, limit,
+	).Scan(&messages).Error
+	if err != nil {
+		return rows, fmt.Errorf("failed to claim queue messages: %w", err)
+	}
+	for _, m := range messages {
+		rows = append(rows, queueRow{table: queueTableMessages, claimedRow: m})
+	}
+	return rows, nil
+}
+
+type queueRow struct {
+	table string
+	claimedRow
+}
+
+func (s *PostgresSubscriber) dispatch(ctx context.Context, pool *keyedPool, row queueRow, handler MessageHandler) {
+	ref := queueRef{table: row.table, id: row.ID, deliveries: row.Deliveries}
+	msg := &Message{
+		Topic:  row.Topic,
+		Key:    row.MessageKey,
+		Value:  row.Message,
+		Offset: int64(row.ID),
+	}
+	if len(row.MessageHeaders) > 0 {
+		if err := json.Unmarshal(row.MessageHeaders, &msg.Headers); err != nil {
+			msg.Headers = nil
+		}
+	}
+
+	s.mu.Lock()
+	s.inflight[ref] = struct{}{}
+	s.mu.Unlock()
+
+	key := msg.Key
+	if len(key) == 0 {
+		key = []byte(fmt.Sprintf("%s/%d", row.table, row.ID))
+	}
+
+	submitted := pool.submit(ctx, key, func() {
+		defer s.done(ref)
+
+		if err := s.handle(ctx, ref, msg, handler); err != nil {
+			if ctx.Err() != nil {
+				// Shutting down: hand the message to another consumer now
+				// rather than after the lease expires.
+				s.release(ref)
+				return
+			}
+			// Left claimed; it becomes visible again once the lease
+			// runs out.
+			s.logger.Error().Err(err).Str("key", string(msg.Key)).Uint64("id", row.ID).Msg("Event left unacknowledged")
+		}
+	})
+	if !submitted {
+		s.release(ref)
+		s.done(ref)
+	}
+}
+
+// errClaimLost is returned when a message is acknowledged after its lease
+// ran out and another consumer claimed it.
+var errClaimLost = errors.New("claim lost")
+
+// handle runs handler and acknowledges msg once it succeeded, in a single
+// transaction when the subscriber is transactional.
+func (s *PostgresSubscriber) handle(ctx context.Context, ref queueRef, msg *Message, handler MessageHandler) error {
+	if !s.transactional {
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+		ackCtx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+		defer cancel()
+		if err := s.ack(s.db.WithContext(ackCtx), ref); err != nil && !errors.Is(err, errClaimLost) {
+			s.logger.Error().Err(err).Str("table", ref.table).Uint64("id", ref.id).Msg("Failed to acknowledge message")
+		}
+		return nil
+	}
+
+	var committed *database.Tx
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx, t := database.WithTx(ctx, tx)
+		if err := handler(txCtx, msg); err != nil {
+			return err
+		}
+		committed = t
+		return s.ack(tx, ref)
+	})
+	if err != nil {
+		return err
+	}
+	committed.Committed()
+	return nil
+}
+
+func (s *PostgresSubscriber) done(ref queueRef) {
+	s.mu.Lock()
+	delete(s.inflight, ref)
+	s.mu.Unlock()
+
+	select {
+	case s.released <- struct{}{}:
+	default:
+	}
+}
+
+func (s *PostgresSubscriber) inflightCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.inflight)
+}
+
+// ack consumes the message: queue messages are deleted, tasks drop their
+// payload and keep the row, whose status the orchestrator owns.
+func (s *PostgresSubscriber) ack(db *gorm.DB, ref queueRef) error {
+	var result *gorm.DB
+	switch ref.table {
+	case queueTableTasks:
+		result = db.Exec(`
+			UPDATE tasks
+			SET message = NULL, message_key = NULL, message_headers = NULL, timeout_at = NULL, claimed_by = NULL
+			WHERE id = ? AND deliveries = ?`,
+			ref.id, ref.deliveries,
+		)
+	default:
+		result = db.Exec(
+			"DELETE FROM queue_messages WHERE id = ? AND deliveries = ?",
+			ref.id, ref.deliveries,
+		)
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errClaimLost
+	}
+	return nil
+}
+
+// release makes a claimed message visible again immediately.
+func (s *PostgresSubscriber) release(ref queueRef) {
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+
+	var err error
+	switch ref.table {
+	case queueTableTasks:
+		err = s.db.WithContext(ctx).Exec(
+			"UPDATE tasks SET timeout_at = NULL, claimed_by = NULL WHERE id = ? AND deliveries = ?",
+			ref.id, ref.deliveries,
+		).Error
+	default:
+		err = s.db.WithContext(ctx).Exec(
+			"UPDATE queue_messages SET visible_at = now(), claimed_by = NULL WHERE id = ? AND deliveries = ?",
+			ref.id, ref.deliveries,
+		).Error
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("table", ref.table).Uint64("id", ref.id).Msg("Failed to release message")
+	}
+}
+
+// extendLeases keeps the claims of running handlers alive so long tasks are
+// not handed to a second consumer.
+func (s *PostgresSubscriber) extendLeases(ctx context.Context) {
+	ticker := time.NewTicker(s.visibilityTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ids := map[string][]uint64{}
+		s.mu.Lock()
+		for ref := range s.inflight {
+			ids[ref.table] = append(ids[ref.table], ref.id)
+		}
+		s.mu.Unlock()
+
+		lease := s.visibilityTimeout.Seconds()
+		if len(ids[queueTableTasks]) > 0 {
+			err := s.db.WithContext(ctx).Exec(
+				"UPDATE tasks SET timeout_at = now() + make_interval(secs => ?) WHERE id IN ? AND claimed_by = ?",
+				lease, ids[queueTableTasks], s.consumer,
+			).Error
+			if err != nil && ctx.Err() == nil {
+				s.logger.Error().Err(err).Msg("Failed to extend task leases")
+			}
+		}
+		if len(ids[queueTableMessages]) > 0 {
+			err := s.db.WithContext(ctx).Exec(
+				"UPDATE queue_messages SET visible_at = now() + make_interval(secs => ?) WHERE id IN ? AND claimed_by = ?",
+				lease, ids[queueTableMessages], s.consumer,
+			).Error
+			if err != nil && ctx.Err() == nil {
+				s.logger.Error().Err(err).Msg("Failed to extend message leases")
+			}
+		}
+	}
+}
+
+// listen forwards NOTIFYs for our topics to wake, reconnecting on errors.
+func (s *PostgresSubscriber) listen(ctx context.Context, topics []string, wake chan<- struct{}) {
+	wanted := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		wanted[topic] = true
+	}
+
+	for ctx.Err() == nil {
+		err := s.listenOnce(ctx, wanted, wake)
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Warn().Err(err).Msg("LISTEN connection lost, falling back to polling until it is restored")
+		select {
+		case <-time.After(s.pollInterval):
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (s *PostgresSubscriber) listenOnce(ctx context.Context, wanted map[string]bool, wake chan<- struct{}) error {
+	conn, err := pgx.Connect(ctx, s.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+queueChannel); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if !wanted[n.Payload] {
+			continue
+		}
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+	"gorm.io/gorm"
+)
+
+func newTestPostgresSubscriber(db *gorm.DB, consumer string) *PostgresSubscriber {
+	return NewPostgresSubscriber(db, os.Getenv(databasetest.EnvURL), consumer, 4, time.Second, 50*time.Millisecond, quietLog)
+}
+
+func TestPostgresQueueRedeliversUntilAcknowledged(t *testing.T) {
+	db := databasetest.Open(t)
+	publisher := NewPostgresPublisher(db, quietLog)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, key := range []string{"exec-1", "exec-2"} {
+		if _, err := publisher.Publish(ctx, &Message{Topic: "completions", Key: []byte(key), Value: []byte(`{}`)}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	var (
+		mu         sync.Mutex
+		deliveries = map[string]int{}
+		acked      int
+	)
+	err := newTestPostgresSubscriber(db, "c1").Subscribe(ctx, []string{"completions"}, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		key := string(msg.Key)
+		deliveries[key]++
+		if key == "exec-1" && deliveries[key] == 1 {
+			// Left claimed until the visibility timeout runs out
+			return errors.New("transient")
+		}
+		if acked++; acked == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Subscribe = %v, want it to stop on cancel", err)
+	}
+
+	if deliveries["exec-1"] != 2 || deliveries["exec-2"] != 1 {
+		t.Errorf("deliveries = %v, want the rejected message delivered twice and the other once", deliveries)
+	}
+	var left int64
+	db.Table("queue_messages").Count(&left)
+	if left != 0 {
+		t.Errorf("%d queue messages left, want acknowledged messages deleted", left)
+	}
+}
+
+func TestPostgresQueueKeepsAcknowledgedTaskRows(t *testing.T) {
+	db := databasetest.Open(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	workflow := &models.Workflow{Name: "queue", Status: "active"}
+	if err := db.Create(workflow).Error; err != nil {
+		t.Fatal(err)
+	}
+	instance := &models.WorkflowInstance{WorkflowID: workflow.ID, ExecutionID: "exec-1", Status: models.InstanceStatusRunning, Version: 1}
+	if err := db.Create(instance).Error; err != nil {
+		t.Fatal(err)
+	}
+	task := &models.Task{InstanceID: instance.ID, TaskID: "task-1", Attempt: 1, StepID: 1, Type: "noop", Status: models.TaskStatusPending}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &Message{Topic: "tasks", Key: []byte("exec-1"), Value: []byte(`{}`), Headers: map[string]string{HeaderTaskID: "task-1"}}
+	report, err := NewPostgresPublisher(db, quietLog).Publish(ctx, msg)
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if report.Offset != int64(task.ID) {
+		t.Errorf("offset = %d, want the task row %d", report.Offset, task.ID)
+	}
+
+	err = newTestPostgresSubscriber(db, "w1").Subscribe(ctx, []string{"tasks"}, func(ctx context.Context, got *Message) error {
+		if got.Headers[HeaderTaskID] != "task-1" {
+			t.Errorf("headers = %v, want the task ID kept", got.Headers)
+		}
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Subscribe = %v, want it to stop on cancel", err)
+	}
+
+	var stored models.Task
+	if err := db.First(&stored, task.ID).Error; err != nil {
+		t.Fatalf("task row gone after acknowledgement: %v", err)
+	}
+	if stored.Message != nil || stored.Deliveries != 1 || stored.Status != models.TaskStatusPending {
+		t.Errorf("task = message %q, %d deliveries, %s; want the message dropped and the status left to the orchestrator", stored.Message, stored.Deliveries, stored.Status)
+	}
+}
+
+// claimOne publishes a message and claims it with a transactional
+// subscriber.
+func claimOne(t *testing.T, db *gorm.DB) (*PostgresSubscriber, queueRef, *Message) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := NewPostgresPublisher(db, quietLog).Publish(ctx, &Message{Topic: "completions", Key: []byte("exec-1"), Value: []byte(`{}`)}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	subscriber := newTestPostgresSubscriber(db, "c1")
+	subscriber.SetTransactional(true)
+	rows, err := subscriber.claim(ctx, []string{"completions"}, 1)
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("claim = %v, %v; want the message", rows, err)
+	}
+	ref := queueRef{table: rows[0].table, id: rows[0].ID, deliveries: rows[0].Deliveries}
+	return subscriber, ref, &Message{Topic: rows[0].Topic, Key: rows[0].MessageKey, Value: rows[0].Message}
+}
+
+// transition stands in for the orchestrator: it changes state as the
+// repositories do and defers an outside effect to the commit.
+func transition(db *gorm.DB, committed *bool) MessageHandler {
+	return func(ctx context.Context, msg *Message) error {
+		database.AfterCommit(ctx, func() { *committed = true })
+		return database.Conn(ctx, db).Create(&models.Workflow{Name: "transition", Status: "active"}).Error
+	}
+}
+
+func TestPostgresTransactionalHandlerCommitsWithAcknowledgement(t *testing.T) {
+	db := databasetest.Open(t)
+	subscriber, ref, msg := claimOne(t, db)
+
+	committed := false
+	if err := subscriber.handle(context.Background(), ref, msg, transition(db, &committed)); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	var workflows, left int64
+	db.Model(&models.Workflow{}).Where("name = ?", "transition").Count(&workflows)
+	db.Table("queue_messages").Count(&left)
+	if workflows != 1 || left != 0 || !committed {
+		t.Errorf("%d workflows, %d messages left, committed %v; want the transition and the acknowledgement committed together", workflows, left, committed)
+	}
+}
+
+func TestPostgresTransactionalHandlerRollsBackWithoutClaim(t *testing.T) {
+	db := databasetest.Open(t)
+	subscriber, ref, msg := claimOne(t, db)
+
+	// The lease ran out and another consumer claimed the message
+	if err := db.Exec("UPDATE queue_messages SET deliveries = deliveries + 1 WHERE id = ?", ref.id).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	committed := false
+	if err := subscriber.handle(context.Background(), ref, msg, transition(db, &committed)); !errors.Is(err, errClaimLost) {
+		t.Fatalf("handle = %v, want %v", err, errClaimLost)
+	}
+
+	var workflows, left int64
+	db.Model(&models.Workflow{}).Where("name = ?", "transition").Count(&workflows)
+	db.Table("queue_messages").Count(&left)
+	if workflows != 0 || left != 1 || committed {
+		t.Errorf("%d workflows, %d messages left, committed %v; want the transition rolled back with the message kept", workflows, left, committed)
+	}
+}
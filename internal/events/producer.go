@@ -1,106 +1,245 @@
 package events
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/Vighnesh-V-H/async/internal/logger"
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/rs/zerolog"
 )
 
 type EventProducer struct {
-	producer *kafka.Producer
-	logger   zerolog.Logger
+	publisher       Publisher
+	deliveryTimeout time.Duration
+	source          string
+	encoding        string
+	codecs          map[string]Codec
+	logger          zerolog.Logger
 }
 
 type TaskEvent struct {
 	ExecutionID string                 `json:"execution_id"`
 	WorkflowID  uint                   `json:"workflow_id"`
+	TaskID      string                 `json:"task_id"`
+	Attempt     uint8                  `json:"attempt"`
 	TaskType    string                 `json:"task_type"`
 	Step        uint8                  `json:"step"`
 	Input       map[string]interface{} `json:"input"`
-	Timestamp   string                 `json:"timestamp"`
+	// Params is the configuration of the workflow state behind the task,
+	// such as a prompt or notification channels, with templates unrendered.
+	Params map[string]interface{} `json:"params,omitempty"`
+	// Context is the scope templates in Params are rendered against: the
+	// trigger payload, the previous step and the execution ID.
+	Context map[string]interface{} `json:"context,omitempty"`
 }
 
+// HeaderTaskID carries the task ID of task events so transports can tie the
+// message to its row in the tasks table.
+const HeaderTaskID = "task-id"
+
+const (
+	CompletionStatusCompleted = "completed"
+	CompletionStatusFailed    = "failed"
+)
+
+// CompletionEvent reports the outcome of a TaskEvent. TaskID and Attempt are
+// copied from the task so the orchestrator can detect duplicates.
 type CompletionEvent struct {
 	ExecutionID string                 `json:"execution_id"`
 	WorkflowID  uint                   `json:"workflow_id"`
+	TaskID      string                 `json:"task_id"`
+	Attempt     uint8                  `json:"attempt"`
 	TaskType    string                 `json:"task_type"`
 	Step        uint8                  `json:"step"`
-	Status      string                 `json:"status"` 
+	Status      string                 `json:"status"`
 	Output      map[string]interface{} `json:"output"`
 	Error       string                 `json:"error,omitempty"`
-	Timestamp   string                 `json:"timestamp"`
+	Artifacts   []Artifact             `json:"artifacts,omitempty"`
 }
 
-func NewEventProducer(producer *kafka.Producer, logCfg logger.Config) *EventProducer {
+// Artifact is a named output an executor stored in the blob store under Key,
+// such as a generated audio file or a transcript.
+type Artifact struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	Key         string `json:"key"`
+}
+
+// NewEventProducer creates a producer on top of a transport. deliveryTimeout
+// bounds how long the synchronous publish methods wait for a delivery report;
+// zero means the caller's context alone decides. The service name of logCfg
+// is recorded as the source of every event.
+func NewEventProducer(publisher Publisher, deliveryTimeout time.Duration, logCfg logger.Config) *EventProducer {
 	return &EventProducer{
-		producer: producer,
-		logger:   logger.New(logCfg),
+		publisher:       publisher,
+		deliveryTimeout: deliveryTimeout,
+		source:          logCfg.ServiceName,
+		encoding:        EncodingEnvelope,
+		codecs:          make(map[string]Codec),
+		logger:          logger.New(logCfg),
 	}
 }
 
-func (ep *EventProducer) PublishTask(topic string, event *TaskEvent) error {
-	payload, err := json.Marshal(event)
-	if err != nil {
-		ep.logger.Error().Err(err).Msg("Failed to marshal task event")
-		return fmt.Errorf("failed to marshal task event: %w", err)
+// SetEncoding selects how events are written: EncodingEnvelope or one of the
+// CloudEvents modes. Consumers read every encoding, so producers can switch
+// without coordinating.
+func (ep *EventProducer) SetEncoding(encoding string) {
+	ep.encoding = encoding
+}
+
+// SetCodec selects the codec for event data published to topic. Topics
+// without one use JSONCodec. Like SetEncoding it only affects writing.
+func (ep *EventProducer) SetCodec(topic string, codec Codec) {
+	ep.codecs[topic] = codec
+}
+
+func (ep *EventProducer) codecFor(topic string) Codec {
+	if codec, ok := ep.codecs[topic]; ok {
+		return codec
 	}
+	return JSONCodec
+}
 
-	msg := &kafka.Message{
-		TopicPartition: kafka.TopicPartition{
-			Topic:     &topic,
-			Partition: kafka.PartitionAny,
-		},
-		Key:   []byte(event.ExecutionID),
-		Value: payload,
+// PublishTask publishes a task event and waits until the broker confirms
+// delivery, the delivery timeout elapses or ctx is cancelled.
+func (ep *EventProducer) PublishTask(ctx context.Context, topic string, event *TaskEvent) error {
+	msg, err := ep.taskMessage(ctx, topic, event)
+	if err != nil {
+		return err
 	}
 
-	if err := ep.producer.Produce(msg, nil); err != nil {
-		ep.logger.Error().Err(err).Str("topic", topic).Msg("Failed to produce task event")
-		return fmt.Errorf("failed to produce task event: %w", err)
+	report, err := ep.publishSync(ctx, msg)
+	if err != nil {
+		ep.logger.Error().
+			Err(err).
+			Str("topic", topic).
+			Str("execution_id", event.ExecutionID).
+			Str("task_id", event.TaskID).
+			Msg("Task event was not delivered")
+		return fmt.Errorf("failed to deliver task event: %w", err)
 	}
 
 	ep.logger.Info().
 		Str("topic", topic).
+		Int32("partition", report.Partition).
+		Int64("offset", report.Offset).
 		Str("execution_id", event.ExecutionID).
+		Str("task_id", event.TaskID).
 		Str("task_type", event.TaskType).
 		Uint8("step", event.Step).
-		Msg("Task event queued for publishing to Kafka")
+		Msg("Task event delivered")
 
 	return nil
 }
 
-func (ep *EventProducer) PublishCompletion(topic string, event *CompletionEvent) error {
-	payload, err := json.Marshal(event)
+// PublishCompletion publishes a completion event and waits until the broker
+// confirms delivery, the delivery timeout elapses or ctx is cancelled.
+func (ep *EventProducer) PublishCompletion(ctx context.Context, topic string, event *CompletionEvent) error {
+	msg, err := ep.completionMessage(ctx, topic, event)
 	if err != nil {
-		ep.logger.Error().Err(err).Msg("Failed to marshal completion event")
-		return fmt.Errorf("failed to marshal completion event: %w", err)
+		return err
 	}
 
-	msg := &kafka.Message{
-		TopicPartition: kafka.TopicPartition{
-			Topic:     &topic,
-			Partition: kafka.PartitionAny,
-		},
-		Key:   []byte(event.ExecutionID),
-		Value: payload,
-	}
-
-	
-	if err := ep.producer.Produce(msg, nil); err != nil {
-		ep.logger.Error().Err(err).Str("topic", topic).Msg("Failed to produce completion event")
-		return fmt.Errorf("failed to produce completion event: %w", err)
+	report, err := ep.publishSync(ctx, msg)
+	if err != nil {
+		ep.logger.Error().
+			Err(err).
+			Str("topic", topic).
+			Str("execution_id", event.ExecutionID).
+			Str("task_id", event.TaskID).
+			Msg("Completion event was not delivered")
+		return fmt.Errorf("failed to deliver completion event: %w", err)
 	}
 
 	ep.logger.Info().
 		Str("topic", topic).
+		Int32("partition", report.Partition).
+		Int64("offset", report.Offset).
 		Str("execution_id", event.ExecutionID).
 		Str("task_type", event.TaskType).
 		Str("status", event.Status).
 		Uint8("step", event.Step).
-		Msg("Completion event queued for publishing to Kafka")
+		Msg("Completion event delivered")
+
+	return nil
+}
+
+// PublishDeadLetter forwards a message that could not be processed to the
+// dead letter topic, keeping its key and payload and recording why and where
+// it came from in the headers.
+func (ep *EventProducer) PublishDeadLetter(ctx context.Context, topic string, original *Message, reason string) error {
+	// Keep the original headers: binary mode CloudEvents live in them.
+	headers := make(map[string]string, len(original.Headers)+2)
+	for k, v := range original.Headers {
+		headers[k] = v
+	}
+	headers["dlq-reason"] = reason
+	headers["dlq-source-topic"] = original.Topic
+
+	msg := &Message{
+		Topic:   topic,
+		Key:     original.Key,
+		Value:   original.Value,
+		Headers: headers,
+	}
 
+	if _, err := ep.publishSync(ctx, msg); err != nil {
+		ep.logger.Error().Err(err).Str("topic", topic).Msg("Failed to publish dead letter")
+		return fmt.Errorf("failed to publish dead letter: %w", err)
+	}
+
+	ep.logger.Warn().
+		Str("topic", topic).
+		Str("reason", reason).
+		Msg("Message forwarded to dead letter queue")
 	return nil
 }
+
+func (ep *EventProducer) publishSync(ctx context.Context, msg *Message) (DeliveryReport, error) {
+	if ep.deliveryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ep.deliveryTimeout)
+		defer cancel()
+	}
+	return ep.publisher.Publish(ctx, msg)
+}
+
+func (ep *EventProducer) taskMessage(ctx context.Context, topic string, event *TaskEvent) (*Message, error) {
+	msg, err := ep.encode(ctx, topic, EventTypeTask, event.ExecutionID, event)
+	if err != nil {
+		ep.logger.Error().Err(err).Msg("Failed to marshal task event")
+		return nil, fmt.Errorf("failed to marshal task event: %w", err)
+	}
+	msg.Headers[HeaderTaskID] = event.TaskID
+	return msg, nil
+}
+
+func (ep *EventProducer) completionMessage(ctx context.Context, topic string, event *CompletionEvent) (*Message, error) {
+	msg, err := ep.encode(ctx, topic, EventTypeCompletion, event.ExecutionID, event)
+	if err != nil {
+		ep.logger.Error().Err(err).Msg("Failed to marshal completion event")
+		return nil, fmt.Errorf("failed to marshal completion event: %w", err)
+	}
+	return msg, nil
+}
+
+// encode wraps event in an envelope and renders it in the configured
+// encoding. Messages are keyed by execution ID.
+func (ep *EventProducer) encode(ctx context.Context, topic, eventType, executionID string, event interface{}) (*Message, error) {
+	env, err := newEnvelope(ctx, eventType, ep.source, executionID, ep.codecFor(topic), event)
+	if err != nil {
+		return nil, err
+	}
+
+	value, headers, err := encodeEnvelope(env, ep.encoding)
+	if err != nil {
+		return nil, err
+	}
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	return &Message{Topic: topic, Key: []byte(executionID), Value: value, Headers: headers}, nil
+}
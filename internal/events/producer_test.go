@@ -0,0 +1,56 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stalledPublisher never hears back from the broker.
+type stalledPublisher struct{}
+
+func (stalledPublisher) Publish(ctx context.Context, msg *Message) (DeliveryReport, error) {
+	<-ctx.Done()
+	return DeliveryReport{}, ctx.Err()
+}
+
+func (stalledPublisher) PublishAsync(msg *Message, done DeliveryCallback) error {
+	return nil
+}
+
+func TestPublishWaitsForDelivery(t *testing.T) {
+	broker := NewMemoryBroker(1)
+	ep := NewEventProducer(broker, time.Second, testLog)
+
+	if err := ep.PublishTask(context.Background(), "tasks", sampleTask()); err != nil {
+		t.Fatalf("PublishTask: %v", err)
+	}
+	msgs := broker.Messages("tasks")
+	if len(msgs) != 1 || string(msgs[0].Key) != sampleTask().ExecutionID || msgs[0].Headers[HeaderTaskID] != sampleTask().TaskID {
+		t.Errorf("messages = %+v, want the task keyed by execution", msgs)
+	}
+}
+
+func TestPublishGivesUpAfterDeliveryTimeout(t *testing.T) {
+	ep := NewEventProducer(stalledPublisher{}, 20*time.Millisecond, testLog)
+
+	started := time.Now()
+	err := ep.PublishCompletion(context.Background(), "completions", sampleCompletion())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("PublishCompletion = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("gave up after %s, want after the delivery timeout", elapsed)
+	}
+}
+
+func TestPublishWithoutDeliveryTimeoutWaitsForCaller(t *testing.T) {
+	ep := NewEventProducer(stalledPublisher{}, 0, testLog)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err := ep.PublishTask(ctx, "tasks", sampleTask()); !errors.Is(err, context.Canceled) {
+		t.Errorf("PublishTask = %v, want it to wait until the caller gives up", err)
+	}
+}
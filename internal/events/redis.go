@@ -0,0 +1,322 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// Stream entry fields. Headers are stored as a single JSON object.
+const (
+	streamFieldKey     = "key"
+	streamFieldValue   = "value"
+	streamFieldHeaders = "headers"
+
+	streamReadCount = 64
+)
+
+// RedisStreamPublisher is the Publisher backed by Redis Streams. Every topic is
+// a stream of the same name and every message one entry.
+type RedisStreamPublisher struct {
+	client *redis.Client
+	maxLen int64
+	logger zerolog.Logger
+}
+
+// NewRedisStreamPublisher creates a publisher that trims streams to roughly
+// maxLen entries; zero disables trimming.
+func NewRedisStreamPublisher(client *redis.Client, maxLen int64, logCfg logger.Config) *RedisStreamPublisher {
+	return &RedisStreamPublisher{
+		client: client,
+		maxLen: maxLen,
+		logger: logger.New(logCfg),
+	}
+}
+
+// Publish appends msg with XADD. The entry is durable once Redis replies, so
+// the reply doubles as the delivery report. Stream IDs are not numeric, so
+// the report carries partition 0 and offset -1.
+func (p *RedisStreamPublisher) Publish(ctx context.Context, msg *Message) (DeliveryReport, error) {
+	values := map[string]interface{}{
+		streamFieldKey:   msg.Key,
+		streamFieldValue: msg.Value,
+	}
+	if len(msg.Headers) > 0 {
+		headers, err := json.Marshal(msg.Headers)
+		if err != nil {
+			return DeliveryReport{}, fmt.Errorf("failed to encode headers: %w", err)
+		}
+		values[streamFieldHeaders] = headers
+	}
+
+	args := &redis.XAddArgs{Stream: msg.Topic, Values: values}
+	if p.maxLen > 0 {
+		args.MaxLen = p.maxLen
+		args.Approx = true
+	}
+
+	if err := p.client.XAdd(ctx, args).Err(); err != nil {
+		report := DeliveryReport{Topic: msg.Topic, Offset: -1, Err: err}
+		return report, err
+	}
+	return DeliveryReport{Topic: msg.Topic, Offset: -1}, nil
+}
+
+func (p *RedisStreamPublisher) PublishAsync(msg *Message, done DeliveryCallback) error {
+	go func() {
+		report, err := p.Publish(context.Background(), msg)
+		if err != nil {
+			p.logger.Error().Err(err).Str("topic", msg.Topic).Msg("Failed to deliver message")
+		}
+		if done != nil {
+			done(report)
+		}
+	}()
+	return nil
+}
+
+// RedisStreamSubscriber consumes streams as a member of a consumer group.
+// Entries are acknowledged with XACK once the handler succeeds; failed entries
+// stay in the group's pending list and are reclaimed with XAUTOCLAIM after
+// claimMinIdle, which also recovers the work of consumers that died.
+//
+// Ordering per key is weaker than on the other transports: entries are
+// handed to the handler in order as they are read, but a reclaimed entry runs
+// after the later entries of its key that were read in the meantime. Keyed
+// events of the orchestrator tolerate that, since each completion is checked
+// against the current state of its execution and a stale one changes
+// nothing.
+type RedisStreamSubscriber struct {
+	client       *redis.Client
+	group        string
+	consumer     string
+	concurrency  int
+	blockTimeout time.Duration
+	claimMinIdle time.Duration
+	logger       zerolog.Logger
+
+	mu       sync.Mutex
+	inflight map[string]struct{}
+}
+
+// NewRedisStreamSubscriber creates a subscriber that joins group as consumer.
+// consumer must be unique per process so pending entries can be told apart.
+func NewRedisStreamSubscriber(client *redis.Client, group, consumer string, concurrency int, blockTimeout, claimMinIdle time.Duration, logCfg logger.Config) *RedisStreamSubscriber {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &RedisStreamSubscriber{
+		client:       client,
+		group:        group,
+		consumer:     consumer,
+		concurrency:  concurrency,
+		blockTimeout: blockTimeout,
+		claimMinIdle: claimMinIdle,
+		logger:       logger.New(logCfg),
+		inflight:     make(map[string]struct{}),
+	}
+}
+
+// Subscribe reads new entries and reclaims stale ones until ctx is cancelled.
+// It returns once the workers have drained.
+func (s *RedisStreamSubscriber) Subscribe(ctx context.Context, topics []string, handler MessageHandler) error {
+	if len(topics) == 0 {
+		return fmt.Errorf("no topics to subscribe to")
+	}
+	for _, topic := range topics {
+		if err := s.ensureGroup(ctx, topic); err != nil {
+			return err
+		}
+	}
+
+	pool := newKeyedPool(s.concurrency, workerQueueSize)
+	defer pool.close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.reclaimLoop(ctx, topics, pool, handler)
+	}()
+	defer wg.Wait()
+
+	s.logger.Info().
+		Strs("topics", topics).
+		Str("group", s.group).
+		Str("consumer", s.consumer).
+		Int("concurrency", s.concurrency).
+		Msg("Redis stream subscriber started")
+
+	streams := make([]string, 0, len(topics)*2)
+	streams = append(streams, topics...)
+	for range topics {
+		streams = append(streams, ">")
+	}
+
+	for {
+		if ctx.Err() != nil {
+			s.logger.Info().Msg("Context cancelled, stopping consumer")
+			return ctx.Err()
+		}
+
+		res, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  streams,
+			Count:    streamReadCount,
+			Block:    s.blockTimeout,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			s.logger.Error().Err(err).Msg("Error reading from Redis streams")
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		for _, stream := range res {
+			for _, entry := range stream.Messages {
+				s.dispatch(ctx, pool, stream.Stream, entry, handler)
+			}
+		}
+	}
+}
+
+// ensureGroup creates the consumer group, and the stream if needed, reading
+// from the start so entries published before the first consumer are kept.
+func (s *RedisStreamSubscriber) ensureGroup(ctx context.Context, topic string) error {
+	err := s.client.XGroupCreateMkStream(ctx, topic, s.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group for %s: %w", topic, err)
+	}
+	return nil
+}
+
+// reclaimLoop periodically takes over entries that stayed pending for longer
+// than claimMinIdle, whoever owned them.
+func (s *RedisStreamSubscriber) reclaimLoop(ctx context.Context, topics []string, pool *keyedPool, handler MessageHandler) {
+	interval := s.claimMinIdle / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, topic := range topics {
+			s.reclaim(ctx, topic, pool, handler)
+		}
+	}
+}
+
+func (s *RedisStreamSubscriber) reclaim(ctx context.Context, topic string, pool *keyedPool, handler MessageHandler) {
+	start := "0-0"
+	for ctx.Err() == nil {
+		entries, next, err := s.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   topic,
+			Group:    s.group,
+			Consumer: s.consumer,
+			MinIdle:  s.claimMinIdle,
+			Start:    start,
+			Count:    streamReadCount,
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error().Err(err).Str("topic", topic).Msg("Failed to reclaim pending entries")
+			}
+			return
+		}
+
+		if len(entries) > 0 {
+			s.logger.Warn().Str("topic", topic).Int("entries", len(entries)).Msg("Reclaimed stale pending entries")
+		}
+		for _, entry := range entries {
+			s.dispatch(ctx, pool, topic, entry, handler)
+		}
+
+		if next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+// dispatch hands an entry to the pool unless it is already being processed
+// here, which happens when a slow handler outlives claimMinIdle.
+func (s *RedisStreamSubscriber) dispatch(ctx context.Context, pool *keyedPool, topic string, entry redis.XMessage, handler MessageHandler) {
+	id := topic + "/" + entry.ID
+
+	s.mu.Lock()
+	if _, busy := s.inflight[id]; busy {
+		s.mu.Unlock()
+		return
+	}
+	s.inflight[id] = struct{}{}
+	s.mu.Unlock()
+
+	msg := fromStreamEntry(topic, entry)
+	key := msg.Key
+	if len(key) == 0 {
+		key = []byte(entry.ID)
+	}
+
+	submitted := pool.submit(ctx, key, func() {
+		defer s.done(id)
+
+		// Failed entries stay pending and are picked up again by the
+		// reclaim loop.
+		if err := handler(ctx, msg); err != nil {
+			s.logger.Error().Err(err).Str("key", string(msg.Key)).Str("id", entry.ID).Msg("Event left unacknowledged")
+			return
+		}
+
+		ackCtx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+		defer cancel()
+		if err := s.client.XAck(ackCtx, topic, s.group, entry.ID).Err(); err != nil {
+			s.logger.Error().Err(err).Str("id", entry.ID).Msg("Failed to acknowledge entry")
+		}
+	})
+	if !submitted {
+		s.done(id)
+	}
+}
+
+func (s *RedisStreamSubscriber) done(id string) {
+	s.mu.Lock()
+	delete(s.inflight, id)
+	s.mu.Unlock()
+}
+
+func fromStreamEntry(topic string, entry redis.XMessage) *Message {
+	msg := &Message{Topic: topic, Offset: -1}
+	if v, ok := entry.Values[streamFieldKey].(string); ok {
+		msg.Key = []byte(v)
+	}
+	if v, ok := entry.Values[streamFieldValue].(string); ok {
+		msg.Value = []byte(v)
+	}
+	if v, ok := entry.Values[streamFieldHeaders].(string); ok && v != "" {
+		if err := json.Unmarshal([]byte(v), &msg.Headers); err != nil {
+			msg.Headers = nil
+		}
+	}
+	return msg
+}
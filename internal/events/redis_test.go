@@ -0,0 +1,185 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// envRedisURL names the variable holding the redis:// URL of the server the
+// stream tests run against. They are skipped when it is unset.
+const envRedisURL = "ASYNC_TEST_REDIS_URL"
+
+const testGroup = "async-test"
+
+// openTestRedis connects to the test server and returns a stream name of
+// its own, deleted when the test ends.
+func openTestRedis(t *testing.T) (*redis.Client, string) {
+	t.Helper()
+	rawURL := os.Getenv(envRedisURL)
+	if rawURL == "" {
+		t.Skipf("%s is not set", envRedisURL)
+	}
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		t.Fatalf("parse %s: %v", envRedisURL, err)
+	}
+	client := redis.NewClient(opts)
+	t.Cleanup(func() { client.Close() })
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	stream := "test-" + hex.EncodeToString(suffix)
+	t.Cleanup(func() { client.Del(context.Background(), stream) })
+	return client, stream
+}
+
+func newTestRedisSubscriber(client *redis.Client, consumer string) *RedisStreamSubscriber {
+	return NewRedisStreamSubscriber(client, testGroup, consumer, 4, 100*time.Millisecond, 200*time.Millisecond, quietLog)
+}
+
+func publishEntries(t *testing.T, client *redis.Client, stream string, keys ...string) {
+	t.Helper()
+	publisher := NewRedisStreamPublisher(client, 0, quietLog)
+	for _, key := range keys {
+		msg := &Message{Topic: stream, Key: []byte(key), Value: []byte(`{}`), Headers: map[string]string{"content-type": "application/json"}}
+		if _, err := publisher.Publish(context.Background(), msg); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+}
+
+func pendingEntries(t *testing.T, client *redis.Client, stream string) int64 {
+	t.Helper()
+	pending, err := client.XPending(context.Background(), stream, testGroup).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pending.Count
+}
+
+func TestRedisStreamCreatesConsumerGroup(t *testing.T) {
+	client, stream := openTestRedis(t)
+	s := newTestRedisSubscriber(client, "c1")
+	ctx := context.Background()
+
+	// Creating it again, as every consumer does on start, is fine
+	for i := 0; i < 2; i++ {
+		if err := s.ensureGroup(ctx, stream); err != nil {
+			t.Fatalf("ensureGroup: %v", err)
+		}
+	}
+	groups, err := client.XInfoGroups(ctx, stream).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Name != testGroup {
+		t.Errorf("groups = %+v, want %s", groups, testGroup)
+	}
+}
+
+func TestRedisStreamAcknowledgesHandledEntries(t *testing.T) {
+	client, stream := openTestRedis(t)
+	publishEntries(t, client, stream, "exec-1", "exec-2")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var (
+		mu      sync.Mutex
+		handled []string
+	)
+	err := newTestRedisSubscriber(client, "c1").Subscribe(ctx, []string{stream}, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if msg.Headers["content-type"] != "application/json" {
+			t.Errorf("headers = %v, want them kept", msg.Headers)
+		}
+		if handled = append(handled, string(msg.Key)); len(handled) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Subscribe = %v, want it to stop on cancel", err)
+	}
+
+	if len(handled) != 2 {
+		t.Errorf("handled %v, want both entries", handled)
+	}
+	if pending := pendingEntries(t, client, stream); pending != 0 {
+		t.Errorf("%d entries pending, want both acknowledged", pending)
+	}
+}
+
+func TestRedisStreamReclaimsFailedEntries(t *testing.T) {
+	client, stream := openTestRedis(t)
+	publishEntries(t, client, stream, "exec-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	deliveries := 0
+	err := newTestRedisSubscriber(client, "c1").Subscribe(ctx, []string{stream}, func(ctx context.Context, msg *Message) error {
+		if deliveries++; deliveries == 1 {
+			return errors.New("transient")
+		}
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Subscribe = %v, want it to stop on cancel", err)
+	}
+
+	if deliveries != 2 {
+		t.Errorf("entry delivered %d times, want it reclaimed once after failing", deliveries)
+	}
+	if pending := pendingEntries(t, client, stream); pending != 0 {
+		t.Errorf("%d entries pending, want the reclaimed entry acknowledged", pending)
+	}
+}
+
+func TestRedisStreamReclaimsEntriesOfDeadConsumers(t *testing.T) {
+	client, stream := openTestRedis(t)
+	publishEntries(t, client, stream, "exec-1")
+
+	// A consumer reads the entry and dies before acknowledging it
+	if err := newTestRedisSubscriber(client, "dead").ensureGroup(context.Background(), stream); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    testGroup,
+		Consumer: "dead",
+		Streams:  []string{stream, ">"},
+		Count:    1,
+	}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var reclaimed string
+	err = newTestRedisSubscriber(client, "c1").Subscribe(ctx, []string{stream}, func(ctx context.Context, msg *Message) error {
+		reclaimed = string(msg.Key)
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Subscribe = %v, want it to stop on cancel", err)
+	}
+
+	if reclaimed != "exec-1" {
+		t.Errorf("reclaimed %q, want the entry of the dead consumer", reclaimed)
+	}
+	if pending := pendingEntries(t, client, stream); pending != 0 {
+		t.Errorf("%d entries pending, want the reclaimed entry acknowledged", pending)
+	}
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+)
+
+// TopicRouter decides which topic a task type is published to. Task types
+// without an explicit route go to the default task topic.
+type TopicRouter struct {
+	mu           sync.RWMutex
+	defaultTopic string
+	routes       map[string]string
+}
+
+func NewTopicRouter(defaultTopic string) *TopicRouter {
+	return &TopicRouter{
+		defaultTopic: defaultTopic,
+		routes:       make(map[string]string),
+	}
+}
+
+// NewTopicRouterFromRegistry builds a router from the topic overrides declared
+// on the states of every registered workflow.
+func NewTopicRouterFromRegistry(defaultTopic string, registry *dsl.Registry) (*TopicRouter, error) {
+	router := NewTopicRouter(defaultTopic)
+	for _, wf := range registry.All() {
+		for _, st := range wf.States {
+			if st.Topic == "" {
+				continue
+			}
+			if err := router.Route(st.TaskType(), st.Topic); err != nil {
+				return nil, fmt.Errorf("workflow %s state %s: %w", wf.Name, st.ID, err)
+			}
+		}
+	}
+	return router, nil
+}
+
+// Route sends taskType to topic. Routing the same task type to two different
+// topics is an error, since workers would only ever see half of the tasks.
+func (r *TopicRouter) Route(taskType, topic string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.routes[taskType]; ok && existing != topic {
+		return fmt.Errorf("task type %s is already routed to %s", taskType, existing)
+	}
+	r.routes[taskType] = topic
+	return nil
+}
+
+func (r *TopicRouter) TopicFor(taskType string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if topic, ok := r.routes[taskType]; ok {
+		return topic
+	}
+	return r.defaultTopic
+}
+
+// Topics returns every topic tasks can be published to, default first.
+func (r *TopicRouter) Topics() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := map[string]bool{r.defaultTopic: true}
+	var extra []string
+	for _, topic := range r.routes {
+		if !seen[topic] {
+			seen[topic] = true
+			extra = append(extra, topic)
+		}
+	}
+	sort.Strings(extra)
+	return append([]string{r.defaultTopic}, extra...)
+}
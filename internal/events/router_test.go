@@ -0,0 +1,109 @@
+package events
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+)
+
+const mediaWorkflow = `
+name: media
+states:
+  - id: transcribe
+    type: task
+    action: transcribe
+    topic: task-queue-gpu
+    on_success: summarize
+  - id: summarize
+    type: llm
+    prompt: "Summarize {{prev.output.text}}"
+    topic: task-queue-llm
+    on_success: notify
+  - id: notify
+    type: notification
+`
+
+// podcastWorkflow reuses the task types of mediaWorkflow without naming a
+// topic.
+const podcastWorkflow = `
+name: podcast
+states:
+  - id: transcribe_episode
+    type: task
+    action: transcribe
+    on_success: show_notes
+  - id: show_notes
+    type: llm
+    prompt: "Write show notes for {{prev.output.text}}"
+`
+
+func newTestRegistry(t *testing.T, definitions ...string) *dsl.Registry {
+	t.Helper()
+	registry := dsl.NewRegistry()
+	for _, def := range definitions {
+		wf, err := dsl.Parse([]byte(def))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := registry.Register(wf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return registry
+}
+
+func TestTopicRouter(t *testing.T) {
+	router, err := NewTopicRouterFromRegistry("task-queue", newTestRegistry(t, mediaWorkflow, podcastWorkflow))
+	if err != nil {
+		t.Fatalf("NewTopicRouterFromRegistry: %v", err)
+	}
+	if err := router.Route("upload", "task-queue-io"); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		taskType string
+		want     string
+	}{
+		{"topic of a custom action state", "transcribe", "task-queue-gpu"},
+		{"topic of a built-in state type", "llm", "task-queue-llm"},
+		{"route added for a task type", "upload", "task-queue-io"},
+		{"state without a topic", "notification", "task-queue"},
+		{"task type of no workflow", "resize_image", "task-queue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := router.TopicFor(tt.taskType); got != tt.want {
+				t.Errorf("TopicFor(%q) = %q, want %q", tt.taskType, got, tt.want)
+			}
+		})
+	}
+
+	want := []string{"task-queue", "task-queue-gpu", "task-queue-io", "task-queue-llm"}
+	if topics := router.Topics(); !reflect.DeepEqual(topics, want) {
+		t.Errorf("Topics = %v, want %v", topics, want)
+	}
+}
+
+func TestTopicRouterRejectsConflictingRoutes(t *testing.T) {
+	conflicting := strings.Replace(podcastWorkflow, "action: transcribe", "action: transcribe\n    topic: task-queue-cpu", 1)
+
+	_, err := NewTopicRouterFromRegistry("task-queue", newTestRegistry(t, mediaWorkflow, conflicting))
+	if err == nil || !strings.Contains(err.Error(), "already routed to task-queue-gpu") {
+		t.Errorf("NewTopicRouterFromRegistry = %v, want the conflicting route rejected", err)
+	}
+
+	// Routing a task type to the topic it already has is fine
+	router := NewTopicRouter("task-queue")
+	for i := 0; i < 2; i++ {
+		if err := router.Route("transcribe", "task-queue-gpu"); err != nil {
+			t.Fatalf("Route: %v", err)
+		}
+	}
+	if err := router.Route("transcribe", "task-queue"); err == nil {
+		t.Error("Route moved a routed task type to another topic")
+	}
+}
@@ -0,0 +1,55 @@
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// ackTimeout bounds how long a subscriber waits for the broker to take an
+// acknowledgement, which is sent after the handler returned and must not
+// depend on its context.
+const ackTimeout = 5 * time.Second
+
+// Message is a broker independent envelope for an encoded event. Partition and
+// Offset are filled in by the transport on delivery.
+type Message struct {
+	Topic     string
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Partition int32
+	Offset    int64
+}
+
+// DeliveryReport is the broker's verdict on a single published message.
+type DeliveryReport struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Err       error
+}
+
+// DeliveryCallback is invoked once the broker has acknowledged or rejected an
+// asynchronously published message. It runs on its own goroutine.
+type DeliveryCallback func(report DeliveryReport)
+
+// Publisher sends messages to a broker.
+type Publisher interface {
+	// Publish blocks until the broker confirmed delivery or ctx is done.
+	Publish(ctx context.Context, msg *Message) (DeliveryReport, error)
+	// PublishAsync returns once msg is queued and reports delivery to done.
+	PublishAsync(msg *Message, done DeliveryCallback) error
+}
+
+// MessageHandler processes one message. Returning nil acknowledges it; an
+// error leaves it unacknowledged so the transport delivers it again.
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// Subscriber delivers messages from a broker. Messages with the same key are
+// handed to the handler in order, one at a time; see the transports for how
+// redelivered messages are ordered.
+type Subscriber interface {
+	// Subscribe blocks, feeding messages from topics to handler, until ctx is
+	// cancelled or the transport fails.
+	Subscribe(ctx context.Context, topics []string, handler MessageHandler) error
+}
@@ -0,0 +1,122 @@
+// Package aitask implements the executor of ai_task states, which send a
+// templated prompt to a language model:
+//
+//	model: "llama3"
+//	prompt: "Extract transcript from audio at {{inputs.file_url}}: {{context}}"
+//
+// The prompt is rendered against the step context, with the task input as
+// inputs. The completion and the token usage reported by the provider become
+// the output of the step.
+package aitask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/workers"
+)
+
+// TaskType is the task type ai_task states are dispatched as.
+const TaskType = "ai_task"
+
+// Request is a single prompt sent to a model.
+type Request struct {
+	Model     string
+	Prompt    string
+	MaxTokens int
+}
+
+// Usage is the token accounting of a completion.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// Response is the completion of a request.
+type Response struct {
+	Text         string
+	Model        string
+	FinishReason string
+	Usage        Usage
+}
+
+// Provider runs prompts against a model.
+type Provider interface {
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// NewProvider creates the provider selected by cfg.
+func NewProvider(cfg *config.AIConfig) (Provider, error) {
+	switch cfg.Provider {
+	case "openai":
+		return NewOpenAIProvider(cfg.BaseURL, cfg.APIKey, cfg.Timeout), nil
+	case "fake":
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q", cfg.Provider)
+	}
+}
+
+// Executor runs ai_task states against a provider.
+type Executor struct {
+	provider     Provider
+	defaultModel string
+	maxTokens    int
+}
+
+// NewExecutor creates an executor. defaultModel is used by states that do
+// not name a model; maxTokens caps completions when positive.
+func NewExecutor(provider Provider, defaultModel string, maxTokens int) *Executor {
+	return &Executor{
+		provider:     provider,
+		defaultModel: defaultModel,
+		maxTokens:    maxTokens,
+	}
+}
+
+// Execute is a workers.Executor.
+func (e *Executor) Execute(ctx context.Context, task *events.TaskEvent) (map[string]interface{}, error) {
+	tmpl, _ := task.Params["prompt"].(string)
+	if tmpl == "" {
+		return nil, errors.New("ai_task has no prompt")
+	}
+	prompt, err := workers.RenderTemplate(ctx, task, tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
+	}
+
+	model, _ := task.Params["model"].(string)
+	if model == "" {
+		model = e.defaultModel
+	}
+	if model == "" {
+		return nil, errors.New("ai_task has no model and no default model is configured")
+	}
+
+	resp, err := e.provider.Complete(ctx, Request{
+		Model:     model,
+		Prompt:    prompt,
+		MaxTokens: e.maxTokens,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("model %s: %w", model, err)
+	}
+	if resp.Model == "" {
+		resp.Model = model
+	}
+
+	return map[string]interface{}{
+		"text":          resp.Text,
+		"model":         resp.Model,
+		"finish_reason": resp.FinishReason,
+		"usage": map[string]interface{}{
+			"prompt_tokens":     resp.Usage.PromptTokens,
+			"completion_tokens": resp.Usage.CompletionTokens,
+			"total_tokens":      resp.Usage.TotalTokens,
+		},
+	}, nil
+}
@@ -0,0 +1,124 @@
+package aitask
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/events"
+)
+
+func extractTask(params map[string]interface{}) *events.TaskEvent {
+	return &events.TaskEvent{
+		ExecutionID: "exec-1",
+		TaskID:      "task-1",
+		TaskType:    TaskType,
+		Input:       map[string]interface{}{"file_url": "s3://uploads/talk.mp3"},
+		Params:      params,
+		Context: map[string]interface{}{
+			"execution_id": "exec-1",
+			"trigger":      map[string]interface{}{"language": "en"},
+		},
+	}
+}
+
+func TestExecutorRendersPromptAndCapturesUsage(t *testing.T) {
+	var got Request
+	fake := NewFakeProvider()
+	fake.Reply = func(req Request) string {
+		got = req
+		return "Hello and welcome to the talk"
+	}
+	e := NewExecutor(fake, "llama3", 0)
+
+	output, err := e.Execute(context.Background(), extractTask(map[string]interface{}{
+		"prompt": "Transcribe {{inputs.file_url}} in {{trigger.language}} for {{context.execution_id}}",
+	}))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	want := Request{Model: "llama3", Prompt: "Transcribe s3://uploads/talk.mp3 in en for exec-1"}
+	if got != want {
+		t.Errorf("request = %+v, want %+v", got, want)
+	}
+	wantOutput := map[string]interface{}{
+		"text":          "Hello and welcome to the talk",
+		"model":         "llama3",
+		"finish_reason": "stop",
+		"usage": map[string]interface{}{
+			"prompt_tokens":     6,
+			"completion_tokens": 6,
+			"total_tokens":      12,
+		},
+	}
+	if !reflect.DeepEqual(output, wantOutput) {
+		t.Errorf("output = %v, want %v", output, wantOutput)
+	}
+}
+
+func TestExecutorModelAndTokenLimit(t *testing.T) {
+	e := NewExecutor(NewFakeProvider(), "llama3", 3)
+
+	output, err := e.Execute(context.Background(), extractTask(map[string]interface{}{
+		"model":  "gpt-4o-mini",
+		"prompt": "one two three four five",
+	}))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if output["model"] != "gpt-4o-mini" {
+		t.Errorf("model = %v, want the model of the state over the default", output["model"])
+	}
+	if output["text"] != "one two three" || output["finish_reason"] != "length" {
+		t.Errorf("output = %v, want the completion cut at 3 tokens", output)
+	}
+}
+
+type failingProvider struct{ err error }
+
+func (p failingProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	return nil, p.err
+}
+
+func TestExecutorErrors(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := NewExecutor(NewFakeProvider(), "llama3", 0).Execute(ctx, extractTask(nil)); err == nil {
+		t.Error("Execute without a prompt succeeded")
+	}
+	if _, err := NewExecutor(NewFakeProvider(), "", 0).Execute(ctx, extractTask(map[string]interface{}{"prompt": "hi"})); err == nil {
+		t.Error("Execute without any model succeeded")
+	}
+
+	unavailable := errors.New("provider responded 503: overloaded")
+	_, err := NewExecutor(failingProvider{unavailable}, "llama3", 0).Execute(ctx, extractTask(map[string]interface{}{"prompt": "hi"}))
+	if !errors.Is(err, unavailable) || !strings.HasPrefix(err.Error(), "model llama3: ") {
+		t.Errorf("Execute = %v, want the provider error wrapped with the model", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := NewExecutor(NewFakeProvider(), "llama3", 0).Execute(cancelled, extractTask(map[string]interface{}{"prompt": "hi"})); !errors.Is(err, context.Canceled) {
+		t.Errorf("Execute on a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	if p, err := NewProvider(&config.AIConfig{Provider: "fake"}); err != nil {
+		t.Errorf("fake: %v", err)
+	} else if _, ok := p.(*FakeProvider); !ok {
+		t.Errorf("fake = %T", p)
+	}
+	if p, err := NewProvider(&config.AIConfig{Provider: "openai", BaseURL: "http://localhost:11434/v1"}); err != nil {
+		t.Errorf("openai: %v", err)
+	} else if _, ok := p.(*OpenAIProvider); !ok {
+		t.Errorf("openai = %T", p)
+	}
+	if _, err := NewProvider(&config.AIConfig{Provider: "palm"}); err == nil {
+		t.Error("unknown provider accepted")
+	}
+}
@@ -0,0 +1,47 @@
+package aitask
+
+import (
+	"context"
+	"strings"
+)
+
+// FakeProvider answers without a model, for tests and local runs. The same
+// request always gets the same response; tokens are counted as words.
+type FakeProvider struct {
+	// Reply computes the completion. By default the prompt is echoed back.
+	Reply func(req Request) string
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		Reply: func(req Request) string { return req.Prompt },
+	}
+}
+
+func (p *FakeProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	text := p.Reply(req)
+	finish := "stop"
+	if req.MaxTokens > 0 {
+		if words := strings.Fields(text); len(words) > req.MaxTokens {
+			text = strings.Join(words[:req.MaxTokens], " ")
+			finish = "length"
+		}
+	}
+
+	usage := Usage{
+		PromptTokens:     len(strings.Fields(req.Prompt)),
+		CompletionTokens: len(strings.Fields(text)),
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	return &Response{
+		Text:         text,
+		Model:        req.Model,
+		FinishReason: finish,
+		Usage:        usage,
+	}, nil
+}
@@ -0,0 +1,180 @@
+package aitask
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBody bounds how much of a failed response is kept in the error.
+const maxErrorBody = 1024
+
+const (
+	// maxAttempts bounds how often a request is sent when the provider is
+	// rate limiting or failing.
+	maxAttempts = 3
+	// retryBackoff is the wait before the first retry, doubled after each
+	// one, unless the provider asks for another with Retry-After.
+	retryBackoff = time.Second
+)
+
+// OpenAIProvider calls the chat completions endpoint of an OpenAI-compatible
+// API. The base URL includes the version prefix, e.g.
+// https://api.openai.com/v1 or http://localhost:11434/v1 for Ollama.
+//
+// Responses with status 429 or 5xx are retried with backoff; other failures
+// are returned at once.
+type OpenAIProvider struct {
+	baseURL  string
+	apiKey   string
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+func NewOpenAIProvider(baseURL, apiKey string, timeout time.Duration) *OpenAIProvider {
+	return &OpenAIProvider{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: timeout},
+		attempts: maxAttempts,
+		backoff:  retryBackoff,
+	}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens,omitempty"`
+}
+
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:     req.Model,
+		Messages:  []chatMessage{{Role: "user", Content: req.Prompt}},
+		MaxTokens: req.MaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := p.backoff
+	for attempt := 1; ; attempt++ {
+		resp, retryAfter, err := p.complete(ctx, body)
+		var transient *transientError
+		if !errors.As(err, &transient) || attempt >= p.attempts {
+			return resp, err
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// transientError is a failure worth retrying, such as a rate limit.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// complete sends a single request. For retryable failures it also returns
+// the wait the provider asked for, if any.
+func (p *OpenAIProvider) complete(ctx context.Context, body []byte) (*Response, time.Duration, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := statusError(resp)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			return nil, time.Duration(retryAfter) * time.Second, &transientError{err}
+		}
+		return nil, 0, err
+	}
+
+	var completion chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode completion: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, 0, errors.New("provider returned no choices")
+	}
+
+	choice := completion.Choices[0]
+	return &Response{
+		Text:         choice.Message.Content,
+		Model:        completion.Model,
+		FinishReason: choice.FinishReason,
+		Usage: Usage{
+			PromptTokens:     completion.Usage.PromptTokens,
+			CompletionTokens: completion.Usage.CompletionTokens,
+			TotalTokens:      completion.Usage.TotalTokens,
+		},
+	}, 0, nil
+}
+
+// statusError describes a failed response, preferring the message of an
+// OpenAI style error body.
+func statusError(resp *http.Response) error {
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var apiErr errorResponse
+	if json.Unmarshal(detail, &apiErr) == nil && apiErr.Error.Message != "" {
+		return fmt.Errorf("provider responded %d: %s", resp.StatusCode, apiErr.Error.Message)
+	}
+	return fmt.Errorf("provider responded %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+}
@@ -0,0 +1,132 @@
+package aitask
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestOpenAIProvider(url string) *OpenAIProvider {
+	p := NewOpenAIProvider(url+"/v1/", "sk-test", time.Second)
+	p.backoff = time.Millisecond
+	return p
+}
+
+func TestOpenAIProviderComplete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		if req.Model != "llama3" || req.MaxTokens != 256 || len(req.Messages) != 1 ||
+			req.Messages[0] != (chatMessage{Role: "user", Content: "Summarise this"}) {
+			t.Errorf("chat request = %+v", req)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"model": "llama3:8b",
+			"choices": [{"message": {"role": "assistant", "content": "A summary."}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}
+		}`))
+	}))
+	defer server.Close()
+
+	resp, err := newTestOpenAIProvider(server.URL).Complete(context.Background(), Request{Model: "llama3", Prompt: "Summarise this", MaxTokens: 256})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	want := Response{Text: "A summary.", Model: "llama3:8b", FinishReason: "stop", Usage: Usage{12, 3, 15}}
+	if *resp != want {
+		t.Errorf("response = %+v, want %+v", *resp, want)
+	}
+}
+
+func TestOpenAIProviderRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"error": {"message": "rate limited"}}`, http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"model": "llama3", "choices": [{"message": {"content": "ok"}, "finish_reason": "stop"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	resp, err := newTestOpenAIProvider(server.URL).Complete(context.Background(), Request{Model: "llama3", Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Text != "ok" || calls.Load() != 3 {
+		t.Errorf("got %q after %d calls, want ok after 3", resp.Text, calls.Load())
+	}
+}
+
+func TestOpenAIProviderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		calls  int32
+		want   string
+	}{
+		{"api error", http.StatusBadRequest, `{"error": {"message": "model not found"}}`, 1, "provider responded 400: model not found"},
+		{"plain error", http.StatusUnauthorized, "invalid key\n", 1, "provider responded 401: invalid key"},
+		{"persistent outage", http.StatusServiceUnavailable, "overloaded", maxAttempts, "provider responded 503: overloaded"},
+		{"no choices", http.StatusOK, `{"model": "llama3", "choices": []}`, 1, "provider returned no choices"},
+		{"malformed", http.StatusOK, `{"choices": [`, 1, "failed to decode completion"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := newTestOpenAIProvider(server.URL).Complete(context.Background(), Request{Model: "llama3", Prompt: "hi"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Complete = %v, want %q", err, tt.want)
+			}
+			if calls.Load() != tt.calls {
+				t.Errorf("%d calls, want %d", calls.Load(), tt.calls)
+			}
+		})
+	}
+}
+
+func TestOpenAIProviderStopsRetryingOnCancel(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := newTestOpenAIProvider(server.URL).Complete(ctx, Request{Model: "llama3", Prompt: "hi"}); err == nil {
+		t.Fatal("Complete succeeded")
+	}
+	if time.Since(started) > 5*time.Second || calls.Load() != 1 {
+		t.Errorf("gave up after %v and %d calls, want it to stop waiting on cancel", time.Since(started), calls.Load())
+	}
+}
@@ -0,0 +1,130 @@
+package audio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+)
+
+// GenerateExecutor speaks the chunks produced by extract_text, or a plain
+// text input, through a TTS. metadata.format and metadata.sample_rate of the
+// request select the encoding.
+type GenerateExecutor struct {
+	tts TTS
+}
+
+func NewGenerateExecutor(tts TTS) *GenerateExecutor {
+	return &GenerateExecutor{tts: tts}
+}
+
+// Execute is a workers.Executor. The output carries the audio with its
+// checksum, which store_audio verifies before storing it.
+func (e *GenerateExecutor) Execute(ctx context.Context, task *events.TaskEvent) (map[string]interface{}, error) {
+	chunks := stringList(task.Input["chunks"])
+	if len(chunks) == 0 {
+		if text, _ := task.Input["text"].(string); text != "" {
+			chunks = Chunk(Normalize(text), DefaultChunkSize)
+		}
+	}
+	if len(chunks) == 0 {
+		return nil, errors.New("no text to speak")
+	}
+
+	metadata, _ := task.Input["metadata"].(map[string]interface{})
+	format, _ := metadata["format"].(string)
+	sampleRate, err := intValue(metadata["sample_rate"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid sample_rate: %w", err)
+	}
+	voice, _ := task.Input["voice"].(string)
+
+	audio, err := e.tts.Synthesize(ctx, SynthesisRequest{
+		Chunks:     chunks,
+		Voice:      voice,
+		Format:     format,
+		SampleRate: sampleRate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(audio.Data)
+	return map[string]interface{}{
+		"audio_data":   audio.Data,
+		"sha256":       hex.EncodeToString(sum[:]),
+		"size":         len(audio.Data),
+		"format":       audio.Format,
+		"content_type": audio.ContentType,
+		"sample_rate":  audio.SampleRate,
+		"duration_ms":  audio.Duration.Milliseconds(),
+		"voice":        voice,
+		"metadata":     task.Input["metadata"],
+	}, nil
+}
+
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// intValue reads a number that went through JSON, YAML or protobuf, where
+// it may have become a float64, a json.Number, an unsigned integer or a
+// string.
+func intValue(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return n, nil
+	case int32:
+		return int(n), nil
+	case int64:
+		return int(n), nil
+	case uint:
+		return uintValue(uint64(n))
+	case uint32:
+		return int(n), nil
+	case uint64:
+		return uintValue(n)
+	case float64:
+		if n != float64(int(n)) {
+			return 0, fmt.Errorf("%v is not an integer", n)
+		}
+		return int(n), nil
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("%v is not an integer", n)
+		}
+		return int(i), nil
+	case string:
+		return strconv.Atoi(n)
+	default:
+		return 0, fmt.Errorf("unexpected %T", v)
+	}
+}
+
+func uintValue(n uint64) (int, error) {
+	if n > math.MaxInt {
+		return 0, fmt.Errorf("%d is too large", n)
+	}
+	return int(n), nil
+}
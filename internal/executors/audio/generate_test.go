@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+)
+
+func TestIntValue(t *testing.T) {
+	tests := []struct {
+		in      interface{}
+		want    int
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{44100, 44100, false},
+		{int32(16000), 16000, false},
+		{int64(22050), 22050, false},
+		{uint(8000), 8000, false},
+		{uint32(48000), 48000, false},
+		{uint64(22050), 22050, false},
+		{float64(44100), 44100, false},
+		{json.Number("24000"), 24000, false},
+		{"11025", 11025, false},
+		{float64(22050.5), 0, true},
+		{json.Number("22050.5"), 0, true},
+		{uint64(math.MaxUint64), 0, true},
+		{"fast", 0, true},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := intValue(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("intValue(%T %v) = %d, %v; want %d, error %v", tt.in, tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGenerateExecutor(t *testing.T) {
+	e := NewGenerateExecutor(NewToneSynthesizer())
+
+	// Protobuf events keep unsigned integers, JSON decoded with UseNumber
+	// yields json.Number
+	for _, rate := range []interface{}{uint64(16000), json.Number("16000")} {
+		output, err := e.Execute(context.Background(), &events.TaskEvent{Input: map[string]interface{}{
+			"chunks":   []interface{}{"Hello there.", "Welcome back."},
+			"metadata": map[string]interface{}{"format": "wav", "sample_rate": rate},
+		}})
+		if err != nil {
+			t.Fatalf("Execute with sample_rate %T: %v", rate, err)
+		}
+		if output["sample_rate"] != 16000 {
+			t.Errorf("sample_rate %T: output sample_rate = %v, want 16000", rate, output["sample_rate"])
+		}
+		data := output["audio_data"].([]byte)
+		if output["size"] != len(data) || output["content_type"] != "audio/wav" {
+			t.Errorf("output = size %v, %v; want %d bytes of audio/wav", output["size"], output["content_type"], len(data))
+		}
+	}
+
+	output, err := e.Execute(context.Background(), &events.TaskEvent{Input: map[string]interface{}{"text": "Plain “text” input."}})
+	if err != nil {
+		t.Fatalf("Execute with text: %v", err)
+	}
+	if output["sample_rate"] != DefaultSampleRate || output["format"] != FormatWAV {
+		t.Errorf("output = %v Hz %v, want the defaults", output["sample_rate"], output["format"])
+	}
+
+	for name, input := range map[string]map[string]interface{}{
+		"no text":             {},
+		"invalid sample rate": {"text": "Hi.", "metadata": map[string]interface{}{"sample_rate": 22050.5}},
+		"unsupported format":  {"text": "Hi.", "metadata": map[string]interface{}{"format": "mp3"}},
+	} {
+		if _, err := e.Execute(context.Background(), &events.TaskEvent{Input: input}); err == nil {
+			t.Errorf("%s: Execute succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package audio
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/workers"
+)
+
+// StoreExecutor keeps the audio produced by generate_audio in the blob store
+// as the execution's audio.<format> artifact, which the API serves for
+// download.
+type StoreExecutor struct{}
+
+func NewStoreExecutor() *StoreExecutor {
+	return &StoreExecutor{}
+}
+
+// Execute is a workers.Executor. The audio is checked against the sha256 of
+// the input, when present, before it is stored.
+func (e *StoreExecutor) Execute(ctx context.Context, task *events.TaskEvent) (map[string]interface{}, error) {
+	data, err := audioBytes(task.Input["audio_data"])
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if want, _ := task.Input["sha256"].(string); want != "" && want != checksum {
+		return nil, fmt.Errorf("audio checksum mismatch: got %s, want %s", checksum, want)
+	}
+
+	format, _ := task.Input["format"].(string)
+	if format == "" {
+		format = FormatWAV
+	}
+	contentType, _ := task.Input["content_type"].(string)
+
+	artifact, err := workers.RegisterArtifact(ctx, "audio."+format, contentType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"artifact":     artifact.Name,
+		"key":          artifact.Key,
+		"size":         artifact.Size,
+		"sha256":       artifact.SHA256,
+		"content_type": artifact.ContentType,
+		"format":       format,
+		"sample_rate":  task.Input["sample_rate"],
+		"duration_ms":  task.Input["duration_ms"],
+		"metadata":     task.Input["metadata"],
+	}, nil
+}
+
+// audioBytes accepts audio as loaded from a claim check, or base64 encoded
+// when it was small enough to travel inside a JSON event.
+func audioBytes(v interface{}) ([]byte, error) {
+	switch data := v.(type) {
+	case []byte:
+		if len(data) > 0 {
+			return data, nil
+		}
+	case string:
+		if data != "" {
+			decoded, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return nil, fmt.Errorf("audio_data is not base64: %w", err)
+			}
+			return decoded, nil
+		}
+	}
+	return nil, errors.New("no audio_data to store")
+}
@@ -0,0 +1,154 @@
+// Package audio provides reference executors for the audio generation
+// pipeline: extract_text prepares the request text for speech, generate_audio
+// turns it into audio through a TTS adapter and store_audio keeps the result
+// as an artifact of the execution.
+//
+// Each executor passes voice and metadata on in its output, so the pipeline
+// runs without input templates.
+package audio
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+)
+
+// Task types of the pipeline.
+const (
+	TaskExtractText   = "extract_text"
+	TaskGenerateAudio = "generate_audio"
+	TaskStoreAudio    = "store_audio"
+)
+
+// DefaultChunkSize is the chunk size used when none is configured.
+const DefaultChunkSize = 500
+
+// punctuation folds typographic characters to the plain ones speech engines
+// read reliably.
+var punctuation = strings.NewReplacer(
+	"‘", "'", "’", "'", "“", `"`, "”", `"`,
+	"–", "-", "—", " - ", "…", "...", "\u00a0", " ",
+)
+
+// TextExecutor normalizes the text of a request and splits it into chunks
+// of whole sentences no longer than chunkSize characters.
+type TextExecutor struct {
+	chunkSize int
+}
+
+func NewTextExecutor(chunkSize int) *TextExecutor {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	return &TextExecutor{chunkSize: chunkSize}
+}
+
+// Execute is a workers.Executor. The input needs text; an ai_task before it
+// can supply it as well.
+func (e *TextExecutor) Execute(ctx context.Context, task *events.TaskEvent) (map[string]interface{}, error) {
+	raw, _ := task.Input["text"].(string)
+	text := Normalize(raw)
+	if text == "" {
+		return nil, errors.New("no text to extract")
+	}
+
+	chunks := Chunk(text, e.chunkSize)
+	list := make([]interface{}, len(chunks))
+	for i, chunk := range chunks {
+		list[i] = chunk
+	}
+
+	return map[string]interface{}{
+		"text":       text,
+		"chunks":     list,
+		"characters": len([]rune(text)),
+		"words":      len(strings.Fields(text)),
+		"voice":      task.Input["voice"],
+		"metadata":   task.Input["metadata"],
+	}, nil
+}
+
+// Normalize folds typographic punctuation, drops control characters and
+// collapses whitespace.
+func Normalize(text string) string {
+	text = punctuation.Replace(text)
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), r == unicode.ReplacementChar:
+			return -1
+		}
+		return r
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Chunk splits normalized text into pieces of at most size characters,
+// breaking between sentences where possible and between words otherwise.
+func Chunk(text string, size int) []string {
+	var chunks []string
+	var current strings.Builder
+	currentLen := 0
+
+	flush := func() {
+		if currentLen > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentLen = 0
+		}
+	}
+	add := func(piece string, n int) {
+		if currentLen > 0 && currentLen+1+n > size {
+			flush()
+		}
+		if currentLen > 0 {
+			current.WriteByte(' ')
+			currentLen++
+		}
+		current.WriteString(piece)
+		currentLen += n
+	}
+
+	for _, sentence := range sentences(text) {
+		n := len([]rune(sentence))
+		if n <= size {
+			add(sentence, n)
+			continue
+		}
+		// A sentence longer than a chunk is split between words; a word
+		// longer than a chunk is cut.
+		for _, word := range strings.Fields(sentence) {
+			runes := []rune(word)
+			for len(runes) > size {
+				flush()
+				chunks = append(chunks, string(runes[:size]))
+				runes = runes[size:]
+			}
+			add(string(runes), len(runes))
+		}
+	}
+	flush()
+	return chunks
+}
+
+// sentences splits text after sentence-ending punctuation followed by a
+// space.
+func sentences(text string) []string {
+	var out []string
+	start := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		if (r == '.' || r == '!' || r == '?') && i+1 < len(runes) && runes[i+1] == ' ' {
+			out = append(out, strings.TrimSpace(string(runes[start:i+1])))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(string(runes[start:])); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package audio
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+)
+
+func TestNormalize(t *testing.T) {
+	got := Normalize("  “Hello” world\u0007 — it’s\n\tdone…  ")
+	if want := `"Hello" world - it's done...`; got != want {
+		t.Errorf("Normalize = %q, want %q", got, want)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		text string
+		size int
+		want []string
+	}{
+		{"One. Two. Three.", 10, []string{"One. Two.", "Three."}},
+		{"One. Two. Three.", 100, []string{"One. Two. Three."}},
+		{"A sentence that is far too long.", 12, []string{"A sentence", "that is far", "too long."}},
+		{"Supercalifragilistic", 8, []string{"Supercal", "ifragili", "stic"}},
+		{"Why? Because! Fine.", 9, []string{"Why?", "Because!", "Fine."}},
+	}
+	for _, tt := range tests {
+		if got := Chunk(tt.text, tt.size); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Chunk(%q, %d) = %q, want %q", tt.text, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestTextExecutor(t *testing.T) {
+	e := NewTextExecutor(0)
+	output, err := e.Execute(context.Background(), &events.TaskEvent{Input: map[string]interface{}{
+		"text":     strings.Repeat("Hello world. ", 60),
+		"voice":    "alloy",
+		"metadata": map[string]interface{}{"format": "wav"},
+	}})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	chunks := output["chunks"].([]interface{})
+	if len(chunks) != 2 || len(chunks[0].(string)) > DefaultChunkSize {
+		t.Errorf("%d chunks, want 2 of at most %d characters", len(chunks), DefaultChunkSize)
+	}
+	if output["words"] != 120 || output["voice"] != "alloy" || output["metadata"] == nil {
+		t.Errorf("output = %v, want the word count, voice and metadata passed on", output)
+	}
+
+	if _, err := e.Execute(context.Background(), &events.TaskEvent{Input: map[string]interface{}{"text": " \n "}}); err == nil {
+		t.Error("Execute on blank text succeeded")
+	}
+}
@@ -0,0 +1,164 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Formats produced by the offline synthesizer.
+const (
+	FormatWAV = "wav"
+	FormatPCM = "pcm"
+)
+
+// DefaultSampleRate is used when a request does not ask for one.
+const DefaultSampleRate = 22050
+
+// ErrUnsupportedFormat is returned by a TTS for formats it cannot produce.
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// SynthesisRequest is the text to speak and how to encode it. Chunks are
+// spoken in order with a pause between them.
+type SynthesisRequest struct {
+	Chunks     []string
+	Voice      string
+	Format     string
+	SampleRate int
+}
+
+// Audio is a synthesized recording.
+type Audio struct {
+	Data        []byte
+	Format      string
+	ContentType string
+	SampleRate  int
+	Duration    time.Duration
+}
+
+// TTS turns text into audio. Adapters for hosted engines implement it next
+// to the offline ToneSynthesizer.
+type TTS interface {
+	Synthesize(ctx context.Context, req SynthesisRequest) (*Audio, error)
+}
+
+// ToneSynthesizer is an offline TTS that renders every word as a short tone,
+// pitched by the voice and the word's vowels, with pauses between words and
+// chunks. It needs no model or network and always produces the same audio
+// for the same request, which makes the pipeline runnable anywhere.
+//
+// It produces 16-bit little-endian mono PCM, either bare or in a WAV
+// container.
+type ToneSynthesizer struct{}
+
+func NewToneSynthesizer() *ToneSynthesizer {
+	return &ToneSynthesizer{}
+}
+
+const (
+	msPerRune  = 55
+	maxWordMs  = 600
+	wordGapMs  = 90
+	chunkGapMs = 350
+	amplitude  = 0.3 * math.MaxInt16
+	fadeMs     = 8
+)
+
+func (s *ToneSynthesizer) Synthesize(ctx context.Context, req SynthesisRequest) (*Audio, error) {
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = FormatWAV
+	}
+	if format != FormatWAV && format != FormatPCM {
+		return nil, fmt.Errorf("%w %q: the offline synthesizer produces wav or pcm", ErrUnsupportedFormat, req.Format)
+	}
+	rate := req.SampleRate
+	if rate == 0 {
+		rate = DefaultSampleRate
+	}
+	if rate < 8000 || rate > 192000 {
+		return nil, fmt.Errorf("sample rate %d is out of range", rate)
+	}
+
+	base := voicePitch(req.Voice)
+	var samples []int16
+	silence := func(ms int) {
+		samples = append(samples, make([]int16, rate*ms/1000)...)
+	}
+
+	for i, chunk := range req.Chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			silence(chunkGapMs)
+		}
+		for j, word := range strings.Fields(chunk) {
+			if j > 0 {
+				silence(wordGapMs)
+			}
+			samples = append(samples, tone(word, base, rate)...)
+		}
+	}
+
+	pcm := make([]byte, 2*len(samples))
+	for i, v := range samples {
+		pcm[2*i] = byte(v)
+		pcm[2*i+1] = byte(uint16(v) >> 8)
+	}
+
+	audio := &Audio{
+		Data:        pcm,
+		Format:      format,
+		ContentType: "application/octet-stream",
+		SampleRate:  rate,
+		Duration:    time.Duration(len(samples)) * time.Second / time.Duration(rate),
+	}
+	if format == FormatWAV {
+		audio.Data = EncodeWAV(pcm, rate, 1, 16)
+		audio.ContentType = "audio/wav"
+	}
+	return audio, nil
+}
+
+// voicePitch maps a voice name to a base frequency between 110 and 230 Hz.
+func voicePitch(voice string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(voice))
+	return 110 + float64(h.Sum32()%120)
+}
+
+// tone renders a word as a sine wave whose length follows the word and whose
+// pitch rises with its share of vowels, faded in and out to avoid clicks.
+func tone(word string, base float64, rate int) []int16 {
+	runes := []rune(word)
+	vowels := 0
+	for _, r := range runes {
+		if strings.ContainsRune("aeiouy", unicode.ToLower(r)) {
+			vowels++
+		}
+	}
+
+	ms := min(len(runes)*msPerRune, maxWordMs)
+	freq := base * (1 + float64(vowels)/float64(len(runes)))
+	n := rate * ms / 1000
+	fade := rate * fadeMs / 1000
+
+	samples := make([]int16, n)
+	for i := range samples {
+		gain := 1.0
+		if i < fade {
+			gain = float64(i) / float64(fade)
+		} else if n-i < fade {
+			gain = float64(n-i) / float64(fade)
+		}
+		v := math.Sin(2 * math.Pi * freq * float64(i) / float64(rate))
+		samples[i] = int16(v * gain * amplitude)
+	}
+	return samples
+}
@@ -0,0 +1,113 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestToneSynthesizerWAV(t *testing.T) {
+	s := NewToneSynthesizer()
+	req := SynthesisRequest{Chunks: []string{"Hello there.", "Bye."}, Voice: "alloy", SampleRate: 16000}
+
+	audio, err := s.Synthesize(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	if audio.Format != FormatWAV || audio.ContentType != "audio/wav" || audio.SampleRate != 16000 {
+		t.Errorf("audio = %s %s %d Hz, want wav audio/wav 16000 Hz", audio.Format, audio.ContentType, audio.SampleRate)
+	}
+
+	data := audio.Data
+	pcmLen := binary.LittleEndian.Uint32(data[40:])
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" || string(data[36:40]) != "data" {
+		t.Fatalf("not a WAV file: % x", data[:44])
+	}
+	if int(pcmLen) != len(data)-44 || binary.LittleEndian.Uint32(data[4:]) != pcmLen+36 {
+		t.Errorf("chunk sizes do not match the %d byte file", len(data))
+	}
+	if rate := binary.LittleEndian.Uint32(data[24:]); rate != 16000 {
+		t.Errorf("sample rate in header = %d, want 16000", rate)
+	}
+
+	// hello (5 runes) and there. (6), a word gap, a chunk gap and bye. (4)
+	wantMs := 5*msPerRune + wordGapMs + 6*msPerRune + chunkGapMs + 4*msPerRune
+	if samples := int(pcmLen) / 2; samples != 16000*wantMs/1000 {
+		t.Errorf("%d samples, want %d", samples, 16000*wantMs/1000)
+	}
+	if want := time.Duration(wantMs) * time.Millisecond; audio.Duration != want {
+		t.Errorf("duration = %v, want %v", audio.Duration, want)
+	}
+
+	again, err := s.Synthesize(context.Background(), req)
+	if err != nil || !bytes.Equal(again.Data, audio.Data) {
+		t.Error("the same request produced different audio")
+	}
+	req.Voice = "echo"
+	other, err := s.Synthesize(context.Background(), req)
+	if err != nil || bytes.Equal(other.Data, audio.Data) {
+		t.Error("another voice produced the same audio")
+	}
+}
+
+func TestToneSynthesizerPCM(t *testing.T) {
+	audio, err := NewToneSynthesizer().Synthesize(context.Background(), SynthesisRequest{Chunks: []string{"Hi"}, Format: "PCM"})
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	if audio.Format != FormatPCM || audio.SampleRate != DefaultSampleRate {
+		t.Errorf("audio = %s at %d Hz, want pcm at the default rate", audio.Format, audio.SampleRate)
+	}
+	if want := 2 * (DefaultSampleRate * 2 * msPerRune / 1000); len(audio.Data) != want {
+		t.Errorf("%d bytes, want %d bytes of bare samples", len(audio.Data), want)
+	}
+}
+
+func TestToneSynthesizerRejects(t *testing.T) {
+	s := NewToneSynthesizer()
+	if _, err := s.Synthesize(context.Background(), SynthesisRequest{Chunks: []string{"Hi"}, Format: "mp3"}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("mp3 = %v, want ErrUnsupportedFormat", err)
+	}
+	for _, rate := range []int{4000, 384000} {
+		if _, err := s.Synthesize(context.Background(), SynthesisRequest{Chunks: []string{"Hi"}, SampleRate: rate}); err == nil {
+			t.Errorf("sample rate %d accepted", rate)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Synthesize(ctx, SynthesisRequest{Chunks: []string{"Hi"}}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled = %v, want context.Canceled", err)
+	}
+}
+
+func TestEncodeWAV(t *testing.T) {
+	pcm := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	data := EncodeWAV(pcm, 44100, 2, 16)
+
+	le := binary.LittleEndian
+	for name, got := range map[string]uint32{
+		"riff size":   le.Uint32(data[4:]),
+		"fmt size":    le.Uint32(data[16:]),
+		"format":      uint32(le.Uint16(data[20:])),
+		"channels":    uint32(le.Uint16(data[22:])),
+		"sample rate": le.Uint32(data[24:]),
+		"byte rate":   le.Uint32(data[28:]),
+		"block align": uint32(le.Uint16(data[32:])),
+		"bits":        uint32(le.Uint16(data[34:])),
+		"data size":   le.Uint32(data[40:]),
+	} {
+		want := map[string]uint32{
+			"riff size": 44, "fmt size": 16, "format": 1, "channels": 2, "sample rate": 44100,
+			"byte rate": 176400, "block align": 4, "bits": 16, "data size": 8,
+		}[name]
+		if got != want {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+	if !bytes.Equal(data[44:], pcm) {
+		t.Errorf("samples = % x, want % x", data[44:], pcm)
+	}
+}
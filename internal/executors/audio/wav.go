@@ -0,0 +1,27 @@
+package audio
+
+import "encoding/binary"
+
+// EncodeWAV wraps little-endian PCM samples in a RIFF/WAVE container.
+func EncodeWAV(pcm []byte, sampleRate, channels, bitsPerSample int) []byte {
+	blockAlign := channels * bitsPerSample / 8
+	out := make([]byte, 44+len(pcm))
+
+	copy(out[0:], "RIFF")
+	binary.LittleEndian.PutUint32(out[4:], uint32(36+len(pcm)))
+	copy(out[8:], "WAVE")
+
+	copy(out[12:], "fmt ")
+	binary.LittleEndian.PutUint32(out[16:], 16) // PCM header size
+	binary.LittleEndian.PutUint16(out[20:], 1)  // PCM
+	binary.LittleEndian.PutUint16(out[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(out[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(out[28:], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(out[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(out[34:], uint16(bitsPerSample))
+
+	copy(out[36:], "data")
+	binary.LittleEndian.PutUint32(out[40:], uint32(len(pcm)))
+	copy(out[44:], pcm)
+	return out
+}
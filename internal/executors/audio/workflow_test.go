@@ -0,0 +1,38 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/executors/aitask"
+	"github.com/Vighnesh-V-H/async/internal/executors/notification"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+)
+
+// TestAudioWorkflowRunsOnBuiltInExecutors keeps an audio workflow runnable end
+// to end by the executors cmd/worker registers, on the default task topic.
+func TestAudioWorkflowRunsOnBuiltInExecutors(t *testing.T) {
+	wf, err := dsl.ParseFile("testdata/audio_workflow.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered := map[string]bool{
+		aitask.TaskType:       true,
+		notification.TaskType: true,
+		TaskExtractText:       true,
+		TaskGenerateAudio:     true,
+		TaskStoreAudio:        true,
+	}
+
+	pipeline := wf.Pipeline()
+	if len(pipeline) != len(wf.States) {
+		t.Errorf("%d of %d states are reachable", len(pipeline), len(wf.States))
+	}
+	for _, state := range pipeline {
+		if !registered[state.TaskType()] {
+			t.Errorf("state %s runs %s, which no executor is registered for", state.ID, state.TaskType())
+		}
+		if state.Topic != "" {
+			t.Errorf("state %s is routed to %s, which workers do not consume by default", state.ID, state.Topic)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package notification
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+)
+
+// DeliveryLog remembers which recipients of a task were notified, so a task
+// that runs again, such as after a partial failure or a redelivery, skips
+// them instead of notifying them twice.
+type DeliveryLog interface {
+	Delivered(ctx context.Context, executionID, taskID, kind, target string) (bool, error)
+	Record(ctx context.Context, executionID, taskID, kind, target string) error
+}
+
+// StoreDeliveryLog keeps an empty marker per delivered recipient in a blob
+// store, next to the artifacts of the execution.
+type StoreDeliveryLog struct {
+	store blob.Store
+}
+
+func NewStoreDeliveryLog(store blob.Store) *StoreDeliveryLog {
+	return &StoreDeliveryLog{store: store}
+}
+
+func (l *StoreDeliveryLog) Delivered(ctx context.Context, executionID, taskID, kind, target string) (bool, error) {
+	_, err := l.store.Get(ctx, deliveryKey(executionID, taskID, kind, target))
+	if errors.Is(err, blob.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (l *StoreDeliveryLog) Record(ctx context.Context, executionID, taskID, kind, target string) error {
+	return l.store.Put(ctx, deliveryKey(executionID, taskID, kind, target), nil, "application/octet-stream")
+}
+
+// deliveryKey hashes the recipient, as targets may be URLs or addresses that
+// make poor object keys.
+func deliveryKey(executionID, taskID, kind, target string) string {
+	sum := sha256.Sum256([]byte(kind + "\n" + target))
+	return fmt.Sprintf("%s/notifications/%s/%s", executionID, taskID, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,207 @@
+// Package notification implements the executor of notification states.
+//
+// A notification state lists channels as kind/target pairs and a message
+// template:
+//
+//	channels: [email: "user@example.com", slack: "#uploads"]
+//	message: "Audio ready: {{prev.output.audio_url}}"
+//
+// The message, the optional subject and the targets are rendered against the
+// step context, so they can refer to the trigger payload, instance variables
+// and the output of the previous step.
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/workers"
+)
+
+// TaskType is the task type notification states are dispatched as.
+const TaskType = "notification"
+
+// Message is the rendered notification.
+type Message struct {
+	Subject string
+	Text    string
+}
+
+// Channel delivers a message to a target, such as an email address or a chat
+// channel. The meaning of the target is up to the channel.
+type Channel interface {
+	Send(ctx context.Context, target string, msg Message) error
+}
+
+// Executor sends the message of a notification state to each of its channels.
+type Executor struct {
+	mu         sync.RWMutex
+	channels   map[string]Channel
+	deliveries DeliveryLog
+}
+
+func NewExecutor() *Executor {
+	return &Executor{channels: make(map[string]Channel)}
+}
+
+// SetChannel makes a channel available to workflows under kind, e.g.
+// "email" or "slack".
+func (e *Executor) SetChannel(kind string, channel Channel) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.channels[kind] = channel
+}
+
+// SetDeliveryLog makes the executor record every recipient it reached and
+// skip recipients already reached by an earlier run of the same task.
+func (e *Executor) SetDeliveryLog(log DeliveryLog) {
+	e.deliveries = log
+}
+
+// Kinds returns the configured channel kinds.
+func (e *Executor) Kinds() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	kinds := make([]string, 0, len(e.channels))
+	for kind := range e.channels {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func (e *Executor) channel(kind string) (Channel, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	channel, ok := e.channels[kind]
+	return channel, ok
+}
+
+type recipient struct {
+	kind   string
+	target string
+}
+
+// Execute is a workers.Executor. Every recipient is attempted; the task fails
+// if any of them could not be notified. The output lists the recipients that
+// were reached along with the rendered message, and under skipped those an
+// earlier run of the task already reached.
+func (e *Executor) Execute(ctx context.Context, task *events.TaskEvent) (map[string]interface{}, error) {
+	recipients, err := e.recipients(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, _ := task.Params["message"].(string)
+	if tmpl == "" {
+		return nil, errors.New("notification has no message")
+	}
+	msg := Message{}
+	if msg.Text, err = workers.RenderTemplate(ctx, task, tmpl); err != nil {
+		return nil, fmt.Errorf("failed to render message: %w", err)
+	}
+	if subject, _ := task.Params["subject"].(string); subject != "" {
+		if msg.Subject, err = workers.RenderTemplate(ctx, task, subject); err != nil {
+			return nil, fmt.Errorf("failed to render subject: %w", err)
+		}
+	} else {
+		msg.Subject = defaultSubject(task)
+	}
+
+	sent := make([]interface{}, 0, len(recipients))
+	skipped := make([]interface{}, 0)
+	var errs []error
+	for _, r := range recipients {
+		entry := map[string]interface{}{"channel": r.kind, "target": r.target}
+		delivered, err := e.delivered(ctx, task, r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", r.kind, r.target, err))
+			continue
+		}
+		if delivered {
+			skipped = append(skipped, entry)
+			continue
+		}
+
+		channel, _ := e.channel(r.kind)
+		if err := channel.Send(ctx, r.target, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", r.kind, r.target, err))
+			continue
+		}
+		sent = append(sent, entry)
+		if err := e.record(ctx, task, r); err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: failed to record delivery: %w", r.kind, r.target, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to send notification: %w", errors.Join(errs...))
+	}
+
+	return map[string]interface{}{
+		"subject": msg.Subject,
+		"message": msg.Text,
+		"sent":    sent,
+		"skipped": skipped,
+	}, nil
+}
+
+func (e *Executor) delivered(ctx context.Context, task *events.TaskEvent, r recipient) (bool, error) {
+	if e.deliveries == nil {
+		return false, nil
+	}
+	return e.deliveries.Delivered(ctx, task.ExecutionID, task.TaskID, r.kind, r.target)
+}
+
+func (e *Executor) record(ctx context.Context, task *events.TaskEvent, r recipient) error {
+	if e.deliveries == nil {
+		return nil
+	}
+	return e.deliveries.Record(ctx, task.ExecutionID, task.TaskID, r.kind, r.target)
+}
+
+// recipients reads and renders the channels param. Unknown channel kinds are
+// rejected before anything is sent.
+func (e *Executor) recipients(ctx context.Context, task *events.TaskEvent) ([]recipient, error) {
+	entries, _ := task.Params["channels"].([]interface{})
+	if len(entries) == 0 {
+		return nil, errors.New("notification has no channels")
+	}
+
+	var recipients []recipient
+	for _, entry := range entries {
+		pairs, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid notification channel %v", entry)
+		}
+		kinds := make([]string, 0, len(pairs))
+		for kind := range pairs {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		for _, kind := range kinds {
+			if _, ok := e.channel(kind); !ok {
+				return nil, fmt.Errorf("notification channel %q is not configured", kind)
+			}
+			tmpl, _ := pairs[kind].(string)
+			target, err := workers.RenderTemplate(ctx, task, tmpl)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render %s target: %w", kind, err)
+			}
+			recipients = append(recipients, recipient{kind: kind, target: target})
+		}
+	}
+	return recipients, nil
+}
+
+func defaultSubject(task *events.TaskEvent) string {
+	if state, _ := task.Params["state"].(string); state != "" {
+		return fmt.Sprintf("Execution %s: %s", task.ExecutionID, state)
+	}
+	return fmt.Sprintf("Execution %s", task.ExecutionID)
+}
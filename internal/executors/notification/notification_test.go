@@ -0,0 +1,143 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+)
+
+// fakeChannel records what it sent and fails for the targets in failing.
+type fakeChannel struct {
+	mu      sync.Mutex
+	sent    []string
+	failing map[string]bool
+}
+
+func (c *fakeChannel) Send(ctx context.Context, target string, msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failing[target] {
+		return errors.New("mailbox unavailable")
+	}
+	c.sent = append(c.sent, target+": "+msg.Subject+": "+msg.Text)
+	return nil
+}
+
+func notificationTask() *events.TaskEvent {
+	return &events.TaskEvent{
+		ExecutionID: "exec-1",
+		TaskID:      "task-1",
+		TaskType:    TaskType,
+		Params: map[string]interface{}{
+			"state": "send_notification",
+			"channels": []interface{}{
+				map[string]interface{}{"email": "{{trigger.email}}"},
+				map[string]interface{}{"email": "ops@example.com", "slack": "#uploads"},
+			},
+			"message": "Audio ready: {{prev.output.audio_url}}",
+		},
+		Context: map[string]interface{}{
+			"trigger": map[string]interface{}{"email": "user@example.com"},
+			"prev":    map[string]interface{}{"output": map[string]interface{}{"audio_url": "https://cdn.example.com/a.wav"}},
+		},
+	}
+}
+
+func TestExecutorRendersAndSends(t *testing.T) {
+	email, slack := &fakeChannel{}, &fakeChannel{}
+	e := NewExecutor()
+	e.SetChannel("email", email)
+	e.SetChannel("slack", slack)
+
+	output, err := e.Execute(context.Background(), notificationTask())
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	wantEmail := []string{
+		"user@example.com: Execution exec-1: send_notification: Audio ready: https://cdn.example.com/a.wav",
+		"ops@example.com: Execution exec-1: send_notification: Audio ready: https://cdn.example.com/a.wav",
+	}
+	if !reflect.DeepEqual(email.sent, wantEmail) {
+		t.Errorf("email sent %q, want %q", email.sent, wantEmail)
+	}
+	if len(slack.sent) != 1 || !strings.HasPrefix(slack.sent[0], "#uploads: ") {
+		t.Errorf("slack sent %q, want one message to #uploads", slack.sent)
+	}
+	if sent := output["sent"].([]interface{}); len(sent) != 3 {
+		t.Errorf("output sent = %v, want 3 recipients", sent)
+	}
+	if output["message"] != "Audio ready: https://cdn.example.com/a.wav" {
+		t.Errorf("output message = %v", output["message"])
+	}
+}
+
+func TestExecutorRejectsInvalidStates(t *testing.T) {
+	e := NewExecutor()
+	e.SetChannel("email", &fakeChannel{})
+
+	for name, params := range map[string]map[string]interface{}{
+		"no channels":       {"message": "hi"},
+		"no message":        {"channels": []interface{}{map[string]interface{}{"email": "a@example.com"}}},
+		"unknown channel":   {"channels": []interface{}{map[string]interface{}{"sms": "+100"}}, "message": "hi"},
+		"malformed channel": {"channels": []interface{}{"a@example.com"}, "message": "hi"},
+	} {
+		if _, err := e.Execute(context.Background(), &events.TaskEvent{TaskID: "t", Params: params}); err == nil {
+			t.Errorf("%s: Execute succeeded, want an error", name)
+		}
+	}
+}
+
+func TestExecutorSkipsRecipientsReachedByAnEarlierRun(t *testing.T) {
+	store, err := blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := &fakeChannel{failing: map[string]bool{"ops@example.com": true}}
+	slack := &fakeChannel{}
+	e := NewExecutor()
+	e.SetChannel("email", email)
+	e.SetChannel("slack", slack)
+	e.SetDeliveryLog(NewStoreDeliveryLog(store))
+
+	task := notificationTask()
+	if _, err := e.Execute(context.Background(), task); err == nil || !strings.Contains(err.Error(), "ops@example.com") {
+		t.Fatalf("Execute = %v, want the failed recipient reported", err)
+	}
+	if len(email.sent) != 1 || len(slack.sent) != 1 {
+		t.Fatalf("first run sent %q and %q, want the reachable recipients notified", email.sent, slack.sent)
+	}
+
+	// The same task runs again once the mailbox is back
+	email.failing = nil
+	output, err := e.Execute(context.Background(), task)
+	if err != nil {
+		t.Fatalf("second Execute: %v", err)
+	}
+	if len(email.sent) != 2 || !strings.HasPrefix(email.sent[1], "ops@example.com: ") || len(slack.sent) != 1 {
+		t.Errorf("second run sent %q and %q, want only ops@example.com notified", email.sent, slack.sent)
+	}
+	wantSkipped := []interface{}{
+		map[string]interface{}{"channel": "email", "target": "user@example.com"},
+		map[string]interface{}{"channel": "slack", "target": "#uploads"},
+	}
+	if !reflect.DeepEqual(output["skipped"], wantSkipped) {
+		t.Errorf("skipped = %v, want %v", output["skipped"], wantSkipped)
+	}
+
+	// Another task notifies everyone again
+	other := notificationTask()
+	other.TaskID = "task-2"
+	if _, err := e.Execute(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+	if len(email.sent) != 4 || len(slack.sent) != 2 {
+		t.Errorf("another task sent %d emails and %d slack messages, want 4 and 2", len(email.sent), len(slack.sent))
+	}
+}
@@ -0,0 +1,156 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpStub is a minimal in-process SMTP server without STARTTLS or AUTH. It
+// rejects recipients at reject.example.com and keeps every message accepted.
+type smtpStub struct {
+	listener net.Listener
+	messages chan smtpMessage
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{listener: listener, messages: make(chan smtpMessage, 10)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStub) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 stub ESMTP")
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250-stub")
+			reply("250 8BITMIME")
+		case "MAIL":
+			// Drop parameters such as BODY=8BITMIME
+			msg = smtpMessage{from: strings.Fields(cmd[len("MAIL FROM:"):])[0]}
+			reply("250 OK")
+		case "RCPT":
+			to := cmd[len("RCPT TO:"):]
+			if strings.Contains(to, "@reject.example.com") {
+				reply("550 no such user")
+				continue
+			}
+			msg.to = append(msg.to, to)
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			msg.data = data.String()
+			s.messages <- msg
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPChannelSends(t *testing.T) {
+	stub := newSMTPStub(t)
+	c := NewSMTPChannel("127.0.0.1", stub.port(), "", "", "Async <async@example.com>", 5*time.Second)
+
+	err := c.Send(context.Background(), "user@example.com", Message{Subject: "Audio prête", Text: "line one\nline two"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	msg := <-stub.messages
+	if msg.from != "<async@example.com>" || len(msg.to) != 1 || msg.to[0] != "<user@example.com>" {
+		t.Errorf("envelope = %s -> %v, want async@example.com -> user@example.com", msg.from, msg.to)
+	}
+	for _, want := range []string{
+		"From: \"Async\" <async@example.com>\r\n",
+		"To: <user@example.com>\r\n",
+		"Subject: =?utf-8?q?Audio_pr=C3=AAte?=\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nline one\r\nline two\r\n",
+	} {
+		if !strings.Contains(msg.data, want) {
+			t.Errorf("message lacks %q:\n%s", want, msg.data)
+		}
+	}
+}
+
+func TestSMTPChannelErrors(t *testing.T) {
+	stub := newSMTPStub(t)
+	c := NewSMTPChannel("127.0.0.1", stub.port(), "", "", "async@example.com", 5*time.Second)
+	ctx := context.Background()
+
+	if err := c.Send(ctx, "nobody@reject.example.com", Message{Text: "hi"}); err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("Send to a rejected recipient = %v, want the 550 reply", err)
+	}
+	if err := c.Send(ctx, "not an address", Message{Text: "hi"}); err == nil {
+		t.Error("Send to an invalid address succeeded")
+	}
+
+	authenticated := NewSMTPChannel("127.0.0.1", stub.port(), "user", "secret", "async@example.com", 5*time.Second)
+	if err := authenticated.Send(ctx, "user@example.com", Message{Text: "hi"}); err == nil {
+		t.Error("Send with credentials succeeded against a server without AUTH")
+	}
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+	unreachable := NewSMTPChannel("127.0.0.1", port, "", "", "async@example.com", time.Second)
+	if err := unreachable.Send(ctx, "user@example.com", Message{Text: "hi"}); err == nil {
+		t.Errorf("Send to closed port %d succeeded", port)
+	}
+}
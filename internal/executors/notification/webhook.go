@@ -0,0 +1,76 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxErrorBody bounds how much of a failed response is kept in the error.
+const maxErrorBody = 512
+
+// WebhookChannel posts notifications to an incoming webhook in the format
+// Slack accepts, {"text": ..., "channel": ...}, which Mattermost, Rocket.Chat
+// and most chat tools understand as well.
+//
+// The target is either the channel to post to through the configured URL,
+// such as "#uploads", or a webhook URL of its own.
+type WebhookChannel struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookChannel creates a channel posting to url by default. url may be
+// empty if every target is a URL.
+func NewWebhookChannel(url string, timeout time.Duration) *WebhookChannel {
+	return &WebhookChannel{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type webhookMessage struct {
+	Text    string `json:"text"`
+	Channel string `json:"channel,omitempty"`
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, target string, msg Message) error {
+	url := c.url
+	payload := webhookMessage{Text: msg.Text}
+	if strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "http://") {
+		url = target
+	} else {
+		payload.Channel = target
+	}
+	if url == "" {
+		return fmt.Errorf("no webhook URL for target %q", target)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("webhook responded %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,51 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookChannel(t *testing.T) {
+	var got []webhookMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		var msg webhookMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		got = append(got, msg)
+		if r.URL.Path == "/broken" {
+			http.Error(w, "channel_not_found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	c := NewWebhookChannel(server.URL+"/default", time.Second)
+	if err := c.Send(ctx, "#uploads", Message{Text: "Audio ready"}); err != nil {
+		t.Fatalf("Send to a channel: %v", err)
+	}
+	if err := c.Send(ctx, server.URL+"/other", Message{Text: "Audio ready"}); err != nil {
+		t.Fatalf("Send to a URL: %v", err)
+	}
+	want := []webhookMessage{{Text: "Audio ready", Channel: "#uploads"}, {Text: "Audio ready"}}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("posted %+v, want %+v", got, want)
+	}
+
+	err := c.Send(ctx, server.URL+"/broken", Message{Text: "Audio ready"})
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("Send to a failing webhook = %v, want the status and body", err)
+	}
+
+	if err := NewWebhookChannel("", time.Second).Send(ctx, "#uploads", Message{Text: "hi"}); err == nil {
+		t.Error("Send without a webhook URL succeeded")
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultApprovalLimit = 100
+	maxApprovalLimit     = 500
+)
+
+// Decider records decisions on approvals and resumes their executions.
+type Decider interface {
+	Decide(ctx context.Context, approvalID string, approved bool, by, comment string) (*models.Approval, error)
+}
+
+type ApprovalHandler struct {
+	approvalSvc *service.ApprovalService
+	decider     Decider
+}
+
+func NewApprovalHandler(approvalSvc *service.ApprovalService) *ApprovalHandler {
+	return &ApprovalHandler{approvalSvc: approvalSvc}
+}
+
+// SetDecider enables the approve and reject endpoints.
+func (h *ApprovalHandler) SetDecider(decider Decider) {
+	h.decider = decider
+}
+
+// DecideRequest names who decides, which must be one of the assignees when
+// the approval has any.
+type DecideRequest struct {
+	By      string `json:"by"`
+	Comment string `json:"comment"`
+}
+
+// ListApprovals returns approvals oldest first, filtered by ?status=,
+// ?assignee= and ?execution_id=.
+func (h *ApprovalHandler) ListApprovals(c *gin.Context) {
+	limit := defaultApprovalLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = min(n, maxApprovalLimit)
+	}
+
+	approvals, err := h.approvalSvc.ListApprovals(c.Request.Context(), repositories.ApprovalFilter{
+		Status:      strings.ToUpper(c.Query("status")),
+		Assignee:    c.Query("assignee"),
+		ExecutionID: c.Query("execution_id"),
+		Limit:       limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list approvals"})
+		return
+	}
+
+	views := make([]service.ApprovalView, 0, len(approvals))
+	for i := range approvals {
+		views = append(views, service.ViewOfApproval(&approvals[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"approvals": views})
+}
+
+func (h *ApprovalHandler) GetApproval(c *gin.Context) {
+	approval, err := h.approvalSvc.GetApproval(c.Request.Context(), c.Param("approval_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "approval not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load approval"})
+		return
+	}
+	c.JSON(http.StatusOK, service.ViewOfApproval(approval))
+}
+
+func (h *ApprovalHandler) Approve(c *gin.Context) {
+	h.decide(c, true)
+}
+
+func (h *ApprovalHandler) Reject(c *gin.Context) {
+	h.decide(c, false)
+}
+
+func (h *ApprovalHandler) decide(c *gin.Context, approved bool) {
+	if h.decider == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "approvals are unavailable"})
+		return
+	}
+
+	var req DecideRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	approval, err := h.decider.Decide(c.Request.Context(), c.Param("approval_id"), approved, req.By, req.Comment)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "approval not found"})
+		case errors.Is(err, service.ErrNotAssignee):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrApprovalClosed), errors.Is(err, service.ErrExecutionFinished):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decide approval"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, service.ViewOfApproval(approval))
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ArtifactHandler struct {
+	instanceSvc *service.InstanceService
+	artifactSvc *service.ArtifactService
+	cfg         config.ArtifactsConfig
+}
+
+func NewArtifactHandler(instanceSvc *service.InstanceService, artifactSvc *service.ArtifactService, cfg config.ArtifactsConfig) *ArtifactHandler {
+	return &ArtifactHandler{
+		instanceSvc: instanceSvc,
+		artifactSvc: artifactSvc,
+		cfg:         cfg,
+	}
+}
+
+type artifactResponse struct {
+	models.Artifact
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ListArtifacts returns the artifacts of an execution with a signed download
+// URL for each.
+func (h *ArtifactHandler) ListArtifacts(c *gin.Context) {
+	executionID := c.Param("execution_id")
+
+	ctx := c.Request.Context()
+	instance, err := h.instanceSvc.GetInstanceByExecutionID(ctx, executionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+		return
+	}
+
+	artifacts, err := h.artifactSvc.ListArtifacts(ctx, instance.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list artifacts"})
+		return
+	}
+
+	expires := time.Now().Add(h.cfg.URLTTL).UTC().Truncate(time.Second)
+	items := make([]artifactResponse, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		items = append(items, artifactResponse{
+			Artifact:    artifact,
+			DownloadURL: h.downloadURL(executionID, artifact.Name, expires),
+			ExpiresAt:   expires,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"execution_id": executionID,
+		"artifacts":    items,
+	})
+}
+
+// GetArtifactURL issues a signed download URL. The optional ttl query
+// parameter (e.g. 1h) sets its lifetime, capped by the configured maximum.
+func (h *ArtifactHandler) GetArtifactURL(c *gin.Context) {
+	executionID := c.Param("execution_id")
+	name := c.Param("name")
+
+	ttl := h.cfg.URLTTL
+	if raw := c.Query("ttl"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ttl"})
+			return
+		}
+		if parsed > h.cfg.MaxURLTTL {
+			parsed = h.cfg.MaxURLTTL
+		}
+		ttl = parsed
+	}
+
+	if _, ok := h.lookup(c, executionID, name); !ok {
+		return
+	}
+
+	expires := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	c.JSON(http.StatusOK, gin.H{
+		"url":        h.downloadURL(executionID, name, expires),
+		"expires_at": expires,
+	})
+}
+
+// DownloadArtifact streams the artifact payload. Signed URLs are verified
+// whenever a signature is present, and required if so configured.
+func (h *ArtifactHandler) DownloadArtifact(c *gin.Context) {
+	executionID := c.Param("execution_id")
+	name := c.Param("name")
+
+	signature, expires := c.Query("signature"), c.Query("expires")
+	if signature != "" || expires != "" || h.cfg.RequireSignedURLs {
+		err := h.artifactSvc.VerifyURL(executionID, name, expires, signature)
+		if errors.Is(err, service.ErrURLExpired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "download URL expired"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid download signature"})
+			return
+		}
+	}
+
+	artifact, ok := h.lookup(c, executionID, name)
+	if !ok {
+		return
+	}
+
+	data, err := h.artifactSvc.ReadArtifact(c.Request.Context(), artifact)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			c.JSON(http.StatusGone, gin.H{"error": "artifact content no longer available"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read artifact"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Name))
+	c.Header("ETag", `"`+artifact.SHA256+`"`)
+	c.Data(http.StatusOK, artifact.ContentType, data)
+}
+
+// lookup loads the named artifact of an execution, writing the error
+// response if there is none.
+func (h *ArtifactHandler) lookup(c *gin.Context, executionID, name string) (*models.Artifact, bool) {
+	ctx := c.Request.Context()
+	instance, err := h.instanceSvc.GetInstanceByExecutionID(ctx, executionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+		return nil, false
+	}
+
+	artifact, err := h.artifactSvc.GetArtifact(ctx, instance.ID, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "artifact not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load artifact"})
+		}
+		return nil, false
+	}
+	return artifact, true
+}
+
+func (h *ArtifactHandler) downloadURL(executionID, name string, expires time.Time) string {
+	query := h.artifactSvc.SignURL(executionID, name, expires)
+	path := fmt.Sprintf("/executions/%s/artifacts/%s", url.PathEscape(executionID), url.PathEscape(name))
+	return strings.TrimRight(h.cfg.BaseURL, "/") + path + "?" + query.Encode()
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDownloadURLRoundTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	artifactSvc, err := service.NewArtifactService(nil, nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewArtifactHandler(nil, artifactSvc, config.ArtifactsConfig{BaseURL: "https://api.example.com/"})
+
+	// The route the orchestrator serves
+	router := gin.New()
+	var verified string
+	router.GET("/executions/:execution_id/artifacts/:name", func(c *gin.Context) {
+		name := c.Param("name")
+		if err := artifactSvc.VerifyURL(c.Param("execution_id"), name, c.Query("expires"), c.Query("signature")); err != nil {
+			c.Status(http.StatusForbidden)
+			return
+		}
+		verified = name
+		c.Status(http.StatusOK)
+	})
+
+	for _, name := range []string{"audio.wav", "q1 final.pdf", "notes?v=2#top", "100% done"} {
+		verified = ""
+		raw := h.downloadURL("exec-1", name, time.Now().Add(time.Hour))
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("downloadURL(%q) = %q: %v", name, raw, err)
+		}
+		if u.Host != "api.example.com" || u.Fragment != "" {
+			t.Errorf("downloadURL(%q) = %q, want the name kept inside the path", name, raw)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+		if w.Code != http.StatusOK || verified != name {
+			t.Errorf("GET %s = %d for name %q, want 200 for %q", u.RequestURI(), w.Code, verified, name)
+		}
+	}
+}
@@ -1,31 +1,30 @@
 package handler
 
 import (
+	"context"
 	"net/http"
-	"time"
 
-	"github.com/Vighnesh-V-H/async/internal/events"
 	"github.com/Vighnesh-V-H/async/internal/models"
 	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// Starter starts executions of a workflow and dispatches their first task.
+type Starter interface {
+	Start(ctx context.Context, workflowName, executionID string, input map[string]interface{}) (*models.WorkflowInstance, error)
+}
+
 type AudioHandler struct {
-	workflowSvc  *service.WorkflowService
-	instanceSvc  *service.InstanceService
-	eventProducer *events.EventProducer
+	workflowSvc *service.WorkflowService
+	starter     Starter
 }
 
-func NewAudioHandler(
-	workflowSvc *service.WorkflowService,
-	instanceSvc *service.InstanceService,
-	eventProducer *events.EventProducer,
-) *AudioHandler {
+func NewAudioHandler(workflowSvc *service.WorkflowService, starter Starter) *AudioHandler {
 	return &AudioHandler{
-		workflowSvc:   workflowSvc,
-		instanceSvc:   instanceSvc,
-		eventProducer: eventProducer,
+		workflowSvc: workflowSvc,
+		starter:     starter,
 	}
 }
 
@@ -58,57 +57,21 @@ func (h *AudioHandler) GenerateAudio(c *gin.Context) {
 		"metadata": req.Metadata,
 	}
 
-	instance := &models.WorkflowInstance{
-		WorkflowID:  workflow.ID,
-		ExecutionID: executionID,
-		Status:      "PENDING",
-		CurrentStep: 0,
-	}
-
-	if err := h.instanceSvc.CreateInstance(ctx, instance, input); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create workflow instance"})
+	if blob.HasRefs(input) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": service.ErrPayloadRefs.Error()})
 		return
 	}
 
-	taskEvent := &events.TaskEvent{
-		ExecutionID: executionID,
-		WorkflowID:  workflow.ID,
-		TaskType:    "extract_text", 
-		Step:        1,
-		Input:       input,
-		Timestamp:   time.Now().UTC().Format(time.RFC3339),
-	}
-
-	topic := "task-queue"
-	if err := h.eventProducer.PublishTask(topic, taskEvent); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to publish task event"})
+	instance, err := h.starter.Start(ctx, workflow.Name, executionID, input)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to start workflow"})
 		return
 	}
 
 	c.JSON(http.StatusAccepted, gin.H{
 		"execution_id": executionID,
 		"workflow":     workflow.Name,
-		"status":       "PENDING",
-		"message":      "Audio generation workflow triggered successfully",
-	})
-}
-
-func (h *AudioHandler) GetStatus(c *gin.Context) {
-	executionID := c.Param("execution_id")
-
-	ctx := c.Request.Context()
-	instance, err := h.instanceSvc.GetInstanceByExecutionID(ctx, executionID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"execution_id": instance.ExecutionID,
-		"workflow_id":  instance.WorkflowID,
 		"status":       instance.Status,
-		"current_step": instance.CurrentStep,
-		"created_at":   instance.CreatedAt,
-		"updated_at":   instance.UpdatedAt,
+		"message":      "Audio generation workflow triggered successfully",
 	})
 }
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// keepAliveInterval is how often idle event streams send a comment so
+// proxies do not close them.
+const keepAliveInterval = 15 * time.Second
+
+// Canceller stops executions, cascading to their child executions.
+type Canceller interface {
+	Cancel(ctx context.Context, executionID, reason string) error
+}
+
+// Signaller delivers external signals to executions.
+type Signaller interface {
+	Signal(ctx context.Context, executionID, name string, payload map[string]interface{}) error
+}
+
+type ExecutionHandler struct {
+	statusSvc *service.StatusService
+	feed      *service.ExecutionFeed
+	canceller Canceller
+	signaller Signaller
+}
+
+func NewExecutionHandler(statusSvc *service.StatusService) *ExecutionHandler {
+	return &ExecutionHandler{statusSvc: statusSvc}
+}
+
+// SetFeed enables the event stream endpoint.
+func (h *ExecutionHandler) SetFeed(feed *service.ExecutionFeed) {
+	h.feed = feed
+}
+
+// SetCanceller enables the cancel endpoint.
+func (h *ExecutionHandler) SetCanceller(canceller Canceller) {
+	h.canceller = canceller
+}
+
+// SetSignaller enables the signal endpoint.
+func (h *ExecutionHandler) SetSignaller(signaller Signaller) {
+	h.signaller = signaller
+}
+
+type CancelExecutionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CancelExecution cancels an execution and its child executions, then
+// returns the resulting status. Cancelling a cancelled execution succeeds;
+// cancelling a finished one is a conflict.
+func (h *ExecutionHandler) CancelExecution(c *gin.Context) {
+	if h.canceller == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "cancellation is unavailable"})
+		return
+	}
+
+	var req CancelExecutionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Reason == "" {
+		req.Reason = "cancelled by request"
+	}
+
+	ctx := c.Request.Context()
+	executionID := c.Param("execution_id")
+	if err := h.canceller.Cancel(ctx, executionID, req.Reason); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+		case errors.Is(err, service.ErrExecutionFinished):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel execution"})
+		}
+		return
+	}
+
+	status, err := h.statusSvc.GetExecutionStatus(ctx, executionID, service.StatusOptions{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load execution status"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// SignalExecution sends a named signal with an optional JSON object payload
+// to an execution. The signal is buffered until a wait_for_signal state of
+// the same name consumes it, so it may be sent before the execution gets
+// there.
+func (h *ExecutionHandler) SignalExecution(c *gin.Context) {
+	if h.signaller == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "signals are unavailable"})
+		return
+	}
+
+	var payload map[string]interface{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	executionID := c.Param("execution_id")
+	name := c.Param("name")
+	if err := h.signaller.Signal(c.Request.Context(), executionID, name, payload); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+		case errors.Is(err, service.ErrExecutionFinished):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrReservedSignal), errors.Is(err, service.ErrPayloadRefs):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to deliver signal"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"execution_id": executionID,
+		"signal":       name,
+	})
+}
+
+// GetExecution returns the status of an execution with its step outputs,
+// final output and last error. ?include=variables,history adds the trigger
+// variables and the event history.
+func (h *ExecutionHandler) GetExecution(c *gin.Context) {
+	opts, err := service.ParseStatusOptions(c.QueryArray("include"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.statusSvc.GetExecutionStatus(c.Request.Context(), c.Param("execution_id"), opts)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load execution status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// StreamEvents streams the transitions of an execution as Server-Sent Events.
+// The stream opens with a status event holding the current status and ends
+// after the execution finished. Clients reconnecting with Last-Event-ID get
+// the transitions they missed replayed from the history.
+func (h *ExecutionHandler) StreamEvents(c *gin.Context) {
+	if h.feed == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "event streaming is unavailable"})
+		return
+	}
+
+	executionID := c.Param("execution_id")
+	ctx := c.Request.Context()
+
+	// Subscribe before reading the status so no transition falls in between
+	events, closeSub, err := h.feed.Subscribe(ctx, executionID)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "event streaming is unavailable"})
+		return
+	}
+	defer closeSub()
+
+	status, err := h.statusSvc.GetExecutionStatus(ctx, executionID, service.StatusOptions{})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load execution status"})
+		return
+	}
+
+	var missed []service.HistoryEvent
+	lastID := lastEventID(c)
+	if lastID > 0 {
+		if missed, err = h.statusSvc.HistorySince(ctx, executionID, lastID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load execution history"})
+			return
+		}
+	}
+
+	// Streams outlive the server write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Render(-1, sse.Event{Event: "status", Data: status})
+	for _, event := range missed {
+		writeHistoryEvent(c, event)
+		lastID = event.ID
+	}
+	c.Writer.Flush()
+
+	if models.IsTerminalStatus(status.Status) {
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.ID <= lastID {
+				continue // already replayed
+			}
+			lastID = event.ID
+			writeHistoryEvent(c, event)
+			c.Writer.Flush()
+			if service.IsTerminalEvent(event.Event) {
+				return
+			}
+		}
+	}
+}
+
+func writeHistoryEvent(c *gin.Context, event service.HistoryEvent) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(uint64(event.ID), 10),
+		Event: event.Event,
+		Data:  event,
+	})
+}
+
+// lastEventID reads the ID of the last event a reconnecting client saw from
+// the Last-Event-ID header or the last_event_id query parameter.
+func lastEventID(c *gin.Context) uint {
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
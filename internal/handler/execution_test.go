@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/cache"
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// pubsubStub speaks just enough RESP2 for SUBSCRIBE and PUBLISH, so the
+// execution feed runs without a Redis server.
+type pubsubStub struct {
+	mu          sync.Mutex
+	subscribers map[string][]net.Conn
+	subscribed  chan string
+}
+
+func newPubSubStub(t *testing.T) *redis.Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &pubsubStub{subscribers: map[string][]net.Conn{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func (s *pubsubStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(args[0]) {
+		case "HELLO":
+			io.WriteString(conn, "-ERR unknown command 'HELLO'\r\n")
+		case "SUBSCRIBE":
+			s.mu.Lock()
+			for i, channel := range args[1:] {
+				s.subscribers[channel] = append(s.subscribers[channel], conn)
+				fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n%s:%d\r\n", bulk(channel), i+1)
+			}
+			s.mu.Unlock()
+		case "UNSUBSCRIBE":
+			fmt.Fprintf(conn, "*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:0\r\n")
+		case "PUBLISH":
+			s.mu.Lock()
+			for _, sub := range s.subscribers[args[1]] {
+				fmt.Fprintf(sub, "*3\r\n$7\r\nmessage\r\n%s%s", bulk(args[1]), bulk(args[2]))
+			}
+			fmt.Fprintf(conn, ":%d\r\n", len(s.subscribers[args[1]]))
+			s.mu.Unlock()
+		case "PING":
+			io.WriteString(conn, "+PONG\r\n")
+		default:
+			io.WriteString(conn, "+OK\r\n")
+		}
+	}
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil { // $<len>
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func newStreamServer(t *testing.T, db *gorm.DB) (*httptest.Server, *service.ExecutionFeed) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	feed := service.NewExecutionFeed(cache.NewPubSub(newPubSubStub(t), "test"))
+	h := NewExecutionHandler(service.NewStatusService(repositories.NewInstanceRepository(db), repositories.NewTaskRepository(db)))
+	h.SetFeed(feed)
+
+	router := gin.New()
+	router.GET("/executions/:execution_id/events", h.StreamEvents)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server, feed
+}
+
+func seedExecution(t *testing.T, db *gorm.DB, executionID, status string) *models.WorkflowInstance {
+	t.Helper()
+	workflow := &models.Workflow{Name: "wf-" + executionID, Status: "active"}
+	if err := db.Create(workflow).Error; err != nil {
+		t.Fatal(err)
+	}
+	instance := &models.WorkflowInstance{WorkflowID: workflow.ID, ExecutionID: executionID, Status: status}
+	if err := db.Create(instance).Error; err != nil {
+		t.Fatal(err)
+	}
+	return instance
+}
+
+// stream reads the event stream until the server ends it.
+func stream(t *testing.T, ctx context.Context, url string) string {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("stream not ended by the server: %v", err)
+	}
+	return string(body)
+}
+
+func TestStreamEventsEndsForFinishedExecutions(t *testing.T) {
+	db := databasetest.Open(t)
+	server, _ := newStreamServer(t, db)
+
+	for _, status := range []string{
+		models.InstanceStatusCompleted,
+		models.InstanceStatusFailed,
+		models.InstanceStatusCancelled,
+		models.InstanceStatusCompensated,
+		models.InstanceStatusCompensationFailed,
+	} {
+		executionID := "exec-" + strings.ToLower(status)
+		seedExecution(t, db, executionID, status)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		body := stream(t, ctx, server.URL+"/executions/"+executionID+"/events")
+		cancel()
+		if !strings.Contains(body, "event:status") || !strings.Contains(body, status) {
+			t.Errorf("%s: stream = %q, want the status event", status, body)
+		}
+	}
+}
+
+func TestStreamEventsEndsOnTerminalEvent(t *testing.T) {
+	db := databasetest.Open(t)
+	server, feed := newStreamServer(t, db)
+	instance := seedExecution(t, db, "exec-1", models.InstanceStatusRunning)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		// Publish once the stream subscribed; until then events are lost
+		for ctx.Err() == nil {
+			time.Sleep(20 * time.Millisecond)
+			feed.Publish(ctx, "exec-1", []models.HistoryEntry{{
+				ID:         1,
+				InstanceID: instance.ID,
+				Event:      models.HistoryExecutionCancelled,
+				Timestamp:  time.Now(),
+			}})
+		}
+	}()
+
+	body := stream(t, ctx, server.URL+"/executions/exec-1/events")
+	if !strings.Contains(body, "event:"+models.HistoryExecutionCancelled) {
+		t.Errorf("stream = %q, want it to end with the cancellation", body)
+	}
+}
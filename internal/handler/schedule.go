@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Triggerer starts runs of schedules on demand.
+type Triggerer interface {
+	Trigger(ctx context.Context, scheduleID string) (*models.WorkflowInstance, error)
+}
+
+type ScheduleHandler struct {
+	scheduleSvc *service.ScheduleService
+	triggerer   Triggerer
+}
+
+func NewScheduleHandler(scheduleSvc *service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{scheduleSvc: scheduleSvc}
+}
+
+// SetTriggerer enables the trigger endpoint.
+func (h *ScheduleHandler) SetTriggerer(triggerer Triggerer) {
+	h.triggerer = triggerer
+}
+
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	schedules, err := h.scheduleSvc.ListSchedules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list schedules"})
+		return
+	}
+
+	views := make([]service.ScheduleView, 0, len(schedules))
+	for i := range schedules {
+		views = append(views, service.ViewOfSchedule(&schedules[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": views})
+}
+
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	schedule, err := h.scheduleSvc.GetSchedule(c.Request.Context(), c.Param("schedule_id"))
+	if err != nil {
+		scheduleError(c, err, "failed to load schedule")
+		return
+	}
+	c.JSON(http.StatusOK, service.ViewOfSchedule(schedule))
+}
+
+func (h *ScheduleHandler) PauseSchedule(c *gin.Context) {
+	schedule, err := h.scheduleSvc.Pause(c.Request.Context(), c.Param("schedule_id"))
+	if err != nil {
+		scheduleError(c, err, "failed to pause schedule")
+		return
+	}
+	c.JSON(http.StatusOK, service.ViewOfSchedule(schedule))
+}
+
+func (h *ScheduleHandler) ResumeSchedule(c *gin.Context) {
+	schedule, err := h.scheduleSvc.Resume(c.Request.Context(), c.Param("schedule_id"))
+	if err != nil {
+		scheduleError(c, err, "failed to resume schedule")
+		return
+	}
+	c.JSON(http.StatusOK, service.ViewOfSchedule(schedule))
+}
+
+// TriggerSchedule starts a run of the schedule now, even while it is paused
+// or its previous run is still running.
+func (h *ScheduleHandler) TriggerSchedule(c *gin.Context) {
+	if h.triggerer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "schedules are unavailable"})
+		return
+	}
+
+	scheduleID := c.Param("schedule_id")
+	instance, err := h.triggerer.Trigger(c.Request.Context(), scheduleID)
+	if err != nil {
+		scheduleError(c, err, "failed to trigger schedule")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"schedule_id":  scheduleID,
+		"execution_id": instance.ExecutionID,
+		"status":       instance.Status,
+	})
+}
+
+func scheduleError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+	case errors.Is(err, service.ErrScheduleConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+type WebhookHandler struct {
+	workflowSvc *service.WorkflowService
+	webhookSvc  *service.WebhookService
+}
+
+func NewWebhookHandler(workflowSvc *service.WorkflowService, webhookSvc *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		workflowSvc: workflowSvc,
+		webhookSvc:  webhookSvc,
+	}
+}
+
+// createdWebhook exposes the secret once, when the subscription is created.
+type createdWebhook struct {
+	models.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+type updateWebhookRequest struct {
+	Active *bool `json:"active" binding:"required"`
+}
+
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	workflowID, ok := uintParam(c, "workflow_id")
+	if !ok {
+		return
+	}
+
+	var req service.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.workflowSvc.GetWorkflowByID(ctx, workflowID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "workflow not found"})
+		return
+	}
+
+	sub, err := h.webhookSvc.CreateSubscription(ctx, workflowID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdWebhook{WebhookSubscription: *sub, Secret: sub.Secret})
+}
+
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	workflowID, ok := uintParam(c, "workflow_id")
+	if !ok {
+		return
+	}
+
+	subs, err := h.webhookSvc.ListSubscriptions(c.Request.Context(), workflowID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": subs})
+}
+
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// UpdateWebhook pauses or resumes a subscription.
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req updateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookSvc.SetSubscriptionActive(c.Request.Context(), sub, *req.Active); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update webhook"})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	workflowID, ok := uintParam(c, "workflow_id")
+	if !ok {
+		return
+	}
+	id, ok := uintParam(c, "webhook_id")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookSvc.DeleteSubscription(c.Request.Context(), workflowID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete webhook"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	limit := defaultDeliveryLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = min(n, maxDeliveryLimit)
+	}
+
+	sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookSvc.ListDeliveries(c.Request.Context(), sub.ID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// Redeliver queues a delivery again, e.g. after it failed permanently.
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	sub, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	id, ok := uintParam(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookSvc.Redeliver(c.Request.Context(), sub.ID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to queue delivery"})
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func (h *WebhookHandler) lookup(c *gin.Context) (*models.WebhookSubscription, bool) {
+	workflowID, ok := uintParam(c, "workflow_id")
+	if !ok {
+		return nil, false
+	}
+	id, ok := uintParam(c, "webhook_id")
+	if !ok {
+		return nil, false
+	}
+
+	sub, err := h.webhookSvc.GetSubscription(c.Request.Context(), workflowID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load webhook"})
+		}
+		return nil, false
+	}
+	return sub, true
+}
+
+// uintParam parses a numeric path parameter, writing a 400 response if it is
+// not one.
+func uintParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -4,6 +4,50 @@ import (
 	"time"
 )
 
+const (
+    InstanceStatusPending   = "PENDING"
+    InstanceStatusRunning   = "RUNNING"
+    InstanceStatusCompleted = "COMPLETED"
+    InstanceStatusFailed    = "FAILED"
+    InstanceStatusCancelled = "CANCELLED"
+    // Saga states: a failed execution undoing its completed steps
+    InstanceStatusCompensating       = "COMPENSATING"
+    InstanceStatusCompensated        = "COMPENSATED"
+    InstanceStatusCompensationFailed = "COMPENSATION_FAILED"
+)
+
+const (
+    TaskStatusPending    = "PENDING"
+    TaskStatusDispatched = "DISPATCHED"
+    TaskStatusCompleted  = "COMPLETED"
+    TaskStatusFailed     = "FAILED"
+    TaskStatusCancelled  = "CANCELLED"
+)
+
+// History events recorded for every execution.
+const (
+    HistoryExecutionStarted   = "EXECUTION_STARTED"
+    HistoryStepCompleted      = "STEP_COMPLETED"
+    HistoryStepFailed         = "STEP_FAILED"
+    HistoryTaskScheduled      = "TASK_SCHEDULED"
+    HistoryExecutionCompleted = "EXECUTION_COMPLETED"
+    HistoryExecutionFailed    = "EXECUTION_FAILED"
+    HistoryExecutionCancelled = "EXECUTION_CANCELLED"
+    HistorySubworkflowStarted = "SUBWORKFLOW_STARTED"
+    HistorySignalReceived     = "SIGNAL_RECEIVED"
+    HistoryApprovalRequested  = "APPROVAL_REQUESTED"
+    HistoryApprovalEscalated  = "APPROVAL_ESCALATED"
+    HistoryApprovalApproved   = "APPROVAL_APPROVED"
+    HistoryApprovalRejected   = "APPROVAL_REJECTED"
+    HistoryApprovalExpired    = "APPROVAL_EXPIRED"
+
+    HistoryCompensationScheduled       = "COMPENSATION_SCHEDULED"
+    HistoryCompensationCompleted       = "COMPENSATION_COMPLETED"
+    HistoryCompensationFailed          = "COMPENSATION_FAILED"
+    HistoryExecutionCompensated        = "EXECUTION_COMPENSATED"
+    HistoryExecutionCompensationFailed = "EXECUTION_COMPENSATION_FAILED"
+)
+
 type Workflow struct {
     ID        uint      `gorm:"primaryKey" json:"id"`
     Name      string    `gorm:"uniqueIndex;size:255" json:"name"`
@@ -24,25 +68,209 @@ type WorkflowInstance struct {
     Status      string         `gorm:"size:50;index" json:"status"`
     Variables   []byte         `gorm:"type:jsonb" json:"variables"`
     CurrentStep uint8          `json:"current_step"`
+    Version     uint           `gorm:"not null;default:1" json:"version"`
     History     []HistoryEntry `gorm:"foreignKey:InstanceID" json:"-"`
+    FinishedAt  *time.Time     `json:"finished_at"`
+    // Set on executions started by a subworkflow state: the execution and
+    // the task of the step waiting for this one.
+    ParentExecutionID string    `gorm:"size:100;index" json:"parent_execution_id,omitempty"`
+    ParentTaskID      string    `gorm:"size:100" json:"-"`
     CreatedAt   time.Time      `json:"created_at"`
     UpdatedAt   time.Time      `json:"updated_at"`
 }
 
+// IsTerminal reports whether the instance has reached a final status and
+// must not be advanced any further.
+func (i *WorkflowInstance) IsTerminal() bool {
+    return IsTerminalStatus(i.Status)
+}
+
+// IsTerminalStatus reports whether an instance status is final.
+func IsTerminalStatus(status string) bool {
+    switch status {
+    case InstanceStatusCompleted, InstanceStatusFailed, InstanceStatusCancelled,
+        InstanceStatusCompensated, InstanceStatusCompensationFailed:
+        return true
+    }
+    return false
+}
+
 type Task struct {
     ID         uint      `gorm:"primaryKey" json:"id"`
     InstanceID uint      `gorm:"index" json:"instance_id"`
+    TaskID     string    `gorm:"uniqueIndex;size:100" json:"task_id"`
+    Attempt    uint8     `json:"attempt"`
     StepID     uint8     `json:"step_id"`
     Type       string    `gorm:"size:50" json:"type"`
     Payload    []byte    `gorm:"type:jsonb" json:"payload"`
+    Params     []byte    `gorm:"type:jsonb" json:"params"`
+    Context    []byte    `gorm:"type:jsonb" json:"-"`
     Output     []byte    `gorm:"type:jsonb" json:"output"`
+    Error      string    `json:"error,omitempty"`
     Status     string    `gorm:"size:50;index" json:"status"`
     Retries    uint8     `json:"retries_left"`
+    // Compensation tasks undo the completed task of the same step
+    Compensation bool    `gorm:"not null;default:false" json:"compensation"`
     TimeoutAt  *time.Time `json:"timeout_at"`
+    // Tasks handled by the orchestrator itself, such as wait_for_signal,
+    // fail once DeadlineAt passes
+    DeadlineAt *time.Time `json:"deadline_at,omitempty"`
+    // Queue columns, used when tasks are delivered through Postgres.
+    // Message holds the encoded task event until a worker acknowledges it.
+    Topic          string `gorm:"size:255" json:"topic,omitempty"`
+    MessageKey     []byte `json:"-"`
+    Message        []byte `json:"-"`
+    MessageHeaders []byte `gorm:"type:jsonb" json:"-"`
+    Deliveries     uint   `gorm:"not null;default:0" json:"deliveries"`
+    ClaimedBy      string `gorm:"size:255" json:"-"`
     CreatedAt  time.Time  `json:"created_at"`
     UpdatedAt  time.Time  `json:"updated_at"`
 }
 
+// QueueMessage is an event waiting in the Postgres queue that is not a task,
+// such as a completion or a dead letter. It is deleted once acknowledged.
+type QueueMessage struct {
+    ID             uint64    `gorm:"primaryKey" json:"id"`
+    Topic          string    `gorm:"size:255;index" json:"topic"`
+    MessageKey     []byte    `json:"-"`
+    Message        []byte    `json:"-"`
+    MessageHeaders []byte    `gorm:"type:jsonb" json:"-"`
+    Deliveries     uint      `gorm:"not null;default:0" json:"deliveries"`
+    ClaimedBy      string    `gorm:"size:255" json:"claimed_by"`
+    VisibleAt      time.Time `json:"visible_at"`
+    CreatedAt      time.Time `json:"created_at"`
+}
+
+// Artifact is a named output of an execution, such as a generated audio file
+// or a transcript. The payload is kept in the blob store under BlobKey.
+type Artifact struct {
+    ID          uint      `gorm:"primaryKey" json:"-"`
+    InstanceID  uint      `gorm:"uniqueIndex:idx_artifacts_instance_name" json:"-"`
+    TaskID      string    `gorm:"size:100" json:"task_id"`
+    StepID      uint8     `json:"step_id"`
+    Name        string    `gorm:"size:255;uniqueIndex:idx_artifacts_instance_name" json:"name"`
+    ContentType string    `gorm:"size:255" json:"content_type"`
+    Size        int64     `json:"size"`
+    SHA256      string    `gorm:"column:sha256;size:64" json:"sha256"`
+    BlobKey     string    `gorm:"size:1024" json:"-"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+const (
+    WebhookDeliveryPending   = "PENDING"
+    WebhookDeliveryDelivered = "DELIVERED"
+    WebhookDeliveryFailed    = "FAILED"
+)
+
+// WebhookSubscription asks for a workflow's lifecycle events to be POSTed to
+// URL. Events is a comma-separated filter such as "completed,failed"; empty
+// means every event.
+type WebhookSubscription struct {
+    ID         uint      `gorm:"primaryKey" json:"id"`
+    WorkflowID uint      `gorm:"index" json:"workflow_id"`
+    URL        string    `gorm:"size:1000" json:"url"`
+    Secret     string    `gorm:"size:255" json:"-"`
+    Events     string    `gorm:"size:500" json:"events"`
+    Active     bool      `gorm:"not null;default:true" json:"active"`
+    CreatedAt  time.Time `json:"created_at"`
+    UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is a single event queued for a subscription. It doubles as
+// the delivery log: the outcome of the latest attempt stays on the row.
+type WebhookDelivery struct {
+    ID             uint       `gorm:"primaryKey" json:"id"`
+    SubscriptionID uint       `gorm:"index" json:"subscription_id"`
+    DeliveryID     string     `gorm:"uniqueIndex;size:100" json:"delivery_id"`
+    ExecutionID    string     `gorm:"size:100;index" json:"execution_id"`
+    Event          string     `gorm:"size:100" json:"event"`
+    Payload        []byte     `gorm:"type:jsonb" json:"payload"`
+    Status         string     `gorm:"size:50" json:"status"`
+    Attempts       uint       `gorm:"not null;default:0" json:"attempts"`
+    NextAttemptAt  time.Time  `json:"next_attempt_at"`
+    ResponseStatus int        `json:"response_status,omitempty"`
+    LastError      string     `json:"last_error,omitempty"`
+    DeliveredAt    *time.Time `json:"delivered_at"`
+    CreatedAt      time.Time  `json:"created_at"`
+    UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Signal is an external event sent to an execution. It is buffered until a
+// wait_for_signal task of the same name claims it.
+type Signal struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    InstanceID uint       `gorm:"index" json:"instance_id"`
+    Name       string     `gorm:"size:255" json:"name"`
+    Payload    []byte     `gorm:"type:jsonb" json:"payload"`
+    TaskID     string     `gorm:"size:100" json:"task_id,omitempty"`
+    ConsumedAt *time.Time `json:"consumed_at"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Approval states of an approval request.
+const (
+    ApprovalStatusPending   = "PENDING"
+    ApprovalStatusApproved  = "APPROVED"
+    ApprovalStatusRejected  = "REJECTED"
+    ApprovalStatusExpired   = "EXPIRED"
+    ApprovalStatusCancelled = "CANCELLED"
+)
+
+// Approval is a decision requested from people by an approval state. Its
+// task waits for the signal the decision sends.
+type Approval struct {
+    ID          uint       `gorm:"primaryKey" json:"-"`
+    ApprovalID  string     `gorm:"uniqueIndex;size:100" json:"approval_id"`
+    InstanceID  uint       `gorm:"index" json:"-"`
+    ExecutionID string     `gorm:"size:100" json:"execution_id"`
+    TaskID      string     `gorm:"uniqueIndex;size:100" json:"task_id"`
+    State       string     `gorm:"size:255" json:"state"`
+    Status      string     `gorm:"size:50;index" json:"status"`
+    Assignees   []byte     `gorm:"type:jsonb" json:"assignees"`
+    Context     []byte     `gorm:"type:jsonb" json:"context"`
+    DeadlineAt  *time.Time `json:"deadline_at"`
+    EscalatedAt *time.Time `json:"escalated_at"`
+    DecidedBy   string     `gorm:"size:255" json:"decided_by,omitempty"`
+    Comment     string     `json:"comment,omitempty"`
+    DecidedAt   *time.Time `json:"decided_at"`
+    CreatedAt   time.Time  `json:"created_at"`
+    UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Schedule is the state of a schedule trigger of a workflow definition,
+// identified as <workflow>:<event>. NextRunAt is the time the next run is
+// scheduled for and FireAt the time it starts, NextRunAt plus jitter.
+// Buffered counts runs waiting for the previous one to finish.
+type Schedule struct {
+    ID              uint          `gorm:"primaryKey" json:"-"`
+    ScheduleID      string        `gorm:"uniqueIndex;size:255" json:"schedule_id"`
+    WorkflowName    string        `gorm:"size:255;index" json:"workflow"`
+    Cron            string        `gorm:"size:255" json:"cron"`
+    Timezone        string        `gorm:"size:100" json:"timezone"`
+    Jitter          time.Duration `json:"jitter"`
+    Overlap         string        `gorm:"size:20" json:"overlap"`
+    CatchUp         string        `gorm:"size:20" json:"catch_up"`
+    Payload         []byte        `gorm:"type:jsonb" json:"payload"`
+    Paused          bool          `json:"paused"`
+    NextRunAt       time.Time     `json:"next_run_at"`
+    FireAt          time.Time     `gorm:"index" json:"fire_at"`
+    Buffered        int           `json:"buffered"`
+    LastRunAt       *time.Time    `json:"last_run_at"`
+    LastExecutionID string        `gorm:"size:100" json:"last_execution_id"`
+    Version         uint          `gorm:"not null;default:1" json:"-"`
+    CreatedAt       time.Time     `json:"created_at"`
+    UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// Lease is held by at most one orchestrator replica at a time, for work only
+// one of them may do, until it expires or its holder releases it.
+type Lease struct {
+    Name      string    `gorm:"primaryKey;size:100" json:"name"`
+    Holder    string    `gorm:"size:255" json:"holder"`
+    ExpiresAt time.Time `json:"expires_at"`
+}
+
 type HistoryEntry struct {
     ID        uint      `gorm:"primaryKey" json:"id"`
     InstanceID uint     `gorm:"index" json:"instance_id"`
@@ -0,0 +1,169 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"gorm.io/gorm"
+)
+
+// SetApprovals enables approval states and the approval endpoints.
+// Decisions are delivered as signals, so SetSignals is needed as well.
+func (o *Orchestrator) SetApprovals(approvalSvc *service.ApprovalService) {
+	o.approvalSvc = approvalSvc
+}
+
+// requestApproval records the pending approval of an approval task, then
+// waits for the decision like a wait_for_signal task. Requesting again is
+// harmless.
+func (o *Orchestrator) requestApproval(ctx context.Context, instance *models.WorkflowInstance, task *models.Task, spec service.TaskSpec) error {
+	if o.approvalSvc == nil {
+		return o.completeWait(ctx, instance, task, nil, "approvals are not enabled")
+	}
+
+	history, err := o.approvalSvc.Request(ctx, instance, task, spec)
+	if err != nil {
+		o.logger.Error().Err(err).Str("execution_id", instance.ExecutionID).Msg("Failed to request approval")
+		return err
+	}
+	if len(history) > 0 {
+		o.publishHistory(ctx, instance.ExecutionID, history)
+		o.logger.Info().
+			Str("execution_id", instance.ExecutionID).
+			Str("task_id", task.TaskID).
+			Msg("Approval requested")
+	}
+
+	// A repeated request may find the decision already buffered
+	return o.awaitSignal(ctx, instance, task)
+}
+
+// Decide approves or rejects a pending approval on behalf of one of its
+// assignees and completes its task: approval continues with the on_success
+// state, rejection fails the step and continues with the on_failure state.
+// Without one, a rejection fails the execution or undoes its completed
+// steps, like any other failure.
+func (o *Orchestrator) Decide(ctx context.Context, approvalID string, approved bool, by, comment string) (*models.Approval, error) {
+	if o.approvalSvc == nil {
+		return nil, errors.New("approvals are not enabled")
+	}
+
+	approval, err := o.approvalSvc.GetApproval(ctx, approvalID)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := o.instanceSvc.GetInstanceByID(ctx, approval.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.IsTerminal() {
+		return nil, service.ErrExecutionFinished
+	}
+
+	history, err := o.approvalSvc.Decide(ctx, instance, approval, approved, by, comment)
+	if err != nil {
+		return nil, err
+	}
+	o.publishHistory(ctx, instance.ExecutionID, history)
+
+	o.logger.Info().
+		Str("execution_id", instance.ExecutionID).
+		Str("approval_id", approval.ApprovalID).
+		Str("status", approval.Status).
+		Str("decided_by", by).
+		Msg("Approval decided")
+
+	task, err := o.taskSvc.GetTaskByTaskID(ctx, approval.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status == models.TaskStatusDispatched {
+		if err := o.awaitSignal(ctx, instance, task); err != nil {
+			return nil, err
+		}
+	}
+	return approval, nil
+}
+
+// completeApproval completes an approval task with the decision signalled
+// for it. The decision is the output of the task either way.
+func (o *Orchestrator) completeApproval(ctx context.Context, instance *models.WorkflowInstance, task *models.Task, decision map[string]interface{}) error {
+	if decision["decision"] == service.DecisionRejected {
+		msg := fmt.Sprintf("approval rejected by %v", decision["decided_by"])
+		if comment, _ := decision["comment"].(string); comment != "" {
+			msg += ": " + comment
+		}
+		return o.completeWait(ctx, instance, task, decision, msg)
+	}
+	return o.completeWait(ctx, instance, task, decision, "")
+}
+
+// expireApproval handles the deadline of an approval task. The first
+// deadline escalates the approval to the escalate_to assignees of its state,
+// if any, with a new deadline; otherwise the approval expires and the step
+// fails with an expired decision as its output. It reports whether the
+// approval was escalated.
+func (o *Orchestrator) expireApproval(ctx context.Context, instance *models.WorkflowInstance, task *models.Task) (bool, error) {
+	var approval *models.Approval
+	if o.approvalSvc != nil {
+		var err error
+		approval, err = o.approvalSvc.GetApprovalByTaskID(ctx, task.TaskID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+	}
+
+	if approval != nil {
+		var params struct {
+			EscalateTo        []string `json:"escalate_to"`
+			EscalationTimeout string   `json:"escalation_timeout"`
+		}
+		if err := json.Unmarshal(task.Params, &params); err != nil {
+			return false, err
+		}
+		timeout, _ := time.ParseDuration(params.EscalationTimeout)
+
+		history, err := o.expireOrEscalate(ctx, instance, approval, params.EscalateTo, timeout)
+		if err != nil {
+			return false, err
+		}
+		if history == nil {
+			// Decided while the deadline passed
+			_, err := o.claimSignal(ctx, instance, task)
+			return false, err
+		}
+		o.publishHistory(ctx, instance.ExecutionID, history)
+
+		if history[0].Event == models.HistoryApprovalEscalated {
+			o.logger.Warn().
+				Str("execution_id", instance.ExecutionID).
+				Str("approval_id", approval.ApprovalID).
+				Strs("escalate_to", params.EscalateTo).
+				Msg("Approval escalated")
+			return true, nil
+		}
+	}
+
+	o.logger.Warn().
+		Str("execution_id", instance.ExecutionID).
+		Str("task_id", task.TaskID).
+		Msg("Approval expired")
+	decision := map[string]interface{}{"decision": service.DecisionExpired}
+	if approval != nil {
+		decision["approval_id"] = approval.ApprovalID
+	}
+	msg := fmt.Sprintf("approval not decided before %s", task.DeadlineAt.UTC().Format(time.RFC3339))
+	return false, o.completeWait(ctx, instance, task, decision, msg)
+}
+
+func (o *Orchestrator) expireOrEscalate(ctx context.Context, instance *models.WorkflowInstance, approval *models.Approval, escalateTo []string, timeout time.Duration) ([]models.HistoryEntry, error) {
+	if approval.EscalatedAt == nil && len(escalateTo) > 0 && timeout > 0 {
+		return o.approvalSvc.Escalate(ctx, instance, approval, escalateTo, timeout)
+	}
+	return o.approvalSvc.Expire(ctx, instance, approval)
+}
@@ -0,0 +1,235 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+)
+
+const reviewWorkflow = `
+name: review
+states:
+  - id: draft
+    type: task
+    action: draft_audio
+    on_success: review
+    compensate:
+      type: task
+      action: delete_draft
+      inputs: { key: "{{prev.output.key}}" }
+  - id: review
+    type: approval
+    assignees: ["editor@example.com"]
+    timeout: 1h
+    on_success: publish
+  - id: publish
+    type: task
+    action: publish_audio
+`
+
+// startReview runs the review workflow up to its pending approval.
+func startReview(t *testing.T, h *harness) (*models.Task, *models.Approval) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := h.orch.Start(ctx, "review", "exec-1", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	h.complete("exec-1", h.dispatched()[0], map[string]interface{}{"key": "drafts/1.wav"}, "")
+
+	task := h.task("exec-1", 2)
+	approval, err := h.orch.approvalSvc.GetApprovalByTaskID(ctx, task.TaskID)
+	if err != nil {
+		t.Fatalf("approval of task %s: %v", task.TaskID, err)
+	}
+	return task, approval
+}
+
+func outputOf(t *testing.T, task *models.Task) map[string]interface{} {
+	t.Helper()
+	var output map[string]interface{}
+	if err := json.Unmarshal(task.Output, &output); err != nil {
+		t.Fatalf("output of task %s = %s: %v", task.TaskID, task.Output, err)
+	}
+	return output
+}
+
+func TestApprovedApprovalContinuesWithDecision(t *testing.T) {
+	h := newHarness(t, reviewWorkflow)
+	_, approval := startReview(t, h)
+
+	if _, err := h.orch.Decide(context.Background(), approval.ApprovalID, true, "editor@example.com", "ship it"); err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+
+	review := h.task("exec-1", 2)
+	if review.Status != models.TaskStatusCompleted {
+		t.Fatalf("approval task is %s, want completed", review.Status)
+	}
+	if output := outputOf(t, review); output["decision"] != service.DecisionApproved || output["decided_by"] != "editor@example.com" {
+		t.Errorf("approval output = %v, want the approval by editor@example.com", output)
+	}
+
+	tasks := h.dispatched()
+	if len(tasks) != 2 || tasks[1].Type != "publish_audio" {
+		t.Fatalf("dispatched %d tasks, want publish_audio after the draft", len(tasks))
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(tasks[1].Payload, &input); err != nil || input["comment"] != "ship it" {
+		t.Errorf("publish input = %s, want the decision", tasks[1].Payload)
+	}
+}
+
+func TestRejectedApprovalCompensatesWithDecision(t *testing.T) {
+	h := newHarness(t, reviewWorkflow)
+	_, approval := startReview(t, h)
+
+	if _, err := h.orch.Decide(context.Background(), approval.ApprovalID, false, "editor@example.com", "too quiet"); err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+
+	review := h.task("exec-1", 2)
+	if review.Status != models.TaskStatusFailed || review.Error != "approval rejected by editor@example.com: too quiet" {
+		t.Errorf("approval task = %s (%q), want it failed by the rejection", review.Status, review.Error)
+	}
+	if output := outputOf(t, review); output["decision"] != service.DecisionRejected || output["comment"] != "too quiet" {
+		t.Errorf("approval output = %v, want the rejection", output)
+	}
+
+	if status := h.instance("exec-1").Status; status != models.InstanceStatusCompensating {
+		t.Fatalf("instance = %s, want COMPENSATING", status)
+	}
+	tasks := h.dispatched()
+	if len(tasks) != 2 || tasks[1].Type != "delete_draft" || !tasks[1].Compensation {
+		t.Fatalf("dispatched %d tasks, want delete_draft compensating the draft", len(tasks))
+	}
+}
+
+func TestExpiredApprovalFailsWithDecision(t *testing.T) {
+	h := newHarness(t, reviewWorkflow)
+	task, approval := startReview(t, h)
+	if task.DeadlineAt == nil {
+		t.Fatal("approval task has no deadline")
+	}
+
+	if err := h.orch.expire(context.Background(), task); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+
+	review := h.task("exec-1", 2)
+	if review.Status != models.TaskStatusFailed {
+		t.Errorf("approval task is %s, want failed", review.Status)
+	}
+	if output := outputOf(t, review); output["decision"] != service.DecisionExpired || output["approval_id"] != approval.ApprovalID {
+		t.Errorf("approval output = %v, want the expiry of approval %s", output, approval.ApprovalID)
+	}
+	if status := h.instance("exec-1").Status; status != models.InstanceStatusCompensating {
+		t.Errorf("instance = %s, want COMPENSATING", status)
+	}
+}
+
+const moderationWorkflow = `
+name: moderation
+states:
+  - id: draft
+    type: task
+    action: draft_audio
+    on_success: review
+  - id: review
+    type: approval
+    assignees: ["editor@example.com"]
+    timeout: 1h
+    on_success: publish
+    on_failure: discard
+  - id: publish
+    type: task
+    action: publish_audio
+  - id: discard
+    type: task
+    action: discard_draft
+    inputs: { decision: "{{prev.output.decision}}", reason: "{{error}}" }
+`
+
+func TestApprovalBranchesOnDecision(t *testing.T) {
+	tests := []struct {
+		name     string
+		approved bool
+		want     string
+	}{
+		{"approved", true, "publish_audio"},
+		{"rejected", false, "discard_draft"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, moderationWorkflow)
+			ctx := context.Background()
+			if _, err := h.orch.Start(ctx, "moderation", "exec-1", nil); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			h.complete("exec-1", h.dispatched()[0], map[string]interface{}{"key": "drafts/1.wav"}, "")
+			approval, err := h.orch.approvalSvc.GetApprovalByTaskID(ctx, h.task("exec-1", 2).TaskID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := h.orch.Decide(ctx, approval.ApprovalID, tt.approved, "editor@example.com", "too quiet"); err != nil {
+				t.Fatalf("Decide: %v", err)
+			}
+			if status := h.instance("exec-1").Status; status != models.InstanceStatusRunning {
+				t.Fatalf("instance = %s, want RUNNING", status)
+			}
+			tasks := h.dispatched()
+			if len(tasks) != 2 || tasks[1].Type != tt.want || tasks[1].Compensation {
+				t.Fatalf("dispatched %d tasks, want %s after the draft", len(tasks), tt.want)
+			}
+
+			next := tasks[1]
+			h.complete("exec-1", next, map[string]interface{}{"done": true}, "")
+			if status := h.instance("exec-1").Status; status != models.InstanceStatusCompleted {
+				t.Errorf("instance = %s after %s, want COMPLETED", status, tt.want)
+			}
+			if n := len(h.dispatched()); n != 2 {
+				t.Errorf("dispatched %d tasks, want nothing after %s", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestRejectionBranchSeesDecisionAndError(t *testing.T) {
+	h := newHarness(t, moderationWorkflow)
+	ctx := context.Background()
+	if _, err := h.orch.Start(ctx, "moderation", "exec-1", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	h.complete("exec-1", h.dispatched()[0], nil, "")
+	review := h.task("exec-1", 2)
+	approval, err := h.orch.approvalSvc.GetApprovalByTaskID(ctx, review.TaskID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.orch.Decide(ctx, approval.ApprovalID, false, "editor@example.com", "too quiet"); err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+
+	discard := h.dispatched()[1]
+	var input map[string]interface{}
+	if err := json.Unmarshal(discard.Payload, &input); err != nil {
+		t.Fatal(err)
+	}
+	if input["decision"] != service.DecisionRejected || input["reason"] != "approval rejected by editor@example.com: too quiet" {
+		t.Errorf("discard input = %v, want the rejection and its error", input)
+	}
+
+	// The rejection delivered again neither fails the branch nor schedules
+	// it twice
+	review = h.task("exec-1", 2)
+	h.complete("exec-1", review, outputOf(t, review), review.Error)
+	if status := h.instance("exec-1").Status; status != models.InstanceStatusRunning {
+		t.Errorf("instance = %s after a redelivered rejection, want RUNNING", status)
+	}
+	if n := len(h.dispatched()); n != 2 {
+		t.Errorf("dispatched %d tasks, want the branch once", n)
+	}
+}
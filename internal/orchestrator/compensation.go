@@ -0,0 +1,223 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"gorm.io/gorm"
+)
+
+// A failed execution whose completed steps declare compensations is not
+// failed right away. It turns COMPENSATING and undoes those steps one at a
+// time, the latest first, with current_step pointing at the step being
+// compensated. It ends COMPENSATED once every compensation succeeded, or
+// COMPENSATION_FAILED at the first one that did not; compensations are not
+// retried.
+
+// nextCompensation returns the compensation task of the latest completed step
+// before the given one that declares one, or nil when nothing is left to
+// undo.
+func (o *Orchestrator) nextCompensation(ctx context.Context, instance *models.WorkflowInstance, workflow *models.Workflow, before uint8) (*models.Task, service.TaskSpec, error) {
+	for s := int(before) - 1; s >= 1; s-- {
+		step := uint8(s)
+		state, ok := o.workflowSvc.StepState(workflow, step)
+		if !ok || state.Compensate == nil {
+			continue
+		}
+
+		done, err := o.taskSvc.GetCompletedTaskForStep(ctx, instance.ID, step)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, service.TaskSpec{}, err
+		}
+
+		var output map[string]interface{}
+		if len(done.Output) > 0 {
+			if err := json.Unmarshal(done.Output, &output); err != nil {
+				return nil, service.TaskSpec{}, err
+			}
+		}
+		vars, err := variablesOf(instance)
+		if err != nil {
+			return nil, service.TaskSpec{}, err
+		}
+		scope := service.StepContext(instance.ExecutionID, vars, map[string]interface{}{
+			"output":    output,
+			"step":      step,
+			"task_type": done.Type,
+		})
+
+		comp := state.Compensate
+		input, err := o.stateInput(ctx, instance, comp, scope, output)
+		if err != nil {
+			return nil, service.TaskSpec{}, err
+		}
+		params := comp.Params()
+		params["state"] = state.ID
+
+		spec := service.TaskSpec{
+			Step:    step,
+			Type:    comp.TaskType(),
+			Input:   input,
+			Params:  params,
+			Context: scope,
+		}
+		task, err := service.NewTask(instance.ID, spec)
+		if err != nil {
+			return nil, service.TaskSpec{}, err
+		}
+		task.Compensation = true
+		return task, spec, nil
+	}
+	return nil, service.TaskSpec{}, nil
+}
+
+// applyCompensation applies the completion of the compensation task of the
+// current step and schedules the next one, if any.
+func (o *Orchestrator) applyCompensation(ctx context.Context, instance *models.WorkflowInstance, completion *events.CompletionEvent) error {
+	task, err := o.taskSvc.GetTaskByTaskID(ctx, completion.TaskID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if task == nil || !task.Compensation || task.StepID != instance.CurrentStep {
+		// A late completion of the forward steps or a redelivered one of
+		// an earlier compensation
+		return o.redispatchPending(ctx, instance)
+	}
+
+	output, err := json.Marshal(completion.Output)
+	if err != nil {
+		return err
+	}
+	transition := repositories.StepTransition{
+		ExecutionID: instance.ExecutionID,
+		Version:     instance.Version,
+		TaskID:      completion.TaskID,
+		TaskStatus:  models.TaskStatusCompleted,
+		Output:      output,
+		Step:        instance.CurrentStep,
+	}
+
+	var spec service.TaskSpec
+	if completion.Status == events.CompletionStatusFailed {
+		o.logger.Error().
+			Str("execution_id", instance.ExecutionID).
+			Uint8("step", completion.Step).
+			Str("error", completion.Error).
+			Msg("Compensation failed, marking workflow as COMPENSATION_FAILED")
+		transition.TaskStatus = models.TaskStatusFailed
+		transition.TaskError = completion.Error
+		transition.Status = models.InstanceStatusCompensationFailed
+	} else {
+		workflow, err := o.workflowSvc.GetWorkflowByID(ctx, instance.WorkflowID)
+		if err != nil {
+			return err
+		}
+		transition.Next, spec, err = o.nextCompensation(ctx, instance, workflow, instance.CurrentStep)
+		if err != nil {
+			o.logger.Error().Err(err).Msg("Failed to render compensation input")
+			return err
+		}
+		transition.Status = models.InstanceStatusCompensated
+		if transition.Next != nil {
+			transition.Status = models.InstanceStatusCompensating
+			transition.Step = transition.Next.StepID
+		}
+	}
+
+	transition.History, err = compensationHistoryOf(instance, completion, &transition)
+	if err != nil {
+		return err
+	}
+	if o.webhookSvc != nil {
+		transition.Webhooks, err = o.webhookSvc.Deliveries(ctx, instance, transition.History, nil)
+		if err != nil {
+			o.logger.Error().Err(err).Msg("Failed to prepare webhook deliveries")
+			return err
+		}
+	}
+
+	if err := o.instanceSvc.ApplyTransition(ctx, transition); err != nil {
+		if errors.Is(err, repositories.ErrStaleTransition) {
+			o.logger.Info().
+				Str("execution_id", instance.ExecutionID).
+				Str("task_id", completion.TaskID).
+				Msg("Completion already applied, ignoring")
+			return nil
+		}
+		o.logger.Error().Err(err).Msg("Failed to apply compensation")
+		return err
+	}
+	o.publishHistory(ctx, instance.ExecutionID, transition.History)
+
+	if transition.Next != nil {
+		if err := o.dispatch(ctx, instance, transition.Next, spec); err != nil {
+			return err
+		}
+		o.markProcessed(ctx, completion.TaskID)
+		return nil
+	}
+
+	o.logger.Info().
+		Str("execution_id", instance.ExecutionID).
+		Str("status", transition.Status).
+		Msg("Compensation finished")
+	if instance.ParentExecutionID != "" {
+		instance.Status = transition.Status
+		if err := o.notifyParent(ctx, instance, nil, completion.Error); err != nil {
+			return err
+		}
+	}
+	o.markProcessed(ctx, completion.TaskID)
+	return nil
+}
+
+// compensationHistoryOf describes the outcome of a compensation, then either
+// the next compensation or the end of the execution.
+func compensationHistoryOf(instance *models.WorkflowInstance, completion *events.CompletionEvent, transition *repositories.StepTransition) ([]models.HistoryEntry, error) {
+	step := map[string]interface{}{
+		"step":      completion.Step,
+		"task_id":   completion.TaskID,
+		"task_type": completion.TaskType,
+	}
+
+	names := []string{models.HistoryCompensationCompleted}
+	data := []map[string]interface{}{step}
+	switch {
+	case completion.Status == events.CompletionStatusFailed:
+		step["error"] = completion.Error
+		names = []string{models.HistoryCompensationFailed, models.HistoryExecutionCompensationFailed}
+		data = []map[string]interface{}{step, {"step": completion.Step, "error": completion.Error}}
+	case transition.Next != nil:
+		names = append(names, models.HistoryCompensationScheduled)
+		data = append(data, compensationScheduled(transition.Next))
+	default:
+		names = append(names, models.HistoryExecutionCompensated)
+		data = append(data, nil)
+	}
+
+	entries := make([]models.HistoryEntry, 0, len(names))
+	for i, name := range names {
+		entry, err := service.NewHistoryEntry(instance.ID, name, data[i])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func compensationScheduled(task *models.Task) map[string]interface{} {
+	return map[string]interface{}{
+		"step":      task.StepID,
+		"task_id":   task.TaskID,
+		"task_type": task.Type,
+	}
+}
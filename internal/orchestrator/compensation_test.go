@@ -0,0 +1,64 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+)
+
+const bookingWorkflow = `
+name: booking
+states:
+  - id: reserve
+    type: task
+    action: reserve_seat
+    on_success: charge
+    compensate:
+      type: task
+      action: release_seat
+      inputs: { reservation: "{{prev.output.reservation_id}}" }
+  - id: charge
+    type: task
+    action: charge_card
+`
+
+func TestFailedStepCompensatesCompletedOnes(t *testing.T) {
+	h := newHarness(t, bookingWorkflow)
+	if _, err := h.orch.Start(context.Background(), "booking", "exec-1", map[string]interface{}{"seat": "12A"}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	reserve := h.dispatched()[0]
+	h.complete("exec-1", reserve, map[string]interface{}{"reservation_id": "R1"}, "")
+	charge := h.dispatched()[1]
+	h.complete("exec-1", charge, nil, "card declined")
+
+	instance := h.instance("exec-1")
+	if instance.Status != models.InstanceStatusCompensating || instance.CurrentStep != reserve.StepID {
+		t.Fatalf("instance = %s at step %d, want COMPENSATING at step %d", instance.Status, instance.CurrentStep, reserve.StepID)
+	}
+
+	tasks := h.dispatched()
+	if len(tasks) != 3 {
+		t.Fatalf("%d tasks dispatched, want the compensation after the two forward tasks", len(tasks))
+	}
+	release := tasks[2]
+	if !release.Compensation || release.StepID != reserve.StepID || release.Attempt != reserve.Attempt || release.Type != "release_seat" {
+		t.Errorf("compensation task = step %d attempt %d %s (compensation %v), want release_seat of step %d",
+			release.StepID, release.Attempt, release.Type, release.Compensation, reserve.StepID)
+	}
+	if release.Status != models.TaskStatusDispatched {
+		t.Errorf("compensation task is %s, want it dispatched", release.Status)
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(release.Payload, &input); err != nil || input["reservation"] != "R1" {
+		t.Errorf("compensation input = %s, want the reservation of the undone step", release.Payload)
+	}
+
+	h.complete("exec-1", release, nil, "")
+	if status := h.instance("exec-1").Status; status != models.InstanceStatusCompensated {
+		t.Errorf("instance = %s after the compensation completed, want COMPENSATED", status)
+	}
+}
@@ -0,0 +1,73 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+)
+
+const narrateWorkflow = `
+name: narrate
+states:
+  - id: transcribe
+    type: task
+    action: transcribe
+    on_success: speak
+  - id: speak
+    type: task
+    action: speak
+    inputs: { text: "{{prev.output.text}}" }
+`
+
+func startNarrate(t *testing.T, h *harness) {
+	t.Helper()
+	if _, err := h.orch.Start(context.Background(), "narrate", "exec-1", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+}
+
+func TestDuplicateCompletionDispatchesNextTaskOnce(t *testing.T) {
+	h := newHarness(t, narrateWorkflow)
+	startNarrate(t, h)
+	transcribe := h.task("exec-1", 1)
+
+	// A redelivery, as after a crash before the offset was committed
+	h.complete("exec-1", transcribe, map[string]interface{}{"text": "hello"}, "")
+	h.complete("exec-1", transcribe, map[string]interface{}{"text": "hello"}, "")
+
+	var speaks int
+	for _, task := range h.dispatched() {
+		if task.Type == "speak" {
+			speaks++
+		}
+	}
+	if speaks != 1 {
+		t.Errorf("dispatched %d speak tasks, want one", speaks)
+	}
+	if instance := h.instance("exec-1"); instance.CurrentStep != 2 || instance.Status != models.InstanceStatusRunning {
+		t.Errorf("instance = %s at step %d, want it running step 2", instance.Status, instance.CurrentStep)
+	}
+}
+
+func TestStaleCompletionIsIgnored(t *testing.T) {
+	h := newHarness(t, narrateWorkflow)
+	startNarrate(t, h)
+	transcribe := h.task("exec-1", 1)
+	h.complete("exec-1", transcribe, map[string]interface{}{"text": "hello"}, "")
+	h.complete("exec-1", h.task("exec-1", 2), map[string]interface{}{"audio": "s3://out/hello.wav"}, "")
+
+	// The first step, reported again as failed once the execution finished
+	dispatched := len(h.dispatched())
+	h.complete("exec-1", transcribe, nil, "worker lost")
+
+	if len(h.dispatched()) != dispatched {
+		t.Errorf("stale completion dispatched %d more tasks", len(h.dispatched())-dispatched)
+	}
+	if status := h.instance("exec-1").Status; status != models.InstanceStatusCompleted {
+		t.Errorf("instance = %s, want it still COMPLETED", status)
+	}
+	if task := h.task("exec-1", 1); task.Status != models.TaskStatusCompleted || task.Error != "" {
+		t.Errorf("first task = %s (%q), want it left completed", task.Status, task.Error)
+	}
+}
@@ -0,0 +1,123 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+	"gorm.io/gorm"
+)
+
+const testTasksTopic = "tasks"
+
+// harness runs an orchestrator against a test database, dispatching into an
+// in-memory broker.
+type harness struct {
+	t      *testing.T
+	db     *gorm.DB
+	orch   *Orchestrator
+	broker *events.MemoryBroker
+}
+
+// newHarness registers the given workflow definitions, both as DSL and as
+// active workflow rows.
+func newHarness(t *testing.T, definitions ...string) *harness {
+	t.Helper()
+	db := databasetest.Open(t)
+
+	registry := dsl.NewRegistry()
+	for _, def := range definitions {
+		wf, err := dsl.Parse([]byte(def))
+		if err != nil {
+			t.Fatalf("parse workflow: %v", err)
+		}
+		if err := registry.Register(wf); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&models.Workflow{Name: wf.Name, Status: "active"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logCfg := logger.Config{Level: "error"}
+	broker := events.NewMemoryBroker(1)
+	orch := NewOrchestrator(
+		service.NewWorkflowService(repositories.NewWorkflowRepository(db), registry),
+		service.NewInstanceService(repositories.NewInstanceRepository(db)),
+		service.NewTaskService(repositories.NewTaskRepository(db)),
+		events.NewEventProducer(broker, time.Second, logCfg),
+		events.NewTopicRouter(testTasksTopic),
+		nil,
+		logCfg,
+	)
+	orch.SetSignals(service.NewSignalService(repositories.NewSignalRepository(db)))
+	orch.SetApprovals(service.NewApprovalService(repositories.NewApprovalRepository(db), nil))
+	return &harness{t: t, db: db, orch: orch, broker: broker}
+}
+
+// dispatched returns the tasks published so far, in order.
+func (h *harness) dispatched() []*models.Task {
+	h.t.Helper()
+	var tasks []*models.Task
+	for _, msg := range h.broker.Messages(testTasksTopic) {
+		var task models.Task
+		if err := h.db.Where("task_id = ?", msg.Headers[events.HeaderTaskID]).First(&task).Error; err != nil {
+			h.t.Fatalf("dispatched task %s not persisted: %v", msg.Headers[events.HeaderTaskID], err)
+		}
+		tasks = append(tasks, &task)
+	}
+	return tasks
+}
+
+// complete reports the outcome of a dispatched task; a non-empty failure
+// fails it.
+func (h *harness) complete(executionID string, task *models.Task, output map[string]interface{}, failure string) {
+	h.t.Helper()
+	completion := &events.CompletionEvent{
+		ExecutionID: executionID,
+		TaskID:      task.TaskID,
+		Attempt:     task.Attempt,
+		TaskType:    task.Type,
+		Step:        task.StepID,
+		Status:      events.CompletionStatusCompleted,
+		Output:      output,
+	}
+	if failure != "" {
+		completion.Status = events.CompletionStatusFailed
+		completion.Error = failure
+	}
+	if err := h.orch.ProcessCompletion(context.Background(), completion); err != nil {
+		h.t.Fatalf("ProcessCompletion(step %d): %v", task.StepID, err)
+	}
+}
+
+// task returns the latest task of a step, including those the orchestrator
+// handles itself and never publishes.
+func (h *harness) task(executionID string, step uint8) *models.Task {
+	h.t.Helper()
+	instance := h.instance(executionID)
+	var task models.Task
+	err := h.db.Where("instance_id = ? AND step_id = ? AND NOT compensation", instance.ID, step).
+		Order("attempt DESC").
+		First(&task).Error
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return &task
+}
+
+func (h *harness) instance(executionID string) *models.WorkflowInstance {
+	h.t.Helper()
+	var instance models.WorkflowInstance
+	if err := h.db.Where("execution_id = ?", executionID).First(&instance).Error; err != nil {
+		h.t.Fatal(err)
+	}
+	return &instance
+}
@@ -0,0 +1,244 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+	"gorm.io/gorm"
+)
+
+// SetSignals enables wait_for_signal states and the signal API. Signals are
+// buffered in Postgres, so one sent before the execution reaches its waiting
+// state is delivered once it does.
+func (o *Orchestrator) SetSignals(signalSvc *service.SignalService) {
+	o.signalSvc = signalSvc
+}
+
+// Signal buffers a signal for an execution and hands it to the task waiting
+// for it, if there is one.
+func (o *Orchestrator) Signal(ctx context.Context, executionID, name string, payload map[string]interface{}) error {
+	if o.signalSvc == nil {
+		return errors.New("signals are not enabled")
+	}
+
+	instance, err := o.instanceSvc.GetInstanceByExecutionID(ctx, executionID)
+	if err != nil {
+		return err
+	}
+	if instance.IsTerminal() {
+		return service.ErrExecutionFinished
+	}
+	if strings.HasPrefix(name, service.ApprovalSignal("")) {
+		// Decisions go through the approval endpoints
+		return service.ErrReservedSignal
+	}
+	if blob.HasRefs(payload) {
+		return service.ErrPayloadRefs
+	}
+
+	_, history, err := o.signalSvc.Buffer(ctx, instance, name, payload)
+	if err != nil {
+		return err
+	}
+	o.publishHistory(ctx, instance.ExecutionID, history)
+
+	o.logger.Info().
+		Str("execution_id", executionID).
+		Str("signal", name).
+		Msg("Signal received")
+
+	// Only the step following the current one can be waiting
+	workflow, err := o.workflowSvc.GetWorkflowByID(ctx, instance.WorkflowID)
+	if err != nil {
+		return err
+	}
+	step, ok, err := o.followUpStep(ctx, instance, workflow)
+	if err != nil || !ok {
+		return err
+	}
+	task, err := o.taskSvc.GetLatestTaskForStep(ctx, instance.ID, step)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if task.Type != dsl.TypeWaitForSignal || task.Status != models.TaskStatusDispatched {
+		return nil
+	}
+	return o.awaitSignal(ctx, instance, task)
+}
+
+// awaitSignal completes a wait_for_signal task with the payload of a
+// buffered signal of its name, or leaves it waiting. Waiting tasks are
+// completed when their signal arrives or failed by the timers once their
+// deadline passes.
+func (o *Orchestrator) awaitSignal(ctx context.Context, instance *models.WorkflowInstance, task *models.Task) error {
+	claimed, err := o.claimSignal(ctx, instance, task)
+	if err != nil || claimed {
+		return err
+	}
+	o.logger.Info().
+		Str("execution_id", instance.ExecutionID).
+		Str("task_id", task.TaskID).
+		Str("signal", signalName(task)).
+		Msg("Waiting for signal")
+	return nil
+}
+
+// claimSignal completes a waiting task with the signal it claimed before or
+// the oldest one buffered for it, and reports whether there was one.
+func (o *Orchestrator) claimSignal(ctx context.Context, instance *models.WorkflowInstance, task *models.Task) (bool, error) {
+	if o.signalSvc == nil {
+		return true, o.completeWait(ctx, instance, task, nil, "signals are not enabled")
+	}
+
+	name := signalName(task)
+	signal, err := o.signalSvc.Claim(ctx, instance.ID, name, task.TaskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var payload map[string]interface{}
+	if len(signal.Payload) > 0 {
+		if err := json.Unmarshal(signal.Payload, &payload); err != nil {
+			return false, err
+		}
+	}
+	if task.Type == dsl.TypeApproval {
+		return true, o.completeApproval(ctx, instance, task, payload)
+	}
+	return true, o.completeWait(ctx, instance, task, map[string]interface{}{
+		"signal":      name,
+		"payload":     payload,
+		"received_at": signal.CreatedAt,
+	}, "")
+}
+
+func signalName(task *models.Task) string {
+	if task.Type == dsl.TypeApproval {
+		return service.ApprovalSignal(task.TaskID)
+	}
+	var params struct {
+		Signal string `json:"signal"`
+	}
+	_ = json.Unmarshal(task.Params, &params)
+	return params.Signal
+}
+
+// completeWait completes a task the orchestrator handles itself as if a
+// worker had reported it: with output, or failed with errMsg when set. A
+// failed task keeps output as well so the reason stays on record.
+func (o *Orchestrator) completeWait(ctx context.Context, instance *models.WorkflowInstance, task *models.Task, output map[string]interface{}, errMsg string) error {
+	completion := &events.CompletionEvent{
+		ExecutionID: instance.ExecutionID,
+		WorkflowID:  instance.WorkflowID,
+		TaskID:      task.TaskID,
+		Attempt:     task.Attempt,
+		TaskType:    task.Type,
+		Step:        task.StepID,
+		Status:      events.CompletionStatusCompleted,
+		Output:      output,
+	}
+	if errMsg != "" {
+		completion.Status = events.CompletionStatusFailed
+		completion.Error = errMsg
+	}
+	return o.ProcessCompletion(ctx, completion)
+}
+
+// waitDeadline returns when a wait_for_signal task of the given step times
+// out, or nil when its state has no timeout.
+func (o *Orchestrator) waitDeadline(workflow *models.Workflow, step uint8) *time.Time {
+	state, ok := o.workflowSvc.StepState(workflow, step)
+	if !ok || state.Timeout <= 0 {
+		return nil
+	}
+	deadline := time.Now().Add(state.Timeout)
+	return &deadline
+}
+
+// RunTimers fails waiting tasks once their deadline passes, polling every
+// interval until ctx is done. Every orchestrator replica may run it; expiring
+// a task twice is a duplicate completion.
+func (o *Orchestrator) RunTimers(ctx context.Context, interval time.Duration, batchSize int) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		tasks, err := o.taskSvc.ListExpiredTasks(ctx, batchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			o.logger.Error().Err(err).Msg("Failed to list expired tasks")
+		}
+
+		failed := false
+		for i := range tasks {
+			if err := o.expire(ctx, &tasks[i]); err != nil {
+				o.logger.Error().Err(err).Str("task_id", tasks[i].TaskID).Msg("Failed to expire task")
+				failed = true
+			}
+		}
+
+		// Keep draining while there is a backlog
+		if len(tasks) == batchSize && !failed {
+			timer.Reset(0)
+		} else {
+			timer.Reset(interval)
+		}
+	}
+}
+
+func (o *Orchestrator) expire(ctx context.Context, task *models.Task) error {
+	instance, err := o.instanceSvc.GetInstanceByID(ctx, task.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	// A signal claimed just before the deadline still wins
+	claimed, err := o.claimSignal(ctx, instance, task)
+	if err != nil {
+		return err
+	}
+	if !claimed && task.Type == dsl.TypeApproval {
+		escalated, err := o.expireApproval(ctx, instance, task)
+		if err != nil || escalated {
+			return err
+		}
+	} else if !claimed {
+		name := signalName(task)
+		o.logger.Warn().
+			Str("execution_id", instance.ExecutionID).
+			Str("task_id", task.TaskID).
+			Str("signal", name).
+			Msg("Timed out waiting for signal")
+		msg := fmt.Sprintf("no %q signal received before %s", name, task.DeadlineAt.UTC().Format(time.RFC3339))
+		if err := o.completeWait(ctx, instance, task, nil, msg); err != nil {
+			return err
+		}
+	}
+
+	// Completions the execution ignored leave the task dispatched; do not
+	// expire it again
+	return o.taskSvc.ClearTaskDeadline(ctx, task.TaskID)
+}
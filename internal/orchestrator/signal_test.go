@@ -0,0 +1,130 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+)
+
+const clipReviewWorkflow = `
+name: clip_review
+states:
+  - id: render
+    type: task
+    action: render_clip
+    on_success: wait_for_review
+  - id: wait_for_review
+    type: wait_for_signal
+    signal: clip_reviewed
+    timeout: 48h
+    on_success: publish
+  - id: publish
+    type: task
+    action: publish_clip
+    inputs: { verdict: "{{prev.output.payload.verdict}}" }
+`
+
+func startClipReview(t *testing.T, h *harness) {
+	t.Helper()
+	if _, err := h.orch.Start(context.Background(), "clip_review", "exec-1", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+}
+
+func (h *harness) signal(verdict string) {
+	h.t.Helper()
+	if err := h.orch.Signal(context.Background(), "exec-1", "clip_reviewed", map[string]interface{}{"verdict": verdict}); err != nil {
+		h.t.Fatalf("Signal: %v", err)
+	}
+}
+
+// signals returns the signals buffered for an execution, oldest first.
+func (h *harness) signals(executionID string) []models.Signal {
+	h.t.Helper()
+	var signals []models.Signal
+	if err := h.db.Where("instance_id = ?", h.instance(executionID).ID).Order("id").Find(&signals).Error; err != nil {
+		h.t.Fatal(err)
+	}
+	return signals
+}
+
+func TestSignalBeforeWaitIsBufferedThenConsumed(t *testing.T) {
+	h := newHarness(t, clipReviewWorkflow)
+	startClipReview(t, h)
+
+	h.signal("ok")
+	if signals := h.signals("exec-1"); len(signals) != 1 || signals[0].TaskID != "" {
+		t.Fatalf("signals = %+v, want one buffered and unclaimed", signals)
+	}
+
+	h.complete("exec-1", h.task("exec-1", 1), nil, "")
+
+	wait := h.task("exec-1", 2)
+	if wait.Status != models.TaskStatusCompleted {
+		t.Fatalf("wait task is %s, want it completed by the buffered signal", wait.Status)
+	}
+	if signals := h.signals("exec-1"); signals[0].TaskID != wait.TaskID || signals[0].ConsumedAt == nil {
+		t.Errorf("signal claimed by %q, want it consumed by the wait task", signals[0].TaskID)
+	}
+	publish := h.task("exec-1", 3)
+	if publish.Type != "publish_clip" || payloadOf(t, publish)["verdict"] != "ok" {
+		t.Errorf("next task = %s with %s, want publish_clip of the signal payload", publish.Type, publish.Payload)
+	}
+}
+
+func TestDuplicateSignalIsIgnored(t *testing.T) {
+	h := newHarness(t, clipReviewWorkflow)
+	startClipReview(t, h)
+	h.complete("exec-1", h.task("exec-1", 1), nil, "")
+	if wait := h.task("exec-1", 2); wait.Status != models.TaskStatusDispatched || wait.DeadlineAt == nil {
+		t.Fatalf("wait task = %s (deadline %v), want it waiting with a deadline", wait.Status, wait.DeadlineAt)
+	}
+
+	h.signal("ok")
+	h.signal("ok again")
+
+	wait := h.task("exec-1", 2)
+	if output := outputOf(t, wait); wait.Status != models.TaskStatusCompleted || output["signal"] != "clip_reviewed" {
+		t.Fatalf("wait task = %s with %v, want it completed by the signal", wait.Status, output)
+	}
+	publishes := 0
+	for _, task := range h.dispatched() {
+		if task.Type == "publish_clip" {
+			publishes++
+			if payloadOf(t, task)["verdict"] != "ok" {
+				t.Errorf("publish input = %s, want the first signal", task.Payload)
+			}
+		}
+	}
+	if publishes != 1 {
+		t.Errorf("dispatched %d publish tasks, want one", publishes)
+	}
+	if signals := h.signals("exec-1"); len(signals) != 2 || signals[1].TaskID != "" {
+		t.Errorf("signals = %+v, want the duplicate left unclaimed", signals)
+	}
+}
+
+func TestWaitTimeoutFailsStep(t *testing.T) {
+	h := newHarness(t, clipReviewWorkflow)
+	startClipReview(t, h)
+	h.complete("exec-1", h.task("exec-1", 1), nil, "")
+
+	if err := h.orch.expire(context.Background(), h.task("exec-1", 2)); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+
+	wait := h.task("exec-1", 2)
+	if wait.Status != models.TaskStatusFailed || !strings.HasPrefix(wait.Error, `no "clip_reviewed" signal received before`) {
+		t.Errorf("wait task = %s (%q), want it failed by the timeout", wait.Status, wait.Error)
+	}
+	if status := h.instance("exec-1").Status; status != models.InstanceStatusFailed {
+		t.Errorf("instance = %s, want FAILED", status)
+	}
+	if err := h.orch.Signal(context.Background(), "exec-1", "clip_reviewed", nil); !errors.Is(err, service.ErrExecutionFinished) {
+		t.Errorf("Signal after the timeout = %v, want %v", err, service.ErrExecutionFinished)
+	}
+}
@@ -0,0 +1,103 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+	"gorm.io/gorm"
+)
+
+// ErrNotStartable is returned by Start for workflows that cannot be started
+// however often it is retried, such as unknown ones.
+var ErrNotStartable = errors.New("workflow cannot be started")
+
+// Start starts an execution of the named workflow with input as its trigger
+// payload and dispatches its first task, whose declared inputs are rendered
+// against that payload. Starting is idempotent on executionID: an execution
+// that already exists, or is started concurrently, only has its first task
+// redispatched if needed. Input from outside must not hold claim checks.
+func (o *Orchestrator) Start(ctx context.Context, workflowName, executionID string, input map[string]interface{}) (*models.WorkflowInstance, error) {
+	instance, err := o.instanceSvc.GetInstanceByExecutionID(ctx, executionID)
+	if err == nil {
+		return instance, o.redispatchPending(ctx, instance)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	workflow, err := o.workflowSvc.GetWorkflowByName(ctx, workflowName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: workflow %q not found", ErrNotStartable, workflowName)
+		}
+		return nil, err
+	}
+
+	if blob.HasRefs(input) {
+		return nil, fmt.Errorf("%w: %v", ErrNotStartable, service.ErrPayloadRefs)
+	}
+	if o.claimCheck != nil {
+		if input, err = o.claimCheck.Offload(ctx, executionID, input); err != nil {
+			return nil, err
+		}
+	}
+
+	instance = &models.WorkflowInstance{
+		WorkflowID:  workflow.ID,
+		ExecutionID: executionID,
+		Status:      models.InstanceStatusPending,
+	}
+
+	spec, ok, err := o.firstSpec(ctx, instance, workflow, input)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: workflow %q has no steps", ErrNotStartable, workflowName)
+	}
+	task, err := service.NewTask(0, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.instanceSvc.StartInstance(ctx, instance, input, task); err != nil {
+		if errors.Is(err, repositories.ErrExecutionExists) {
+			// Lost the race against a redelivery of the same trigger
+			existing, err := o.instanceSvc.GetInstanceByExecutionID(ctx, executionID)
+			if err != nil {
+				return nil, err
+			}
+			return existing, o.redispatchPending(ctx, existing)
+		}
+		o.logger.Error().Err(err).Str("execution_id", executionID).Msg("Failed to start execution")
+		return nil, err
+	}
+
+	o.logger.Info().
+		Str("execution_id", executionID).
+		Str("workflow", workflowName).
+		Msg("Started execution")
+
+	return instance, o.dispatch(ctx, instance, task, spec)
+}
+
+// firstSpec builds the first task of an execution, rendering the inputs its
+// first state declares against the trigger payload, or returns false for
+// workflows without steps.
+func (o *Orchestrator) firstSpec(ctx context.Context, instance *models.WorkflowInstance, workflow *models.Workflow, input map[string]interface{}) (service.TaskSpec, bool, error) {
+	scope := service.StepContext(instance.ExecutionID, input, nil)
+	taskInput := input
+	if state, ok := o.workflowSvc.StepState(workflow, 1); ok {
+		var err error
+		if taskInput, err = o.stateInput(ctx, instance, state, scope, input); err != nil {
+			return service.TaskSpec{}, false, err
+		}
+	}
+	spec, ok := o.workflowSvc.StepSpec(workflow, 1, taskInput, scope)
+	return spec, ok, nil
+}
@@ -0,0 +1,60 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+)
+
+const transcribeWorkflow = `
+name: transcribe
+states:
+  - id: fetch
+    type: task
+    action: fetch_audio
+    inputs: { url: "{{trigger.file_url}}", execution: "{{execution_id}}" }
+`
+
+func TestStartRendersFirstStateInputs(t *testing.T) {
+	h := newHarness(t, transcribeWorkflow)
+	if _, err := h.orch.Start(context.Background(), "transcribe", "exec-1", map[string]interface{}{"file_url": "s3://in/a.wav"}); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	tasks := h.dispatched()
+	if len(tasks) != 1 {
+		t.Fatalf("dispatched %d tasks, want the first one", len(tasks))
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(tasks[0].Payload, &input); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"url": "s3://in/a.wav", "execution": "exec-1"}; !reflect.DeepEqual(input, want) {
+		t.Errorf("first task input = %v, want %v", input, want)
+	}
+}
+
+func TestStartRejectsBlobRefs(t *testing.T) {
+	h := newHarness(t, transcribeWorkflow)
+	forged := (&blob.Ref{Key: "other-exec/artifacts/task/audio.wav"}).Value()
+	_, err := h.orch.Start(context.Background(), "transcribe", "exec-1", map[string]interface{}{
+		"file_url": "s3://in/a.wav",
+		"metadata": map[string]interface{}{"cover": forged},
+	})
+	if !errors.Is(err, ErrNotStartable) {
+		t.Fatalf("Start = %v, want input holding a claim check rejected", err)
+	}
+
+	var count int64
+	if err := h.db.Model(&models.WorkflowInstance{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || len(h.dispatched()) != 0 {
+		t.Errorf("%d executions and %d tasks, want nothing started", count, len(h.dispatched()))
+	}
+}
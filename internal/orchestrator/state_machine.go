@@ -2,129 +2,615 @@ package orchestrator
 
 import (
 	"context"
-	"time"
+	"encoding/json"
+	"errors"
 
 	"github.com/Vighnesh-V-H/async/internal/events"
 	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
 	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+	"github.com/Vighnesh-V-H/async/pkg/cache"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
 	"github.com/rs/zerolog"
+	"gorm.io/gorm"
 )
 
+// maxConflictRetries bounds how often a completion is re-evaluated after
+// losing a compare-and-swap race on the instance version.
+const maxConflictRetries = 5
+
 type Orchestrator struct {
 	workflowSvc   *service.WorkflowService
 	instanceSvc   *service.InstanceService
+	taskSvc       *service.TaskService
 	eventProducer *events.EventProducer
+	router        *events.TopicRouter
+	dedupe        *cache.Deduper
+	claimCheck    *blob.ClaimCheck
+	renderer      *dsl.Renderer
+	feed          *service.ExecutionFeed
+	webhookSvc    *service.WebhookService
+	signalSvc     *service.SignalService
+	approvalSvc   *service.ApprovalService
 	logger        zerolog.Logger
 }
 
+// NewOrchestrator creates the state machine. dedupe may be nil, in which case
+// duplicate completions are only caught by the database checks.
 func NewOrchestrator(
 	workflowSvc *service.WorkflowService,
 	instanceSvc *service.InstanceService,
+	taskSvc *service.TaskService,
 	eventProducer *events.EventProducer,
+	router *events.TopicRouter,
+	dedupe *cache.Deduper,
 	logCfg logger.Config,
 ) *Orchestrator {
 	return &Orchestrator{
 		workflowSvc:   workflowSvc,
 		instanceSvc:   instanceSvc,
+		taskSvc:       taskSvc,
 		eventProducer: eventProducer,
+		router:        router,
+		dedupe:        dedupe,
+		renderer:      dsl.NewRenderer(nil),
 		logger:        logger.New(logCfg),
 	}
 }
 
-// ProcessCompletion handles completion events and orchestrates the next step
+// SetClaimCheck lets state inputs reference offloaded payloads: references
+// are passed through as they are, or loaded when embedded in text if they
+// belong to the execution, and large rendered inputs are offloaded before
+// dispatch.
+func (o *Orchestrator) SetClaimCheck(claimCheck *blob.ClaimCheck) {
+	o.claimCheck = claimCheck
+}
+
+// SetFeed broadcasts the history of every applied transition so clients can
+// follow executions live.
+func (o *Orchestrator) SetFeed(feed *service.ExecutionFeed) {
+	o.feed = feed
+}
+
+// SetWebhooks queues webhook deliveries for subscribers of the lifecycle
+// events a transition produces, in the same transaction as the transition.
+func (o *Orchestrator) SetWebhooks(webhookSvc *service.WebhookService) {
+	o.webhookSvc = webhookSvc
+}
+
+// ProcessCompletion handles completion events and orchestrates the next step.
+// Completions are applied at most once: redelivered, duplicate or out of order
+// events are logged and acknowledged without side effects.
 func (o *Orchestrator) ProcessCompletion(ctx context.Context, completion *events.CompletionEvent) error {
 	o.logger.Info().
 		Str("execution_id", completion.ExecutionID).
+		Str("task_id", completion.TaskID).
 		Str("task_type", completion.TaskType).
 		Uint8("step", completion.Step).
 		Str("status", completion.Status).
 		Msg("Processing completion event")
 
-	// 1. Update instance step status
-	if err := o.instanceSvc.UpdateInstanceStep(ctx, completion.ExecutionID, completion.Step, "COMPLETED"); err != nil {
-		o.logger.Error().Err(err).Msg("Failed to update instance step")
+	// 1. Fast path: drop completions we have already processed
+	if o.dedupe != nil && completion.TaskID != "" {
+		seen, err := o.dedupe.Seen(ctx, completion.TaskID)
+		if err != nil {
+			o.logger.Warn().Err(err).Msg("Dedupe lookup failed, falling back to database checks")
+		} else if seen {
+			o.logger.Info().
+				Str("execution_id", completion.ExecutionID).
+				Str("task_id", completion.TaskID).
+				Msg("Ignoring duplicate completion event")
+			return nil
+		}
+	}
+
+	// 2. Apply against the latest instance state, reloading on version conflicts
+	for attempt := 1; ; attempt++ {
+		err := o.applyCompletion(ctx, completion)
+
+		var conflict *repositories.VersionConflictError
+		if !errors.As(err, &conflict) {
+			return err
+		}
+		if attempt >= maxConflictRetries {
+			o.logger.Error().
+				Err(err).
+				Int("attempts", attempt).
+				Msg("Giving up after repeated version conflicts")
+			return err
+		}
+
+		o.logger.Warn().
+			Str("execution_id", completion.ExecutionID).
+			Uint("expected_version", conflict.Expected).
+			Uint("actual_version", conflict.Actual).
+			Msg("Instance changed concurrently, reloading and reapplying")
+	}
+}
+
+// applyCompletion evaluates a completion against a fresh read of the instance
+// and applies the resulting transition with a compare-and-swap on its version.
+func (o *Orchestrator) applyCompletion(ctx context.Context, completion *events.CompletionEvent) error {
+	instance, err := o.instanceSvc.GetInstanceByExecutionID(ctx, completion.ExecutionID)
+	if err != nil {
+		o.logger.Error().Err(err).Msg("Failed to get workflow instance")
 		return err
 	}
 
-	// 2. Check if task failed
-	if completion.Status == "failed" {
-		o.logger.Error().
+	if instance.IsTerminal() {
+		if instance.ParentExecutionID != "" && instance.Status != models.InstanceStatusCancelled && completion.Step == instance.CurrentStep {
+			// Redelivery of the completion that finished a child; the
+			// parent may not have heard of it yet.
+			return o.notifyParent(ctx, instance, completion.Output, completion.Error)
+		}
+		o.logger.Info().
 			Str("execution_id", completion.ExecutionID).
-			Str("error", completion.Error).
-			Msg("Task failed, marking workflow as FAILED")
-		return o.instanceSvc.UpdateInstanceStatus(ctx, completion.ExecutionID, "FAILED")
+			Str("status", instance.Status).
+			Msg("Ignoring completion for finished workflow")
+		return nil
+	}
+
+	if instance.Status == models.InstanceStatusCompensating {
+		return o.applyCompensation(ctx, instance, completion)
 	}
 
-	// 3. Get workflow to determine next step
-	workflow, err := o.workflowSvc.GetWorkflowByEvent(ctx, "generate_audio")
+	workflow, err := o.workflowSvc.GetWorkflowByID(ctx, instance.WorkflowID)
 	if err != nil {
 		o.logger.Error().Err(err).Msg("Failed to get workflow")
 		return err
 	}
 
-	// 4. Determine next task based on current step
-	nextTask := o.getNextTask(completion.Step, workflow.Steps)
-
-	if nextTask == nil {
-		// Workflow completed
-		o.logger.Info().
+	expected, ok, err := o.followUpStep(ctx, instance, workflow)
+	if err != nil {
+		return err
+	}
+	if !ok || completion.Step != expected {
+		if completion.Step == instance.CurrentStep {
+			// Redelivery after the step was applied; make sure the follow-up
+			// task actually left the building before acknowledging.
+			return o.redispatchPending(ctx, instance)
+		}
+		o.logger.Warn().
 			Str("execution_id", completion.ExecutionID).
-			Msg("Workflow completed successfully")
-		return o.instanceSvc.UpdateInstanceStatus(ctx, completion.ExecutionID, "COMPLETED")
+			Uint8("step", completion.Step).
+			Uint8("current_step", instance.CurrentStep).
+			Msg("Ignoring out of order completion event")
+		return nil
 	}
 
-	// 5. Publish next task event
-	taskEvent := &events.TaskEvent{
+	output, err := json.Marshal(completion.Output)
+	if err != nil {
+		return err
+	}
+
+	transition := repositories.StepTransition{
 		ExecutionID: completion.ExecutionID,
-		WorkflowID:  completion.WorkflowID,
-		TaskType:    nextTask.TaskType,
-		Step:        nextTask.Step,
-		Input:       completion.Output, // Pass output from previous task as input
-		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Version:     instance.Version,
+		TaskID:      completion.TaskID,
+		TaskStatus:  models.TaskStatusCompleted,
+		Output:      output,
+		Step:        completion.Step,
 	}
 
-	topic := "task-queue"
-	if err := o.eventProducer.PublishTask(topic, taskEvent); err != nil {
+	failed := completion.Status == events.CompletionStatusFailed
+	if failed {
+		transition.TaskStatus = models.TaskStatusFailed
+		transition.TaskError = completion.Error
+	} else {
+		transition.Artifacts = artifactsOf(instance, completion)
+	}
+
+	// Determine the next task: the on_success target of the step, or its
+	// on_failure target if it failed
+	var nextSpec service.TaskSpec
+	nextTask := o.getNextTask(workflow, completion.Step, failed)
+	switch {
+	case nextTask != nil:
+		if failed {
+			o.logger.Warn().
+				Str("execution_id", completion.ExecutionID).
+				Str("error", completion.Error).
+				Uint8("next_step", nextTask.Step).
+				Msg("Task failed, continuing with its on_failure state")
+		}
+		transition.Status = models.InstanceStatusRunning
+		nextSpec, err = o.nextSpec(ctx, instance, workflow, nextTask, completion)
+		if err != nil {
+			o.logger.Error().Err(err).Msg("Failed to render next task input")
+			return err
+		}
+		transition.Next, err = service.NewTask(instance.ID, nextSpec)
+		if err != nil {
+			return err
+		}
+		switch nextSpec.Type {
+		case dsl.TypeSubworkflow:
+			// Handed to the child execution rather than a worker
+			transition.Next.Status = models.TaskStatusDispatched
+		case dsl.TypeWaitForSignal, dsl.TypeApproval:
+			// Completed by a signal or its deadline rather than a worker
+			transition.Next.Status = models.TaskStatusDispatched
+			transition.Next.DeadlineAt = o.waitDeadline(workflow, nextTask.Step)
+		}
+	case failed:
+		transition.Status = models.InstanceStatusFailed
+
+		// Undo the completed steps before giving up, if they say how
+		transition.Next, nextSpec, err = o.nextCompensation(ctx, instance, workflow, completion.Step)
+		if err != nil {
+			o.logger.Error().Err(err).Msg("Failed to render compensation input")
+			return err
+		}
+		if transition.Next != nil {
+			o.logger.Error().
+				Str("execution_id", completion.ExecutionID).
+				Str("error", completion.Error).
+				Msg("Task failed, compensating completed steps")
+			transition.Status = models.InstanceStatusCompensating
+			transition.Step = transition.Next.StepID
+		} else {
+			o.logger.Error().
+				Str("execution_id", completion.ExecutionID).
+				Str("error", completion.Error).
+				Msg("Task failed, marking workflow as FAILED")
+		}
+	default:
+		transition.Status = models.InstanceStatusCompleted
+	}
+
+	transition.History, err = historyOf(instance, completion, &transition)
+	if err != nil {
+		return err
+	}
+
+	if o.webhookSvc != nil {
+		var output map[string]interface{}
+		if transition.Status == models.InstanceStatusCompleted {
+			output = completion.Output
+		}
+		transition.Webhooks, err = o.webhookSvc.Deliveries(ctx, instance, transition.History, output)
+		if err != nil {
+			o.logger.Error().Err(err).Msg("Failed to prepare webhook deliveries")
+			return err
+		}
+	}
+
+	// Apply the transition atomically; a stale transition means a duplicate
+	if err := o.instanceSvc.ApplyTransition(ctx, transition); err != nil {
+		if errors.Is(err, repositories.ErrStaleTransition) {
+			o.logger.Info().
+				Str("execution_id", completion.ExecutionID).
+				Str("task_id", completion.TaskID).
+				Msg("Completion already applied, ignoring")
+			return nil
+		}
+		o.logger.Error().Err(err).Msg("Failed to apply step transition")
+		return err
+	}
+	o.publishHistory(ctx, instance.ExecutionID, transition.History)
+
+	if transition.Next == nil {
+		if transition.Status == models.InstanceStatusCompleted {
+			o.logger.Info().
+				Str("execution_id", completion.ExecutionID).
+				Msg("Workflow completed successfully")
+		}
+		if instance.ParentExecutionID != "" {
+			instance.Status = transition.Status
+			if err := o.notifyParent(ctx, instance, completion.Output, completion.Error); err != nil {
+				return err
+			}
+		}
+		o.markProcessed(ctx, completion.TaskID)
+		return nil
+	}
+
+	// Publish next task event, start the child execution of a subworkflow
+	// or wait for a signal
+	switch nextSpec.Type {
+	case dsl.TypeSubworkflow:
+		err = o.startChild(ctx, instance, transition.Next, nextSpec)
+	case dsl.TypeWaitForSignal:
+		err = o.awaitSignal(ctx, instance, transition.Next)
+	case dsl.TypeApproval:
+		err = o.requestApproval(ctx, instance, transition.Next, nextSpec)
+	default:
+		err = o.dispatch(ctx, instance, transition.Next, nextSpec)
+	}
+	if err != nil {
+		return err
+	}
+
+	o.markProcessed(ctx, completion.TaskID)
+	return nil
+}
+
+// dispatch publishes a recorded task and marks it as handed to the broker.
+func (o *Orchestrator) dispatch(ctx context.Context, instance *models.WorkflowInstance, task *models.Task, spec service.TaskSpec) error {
+	taskEvent := &events.TaskEvent{
+		ExecutionID: instance.ExecutionID,
+		WorkflowID:  instance.WorkflowID,
+		TaskID:      task.TaskID,
+		Attempt:     task.Attempt,
+		TaskType:    task.Type,
+		Step:        task.StepID,
+		Input:       spec.Input,
+		Params:      spec.Params,
+		Context:     spec.Context,
+	}
+
+	topic := o.router.TopicFor(task.Type)
+	if err := o.eventProducer.PublishTask(ctx, topic, taskEvent); err != nil {
 		o.logger.Error().Err(err).Msg("Failed to publish next task")
 		return err
 	}
 
+	if err := o.taskSvc.MarkTaskDispatched(ctx, task.TaskID); err != nil {
+		o.logger.Error().Err(err).Str("task_id", task.TaskID).Msg("Failed to mark task as dispatched")
+		return err
+	}
+
 	o.logger.Info().
-		Str("execution_id", completion.ExecutionID).
-		Str("next_task", nextTask.TaskType).
-		Uint8("next_step", nextTask.Step).
+		Str("execution_id", instance.ExecutionID).
+		Str("task_id", task.TaskID).
+		Str("topic", topic).
+		Str("next_task", task.Type).
+		Uint8("next_step", task.StepID).
 		Msg("Published next task")
 
 	return nil
 }
 
+// redispatchPending re-publishes the follow-up task of the current step if a
+// previous attempt recorded it but failed before publishing.
+func (o *Orchestrator) redispatchPending(ctx context.Context, instance *models.WorkflowInstance) error {
+	// The compensation of the current step is the follow-up while
+	// compensating
+	step := instance.CurrentStep
+	if instance.Status != models.InstanceStatusCompensating {
+		workflow, err := o.workflowSvc.GetWorkflowByID(ctx, instance.WorkflowID)
+		if err != nil {
+			return err
+		}
+		next, ok, err := o.followUpStep(ctx, instance, workflow)
+		if err != nil || !ok {
+			return err
+		}
+		step = next
+	}
+
+	task, err := o.taskSvc.GetPendingTaskForStep(ctx, instance.ID, step)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Subworkflow and wait_for_signal tasks are never pending; make
+			// sure their child was started or their signal claimed instead
+			sub, err := o.taskSvc.GetLatestTaskForStep(ctx, instance.ID, step)
+			if err == nil && sub.Status == models.TaskStatusDispatched {
+				switch sub.Type {
+				case dsl.TypeSubworkflow:
+					spec, err := service.SpecOf(sub)
+					if err != nil {
+						return err
+					}
+					return o.startChild(ctx, instance, sub, spec)
+				case dsl.TypeWaitForSignal:
+					return o.awaitSignal(ctx, instance, sub)
+				case dsl.TypeApproval:
+					spec, err := service.SpecOf(sub)
+					if err != nil {
+						return err
+					}
+					return o.requestApproval(ctx, instance, sub, spec)
+				}
+			}
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			o.logger.Info().
+				Str("execution_id", instance.ExecutionID).
+				Uint8("step", instance.CurrentStep).
+				Msg("Ignoring duplicate completion event")
+			return nil
+		}
+		return err
+	}
+
+	spec, err := service.SpecOf(task)
+	if err != nil {
+		return err
+	}
+
+	o.logger.Warn().
+		Str("execution_id", instance.ExecutionID).
+		Str("task_id", task.TaskID).
+		Msg("Re-dispatching task left pending by an earlier attempt")
+	return o.dispatch(ctx, instance, task, spec)
+}
+
+// nextSpec builds the next task. States that declare inputs get them
+// rendered against the trigger payload and the previous output, and the
+// error of the previous step when it failed; otherwise the previous output
+// is passed on as is. The same scope travels with the
+// task so executors can render their own templates.
+func (o *Orchestrator) nextSpec(ctx context.Context, instance *models.WorkflowInstance, workflow *models.Workflow, next *NextTask, completion *events.CompletionEvent) (service.TaskSpec, error) {
+	vars, err := variablesOf(instance)
+	if err != nil {
+		return service.TaskSpec{}, err
+	}
+	prev := map[string]interface{}{
+		"output":    completion.Output,
+		"step":      completion.Step,
+		"task_type": completion.TaskType,
+	}
+	if completion.Status == events.CompletionStatusFailed {
+		prev["error"] = completion.Error
+	}
+	scope := service.StepContext(instance.ExecutionID, vars, prev)
+	if completion.Status == events.CompletionStatusFailed {
+		scope["error"] = completion.Error
+	}
+
+	input := completion.Output
+	if state, ok := o.workflowSvc.StepState(workflow, next.Step); ok {
+		if input, err = o.stateInput(ctx, instance, state, scope, input); err != nil {
+			return service.TaskSpec{}, err
+		}
+	}
+
+	spec, _ := o.workflowSvc.StepSpec(workflow, next.Step, input, scope)
+	return spec, nil
+}
+
+// stateInput renders the inputs declared by a state, offloading large ones
+// through the claim check, or returns fallback when it declares none.
+func (o *Orchestrator) stateInput(ctx context.Context, instance *models.WorkflowInstance, state *dsl.State, scope, fallback map[string]interface{}) (map[string]interface{}, error) {
+	if len(state.Inputs) == 0 {
+		return fallback, nil
+	}
+	renderer := o.renderer
+	if o.claimCheck != nil {
+		renderer = dsl.NewRenderer(o.claimCheck.Scoped(instance.ExecutionID).ResolveValue)
+	}
+	rendered, err := renderer.RenderInputs(ctx, state.Inputs, scope)
+	if err != nil {
+		return nil, err
+	}
+	if o.claimCheck != nil {
+		if rendered, err = o.claimCheck.Offload(ctx, instance.ExecutionID, rendered); err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// variablesOf decodes the trigger payload of an instance.
+func variablesOf(instance *models.WorkflowInstance) (map[string]interface{}, error) {
+	var vars map[string]interface{}
+	if len(instance.Variables) > 0 {
+		if err := json.Unmarshal(instance.Variables, &vars); err != nil {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
+
+// historyOf describes a transition as history entries: the outcome of the
+// step, then either the next task or the end of the execution.
+func historyOf(instance *models.WorkflowInstance, completion *events.CompletionEvent, transition *repositories.StepTransition) ([]models.HistoryEntry, error) {
+	step := map[string]interface{}{
+		"step":      completion.Step,
+		"task_id":   completion.TaskID,
+		"task_type": completion.TaskType,
+		"attempt":   completion.Attempt,
+	}
+
+	type event struct {
+		name string
+		data map[string]interface{}
+	}
+	var evts []event
+
+	if completion.Status == events.CompletionStatusFailed {
+		step["error"] = completion.Error
+		evts = append(evts, event{models.HistoryStepFailed, step})
+	} else {
+		evts = append(evts, event{models.HistoryStepCompleted, step})
+	}
+
+	switch {
+	case transition.Status == models.InstanceStatusCompensating:
+		evts = append(evts, event{models.HistoryCompensationScheduled, compensationScheduled(transition.Next)})
+	case transition.Next != nil:
+		evts = append(evts, event{models.HistoryTaskScheduled, map[string]interface{}{
+			"step":      transition.Next.StepID,
+			"task_id":   transition.Next.TaskID,
+			"task_type": transition.Next.Type,
+			"attempt":   transition.Next.Attempt,
+		}})
+	case transition.Status == models.InstanceStatusCompleted:
+		evts = append(evts, event{models.HistoryExecutionCompleted, nil})
+	case transition.Status == models.InstanceStatusFailed:
+		evts = append(evts, event{models.HistoryExecutionFailed, map[string]interface{}{
+			"step":  completion.Step,
+			"error": completion.Error,
+		}})
+	}
+
+	entries := make([]models.HistoryEntry, 0, len(evts))
+	for _, e := range evts {
+		entry, err := service.NewHistoryEntry(instance.ID, e.name, e.data)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// artifactsOf converts the artifacts reported by a completion into records of
+// the instance.
+func artifactsOf(instance *models.WorkflowInstance, completion *events.CompletionEvent) []models.Artifact {
+	artifacts := make([]models.Artifact, 0, len(completion.Artifacts))
+	for _, a := range completion.Artifacts {
+		artifacts = append(artifacts, models.Artifact{
+			InstanceID:  instance.ID,
+			TaskID:      completion.TaskID,
+			StepID:      completion.Step,
+			Name:        a.Name,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			SHA256:      a.SHA256,
+			BlobKey:     a.Key,
+		})
+	}
+	return artifacts
+}
+
+// publishHistory broadcasts persisted history entries once they commit.
+// Failures only cost live subscribers an update; the history itself is
+// already committed.
+func (o *Orchestrator) publishHistory(ctx context.Context, executionID string, entries []models.HistoryEntry) {
+	if o.feed == nil {
+		return
+	}
+	database.AfterCommit(ctx, func() {
+		if err := o.feed.Publish(ctx, executionID, entries); err != nil {
+			o.logger.Warn().Err(err).Str("execution_id", executionID).Msg("Failed to publish execution events")
+		}
+	})
+}
+
+// markProcessed records a completion as processed once its transition
+// commits, so a rolled back transition is not mistaken for a duplicate.
+func (o *Orchestrator) markProcessed(ctx context.Context, taskID string) {
+	if o.dedupe == nil || taskID == "" {
+		return
+	}
+	database.AfterCommit(ctx, func() {
+		if err := o.dedupe.Mark(ctx, taskID); err != nil {
+			o.logger.Warn().Err(err).Str("task_id", taskID).Msg("Failed to record processed completion")
+		}
+	})
+}
+
 // NextTask represents the next task in the workflow
 type NextTask struct {
 	TaskType string
 	Step     uint8
 }
 
-// getNextTask determines the next task based on the workflow definition
-func (o *Orchestrator) getNextTask(currentStep uint8, totalSteps uint8) *NextTask {
-	// Hardcoded workflow for audio generation (3 steps)
-	// Step 1: extract_text
-	// Step 2: generate_audio
-	// Step 3: store_audio
-
-	nextStep := currentStep + 1
-	if nextStep > totalSteps {
+// getNextTask determines the next task based on the workflow definition:
+// the on_success target of the step, or its on_failure target if it failed
+func (o *Orchestrator) getNextTask(workflow *models.Workflow, currentStep uint8, failed bool) *NextTask {
+	nextStep, ok := o.workflowSvc.NextStep(workflow, currentStep, failed)
+	if !ok {
 		return nil // No more steps
 	}
-
-	var taskType string
-	switch nextStep {
-	case 1:
-		taskType = "extract_text"
-	case 2:
-		taskType = "generate_audio"
-	case 3:
-		taskType = "store_audio"
-	default:
+	taskType, ok := o.workflowSvc.StepTaskType(workflow, nextStep)
+	if !ok {
 		return nil
 	}
 
@@ -133,3 +619,20 @@ func (o *Orchestrator) getNextTask(currentStep uint8, totalSteps uint8) *NextTas
 		Step:     nextStep,
 	}
 }
+
+// followUpStep returns the step whose task follows the current step of a
+// running instance, or false when none does. A current step that failed is
+// followed by its on_failure target, which only states declaring one need
+// to look up.
+func (o *Orchestrator) followUpStep(ctx context.Context, instance *models.WorkflowInstance, workflow *models.Workflow) (uint8, bool, error) {
+	failed := false
+	if state, ok := o.workflowSvc.StepState(workflow, instance.CurrentStep); ok && state.OnFailure != "" {
+		task, err := o.taskSvc.GetLatestTaskForStep(ctx, instance.ID, instance.CurrentStep)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, err
+		}
+		failed = err == nil && task.Status == models.TaskStatusFailed
+	}
+	next, ok := o.workflowSvc.NextStep(workflow, instance.CurrentStep, failed)
+	return next, ok, nil
+}
@@ -0,0 +1,277 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxSubworkflowDepth bounds how deeply subworkflows may nest, so a workflow
+// that starts itself fails instead of running forever.
+const maxSubworkflowDepth = 8
+
+// startChild starts the child execution of a subworkflow task. The task
+// stays dispatched until the child finishes and reports back through
+// notifyParent. Starting is idempotent: a child that already exists only has
+// its first task redispatched if needed. Children that cannot be started
+// fail the task.
+func (o *Orchestrator) startChild(ctx context.Context, parent *models.WorkflowInstance, task *models.Task, spec service.TaskSpec) error {
+	child, err := o.instanceSvc.GetChildByTaskID(ctx, task.TaskID)
+	if err == nil {
+		return o.redispatchPending(ctx, child)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	name, _ := spec.Params["workflow"].(string)
+	workflow, err := o.workflowSvc.GetWorkflowByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return o.failSubworkflow(ctx, parent, task, fmt.Sprintf("workflow %q not found", name))
+		}
+		return err
+	}
+
+	depth, err := o.depth(ctx, parent)
+	if err != nil {
+		return err
+	}
+	if depth >= maxSubworkflowDepth {
+		return o.failSubworkflow(ctx, parent, task, fmt.Sprintf("subworkflows nested deeper than %d levels", maxSubworkflowDepth))
+	}
+
+	executionID := uuid.New().String()
+	input := spec.Input
+	if o.claimCheck != nil {
+		// The child only loads payloads stored for it
+		if input, err = o.claimCheck.Scoped(executionID).Adopt(ctx, input); err != nil {
+			return err
+		}
+	}
+	child = &models.WorkflowInstance{
+		WorkflowID:        workflow.ID,
+		ExecutionID:       executionID,
+		Status:            models.InstanceStatusPending,
+		ParentExecutionID: parent.ExecutionID,
+		ParentTaskID:      task.TaskID,
+	}
+	childSpec, ok, err := o.firstSpec(ctx, child, workflow, input)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return o.failSubworkflow(ctx, parent, task, fmt.Sprintf("workflow %q has no steps", name))
+	}
+	childTask, err := service.NewTask(0, childSpec)
+	if err != nil {
+		return err
+	}
+
+	entry, err := service.NewHistoryEntry(parent.ID, models.HistorySubworkflowStarted, map[string]interface{}{
+		"step":               task.StepID,
+		"task_id":            task.TaskID,
+		"workflow":           name,
+		"child_execution_id": executionID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := o.instanceSvc.StartChild(ctx, child, input, childTask, &entry); err != nil {
+		o.logger.Error().Err(err).Str("execution_id", parent.ExecutionID).Msg("Failed to start child execution")
+		return err
+	}
+	o.publishHistory(ctx, parent.ExecutionID, []models.HistoryEntry{entry})
+
+	o.logger.Info().
+		Str("execution_id", parent.ExecutionID).
+		Str("child_execution_id", executionID).
+		Str("workflow", name).
+		Uint8("step", task.StepID).
+		Msg("Started child execution")
+
+	return o.dispatch(ctx, child, childTask, childSpec)
+}
+
+// depth returns how many ancestors an execution has.
+func (o *Orchestrator) depth(ctx context.Context, instance *models.WorkflowInstance) (int, error) {
+	depth := 0
+	for instance.ParentExecutionID != "" && depth < maxSubworkflowDepth {
+		parent, err := o.instanceSvc.GetInstanceByExecutionID(ctx, instance.ParentExecutionID)
+		if err != nil {
+			return 0, err
+		}
+		instance = parent
+		depth++
+	}
+	return depth, nil
+}
+
+// failSubworkflow fails a subworkflow task as if a worker had reported it.
+func (o *Orchestrator) failSubworkflow(ctx context.Context, parent *models.WorkflowInstance, task *models.Task, message string) error {
+	o.logger.Error().
+		Str("execution_id", parent.ExecutionID).
+		Str("task_id", task.TaskID).
+		Str("error", message).
+		Msg("Failed to start child execution")
+
+	return o.ProcessCompletion(ctx, &events.CompletionEvent{
+		ExecutionID: parent.ExecutionID,
+		WorkflowID:  parent.WorkflowID,
+		TaskID:      task.TaskID,
+		Attempt:     task.Attempt,
+		TaskType:    task.Type,
+		Step:        task.StepID,
+		Status:      events.CompletionStatusFailed,
+		Error:       message,
+	})
+}
+
+// notifyParent completes the subworkflow task of the parent with the outcome
+// of a finished child: its final output on success, its error otherwise.
+// The parent applies it like any other completion, so repeated notifications
+// are harmless.
+func (o *Orchestrator) notifyParent(ctx context.Context, child *models.WorkflowInstance, output map[string]interface{}, childErr string) error {
+	parent, err := o.instanceSvc.GetInstanceByExecutionID(ctx, child.ParentExecutionID)
+	if err != nil {
+		return err
+	}
+	task, err := o.taskSvc.GetTaskByTaskID(ctx, child.ParentTaskID)
+	if err != nil {
+		return err
+	}
+
+	completion := &events.CompletionEvent{
+		ExecutionID: parent.ExecutionID,
+		WorkflowID:  parent.WorkflowID,
+		TaskID:      task.TaskID,
+		Attempt:     task.Attempt,
+		TaskType:    task.Type,
+		Step:        task.StepID,
+		Status:      events.CompletionStatusCompleted,
+		Output:      output,
+	}
+	if child.Status == models.InstanceStatusCompleted && o.claimCheck != nil {
+		// The parent only loads payloads stored for it
+		if completion.Output, err = o.claimCheck.Scoped(parent.ExecutionID).Adopt(ctx, output); err != nil {
+			return err
+		}
+	}
+	if child.Status != models.InstanceStatusCompleted {
+		completion.Status = events.CompletionStatusFailed
+		completion.Output = nil
+		completion.Error = fmt.Sprintf("subworkflow %s %s", child.ExecutionID, statusVerb(child.Status))
+		if childErr != "" {
+			completion.Error += ": " + childErr
+		}
+	}
+	return o.ProcessCompletion(ctx, completion)
+}
+
+func statusVerb(status string) string {
+	switch status {
+	case models.InstanceStatusCancelled:
+		return "was cancelled"
+	case models.InstanceStatusCompensated:
+		return "failed and was compensated"
+	case models.InstanceStatusCompensationFailed:
+		return "failed and could not be compensated"
+	}
+	return "failed"
+}
+
+// Cancel stops an execution and every execution started below it. Tasks
+// already running finish, but their completions are ignored. The parent of
+// a cancelled child sees its subworkflow step fail. Cancelling a cancelled
+// execution only finishes cascading to its children.
+func (o *Orchestrator) Cancel(ctx context.Context, executionID, reason string) error {
+	for attempt := 1; ; attempt++ {
+		err := o.cancel(ctx, executionID, reason)
+
+		var conflict *repositories.VersionConflictError
+		if !errors.As(err, &conflict) || attempt >= maxConflictRetries {
+			return err
+		}
+	}
+}
+
+func (o *Orchestrator) cancel(ctx context.Context, executionID, reason string) error {
+	instance, err := o.instanceSvc.GetInstanceByExecutionID(ctx, executionID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case instance.Status == models.InstanceStatusCancelled:
+	case instance.IsTerminal():
+		return service.ErrExecutionFinished
+	default:
+		if err := o.applyCancel(ctx, instance, reason); err != nil {
+			return err
+		}
+		instance.Status = models.InstanceStatusCancelled
+	}
+
+	children, err := o.instanceSvc.ListChildren(ctx, executionID)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, child := range children {
+		if child.IsTerminal() {
+			continue
+		}
+		if err := o.Cancel(ctx, child.ExecutionID, "parent execution cancelled"); err != nil && !errors.Is(err, service.ErrExecutionFinished) {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if instance.ParentExecutionID != "" {
+		return o.notifyParent(ctx, instance, nil, reason)
+	}
+	return nil
+}
+
+func (o *Orchestrator) applyCancel(ctx context.Context, instance *models.WorkflowInstance, reason string) error {
+	entry, err := service.NewHistoryEntry(instance.ID, models.HistoryExecutionCancelled, map[string]interface{}{
+		"reason": reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	transition := repositories.StepTransition{
+		ExecutionID: instance.ExecutionID,
+		Version:     instance.Version,
+		Step:        instance.CurrentStep,
+		Status:      models.InstanceStatusCancelled,
+		History:     []models.HistoryEntry{entry},
+	}
+	if o.webhookSvc != nil {
+		transition.Webhooks, err = o.webhookSvc.Deliveries(ctx, instance, transition.History, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := o.instanceSvc.ApplyTransition(ctx, transition); err != nil {
+		return err
+	}
+	o.publishHistory(ctx, instance.ExecutionID, transition.History)
+
+	o.logger.Info().
+		Str("execution_id", instance.ExecutionID).
+		Str("reason", reason).
+		Msg("Execution cancelled")
+	return nil
+}
@@ -0,0 +1,255 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+)
+
+const onboardingWorkflow = `
+name: onboarding
+states:
+  - id: prepare
+    type: task
+    action: prepare_text
+    on_success: narrate
+  - id: narrate
+    type: subworkflow
+    workflow: narration
+    inputs: { text: "{{prev.output.text}}" }
+    on_success: send
+  - id: send
+    type: task
+    action: send_audio
+    inputs: { audio: "{{prev.output.audio}}" }
+`
+
+const narrationWorkflow = `
+name: narration
+states:
+  - id: speak
+    type: task
+    action: speak
+    inputs: { text: "{{trigger.text}}", voice: "alloy" }
+`
+
+// startOnboarding runs the onboarding workflow up to its child execution.
+func startOnboarding(t *testing.T, h *harness, text interface{}) *models.WorkflowInstance {
+	t.Helper()
+	if _, err := h.orch.Start(context.Background(), "onboarding", "exec-1", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	h.complete("exec-1", h.task("exec-1", 1), map[string]interface{}{"text": text}, "")
+
+	child, ok := h.child("exec-1")
+	if !ok {
+		t.Fatal("no child execution started")
+	}
+	return child
+}
+
+// child returns the child execution started by an execution, if any.
+func (h *harness) child(executionID string) (*models.WorkflowInstance, bool) {
+	h.t.Helper()
+	var children []models.WorkflowInstance
+	if err := h.db.Where("parent_execution_id = ?", executionID).Find(&children).Error; err != nil {
+		h.t.Fatal(err)
+	}
+	if len(children) > 1 {
+		h.t.Fatalf("%s started %d child executions, want at most one", executionID, len(children))
+	}
+	if len(children) == 0 {
+		return nil, false
+	}
+	return &children[0], true
+}
+
+func payloadOf(t *testing.T, task *models.Task) map[string]interface{} {
+	t.Helper()
+	var payload map[string]interface{}
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		t.Fatalf("payload of task %s = %s: %v", task.TaskID, task.Payload, err)
+	}
+	return payload
+}
+
+func TestSubworkflowStartsChildWithRenderedInputs(t *testing.T) {
+	h := newHarness(t, onboardingWorkflow, narrationWorkflow)
+	child := startOnboarding(t, h, "Welcome!")
+
+	narrate := h.task("exec-1", 2)
+	if child.ParentTaskID != narrate.TaskID || narrate.Status != models.TaskStatusDispatched {
+		t.Errorf("child of task %s, subworkflow task %s is %s; want the dispatched subworkflow task as parent", child.ParentTaskID, narrate.TaskID, narrate.Status)
+	}
+
+	speak := h.task(child.ExecutionID, 1)
+	if speak.Type != "speak" {
+		t.Fatalf("child task = %s, want speak", speak.Type)
+	}
+	if want := map[string]interface{}{"text": "Welcome!", "voice": "alloy"}; !reflect.DeepEqual(payloadOf(t, speak), want) {
+		t.Errorf("child task input = %s, want %v", speak.Payload, want)
+	}
+}
+
+func TestSubworkflowOutputCompletesParentStep(t *testing.T) {
+	h := newHarness(t, onboardingWorkflow, narrationWorkflow)
+	child := startOnboarding(t, h, "Welcome!")
+
+	h.complete(child.ExecutionID, h.task(child.ExecutionID, 1), map[string]interface{}{"audio": "s3://out/welcome.wav"}, "")
+
+	if status := h.instance(child.ExecutionID).Status; status != models.InstanceStatusCompleted {
+		t.Fatalf("child = %s, want COMPLETED", status)
+	}
+	narrate := h.task("exec-1", 2)
+	if narrate.Status != models.TaskStatusCompleted || outputOf(t, narrate)["audio"] != "s3://out/welcome.wav" {
+		t.Fatalf("subworkflow task = %s with %s, want it completed with the child output", narrate.Status, narrate.Output)
+	}
+	send := h.task("exec-1", 3)
+	if send.Type != "send_audio" || payloadOf(t, send)["audio"] != "s3://out/welcome.wav" {
+		t.Errorf("next task = %s with %s, want send_audio of the child output", send.Type, send.Payload)
+	}
+}
+
+func TestSubworkflowFailureFailsParentStep(t *testing.T) {
+	h := newHarness(t, onboardingWorkflow, narrationWorkflow)
+	child := startOnboarding(t, h, "Welcome!")
+
+	h.complete(child.ExecutionID, h.task(child.ExecutionID, 1), nil, "tts unavailable")
+
+	if status := h.instance(child.ExecutionID).Status; status != models.InstanceStatusFailed {
+		t.Fatalf("child = %s, want FAILED", status)
+	}
+	narrate := h.task("exec-1", 2)
+	if narrate.Status != models.TaskStatusFailed || !strings.Contains(narrate.Error, "tts unavailable") {
+		t.Errorf("subworkflow task = %s (%q), want it failed with the child error", narrate.Status, narrate.Error)
+	}
+	if status := h.instance("exec-1").Status; status != models.InstanceStatusFailed {
+		t.Errorf("parent = %s, want FAILED", status)
+	}
+}
+
+const recursiveWorkflow = `
+name: recursive
+states:
+  - id: tick
+    type: task
+    action: tick
+    on_success: recurse
+  - id: recurse
+    type: subworkflow
+    workflow: recursive
+`
+
+func TestSubworkflowDepthIsLimited(t *testing.T) {
+	h := newHarness(t, recursiveWorkflow)
+	if _, err := h.orch.Start(context.Background(), "recursive", "exec-1", nil); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	executionID := "exec-1"
+	depth := 0
+	for ; depth <= maxSubworkflowDepth; depth++ {
+		h.complete(executionID, h.task(executionID, 1), nil, "")
+		child, ok := h.child(executionID)
+		if !ok {
+			break
+		}
+		executionID = child.ExecutionID
+	}
+	if depth != maxSubworkflowDepth {
+		t.Fatalf("started %d nested children, want %d", depth, maxSubworkflowDepth)
+	}
+
+	// The failure travels up to the root
+	recurse := h.task("exec-1", 2)
+	if recurse.Status != models.TaskStatusFailed || !strings.Contains(recurse.Error, "nested deeper than") {
+		t.Errorf("root subworkflow task = %s (%q), want it failed by the depth limit", recurse.Status, recurse.Error)
+	}
+	if status := h.instance("exec-1").Status; status != models.InstanceStatusFailed {
+		t.Errorf("root = %s, want FAILED", status)
+	}
+}
+
+func TestCancelCascadesToChildren(t *testing.T) {
+	h := newHarness(t, onboardingWorkflow, narrationWorkflow)
+	child := startOnboarding(t, h, "Welcome!")
+
+	if err := h.orch.Cancel(context.Background(), "exec-1", "no longer needed"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if status := h.instance("exec-1").Status; status != models.InstanceStatusCancelled {
+		t.Errorf("parent = %s, want CANCELLED", status)
+	}
+	if status := h.instance(child.ExecutionID).Status; status != models.InstanceStatusCancelled {
+		t.Errorf("child = %s, want CANCELLED", status)
+	}
+
+	// The task still running on a worker finishes without effect
+	dispatched := len(h.dispatched())
+	h.complete(child.ExecutionID, h.task(child.ExecutionID, 1), map[string]interface{}{"audio": "s3://out/welcome.wav"}, "")
+	if len(h.dispatched()) != dispatched || h.instance(child.ExecutionID).Status != models.InstanceStatusCancelled {
+		t.Errorf("completion of a cancelled child was applied")
+	}
+}
+
+func TestCancellingChildFailsParentStep(t *testing.T) {
+	h := newHarness(t, onboardingWorkflow, narrationWorkflow)
+	child := startOnboarding(t, h, "Welcome!")
+
+	if err := h.orch.Cancel(context.Background(), child.ExecutionID, "wrong voice"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	narrate := h.task("exec-1", 2)
+	if narrate.Status != models.TaskStatusFailed || !strings.Contains(narrate.Error, "was cancelled: wrong voice") {
+		t.Errorf("subworkflow task = %s (%q), want it failed by the cancellation", narrate.Status, narrate.Error)
+	}
+}
+
+func TestSubworkflowAdoptsClaimChecks(t *testing.T) {
+	h := newHarness(t, onboardingWorkflow, narrationWorkflow)
+	store, err := blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimCheck := blob.NewClaimCheck(store, 16)
+	h.orch.SetClaimCheck(claimCheck)
+	ctx := context.Background()
+
+	// The worker of the first step offloaded its long output
+	text := strings.Repeat("Welcome aboard! ", 8)
+	output, err := claimCheck.Offload(ctx, "exec-1", map[string]interface{}{"text": text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := startOnboarding(t, h, output["text"])
+
+	// The child renders its inputs from a copy of the payload it may load
+	speak := h.task(child.ExecutionID, 1)
+	ref, ok := blob.AsRef(payloadOf(t, speak)["text"])
+	if !ok || !strings.HasPrefix(ref.Key, child.ExecutionID+"/") {
+		t.Fatalf("child task input = %s, want the text stored for the child", speak.Payload)
+	}
+	if data, err := claimCheck.Scoped(child.ExecutionID).Load(ctx, ref); err != nil || string(data) != text {
+		t.Fatalf("child input loads %q, %v; want the text", data, err)
+	}
+
+	// And hands its output back as a copy the parent may load
+	audio, err := claimCheck.Offload(ctx, child.ExecutionID, map[string]interface{}{"audio": strings.Repeat("pcm", 16)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.complete(child.ExecutionID, speak, audio, "")
+
+	ref, ok = blob.AsRef(outputOf(t, h.task("exec-1", 2))["audio"])
+	if !ok || !strings.HasPrefix(ref.Key, "exec-1/") {
+		t.Fatalf("subworkflow output = %v, want the audio stored for the parent", outputOf(t, h.task("exec-1", 2)))
+	}
+	if _, err := claimCheck.Scoped("exec-1").Load(ctx, ref); err != nil {
+		t.Errorf("parent cannot load the child output: %v", err)
+	}
+}
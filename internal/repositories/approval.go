@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ApprovalFilter narrows a listing of approvals; empty fields match all.
+type ApprovalFilter struct {
+	Status      string
+	Assignee    string
+	ExecutionID string
+	Limit       int
+}
+
+type ApprovalRepository struct {
+	db *gorm.DB
+}
+
+func NewApprovalRepository(db *gorm.DB) *ApprovalRepository {
+	return &ApprovalRepository{db: db}
+}
+
+// Create inserts an approval with its history and webhook deliveries unless
+// its task already requested one. It reports whether it was inserted.
+func (r *ApprovalRepository) Create(ctx context.Context, approval *models.Approval, history []models.HistoryEntry, webhooks []models.WebhookDelivery) (bool, error) {
+	created := false
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "task_id"}},
+			DoNothing: true,
+		}).Create(approval)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		created = true
+		return saveRecords(tx, history, webhooks)
+	})
+	return created, err
+}
+
+func (r *ApprovalRepository) GetByApprovalID(ctx context.Context, approvalID string) (*models.Approval, error) {
+	var approval models.Approval
+	err := database.Conn(ctx, r.db).Where("approval_id = ?", approvalID).First(&approval).Error
+	if err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+func (r *ApprovalRepository) GetByTaskID(ctx context.Context, taskID string) (*models.Approval, error) {
+	var approval models.Approval
+	err := database.Conn(ctx, r.db).Where("task_id = ?", taskID).First(&approval).Error
+	if err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+// List returns approvals matching filter, oldest first.
+func (r *ApprovalRepository) List(ctx context.Context, filter ApprovalFilter) ([]models.Approval, error) {
+	query := database.Conn(ctx, r.db).Order("created_at, id")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ExecutionID != "" {
+		query = query.Where("execution_id = ?", filter.ExecutionID)
+	}
+	if filter.Assignee != "" {
+		assignee, err := json.Marshal([]string{filter.Assignee})
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("assignees @> ?::jsonb", string(assignee))
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var approvals []models.Approval
+	err := query.Find(&approvals).Error
+	return approvals, err
+}
+
+// Decide records the decision on a pending approval and buffers the signal
+// that completes its task, in one transaction. It reports false when the
+// approval was no longer pending.
+func (r *ApprovalRepository) Decide(ctx context.Context, approval *models.Approval, signal *models.Signal, history []models.HistoryEntry, webhooks []models.WebhookDelivery) (bool, error) {
+	return r.updatePending(ctx, approval, map[string]interface{}{
+		"status":     approval.Status,
+		"decided_by": approval.DecidedBy,
+		"comment":    approval.Comment,
+		"decided_at": approval.DecidedAt,
+	}, func(tx *gorm.DB) error {
+		return tx.Create(signal).Error
+	}, history, webhooks)
+}
+
+// Escalate hands a pending approval to more assignees and moves the
+// deadline of the approval and its task. It reports false when the approval
+// was no longer pending.
+func (r *ApprovalRepository) Escalate(ctx context.Context, approval *models.Approval, history []models.HistoryEntry, webhooks []models.WebhookDelivery) (bool, error) {
+	return r.updatePending(ctx, approval, map[string]interface{}{
+		"assignees":    approval.Assignees,
+		"deadline_at":  approval.DeadlineAt,
+		"escalated_at": approval.EscalatedAt,
+	}, func(tx *gorm.DB) error {
+		return tx.Model(&models.Task{}).
+			Where("task_id = ?", approval.TaskID).
+			Update("deadline_at", approval.DeadlineAt).Error
+	}, history, webhooks)
+}
+
+// Expire marks a pending approval as expired. It reports false when the
+// approval was no longer pending.
+func (r *ApprovalRepository) Expire(ctx context.Context, approval *models.Approval, history []models.HistoryEntry, webhooks []models.WebhookDelivery) (bool, error) {
+	return r.updatePending(ctx, approval, map[string]interface{}{
+		"status": models.ApprovalStatusExpired,
+	}, nil, history, webhooks)
+}
+
+// updatePending updates an approval and applies the rest of the change only
+// if it still was pending.
+func (r *ApprovalRepository) updatePending(ctx context.Context, approval *models.Approval, values map[string]interface{}, then func(tx *gorm.DB) error, history []models.HistoryEntry, webhooks []models.WebhookDelivery) (bool, error) {
+	updated := false
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		values["updated_at"] = time.Now()
+		res := tx.Model(&models.Approval{}).
+			Where("approval_id = ? AND status = ?", approval.ApprovalID, models.ApprovalStatusPending).
+			Updates(values)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		updated = true
+
+		if then != nil {
+			if err := then(tx); err != nil {
+				return err
+			}
+		}
+		return saveRecords(tx, history, webhooks)
+	})
+	return updated, err
+}
+
+func saveRecords(tx *gorm.DB, history []models.HistoryEntry, webhooks []models.WebhookDelivery) error {
+	if len(history) > 0 {
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+	}
+	return saveWebhookDeliveries(tx, webhooks)
+}
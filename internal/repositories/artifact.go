@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ArtifactRepository struct {
+	db *gorm.DB
+}
+
+func NewArtifactRepository(db *gorm.DB) *ArtifactRepository {
+	return &ArtifactRepository{db: db}
+}
+
+// ListByInstance returns the artifacts of an instance ordered by name.
+func (r *ArtifactRepository) ListByInstance(ctx context.Context, instanceID uint) ([]models.Artifact, error) {
+	var artifacts []models.Artifact
+	err := database.Conn(ctx, r.db).
+		Where("instance_id = ?", instanceID).
+		Order("name").
+		Find(&artifacts).Error
+	return artifacts, err
+}
+
+func (r *ArtifactRepository) Get(ctx context.Context, instanceID uint, name string) (*models.Artifact, error) {
+	var artifact models.Artifact
+	err := database.Conn(ctx, r.db).
+		Where("instance_id = ? AND name = ?", instanceID, name).
+		First(&artifact).Error
+	if err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
+
+// saveArtifacts inserts artifacts, replacing earlier ones of the same name.
+func saveArtifacts(db *gorm.DB, artifacts []models.Artifact) error {
+	if len(artifacts) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "instance_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"task_id", "step_id", "content_type", "size", "sha256", "blob_key", "updated_at"}),
+	}).Create(&artifacts).Error
+}
@@ -2,11 +2,53 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 )
 
+// ErrStaleTransition is returned when a completion no longer applies because
+// its task was already completed or failed.
+var ErrStaleTransition = errors.New("stale step transition")
+
+// ErrExecutionExists is returned when an instance is created with the
+// execution ID of an existing one.
+var ErrExecutionExists = errors.New("execution already exists")
+
+// VersionConflictError is returned by compare-and-swap updates when the
+// instance was modified since it was read. Callers should reload and retry.
+type VersionConflictError struct {
+	ExecutionID string
+	Expected    uint
+	Actual      uint
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict on instance %s: expected %d, found %d", e.ExecutionID, e.Expected, e.Actual)
+}
+
+// StepTransition describes the state changes caused by a single completion.
+// Version is the instance version the transition was computed from.
+type StepTransition struct {
+	ExecutionID string
+	Version     uint
+	TaskID      string
+	TaskStatus  string
+	TaskError   string
+	Output      []byte
+	Step        uint8
+	Status      string
+	Next        *models.Task
+	Artifacts   []models.Artifact
+	History     []models.HistoryEntry
+	Webhooks    []models.WebhookDelivery
+}
+
 type InstanceRepository struct {
 	db *gorm.DB
 }
@@ -15,32 +57,237 @@ func NewInstanceRepository(db *gorm.DB) *InstanceRepository {
 	return &InstanceRepository{db: db}
 }
 
+// Create inserts the instance together with its EXECUTION_STARTED history
+// entry.
 func (r *InstanceRepository) Create(ctx context.Context, instance *models.WorkflowInstance) error {
-	return r.db.WithContext(ctx).Create(instance).Error
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return createInstance(tx, instance)
+	})
+}
+
+// CreateWithTask inserts the instance with its first task, in one
+// transaction.
+func (r *InstanceRepository) CreateWithTask(ctx context.Context, instance *models.WorkflowInstance, task *models.Task) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := createInstance(tx, instance); err != nil {
+			return err
+		}
+		task.InstanceID = instance.ID
+		return tx.Create(task).Error
+	})
+}
+
+// CreateChild inserts a child execution with its first task and records
+// parentEntry in the history of the parent, in one transaction.
+func (r *InstanceRepository) CreateChild(ctx context.Context, child *models.WorkflowInstance, task *models.Task, parentEntry *models.HistoryEntry) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := createInstance(tx, child); err != nil {
+			return err
+		}
+		task.InstanceID = child.ID
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		return tx.Create(parentEntry).Error
+	})
+}
+
+func createInstance(tx *gorm.DB, instance *models.WorkflowInstance) error {
+	if instance.Version == 0 {
+		instance.Version = 1
+	}
+	if err := tx.Create(instance).Error; err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %s", ErrExecutionExists, instance.ExecutionID)
+		}
+		return err
+	}
+	return tx.Create(&models.HistoryEntry{
+		InstanceID: instance.ID,
+		Event:      models.HistoryExecutionStarted,
+		Timestamp:  instance.CreatedAt,
+	}).Error
 }
 
 func (r *InstanceRepository) GetByExecutionID(ctx context.Context, executionID string) (*models.WorkflowInstance, error) {
 	var instance models.WorkflowInstance
-	err := r.db.WithContext(ctx).Where("execution_id = ?", executionID).First(&instance).Error
+	err := database.Conn(ctx, r.db).Where("execution_id = ?", executionID).First(&instance).Error
 	if err != nil {
 		return nil, err
 	}
 	return &instance, nil
 }
 
-func (r *InstanceRepository) UpdateStep(ctx context.Context, executionID string, step uint8, status string) error {
-	return r.db.WithContext(ctx).
-		Model(&models.WorkflowInstance{}).
-		Where("execution_id = ?", executionID).
-		Updates(map[string]interface{}{
-			"current_step": step,
-			"status":       status,
-		}).Error
+func (r *InstanceRepository) GetByID(ctx context.Context, id uint) (*models.WorkflowInstance, error) {
+	var instance models.WorkflowInstance
+	err := database.Conn(ctx, r.db).First(&instance, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// GetByParentTaskID returns the child execution started by a task.
+func (r *InstanceRepository) GetByParentTaskID(ctx context.Context, taskID string) (*models.WorkflowInstance, error) {
+	var instance models.WorkflowInstance
+	err := database.Conn(ctx, r.db).Where("parent_task_id = ?", taskID).First(&instance).Error
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// ListChildren returns the child executions of an execution in the order
+// they were started.
+func (r *InstanceRepository) ListChildren(ctx context.Context, executionID string) ([]models.WorkflowInstance, error) {
+	var children []models.WorkflowInstance
+	err := database.Conn(ctx, r.db).
+		Where("parent_execution_id = ?", executionID).
+		Order("id").
+		Find(&children).Error
+	return children, err
+}
+
+// ListHistory returns the history of an instance after the entry with ID
+// afterID, in the order it was written.
+func (r *InstanceRepository) ListHistory(ctx context.Context, instanceID uint, afterID uint) ([]models.HistoryEntry, error) {
+	var entries []models.HistoryEntry
+	err := database.Conn(ctx, r.db).
+		Where("instance_id = ? AND id > ?", instanceID, afterID).
+		Order("id").
+		Find(&entries).Error
+	return entries, err
+}
+
+// UpdateStep sets the step and status if the instance is still at version.
+func (r *InstanceRepository) UpdateStep(ctx context.Context, executionID string, version uint, step uint8, status string) error {
+	return casUpdate(database.Conn(ctx, r.db), executionID, version, map[string]interface{}{
+		"current_step": step,
+		"status":       status,
+	})
+}
+
+// UpdateStatus sets the status if the instance is still at version.
+func (r *InstanceRepository) UpdateStatus(ctx context.Context, executionID string, version uint, status string) error {
+	return casUpdate(database.Conn(ctx, r.db), executionID, version, map[string]interface{}{
+		"status": status,
+	})
+}
+
+// ApplyTransition completes the task, advances the instance and records its
+// history, artifacts, webhook deliveries and the next task in one
+// transaction. Cancelling an instance also cancels its open tasks. It returns
+// ErrStaleTransition if the task was already completed or failed, and a
+// *VersionConflictError if the instance changed since t.Version was read,
+// such as by another transition.
+func (r *InstanceRepository) ApplyTransition(ctx context.Context, t StepTransition) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if t.TaskID != "" {
+			res := tx.Model(&models.Task{}).
+				Where("task_id = ? AND status IN ?", t.TaskID, []string{models.TaskStatusPending, models.TaskStatusDispatched}).
+				Updates(map[string]interface{}{
+					"status": t.TaskStatus,
+					"output": t.Output,
+					"error":  t.TaskError,
+				})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrStaleTransition
+			}
+		}
+
+		values := map[string]interface{}{
+			"current_step": t.Step,
+			"status":       t.Status,
+		}
+		if models.IsTerminalStatus(t.Status) {
+			values["finished_at"] = time.Now()
+		}
+		if err := casUpdate(tx, t.ExecutionID, t.Version, values); err != nil {
+			return err
+		}
+
+		if models.IsTerminalStatus(t.Status) {
+			// Nobody needs to decide for a finished execution anymore
+			err := tx.Model(&models.Approval{}).
+				Where("execution_id = ? AND status = ?", t.ExecutionID, models.ApprovalStatusPending).
+				Updates(map[string]interface{}{
+					"status":     models.ApprovalStatusCancelled,
+					"updated_at": time.Now(),
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if t.Status == models.InstanceStatusCancelled {
+			err := tx.Model(&models.Task{}).
+				Where("instance_id = (?) AND status IN ?",
+					tx.Model(&models.WorkflowInstance{}).Select("id").Where("execution_id = ?", t.ExecutionID),
+					[]string{models.TaskStatusPending, models.TaskStatusDispatched}).
+				Updates(map[string]interface{}{
+					"status": models.TaskStatusCancelled,
+					"error":  "execution cancelled",
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(t.History) > 0 {
+			if err := tx.Create(&t.History).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := saveArtifacts(tx, t.Artifacts); err != nil {
+			return err
+		}
+
+		if err := saveWebhookDeliveries(tx, t.Webhooks); err != nil {
+			return err
+		}
+
+		if t.Next != nil {
+			if err := tx.Create(t.Next).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// casUpdate applies values and bumps the version only if the row is still at
+// the expected version.
+func casUpdate(db *gorm.DB, executionID string, version uint, values map[string]interface{}) error {
+	values["version"] = gorm.Expr("version + 1")
+
+	res := db.Model(&models.WorkflowInstance{}).
+		Where("execution_id = ? AND version = ?", executionID, version).
+		Updates(values)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+
+	var current models.WorkflowInstance
+	if err := db.Select("version").Where("execution_id = ?", executionID).First(&current).Error; err != nil {
+		return err
+	}
+	return &VersionConflictError{
+		ExecutionID: executionID,
+		Expected:    version,
+		Actual:      current.Version,
+	}
 }
 
-func (r *InstanceRepository) UpdateStatus(ctx context.Context, executionID string, status string) error {
-	return r.db.WithContext(ctx).
-		Model(&models.WorkflowInstance{}).
-		Where("execution_id = ?", executionID).
-		Update("status", status).Error
+// isUniqueViolation reports whether err is a Postgres unique constraint
+// violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
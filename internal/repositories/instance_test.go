@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+	"gorm.io/gorm"
+)
+
+// seedInstance creates a running instance at step 0 with a dispatched task
+// for each of the given task IDs, one step apart.
+func seedInstance(t *testing.T, db *gorm.DB, executionID string, taskIDs ...string) *models.WorkflowInstance {
+	t.Helper()
+	ctx := context.Background()
+
+	workflow := &models.Workflow{Name: "wf-" + executionID, Status: "active"}
+	if err := NewWorkflowRepository(db).Create(ctx, workflow); err != nil {
+		t.Fatalf("create workflow: %v", err)
+	}
+	instance := &models.WorkflowInstance{
+		WorkflowID:  workflow.ID,
+		ExecutionID: executionID,
+		Status:      models.InstanceStatusRunning,
+	}
+	if err := NewInstanceRepository(db).Create(ctx, instance); err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+	for i, taskID := range taskIDs {
+		task := &models.Task{
+			InstanceID: instance.ID,
+			TaskID:     taskID,
+			Attempt:    1,
+			StepID:     uint8(i + 1),
+			Type:       "noop",
+			Status:     models.TaskStatusDispatched,
+		}
+		if err := db.Create(task).Error; err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+	}
+	return instance
+}
+
+// race applies the transitions at the same time and returns their errors.
+func race(repo *InstanceRepository, transitions ...StepTransition) []error {
+	errs := make([]error, len(transitions))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, tr := range transitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = repo.ApplyTransition(context.Background(), tr)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func TestApplyTransitionConcurrentCompletions(t *testing.T) {
+	db := databasetest.Open(t)
+	repo := NewInstanceRepository(db)
+	instance := seedInstance(t, db, "exec-concurrent", "task-a", "task-b")
+
+	transition := func(taskID string) StepTransition {
+		return StepTransition{
+			ExecutionID: instance.ExecutionID,
+			Version:     instance.Version,
+			TaskID:      taskID,
+			TaskStatus:  models.TaskStatusCompleted,
+			Step:        1,
+			Status:      models.InstanceStatusRunning,
+		}
+	}
+	errs := race(repo, transition("task-a"), transition("task-b"))
+
+	var won, lost int
+	for _, err := range errs {
+		var conflict *VersionConflictError
+		switch {
+		case err == nil:
+			won++
+		case errors.As(err, &conflict):
+			lost++
+			if conflict.Expected != instance.Version || conflict.Actual != instance.Version+1 {
+				t.Errorf("conflict = %+v, want expected %d and actual %d", conflict, instance.Version, instance.Version+1)
+			}
+		default:
+			t.Errorf("ApplyTransition: unexpected error %v", err)
+		}
+	}
+	if won != 1 || lost != 1 {
+		t.Fatalf("got %d winners and %d conflicts, want one of each", won, lost)
+	}
+
+	current, err := repo.GetByExecutionID(context.Background(), instance.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Version != instance.Version+1 {
+		t.Errorf("version = %d, want %d", current.Version, instance.Version+1)
+	}
+
+	var completed int64
+	db.Model(&models.Task{}).Where("status = ?", models.TaskStatusCompleted).Count(&completed)
+	if completed != 1 {
+		t.Errorf("%d tasks completed, want the loser's completion rolled back", completed)
+	}
+}
+
+func TestApplyTransitionDuplicateCompletion(t *testing.T) {
+	db := databasetest.Open(t)
+	repo := NewInstanceRepository(db)
+	instance := seedInstance(t, db, "exec-duplicate", "task-a")
+
+	transition := StepTransition{
+		ExecutionID: instance.ExecutionID,
+		Version:     instance.Version,
+		TaskID:      "task-a",
+		TaskStatus:  models.TaskStatusCompleted,
+		Step:        1,
+		Status:      models.InstanceStatusCompleted,
+	}
+	errs := race(repo, transition, transition)
+
+	var won, stale int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			won++
+		case errors.Is(err, ErrStaleTransition):
+			stale++
+		default:
+			t.Errorf("ApplyTransition: unexpected error %v", err)
+		}
+	}
+	if won != 1 || stale != 1 {
+		t.Fatalf("got %d winners and %d stale transitions, want one of each", won, stale)
+	}
+}
+
+func TestUpdateStepStaleVersion(t *testing.T) {
+	db := databasetest.Open(t)
+	repo := NewInstanceRepository(db)
+	instance := seedInstance(t, db, "exec-stale")
+	ctx := context.Background()
+
+	if err := repo.UpdateStep(ctx, instance.ExecutionID, instance.Version, 1, models.InstanceStatusRunning); err != nil {
+		t.Fatalf("UpdateStep: %v", err)
+	}
+
+	err := repo.UpdateStatus(ctx, instance.ExecutionID, instance.Version, models.InstanceStatusFailed)
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("UpdateStatus with a stale version: err = %v, want a version conflict", err)
+	}
+
+	current, err := repo.GetByExecutionID(ctx, instance.ExecutionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Status != models.InstanceStatusRunning || current.CurrentStep != 1 {
+		t.Errorf("instance = %s at step %d, want the first update to stick", current.Status, current.CurrentStep)
+	}
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"gorm.io/gorm"
+)
+
+type LeaseRepository struct {
+	db *gorm.DB
+}
+
+func NewLeaseRepository(db *gorm.DB) *LeaseRepository {
+	return &LeaseRepository{db: db}
+}
+
+// Acquire takes or renews the named lease for holder for ttl. It reports
+// false while another holder has it. Expiry is judged by the database clock
+// so replicas with skewed clocks agree.
+func (r *LeaseRepository) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	res := database.Conn(ctx, r.db).Exec(`
+		INSERT INTO leases (name, holder, expires_at)
+		VALUES (?, ?, now() + make_interval(secs => ?))
+		ON CONFLICT (name) DO UPDATE
+		SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE leases.holder = EXCLUDED.holder OR leases.expires_at < now()`,
+		name, holder, ttl.Seconds(),
+	)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Release gives up the named lease if holder has it.
+func (r *LeaseRepository) Release(ctx context.Context, name, holder string) error {
+	return database.Conn(ctx, r.db).
+		Where("name = ? AND holder = ?", name, holder).
+		Delete(&models.Lease{}).Error
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+)
+
+func TestLeaseAcquire(t *testing.T) {
+	repo := NewLeaseRepository(databasetest.Open(t))
+	ctx := context.Background()
+
+	acquire := func(holder string, ttl time.Duration) bool {
+		t.Helper()
+		ok, err := repo.Acquire(ctx, "scheduler", holder, ttl)
+		if err != nil {
+			t.Fatalf("Acquire(%s): %v", holder, err)
+		}
+		return ok
+	}
+
+	if !acquire("a", time.Minute) {
+		t.Fatal("a could not take a free lease")
+	}
+	if acquire("b", time.Minute) {
+		t.Fatal("b took a lease a holds")
+	}
+	if !acquire("a", time.Minute) {
+		t.Fatal("a could not renew its lease")
+	}
+
+	if err := repo.Release(ctx, "scheduler", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if acquire("b", time.Minute) {
+		t.Fatal("b took the lease after releasing a lease it did not hold")
+	}
+	if err := repo.Release(ctx, "scheduler", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if !acquire("b", 10*time.Millisecond) {
+		t.Fatal("b could not take a released lease")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if !acquire("a", time.Minute) {
+		t.Fatal("a could not take an expired lease")
+	}
+}
+
+func TestLeaseAcquireRace(t *testing.T) {
+	repo := NewLeaseRepository(databasetest.Open(t))
+
+	const holders = 8
+	won := make([]bool, holders)
+	errs := make([]error, holders)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range holders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			won[i], errs[i] = repo.Acquire(context.Background(), "scheduler", fmt.Sprintf("holder-%d", i), time.Minute)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	winners := 0
+	for i := range holders {
+		if errs[i] != nil {
+			t.Fatalf("Acquire(holder-%d): %v", i, errs[i])
+		}
+		if won[i] {
+			winners++
+		}
+	}
+	if winners != 1 {
+		t.Errorf("%d holders acquired the lease, want 1", winners)
+	}
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ScheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository(db *gorm.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+// Create inserts a schedule unless one with its ID exists.
+func (r *ScheduleRepository) Create(ctx context.Context, schedule *models.Schedule) error {
+	if schedule.Version == 0 {
+		schedule.Version = 1
+	}
+	return database.Conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "schedule_id"}},
+		DoNothing: true,
+	}).Create(schedule).Error
+}
+
+func (r *ScheduleRepository) GetByScheduleID(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	var schedule models.Schedule
+	err := database.Conn(ctx, r.db).Where("schedule_id = ?", scheduleID).First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// List returns every schedule ordered by schedule ID.
+func (r *ScheduleRepository) List(ctx context.Context) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := database.Conn(ctx, r.db).Order("schedule_id").Find(&schedules).Error
+	return schedules, err
+}
+
+// ListDue returns unpaused schedules whose next run is due or that buffer
+// runs, the most overdue first.
+func (r *ScheduleRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := database.Conn(ctx, r.db).
+		Where("NOT paused AND (fire_at <= ? OR buffered > 0)", now).
+		Order("fire_at, id").
+		Limit(limit).
+		Find(&schedules).Error
+	return schedules, err
+}
+
+// Update changes a schedule with a compare-and-swap on its version and bumps
+// the version. It reports false when the schedule changed meanwhile.
+func (r *ScheduleRepository) Update(ctx context.Context, id, version uint, values map[string]interface{}) (bool, error) {
+	values["version"] = version + 1
+	values["updated_at"] = time.Now()
+	res := database.Conn(ctx, r.db).
+		Model(&models.Schedule{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(values)
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteExcept removes the schedules whose ID is not in keep.
+func (r *ScheduleRepository) DeleteExcept(ctx context.Context, keep []string) (int64, error) {
+	query := database.Conn(ctx, r.db)
+	if len(keep) > 0 {
+		query = query.Where("schedule_id NOT IN ?", keep)
+	} else {
+		query = query.Where("1 = 1")
+	}
+	res := query.Delete(&models.Schedule{})
+	return res.RowsAffected, res.Error
+}
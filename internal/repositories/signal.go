@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SignalRepository struct {
+	db *gorm.DB
+}
+
+func NewSignalRepository(db *gorm.DB) *SignalRepository {
+	return &SignalRepository{db: db}
+}
+
+// Create buffers a signal together with its SIGNAL_RECEIVED history entry.
+func (r *SignalRepository) Create(ctx context.Context, signal *models.Signal, entry *models.HistoryEntry) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(signal).Error; err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+// Claim hands the oldest unclaimed signal of the given name to a task. A task
+// claims at most one signal: claiming again returns the same one. Claims of
+// the same task are serialized on its row. It returns gorm.ErrRecordNotFound
+// when no signal is waiting.
+func (r *SignalRepository) Claim(ctx context.Context, instanceID uint, name, taskID string) (*models.Signal, error) {
+	var signal models.Signal
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var task models.Task
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("task_id = ?", taskID).
+			First(&task).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("task_id = ?", taskID).First(&signal).Error
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("instance_id = ? AND name = ? AND task_id = ''", instanceID, name).
+			Order("id").
+			First(&signal).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		signal.TaskID = taskID
+		signal.ConsumedAt = &now
+		return tx.Model(&signal).Updates(map[string]interface{}{
+			"task_id":     taskID,
+			"consumed_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &signal, nil
+}
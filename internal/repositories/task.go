@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"gorm.io/gorm"
+)
+
+type TaskRepository struct {
+	db *gorm.DB
+}
+
+func NewTaskRepository(db *gorm.DB) *TaskRepository {
+	return &TaskRepository{db: db}
+}
+
+func (r *TaskRepository) Create(ctx context.Context, task *models.Task) error {
+	return database.Conn(ctx, r.db).Create(task).Error
+}
+
+func (r *TaskRepository) GetByTaskID(ctx context.Context, taskID string) (*models.Task, error) {
+	var task models.Task
+	err := database.Conn(ctx, r.db).Where("task_id = ?", taskID).First(&task).Error
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListByInstance returns every task of an instance ordered by step and
+// attempt.
+func (r *TaskRepository) ListByInstance(ctx context.Context, instanceID uint) ([]models.Task, error) {
+	var tasks []models.Task
+	err := database.Conn(ctx, r.db).
+		Omit("message_key", "message", "message_headers", "context").
+		Where("instance_id = ?", instanceID).
+		Order("step_id, attempt, id").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// GetPendingForStep returns the task for the given step that was recorded but
+// never handed to the broker, if any.
+func (r *TaskRepository) GetPendingForStep(ctx context.Context, instanceID uint, step uint8) (*models.Task, error) {
+	var task models.Task
+	err := database.Conn(ctx, r.db).
+		Where("instance_id = ? AND step_id = ? AND status = ?", instanceID, step, models.TaskStatusPending).
+		Order("attempt DESC").
+		First(&task).Error
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetLatestForStep returns the latest attempt of a step in any status,
+// leaving out its compensation.
+func (r *TaskRepository) GetLatestForStep(ctx context.Context, instanceID uint, step uint8) (*models.Task, error) {
+	var task models.Task
+	err := database.Conn(ctx, r.db).
+		Omit("message_key", "message", "message_headers").
+		Where("instance_id = ? AND step_id = ? AND compensation = ?", instanceID, step, false).
+		Order("attempt DESC").
+		First(&task).Error
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetCompletedForStep returns the task that completed a step, whose output a
+// compensation of the step works from.
+func (r *TaskRepository) GetCompletedForStep(ctx context.Context, instanceID uint, step uint8) (*models.Task, error) {
+	var task models.Task
+	err := database.Conn(ctx, r.db).
+		Omit("message_key", "message", "message_headers").
+		Where("instance_id = ? AND step_id = ? AND compensation = ? AND status = ?", instanceID, step, false, models.TaskStatusCompleted).
+		Order("attempt DESC").
+		First(&task).Error
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListExpired returns dispatched tasks whose deadline has passed, oldest
+// deadline first.
+func (r *TaskRepository) ListExpired(ctx context.Context, limit int) ([]models.Task, error) {
+	var tasks []models.Task
+	err := database.Conn(ctx, r.db).
+		Omit("message_key", "message", "message_headers", "context").
+		Where("deadline_at <= ? AND status = ?", time.Now(), models.TaskStatusDispatched).
+		Order("deadline_at").
+		Limit(limit).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *TaskRepository) ClearDeadline(ctx context.Context, taskID string) error {
+	return database.Conn(ctx, r.db).
+		Model(&models.Task{}).
+		Where("task_id = ?", taskID).
+		Update("deadline_at", nil).Error
+}
+
+func (r *TaskRepository) MarkDispatched(ctx context.Context, taskID string) error {
+	return database.Conn(ctx, r.db).
+		Model(&models.Task{}).
+		Where("task_id = ? AND status = ?", taskID, models.TaskStatusPending).
+		Update("status", models.TaskStatusDispatched).Error
+}
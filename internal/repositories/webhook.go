@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	return database.Conn(ctx, r.db).Create(sub).Error
+}
+
+func (r *WebhookRepository) GetSubscription(ctx context.Context, workflowID, id uint) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	err := database.Conn(ctx, r.db).
+		Where("workflow_id = ? AND id = ?", workflowID, id).
+		First(&sub).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context, workflowID uint) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	err := database.Conn(ctx, r.db).
+		Where("workflow_id = ?", workflowID).
+		Order("id").
+		Find(&subs).Error
+	return subs, err
+}
+
+// ListActiveSubscriptions returns the subscriptions events of a workflow are
+// delivered to.
+func (r *WebhookRepository) ListActiveSubscriptions(ctx context.Context, workflowID uint) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	err := database.Conn(ctx, r.db).
+		Where("workflow_id = ? AND active", workflowID).
+		Find(&subs).Error
+	return subs, err
+}
+
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	return database.Conn(ctx, r.db).Save(sub).Error
+}
+
+// DeleteSubscription removes a subscription and its delivery log.
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, workflowID, id uint) error {
+	res := database.Conn(ctx, r.db).
+		Where("workflow_id = ? AND id = ?", workflowID, id).
+		Delete(&models.WebhookSubscription{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns the most recent deliveries of a subscription.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := database.Conn(ctx, r.db).
+		Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDue leases up to limit pending deliveries whose next attempt is due,
+// hiding them from other dispatchers for lease, and counts the attempt.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := database.Conn(ctx, r.db).Raw(`
+		UPDATE webhook_deliveries
+		SET next_attempt_at = now() + make_interval(secs => ?), attempts = attempts + 1, updated_at = now()
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		lease.Seconds(), models.WebhookDeliveryPending, limit,
+	).Scan(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetSubscriptionsByID loads subscriptions regardless of their workflow.
+func (r *WebhookRepository) GetSubscriptionsByID(ctx context.Context, ids []uint) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	err := database.Conn(ctx, r.db).Where("id IN ?", ids).Find(&subs).Error
+	return subs, err
+}
+
+// RecordAttempt stores the outcome of a delivery attempt. status is the new
+// delivery status; nextAttempt only matters while it stays pending.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, id uint, status string, responseStatus int, lastError string, nextAttempt time.Time) error {
+	values := map[string]interface{}{
+		"status":          status,
+		"response_status": responseStatus,
+		"last_error":      lastError,
+		"next_attempt_at": nextAttempt,
+	}
+	if status == models.WebhookDeliveryDelivered {
+		values["delivered_at"] = time.Now()
+	}
+	return database.Conn(ctx, r.db).
+		Model(&models.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(values).Error
+}
+
+// Redeliver queues a finished delivery again.
+func (r *WebhookRepository) Redeliver(ctx context.Context, subscriptionID, id uint) error {
+	res := database.Conn(ctx, r.db).
+		Model(&models.WebhookDelivery{}).
+		Where("subscription_id = ? AND id = ?", subscriptionID, id).
+		Updates(map[string]interface{}{
+			"status":          models.WebhookDeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// saveWebhookDeliveries queues deliveries inside a transition.
+func saveWebhookDeliveries(db *gorm.DB, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return db.Create(&deliveries).Error
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/database"
 	"gorm.io/gorm"
 )
 
@@ -16,7 +17,7 @@ func NewWorkflowRepository(db *gorm.DB) *WorkflowRepository {
 }
 
 func (r *WorkflowRepository) Create(ctx context.Context, wf *models.Workflow) error {
-    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+    return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
         if err := tx.Create(wf).Error; err != nil {
             return err
         }
@@ -27,7 +28,26 @@ func (r *WorkflowRepository) Create(ctx context.Context, wf *models.Workflow) er
 // GetByEvent retrieves a workflow by event name
 func (r *WorkflowRepository) GetByEvent(ctx context.Context, event string) (*models.Workflow, error) {
     var wf models.Workflow
-    err := r.db.WithContext(ctx).Where("event = ? AND status = ?", event, "active").First(&wf).Error
+    err := database.Conn(ctx, r.db).Where("event = ? AND status = ?", event, "active").First(&wf).Error
+    if err != nil {
+        return nil, err
+    }
+    return &wf, nil
+}
+
+// GetByName retrieves an active workflow by name
+func (r *WorkflowRepository) GetByName(ctx context.Context, name string) (*models.Workflow, error) {
+    var wf models.Workflow
+    err := database.Conn(ctx, r.db).Where("name = ? AND status = ?", name, "active").First(&wf).Error
+    if err != nil {
+        return nil, err
+    }
+    return &wf, nil
+}
+
+func (r *WorkflowRepository) GetByID(ctx context.Context, id uint) (*models.Workflow, error) {
+    var wf models.Workflow
+    err := database.Conn(ctx, r.db).First(&wf, id).Error
     if err != nil {
         return nil, err
     }
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/Vighnesh-V-H/async/internal/handler"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupApprovalRoutes(router *gin.Engine, h *handler.ApprovalHandler) {
+	approvals := router.Group("/approvals")
+	{
+		approvals.GET("", h.ListApprovals)
+		approvals.GET("/:approval_id", h.GetApproval)
+		approvals.POST("/:approval_id/approve", h.Approve)
+		approvals.POST("/:approval_id/reject", h.Reject)
+	}
+}
@@ -5,10 +5,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupAudioRoutes(router *gin.Engine, h *handler.AudioHandler) {
+func SetupAudioRoutes(router *gin.Engine, h *handler.AudioHandler, executions *handler.ExecutionHandler) {
 	audio := router.Group("/audio")
 	{
 		audio.POST("/generate", h.GenerateAudio)
-		audio.GET("/status/:execution_id", h.GetStatus)
+		audio.GET("/status/:execution_id", executions.GetExecution)
 	}
 }
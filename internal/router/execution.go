@@ -0,0 +1,19 @@
+package router
+
+import (
+	"github.com/Vighnesh-V-H/async/internal/handler"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupExecutionRoutes(router *gin.Engine, h *handler.ExecutionHandler, artifacts *handler.ArtifactHandler) {
+	executions := router.Group("/executions/:execution_id")
+	{
+		executions.GET("", h.GetExecution)
+		executions.GET("/events", h.StreamEvents)
+		executions.POST("/cancel", h.CancelExecution)
+		executions.POST("/signals/:name", h.SignalExecution)
+		executions.GET("/artifacts", artifacts.ListArtifacts)
+		executions.GET("/artifacts/:name", artifacts.DownloadArtifact)
+		executions.GET("/artifacts/:name/url", artifacts.GetArtifactURL)
+	}
+}
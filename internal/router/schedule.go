@@ -0,0 +1,17 @@
+package router
+
+import (
+	"github.com/Vighnesh-V-H/async/internal/handler"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupScheduleRoutes(router *gin.Engine, h *handler.ScheduleHandler) {
+	schedules := router.Group("/schedules")
+	{
+		schedules.GET("", h.ListSchedules)
+		schedules.GET("/:schedule_id", h.GetSchedule)
+		schedules.POST("/:schedule_id/pause", h.PauseSchedule)
+		schedules.POST("/:schedule_id/resume", h.ResumeSchedule)
+		schedules.POST("/:schedule_id/trigger", h.TriggerSchedule)
+	}
+}
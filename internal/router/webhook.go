@@ -0,0 +1,19 @@
+package router
+
+import (
+	"github.com/Vighnesh-V-H/async/internal/handler"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupWebhookRoutes(router *gin.Engine, h *handler.WebhookHandler) {
+	webhooks := router.Group("/workflow/:workflow_id/webhooks")
+	{
+		webhooks.POST("", h.CreateWebhook)
+		webhooks.GET("", h.ListWebhooks)
+		webhooks.GET("/:webhook_id", h.GetWebhook)
+		webhooks.PATCH("/:webhook_id", h.UpdateWebhook)
+		webhooks.DELETE("/:webhook_id", h.DeleteWebhook)
+		webhooks.GET("/:webhook_id/deliveries", h.ListDeliveries)
+		webhooks.POST("/:webhook_id/deliveries/:delivery_id/redeliver", h.Redeliver)
+	}
+}
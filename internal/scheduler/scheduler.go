@@ -0,0 +1,321 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+// runNamespace derives the execution IDs of scheduled runs, so a run fired
+// twice starts a single execution.
+var runNamespace = uuid.MustParse("5b0c2f3e-7a4d-4c61-9a0e-3f8d2b6c1e47")
+
+// Starter starts executions of workflows.
+type Starter interface {
+	Start(ctx context.Context, workflowName, executionID string, input map[string]interface{}) (*models.WorkflowInstance, error)
+}
+
+// Scheduler fires the schedule triggers of the workflow definitions. Every
+// orchestrator replica runs one, but only the one holding the scheduler
+// lease fires schedules; the others stand by to take over. The executions of
+// due runs are started before the schedule is advanced past them with a
+// compare-and-swap. Their execution IDs derive from the schedule and
+// activation, so a run started again, after a failure or while the lease
+// changes hands, starts a single execution.
+type Scheduler struct {
+	svc         *service.ScheduleService
+	instanceSvc *service.InstanceService
+	starter     Starter
+	definitions *dsl.Registry
+	cfg         config.SchedulerConfig
+	holder      string
+	logger      zerolog.Logger
+}
+
+func NewScheduler(
+	svc *service.ScheduleService,
+	instanceSvc *service.InstanceService,
+	starter Starter,
+	definitions *dsl.Registry,
+	cfg config.SchedulerConfig,
+	logCfg logger.Config,
+) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		svc:         svc,
+		instanceSvc: instanceSvc,
+		starter:     starter,
+		definitions: definitions,
+		cfg:         cfg,
+		holder:      fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8]),
+		logger:      logger.New(logCfg),
+	}
+}
+
+// Run fires due schedules while holding the scheduler lease, until ctx is
+// done. The lease is released on the way out.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.svc.ReleaseLease(releaseCtx, s.holder); err != nil {
+			s.logger.Warn().Err(err).Msg("Failed to release scheduler lease")
+		}
+	}()
+
+	leader := false
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		timer.Reset(s.cfg.PollInterval)
+
+		acquired, err := s.svc.AcquireLease(ctx, s.holder, s.cfg.LeaseTTL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.logger.Error().Err(err).Msg("Failed to renew scheduler lease")
+			acquired = false
+		}
+		if !acquired {
+			if leader {
+				s.logger.Warn().Str("holder", s.holder).Msg("Lost scheduler lease")
+			}
+			leader = false
+			continue
+		}
+
+		if !leader {
+			// Definitions may have changed while another replica led
+			if err := s.svc.Sync(ctx, s.definitions.All(), time.Now().UTC()); err != nil {
+				s.logger.Error().Err(err).Msg("Failed to sync schedules")
+				continue
+			}
+			s.logger.Info().Str("holder", s.holder).Msg("Acquired scheduler lease")
+			leader = true
+		}
+
+		s.fireDue(ctx)
+	}
+}
+
+func (s *Scheduler) fireDue(ctx context.Context) {
+	now := time.Now().UTC()
+	schedules, err := s.svc.ListDueSchedules(ctx, now, s.cfg.BatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error().Err(err).Msg("Failed to list due schedules")
+		}
+		return
+	}
+	for i := range schedules {
+		if err := s.fire(ctx, &schedules[i], now); err != nil {
+			s.logger.Error().Err(err).Str("schedule_id", schedules[i].ScheduleID).Msg("Failed to fire schedule")
+		}
+	}
+}
+
+// run is an execution a schedule is about to start. Buffered runs have no
+// activation of their own.
+type run struct {
+	at       time.Time
+	buffered bool
+}
+
+// fire starts the runs of a schedule that are due, following its catch-up
+// and overlap policies.
+func (s *Scheduler) fire(ctx context.Context, schedule *models.Schedule, now time.Time) error {
+	running, err := s.running(ctx, schedule)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]interface{}{}
+	var runs []run
+	buffered := schedule.Buffered
+	if buffered > 0 && !running {
+		runs = append(runs, run{at: now, buffered: true})
+		buffered--
+		running = true
+	}
+
+	if !schedule.FireAt.After(now) {
+		due, next, err := service.DueRuns(schedule, now, s.cfg.MaxCatchUp)
+		if err != nil {
+			return err
+		}
+		due = s.catchUp(schedule, due, now)
+
+		skipped := 0
+		for _, at := range due {
+			switch {
+			case !running || schedule.Overlap == dsl.OverlapAllow:
+				runs = append(runs, run{at: at})
+				running = true
+			case schedule.Overlap == dsl.OverlapBuffer && buffered < s.cfg.MaxBuffered:
+				buffered++
+			default:
+				skipped++
+			}
+		}
+		if skipped > 0 {
+			s.logger.Warn().
+				Str("schedule_id", schedule.ScheduleID).
+				Str("last_execution_id", schedule.LastExecutionID).
+				Int("skipped", skipped).
+				Msg("Skipping runs while the previous one is still running")
+		}
+
+		if next.IsZero() {
+			s.logger.Warn().Str("schedule_id", schedule.ScheduleID).Msg("Schedule never fires again, pausing it")
+			values["paused"] = true
+		} else {
+			values["next_run_at"] = next
+			values["fire_at"] = service.FireTime(schedule, next)
+		}
+	}
+
+	if buffered == schedule.Buffered && len(runs) == 0 && len(values) == 0 {
+		return nil
+	}
+	values["buffered"] = buffered
+	if len(runs) > 0 {
+		values["last_run_at"] = now
+	}
+
+	// Start the runs before claiming them, so a run that fails to start is
+	// fired again on the next poll; runs that did start then start nothing
+	// new
+	for _, r := range runs {
+		executionID := runExecutionID(schedule, r)
+		if err := s.start(ctx, schedule, executionID, r.at, false); err != nil {
+			return err
+		}
+		values["last_execution_id"] = executionID
+	}
+
+	// Losing the claim means another replica or an API call changed the
+	// schedule, which is reconsidered on the next poll
+	_, err = s.svc.Advance(ctx, schedule, values)
+	return err
+}
+
+// runExecutionID derives the execution ID of a run. A buffered run is the one left at
+// the schedule's next activation with its buffered count, which changes once
+// it is claimed.
+func runExecutionID(schedule *models.Schedule, r run) string {
+	name := schedule.ScheduleID + "@" + r.at.Format(time.RFC3339)
+	if r.buffered {
+		name = fmt.Sprintf("%s@%s#%d", schedule.ScheduleID, schedule.NextRunAt.UTC().Format(time.RFC3339), schedule.Buffered)
+	}
+	return uuid.NewSHA1(runNamespace, []byte(name)).String()
+}
+
+// catchUp applies the catch-up policy of a schedule to its due runs: those
+// due for longer than the misfire threshold plus jitter were missed.
+func (s *Scheduler) catchUp(schedule *models.Schedule, due []time.Time, now time.Time) []time.Time {
+	missed := 0
+	for missed < len(due) && now.Sub(due[missed]) > s.cfg.MisfireThreshold+schedule.Jitter {
+		missed++
+	}
+	if missed == 0 {
+		return due
+	}
+
+	kept := due[missed:]
+	switch schedule.CatchUp {
+	case dsl.CatchUpAll:
+		kept = due
+	case dsl.CatchUpLatest:
+		if len(kept) == 0 {
+			kept = due[missed-1:]
+		}
+	}
+	if dropped := len(due) - len(kept); dropped > 0 {
+		s.logger.Warn().
+			Str("schedule_id", schedule.ScheduleID).
+			Str("catch_up", schedule.CatchUp).
+			Int("dropped", dropped).
+			Msg("Dropping missed runs")
+	}
+	return kept
+}
+
+// running reports whether the last execution the schedule started has not
+// finished yet.
+func (s *Scheduler) running(ctx context.Context, schedule *models.Schedule) (bool, error) {
+	if schedule.LastExecutionID == "" {
+		return false, nil
+	}
+	instance, err := s.instanceSvc.GetInstanceByExecutionID(ctx, schedule.LastExecutionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return !instance.IsTerminal(), nil
+}
+
+// Trigger starts a run of a schedule right away, whether it is paused or
+// its previous run is still running.
+func (s *Scheduler) Trigger(ctx context.Context, scheduleID string) (*models.WorkflowInstance, error) {
+	schedule, err := s.svc.GetSchedule(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	executionID := uuid.New().String()
+	if err := s.start(ctx, schedule, executionID, time.Now().UTC(), true); err != nil {
+		return nil, err
+	}
+	if _, err := s.svc.RecordRun(ctx, schedule.ScheduleID, executionID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return s.instanceSvc.GetInstanceByExecutionID(ctx, executionID)
+}
+
+// start starts the execution of a run. Its trigger payload is the payload of the schedule with the run
+// described under schedule.
+func (s *Scheduler) start(ctx context.Context, schedule *models.Schedule, executionID string, at time.Time, manual bool) error {
+	input := map[string]interface{}{}
+	if len(schedule.Payload) > 0 {
+		if err := json.Unmarshal(schedule.Payload, &input); err != nil {
+			return err
+		}
+	}
+	input["schedule"] = map[string]interface{}{
+		"id":           schedule.ScheduleID,
+		"scheduled_at": at.Format(time.RFC3339),
+		"manual":       manual,
+	}
+
+	if _, err := s.starter.Start(ctx, schedule.WorkflowName, executionID, input); err != nil {
+		return err
+	}
+
+	s.logger.Info().
+		Str("schedule_id", schedule.ScheduleID).
+		Str("execution_id", executionID).
+		Time("scheduled_at", at).
+		Bool("manual", manual).
+		Msg("Started scheduled run")
+	return nil
+}
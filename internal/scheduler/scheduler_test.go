@@ -0,0 +1,308 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+var testConfig = config.SchedulerConfig{
+	PollInterval:     time.Second,
+	LeaseTTL:         5 * time.Second,
+	BatchSize:        10,
+	MisfireThreshold: time.Minute,
+	MaxCatchUp:       10,
+	MaxBuffered:      2,
+}
+
+func utc(text string) time.Time {
+	at, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		panic(err)
+	}
+	return at.UTC()
+}
+
+func TestCatchUp(t *testing.T) {
+	due := []time.Time{utc("2026-10-19T10:00:00Z"), utc("2026-10-19T10:10:00Z"), utc("2026-10-19T10:20:00Z")}
+	tests := []struct {
+		name    string
+		catchUp string
+		now     string
+		want    int // runs kept, the latest ones
+	}{
+		{"on time", dsl.CatchUpNone, "2026-10-19T10:00:30Z", 3},
+		{"none drops missed runs", dsl.CatchUpNone, "2026-10-19T10:20:30Z", 1},
+		{"latest keeps the runs on time", dsl.CatchUpLatest, "2026-10-19T10:20:30Z", 1},
+		{"all keeps missed runs", dsl.CatchUpAll, "2026-10-19T10:20:30Z", 3},
+		{"none drops all missed runs", dsl.CatchUpNone, "2026-10-19T11:00:00Z", 0},
+		{"latest makes up for the last missed run", dsl.CatchUpLatest, "2026-10-19T11:00:00Z", 1},
+		{"all makes up for every missed run", dsl.CatchUpAll, "2026-10-19T11:00:00Z", 3},
+	}
+
+	s := &Scheduler{cfg: testConfig, logger: zerolog.Nop()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.catchUp(&models.Schedule{CatchUp: tt.catchUp}, due, utc(tt.now))
+			want := due[len(due)-tt.want:]
+			if len(got) != len(want) {
+				t.Fatalf("catchUp = %v, want %v", got, want)
+			}
+			for i := range want {
+				if !got[i].Equal(want[i]) {
+					t.Errorf("catchUp = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCatchUpJitterExtendsThreshold(t *testing.T) {
+	s := &Scheduler{cfg: testConfig, logger: zerolog.Nop()}
+	due := []time.Time{utc("2026-10-19T10:00:00Z")}
+	schedule := &models.Schedule{CatchUp: dsl.CatchUpNone, Jitter: 5 * time.Minute}
+	if got := s.catchUp(schedule, due, utc("2026-10-19T10:04:00Z")); len(got) != 1 {
+		t.Errorf("catchUp = %v, want the run within its jitter kept", got)
+	}
+}
+
+// fakeStarter records the executions it starts as running instances. Like
+// the orchestrator, starting an execution again returns the existing one.
+type fakeStarter struct {
+	db       *gorm.DB
+	workflow uint
+	started  []string
+	err      error
+}
+
+func (f *fakeStarter) Start(ctx context.Context, workflowName, executionID string, input map[string]interface{}) (*models.WorkflowInstance, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	existing := &models.WorkflowInstance{}
+	if err := f.db.WithContext(ctx).Where("execution_id = ?", executionID).First(existing).Error; err == nil {
+		return existing, nil
+	}
+	instance := &models.WorkflowInstance{WorkflowID: f.workflow, ExecutionID: executionID, Status: models.InstanceStatusRunning}
+	if err := f.db.WithContext(ctx).Create(instance).Error; err != nil {
+		return nil, err
+	}
+	f.started = append(f.started, executionID)
+	return instance, nil
+}
+
+const testScheduleID = "digest:nightly"
+
+// newTestScheduler stores a schedule firing every ten minutes from 10:00
+// with the given overlap policy, making up for every missed run.
+func newTestScheduler(t *testing.T, overlap string) (*Scheduler, *fakeStarter) {
+	t.Helper()
+	db := databasetest.Open(t)
+
+	workflow := &models.Workflow{Name: "digest", Status: "active"}
+	if err := db.Create(workflow).Error; err != nil {
+		t.Fatal(err)
+	}
+	schedule := &models.Schedule{
+		ScheduleID:   testScheduleID,
+		WorkflowName: "digest",
+		Cron:         "*/10 * * * *",
+		Overlap:      overlap,
+		CatchUp:      dsl.CatchUpAll,
+		NextRunAt:    utc("2026-10-19T10:00:00Z"),
+		FireAt:       utc("2026-10-19T10:00:00Z"),
+	}
+	if err := repositories.NewScheduleRepository(db).Create(context.Background(), schedule); err != nil {
+		t.Fatal(err)
+	}
+
+	starter := &fakeStarter{db: db, workflow: workflow.ID}
+	s := NewScheduler(
+		service.NewScheduleService(repositories.NewScheduleRepository(db), repositories.NewLeaseRepository(db)),
+		service.NewInstanceService(repositories.NewInstanceRepository(db)),
+		starter,
+		dsl.NewRegistry(),
+		testConfig,
+		logger.Config{Level: "error"},
+	)
+	return s, starter
+}
+
+// fireAt fires the stored schedule as the scheduler would at now.
+func fireAt(t *testing.T, s *Scheduler, now string) *models.Schedule {
+	t.Helper()
+	ctx := context.Background()
+	schedule, err := s.svc.GetSchedule(ctx, testScheduleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.fire(ctx, schedule, utc(now)); err != nil {
+		t.Fatalf("fire: %v", err)
+	}
+	if schedule, err = s.svc.GetSchedule(ctx, testScheduleID); err != nil {
+		t.Fatal(err)
+	}
+	return schedule
+}
+
+func finish(t *testing.T, s *Scheduler, executionID string) {
+	t.Helper()
+	instance, err := s.instanceSvc.GetInstanceByExecutionID(context.Background(), executionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.instanceSvc.UpdateInstanceStatus(context.Background(), executionID, instance.Version, models.InstanceStatusCompleted); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runID(at string) string {
+	return uuid.NewSHA1(runNamespace, []byte(testScheduleID+"@"+at)).String()
+}
+
+func TestFireSkipsOverlappingRuns(t *testing.T) {
+	s, starter := newTestScheduler(t, dsl.OverlapSkip)
+
+	schedule := fireAt(t, s, "2026-10-19T10:25:00Z")
+	if len(starter.started) != 1 || starter.started[0] != runID("2026-10-19T10:00:00Z") {
+		t.Fatalf("started %v, want only the 10:00 run", starter.started)
+	}
+	if !schedule.NextRunAt.Equal(utc("2026-10-19T10:30:00Z")) || schedule.LastExecutionID != starter.started[0] {
+		t.Errorf("schedule next runs at %s after %s, want 10:30 after the started run", schedule.NextRunAt, schedule.LastExecutionID)
+	}
+
+	fireAt(t, s, "2026-10-19T10:30:00Z")
+	if len(starter.started) != 1 {
+		t.Fatalf("started %v while the previous run was running, want it skipped", starter.started)
+	}
+
+	finish(t, s, starter.started[0])
+	fireAt(t, s, "2026-10-19T10:40:00Z")
+	if len(starter.started) != 2 || starter.started[1] != runID("2026-10-19T10:40:00Z") {
+		t.Errorf("started %v, want the 10:40 run once the previous one finished", starter.started)
+	}
+}
+
+func TestFireBuffersOverlappingRuns(t *testing.T) {
+	s, starter := newTestScheduler(t, dsl.OverlapBuffer)
+
+	schedule := fireAt(t, s, "2026-10-19T10:35:00Z")
+	if len(starter.started) != 1 {
+		t.Fatalf("started %v, want one run", starter.started)
+	}
+	if schedule.Buffered != testConfig.MaxBuffered {
+		t.Errorf("buffered %d runs, want at most %d", schedule.Buffered, testConfig.MaxBuffered)
+	}
+
+	fireAt(t, s, "2026-10-19T10:36:00Z")
+	if len(starter.started) != 1 {
+		t.Fatalf("started %v while the previous run was running, want it buffered", starter.started)
+	}
+
+	finish(t, s, starter.started[0])
+	schedule = fireAt(t, s, "2026-10-19T10:37:00Z")
+	if len(starter.started) != 2 || schedule.Buffered != testConfig.MaxBuffered-1 {
+		t.Errorf("started %v with %d buffered, want one buffered run started", starter.started, schedule.Buffered)
+	}
+}
+
+func TestFireAllowsOverlappingRuns(t *testing.T) {
+	s, starter := newTestScheduler(t, dsl.OverlapAllow)
+
+	fireAt(t, s, "2026-10-19T10:25:00Z")
+	want := []string{runID("2026-10-19T10:00:00Z"), runID("2026-10-19T10:10:00Z"), runID("2026-10-19T10:20:00Z")}
+	if len(starter.started) != len(want) {
+		t.Fatalf("started %v, want %v", starter.started, want)
+	}
+	for i := range want {
+		if starter.started[i] != want[i] {
+			t.Errorf("started %v, want %v", starter.started, want)
+		}
+	}
+}
+
+func TestFireClaimsRunsOnce(t *testing.T) {
+	s, starter := newTestScheduler(t, dsl.OverlapAllow)
+	ctx := context.Background()
+
+	// Two replicas read the same schedule before either fires it
+	first, err := s.svc.GetSchedule(ctx, testScheduleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := *first
+
+	if err := s.fire(ctx, first, utc("2026-10-19T10:05:00Z")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.fire(ctx, &second, utc("2026-10-19T10:05:00Z")); err != nil {
+		t.Fatal(err)
+	}
+	if len(starter.started) != 1 {
+		t.Errorf("started %v, want the run started once", starter.started)
+	}
+}
+
+func TestFireRetriesRunsThatFailedToStart(t *testing.T) {
+	s, starter := newTestScheduler(t, dsl.OverlapAllow)
+	ctx := context.Background()
+
+	starter.err = errors.New("broker unavailable")
+	schedule, err := s.svc.GetSchedule(ctx, testScheduleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.fire(ctx, schedule, utc("2026-10-19T10:05:00Z")); err == nil {
+		t.Fatal("fire succeeded while the run failed to start")
+	}
+	if schedule, err = s.svc.GetSchedule(ctx, testScheduleID); err != nil {
+		t.Fatal(err)
+	}
+	if !schedule.NextRunAt.Equal(utc("2026-10-19T10:00:00Z")) || schedule.LastExecutionID != "" {
+		t.Fatalf("schedule next runs at %s after %q, want it left at 10:00", schedule.NextRunAt, schedule.LastExecutionID)
+	}
+
+	starter.err = nil
+	schedule = fireAt(t, s, "2026-10-19T10:06:00Z")
+	if len(starter.started) != 1 || starter.started[0] != runID("2026-10-19T10:00:00Z") {
+		t.Fatalf("started %v, want the 10:00 run on the next poll", starter.started)
+	}
+	if !schedule.NextRunAt.Equal(utc("2026-10-19T10:10:00Z")) || schedule.LastExecutionID != starter.started[0] {
+		t.Errorf("schedule next runs at %s after %s, want 10:10 after the started run", schedule.NextRunAt, schedule.LastExecutionID)
+	}
+}
+
+func TestFireRestartsBufferedRunsWithTheSameID(t *testing.T) {
+	s, starter := newTestScheduler(t, dsl.OverlapBuffer)
+	ctx := context.Background()
+
+	fireAt(t, s, "2026-10-19T10:15:00Z")
+	finish(t, s, starter.started[0])
+
+	// A buffered run starts but the schedule is not advanced past it
+	schedule, err := s.svc.GetSchedule(ctx, testScheduleID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffered := run{at: utc("2026-10-19T10:16:00Z"), buffered: true}
+	if err := s.start(ctx, schedule, runExecutionID(schedule, buffered), buffered.at, false); err != nil {
+		t.Fatal(err)
+	}
+
+	schedule = fireAt(t, s, "2026-10-19T10:17:00Z")
+	if len(starter.started) != 2 || schedule.LastExecutionID != starter.started[1] {
+		t.Errorf("started %v after %s, want the buffered run started once", starter.started, schedule.LastExecutionID)
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrApprovalClosed is returned when deciding on an approval that was
+	// already decided, expired or cancelled.
+	ErrApprovalClosed = errors.New("approval is no longer pending")
+	// ErrNotAssignee is returned when someone outside the assignees of an
+	// approval decides on it.
+	ErrNotAssignee = errors.New("not an assignee of the approval")
+)
+
+// Decisions carried by the signal that completes an approval task.
+const (
+	DecisionApproved = "approved"
+	DecisionRejected = "rejected"
+	// DecisionExpired is never signalled; it is the decision recorded on
+	// approval tasks whose deadline passed.
+	DecisionExpired = "expired"
+)
+
+// ApprovalSignal is the name of the signal the decision on the approval of a
+// task is delivered with.
+func ApprovalSignal(taskID string) string {
+	return "approval:" + taskID
+}
+
+type ApprovalService struct {
+	repo       *repositories.ApprovalRepository
+	webhookSvc *WebhookService
+}
+
+// NewApprovalService creates the service. webhookSvc may be nil, in which
+// case approval events are only recorded in the history.
+func NewApprovalService(repo *repositories.ApprovalRepository, webhookSvc *WebhookService) *ApprovalService {
+	return &ApprovalService{repo: repo, webhookSvc: webhookSvc}
+}
+
+// Request records the pending approval of an approval task: its assignees
+// from the state, its input as context and the task deadline. Requesting
+// again for the same task does nothing and returns no history.
+func (s *ApprovalService) Request(ctx context.Context, instance *models.WorkflowInstance, task *models.Task, spec TaskSpec) ([]models.HistoryEntry, error) {
+	assignees := stringsOf(spec.Params["assignees"])
+	approval := &models.Approval{
+		ApprovalID:  uuid.New().String(),
+		InstanceID:  instance.ID,
+		ExecutionID: instance.ExecutionID,
+		TaskID:      task.TaskID,
+		Status:      models.ApprovalStatusPending,
+		DeadlineAt:  task.DeadlineAt,
+	}
+	approval.State, _ = spec.Params["state"].(string)
+
+	var err error
+	if approval.Assignees, err = json.Marshal(assignees); err != nil {
+		return nil, err
+	}
+	if approval.Context, err = json.Marshal(spec.Input); err != nil {
+		return nil, err
+	}
+
+	history, webhooks, err := s.record(ctx, instance, models.HistoryApprovalRequested, map[string]interface{}{
+		"approval_id": approval.ApprovalID,
+		"step":        task.StepID,
+		"task_id":     task.TaskID,
+		"state":       approval.State,
+		"assignees":   assignees,
+		"deadline_at": approval.DeadlineAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.Create(ctx, approval, history, webhooks)
+	if err != nil || !created {
+		return nil, err
+	}
+	return history, nil
+}
+
+// ApprovalView is an approval as returned by the API.
+type ApprovalView struct {
+	ApprovalID  string                 `json:"approval_id"`
+	ExecutionID string                 `json:"execution_id"`
+	TaskID      string                 `json:"task_id"`
+	State       string                 `json:"state"`
+	Status      string                 `json:"status"`
+	Assignees   []string               `json:"assignees"`
+	Context     map[string]interface{} `json:"context"`
+	DeadlineAt  *time.Time             `json:"deadline_at"`
+	EscalatedAt *time.Time             `json:"escalated_at"`
+	DecidedBy   string                 `json:"decided_by,omitempty"`
+	Comment     string                 `json:"comment,omitempty"`
+	DecidedAt   *time.Time             `json:"decided_at"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+func ViewOfApproval(approval *models.Approval) ApprovalView {
+	view := ApprovalView{
+		ApprovalID:  approval.ApprovalID,
+		ExecutionID: approval.ExecutionID,
+		TaskID:      approval.TaskID,
+		State:       approval.State,
+		Status:      approval.Status,
+		Assignees:   []string{},
+		Context:     decodeMap(approval.Context),
+		DeadlineAt:  approval.DeadlineAt,
+		EscalatedAt: approval.EscalatedAt,
+		DecidedBy:   approval.DecidedBy,
+		Comment:     approval.Comment,
+		DecidedAt:   approval.DecidedAt,
+		CreatedAt:   approval.CreatedAt,
+	}
+	if len(approval.Assignees) > 0 {
+		_ = json.Unmarshal(approval.Assignees, &view.Assignees)
+	}
+	return view
+}
+
+func (s *ApprovalService) GetApproval(ctx context.Context, approvalID string) (*models.Approval, error) {
+	return s.repo.GetByApprovalID(ctx, approvalID)
+}
+
+func (s *ApprovalService) GetApprovalByTaskID(ctx context.Context, taskID string) (*models.Approval, error) {
+	return s.repo.GetByTaskID(ctx, taskID)
+}
+
+func (s *ApprovalService) ListApprovals(ctx context.Context, filter repositories.ApprovalFilter) ([]models.Approval, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Decide approves or rejects a pending approval on behalf of one of its
+// assignees, and buffers the signal that completes its task.
+func (s *ApprovalService) Decide(ctx context.Context, instance *models.WorkflowInstance, approval *models.Approval, approved bool, by, comment string) ([]models.HistoryEntry, error) {
+	if approval.Status != models.ApprovalStatusPending {
+		return nil, ErrApprovalClosed
+	}
+	var assignees []string
+	if len(approval.Assignees) > 0 {
+		if err := json.Unmarshal(approval.Assignees, &assignees); err != nil {
+			return nil, err
+		}
+	}
+	if len(assignees) > 0 && !slices.Contains(assignees, by) {
+		return nil, ErrNotAssignee
+	}
+
+	now := time.Now()
+	decision, event := DecisionApproved, models.HistoryApprovalApproved
+	approval.Status = models.ApprovalStatusApproved
+	if !approved {
+		decision, event = DecisionRejected, models.HistoryApprovalRejected
+		approval.Status = models.ApprovalStatusRejected
+	}
+	approval.DecidedBy = by
+	approval.Comment = comment
+	approval.DecidedAt = &now
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"approval_id": approval.ApprovalID,
+		"decision":    decision,
+		"decided_by":  by,
+		"comment":     comment,
+		"decided_at":  now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	signal := &models.Signal{
+		InstanceID: instance.ID,
+		Name:       ApprovalSignal(approval.TaskID),
+		Payload:    payload,
+	}
+
+	history, webhooks, err := s.record(ctx, instance, event, map[string]interface{}{
+		"approval_id": approval.ApprovalID,
+		"task_id":     approval.TaskID,
+		"decided_by":  by,
+		"comment":     comment,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	decided, err := s.repo.Decide(ctx, approval, signal, history, webhooks)
+	if err != nil {
+		return nil, err
+	}
+	if !decided {
+		return nil, ErrApprovalClosed
+	}
+	return history, nil
+}
+
+// Escalate adds escalateTo to the assignees of a pending approval and gives
+// them until timeout from now. It returns no history when the approval was
+// no longer pending.
+func (s *ApprovalService) Escalate(ctx context.Context, instance *models.WorkflowInstance, approval *models.Approval, escalateTo []string, timeout time.Duration) ([]models.HistoryEntry, error) {
+	var assignees []string
+	if len(approval.Assignees) > 0 {
+		if err := json.Unmarshal(approval.Assignees, &assignees); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range escalateTo {
+		if !slices.Contains(assignees, a) {
+			assignees = append(assignees, a)
+		}
+	}
+
+	now := time.Now()
+	deadline := now.Add(timeout)
+	var err error
+	if approval.Assignees, err = json.Marshal(assignees); err != nil {
+		return nil, err
+	}
+	approval.DeadlineAt = &deadline
+	approval.EscalatedAt = &now
+
+	history, webhooks, err := s.record(ctx, instance, models.HistoryApprovalEscalated, map[string]interface{}{
+		"approval_id": approval.ApprovalID,
+		"task_id":     approval.TaskID,
+		"state":       approval.State,
+		"escalate_to": escalateTo,
+		"assignees":   assignees,
+		"deadline_at": deadline,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	escalated, err := s.repo.Escalate(ctx, approval, history, webhooks)
+	if err != nil || !escalated {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Expire closes a pending approval nobody decided on in time. It returns no
+// history when the approval was no longer pending.
+func (s *ApprovalService) Expire(ctx context.Context, instance *models.WorkflowInstance, approval *models.Approval) ([]models.HistoryEntry, error) {
+	history, webhooks, err := s.record(ctx, instance, models.HistoryApprovalExpired, map[string]interface{}{
+		"approval_id": approval.ApprovalID,
+		"task_id":     approval.TaskID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expired, err := s.repo.Expire(ctx, approval, history, webhooks)
+	if err != nil || !expired {
+		return nil, err
+	}
+	approval.Status = models.ApprovalStatusExpired
+	return history, nil
+}
+
+// record builds a history entry and the webhook deliveries it triggers.
+func (s *ApprovalService) record(ctx context.Context, instance *models.WorkflowInstance, event string, data map[string]interface{}) ([]models.HistoryEntry, []models.WebhookDelivery, error) {
+	entry, err := NewHistoryEntry(instance.ID, event, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	history := []models.HistoryEntry{entry}
+	if s.webhookSvc == nil {
+		return history, nil, nil
+	}
+	webhooks, err := s.webhookSvc.Deliveries(ctx, instance, history, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return history, webhooks, nil
+}
+
+// stringsOf reads a list of strings that went through JSON.
+func stringsOf(v interface{}) []string {
+	list, _ := v.([]interface{})
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+)
+
+var (
+	// ErrInvalidSignature is returned for download URLs that were not signed
+	// with the service key or were tampered with.
+	ErrInvalidSignature = errors.New("invalid artifact signature")
+	// ErrURLExpired is returned for download URLs past their expiry.
+	ErrURLExpired = errors.New("artifact URL expired")
+)
+
+type ArtifactService struct {
+	repo       *repositories.ArtifactRepository
+	store      blob.Store
+	signingKey []byte
+}
+
+// NewArtifactService creates the service. An empty signingKey is replaced by
+// a random one, so signed URLs are only valid on this process.
+func NewArtifactService(repo *repositories.ArtifactRepository, store blob.Store, signingKey string) (*ArtifactService, error) {
+	key := []byte(signingKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &ArtifactService{repo: repo, store: store, signingKey: key}, nil
+}
+
+func (s *ArtifactService) ListArtifacts(ctx context.Context, instanceID uint) ([]models.Artifact, error) {
+	return s.repo.ListByInstance(ctx, instanceID)
+}
+
+func (s *ArtifactService) GetArtifact(ctx context.Context, instanceID uint, name string) (*models.Artifact, error) {
+	return s.repo.Get(ctx, instanceID, name)
+}
+
+// ReadArtifact loads the payload of an artifact and verifies its checksum.
+func (s *ArtifactService) ReadArtifact(ctx context.Context, artifact *models.Artifact) ([]byte, error) {
+	data, err := s.store.Get(ctx, artifact.BlobKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != artifact.SHA256 {
+		return nil, fmt.Errorf("artifact %s does not match its checksum", artifact.Name)
+	}
+	return data, nil
+}
+
+// SignURL returns the query string of a download URL for the named artifact
+// that is valid until expires. The signature covers the name as escaped in
+// the URL path, so a name cannot be split into another one.
+func (s *ArtifactService) SignURL(executionID, name string, expires time.Time) url.Values {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return url.Values{
+		"expires":   {exp},
+		"signature": {s.sign(executionID, name, exp)},
+	}
+}
+
+// VerifyURL checks the expires and signature parameters of a download URL.
+func (s *ArtifactService) VerifyURL(executionID, name, expires, signature string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	want, _ := hex.DecodeString(s.sign(executionID, name, expires))
+	if !hmac.Equal(given, want) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > exp {
+		return ErrURLExpired
+	}
+	return nil
+}
+
+func (s *ArtifactService) sign(executionID, name, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(executionID + "\n" + url.PathEscape(name) + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
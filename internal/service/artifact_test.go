@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestArtifactURLSignature(t *testing.T) {
+	svc, err := NewArtifactService(nil, nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(time.Hour)
+	query := svc.SignURL("exec-1", "reports/q1 final?.pdf#2", expires)
+	exp, sig := query.Get("expires"), query.Get("signature")
+
+	if err := svc.VerifyURL("exec-1", "reports/q1 final?.pdf#2", exp, sig); err != nil {
+		t.Errorf("VerifyURL = %v, want the signed name accepted", err)
+	}
+	for _, name := range []string{"reports", "reports/q1 final", "reports%2Fq1 final?.pdf#2"} {
+		if err := svc.VerifyURL("exec-1", name, exp, sig); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("VerifyURL(%q) = %v, want ErrInvalidSignature", name, err)
+		}
+	}
+	if err := svc.VerifyURL("exec-2", "reports/q1 final?.pdf#2", exp, sig); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyURL for another execution = %v, want ErrInvalidSignature", err)
+	}
+
+	past := svc.SignURL("exec-1", "audio.wav", time.Now().Add(-time.Minute))
+	if err := svc.VerifyURL("exec-1", "audio.wav", past.Get("expires"), past.Get("signature")); !errors.Is(err, ErrURLExpired) {
+		t.Errorf("VerifyURL of an expired URL = %v, want ErrURLExpired", err)
+	}
+}
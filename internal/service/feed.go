@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/pkg/cache"
+)
+
+// ExecutionFeed broadcasts the history of executions as it is written, so any
+// API replica can stream transitions applied by any orchestrator replica.
+type ExecutionFeed struct {
+	pubsub *cache.PubSub
+}
+
+func NewExecutionFeed(pubsub *cache.PubSub) *ExecutionFeed {
+	return &ExecutionFeed{pubsub: pubsub}
+}
+
+// Publish broadcasts persisted history entries of an execution.
+func (f *ExecutionFeed) Publish(ctx context.Context, executionID string, entries []models.HistoryEntry) error {
+	for _, entry := range entries {
+		payload, err := json.Marshal(newHistoryEvent(entry))
+		if err != nil {
+			return err
+		}
+		if err := f.pubsub.Publish(ctx, executionID, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe streams the history events of an execution published from now
+// on, until ctx is done or the returned close function is called.
+func (f *ExecutionFeed) Subscribe(ctx context.Context, executionID string) (<-chan HistoryEvent, func() error, error) {
+	messages, closeSub, err := f.pubsub.Subscribe(ctx, executionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make(chan HistoryEvent)
+	go func() {
+		defer close(out)
+		for payload := range messages {
+			var event HistoryEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				continue
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, closeSub, nil
+}
+
+// IsTerminalEvent reports whether no further events follow event.
+func IsTerminalEvent(event string) bool {
+	return event == models.HistoryExecutionCompleted ||
+		event == models.HistoryExecutionFailed ||
+		event == models.HistoryExecutionCancelled ||
+		event == models.HistoryExecutionCompensated ||
+		event == models.HistoryExecutionCompensationFailed
+}
@@ -3,11 +3,17 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
 
 	"github.com/Vighnesh-V-H/async/internal/models"
 	"github.com/Vighnesh-V-H/async/internal/repositories"
 )
 
+// ErrExecutionFinished is returned when an execution that already completed
+// or failed is asked to change.
+var ErrExecutionFinished = errors.New("execution already finished")
+
 type InstanceService struct {
 	repo *repositories.InstanceRepository
 }
@@ -25,14 +31,72 @@ func (s *InstanceService) CreateInstance(ctx context.Context, instance *models.W
 	return s.repo.Create(ctx, instance)
 }
 
+// StartInstance records an execution with the given trigger variables and
+// its first task.
+func (s *InstanceService) StartInstance(ctx context.Context, instance *models.WorkflowInstance, variables map[string]interface{}, task *models.Task) error {
+	varsJSON, err := json.Marshal(variables)
+	if err != nil {
+		return err
+	}
+	instance.Variables = varsJSON
+	return s.repo.CreateWithTask(ctx, instance, task)
+}
+
+// StartChild records a child execution with the given trigger variables and
+// its first task, and notes the start in the history of the parent.
+func (s *InstanceService) StartChild(ctx context.Context, child *models.WorkflowInstance, variables map[string]interface{}, task *models.Task, parentEntry *models.HistoryEntry) error {
+	varsJSON, err := json.Marshal(variables)
+	if err != nil {
+		return err
+	}
+	child.Variables = varsJSON
+	return s.repo.CreateChild(ctx, child, task, parentEntry)
+}
+
+// GetChildByTaskID returns the child execution started by a subworkflow task.
+func (s *InstanceService) GetChildByTaskID(ctx context.Context, taskID string) (*models.WorkflowInstance, error) {
+	return s.repo.GetByParentTaskID(ctx, taskID)
+}
+
+// ListChildren returns the child executions of an execution.
+func (s *InstanceService) ListChildren(ctx context.Context, executionID string) ([]models.WorkflowInstance, error) {
+	return s.repo.ListChildren(ctx, executionID)
+}
+
 func (s *InstanceService) GetInstanceByExecutionID(ctx context.Context, executionID string) (*models.WorkflowInstance, error) {
 	return s.repo.GetByExecutionID(ctx, executionID)
 }
 
-func (s *InstanceService) UpdateInstanceStep(ctx context.Context, executionID string, step uint8, status string) error {
-	return s.repo.UpdateStep(ctx, executionID, step, status)
+func (s *InstanceService) GetInstanceByID(ctx context.Context, id uint) (*models.WorkflowInstance, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *InstanceService) UpdateInstanceStep(ctx context.Context, executionID string, version uint, step uint8, status string) error {
+	return s.repo.UpdateStep(ctx, executionID, version, step, status)
 }
 
-func (s *InstanceService) UpdateInstanceStatus(ctx context.Context, executionID string, status string) error {
-	return s.repo.UpdateStatus(ctx, executionID, status)
+func (s *InstanceService) UpdateInstanceStatus(ctx context.Context, executionID string, version uint, status string) error {
+	return s.repo.UpdateStatus(ctx, executionID, version, status)
+}
+
+func (s *InstanceService) ApplyTransition(ctx context.Context, t repositories.StepTransition) error {
+	return s.repo.ApplyTransition(ctx, t)
+}
+
+// NewHistoryEntry builds a history entry for the instance. It is not
+// persisted.
+func NewHistoryEntry(instanceID uint, event string, data map[string]interface{}) (models.HistoryEntry, error) {
+	entry := models.HistoryEntry{
+		InstanceID: instanceID,
+		Event:      event,
+		Timestamp:  time.Now(),
+	}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return entry, err
+		}
+		entry.Data = raw
+	}
+	return entry, nil
 }
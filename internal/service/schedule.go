@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/pkg/cron"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+)
+
+// ScheduleLease is the lease held by the orchestrator replica that fires
+// schedules.
+const ScheduleLease = "scheduler"
+
+// maxScheduleRetries bounds how often a change to a schedule is retried
+// after losing a race on its version.
+const maxScheduleRetries = 5
+
+// ErrScheduleConflict is returned when a schedule kept changing while it
+// was being updated.
+var ErrScheduleConflict = errors.New("schedule changed concurrently")
+
+// ScheduleID identifies the schedule trigger named event of a workflow.
+func ScheduleID(workflow, event string) string {
+	return workflow + ":" + event
+}
+
+type ScheduleService struct {
+	repo   *repositories.ScheduleRepository
+	leases *repositories.LeaseRepository
+}
+
+func NewScheduleService(repo *repositories.ScheduleRepository, leases *repositories.LeaseRepository) *ScheduleService {
+	return &ScheduleService{repo: repo, leases: leases}
+}
+
+// Sync brings the stored schedules in line with the schedule triggers of
+// definitions. New schedules first run at their next activation after now.
+// Changing the cron expression or time zone of a schedule moves its next
+// run; other changes keep it, as do pauses. Schedules whose trigger is gone
+// are deleted.
+func (s *ScheduleService) Sync(ctx context.Context, definitions []*dsl.Workflow, now time.Time) error {
+	existing, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	stored := make(map[string]*models.Schedule, len(existing))
+	for i := range existing {
+		stored[existing[i].ScheduleID] = &existing[i]
+	}
+
+	var keep []string
+	for _, wf := range definitions {
+		for _, trigger := range wf.Schedules() {
+			want, err := scheduleOf(wf.Name, trigger)
+			if err != nil {
+				return err
+			}
+			keep = append(keep, want.ScheduleID)
+
+			current, ok := stored[want.ScheduleID]
+			if !ok {
+				if want.NextRunAt, want.FireAt, err = NextRun(want, now); err != nil {
+					return err
+				}
+				if err := s.repo.Create(ctx, want); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := s.resync(ctx, current, want, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = s.repo.DeleteExcept(ctx, keep)
+	return err
+}
+
+// resync updates a stored schedule whose trigger changed.
+func (s *ScheduleService) resync(ctx context.Context, current, want *models.Schedule, now time.Time) error {
+	values := map[string]interface{}{}
+	if current.WorkflowName != want.WorkflowName {
+		values["workflow_name"] = want.WorkflowName
+	}
+	if current.Jitter != want.Jitter {
+		values["jitter"] = want.Jitter
+	}
+	if current.Overlap != want.Overlap {
+		values["overlap"] = want.Overlap
+	}
+	if current.CatchUp != want.CatchUp {
+		values["catch_up"] = want.CatchUp
+	}
+	if string(current.Payload) != string(want.Payload) {
+		values["payload"] = want.Payload
+	}
+	if current.Cron != want.Cron || current.Timezone != want.Timezone {
+		next, fireAt, err := NextRun(want, now)
+		if err != nil {
+			return err
+		}
+		values["cron"] = want.Cron
+		values["timezone"] = want.Timezone
+		values["next_run_at"] = next
+		values["fire_at"] = fireAt
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	// Losing the race means the scheduler fired it meanwhile; the next sync
+	// catches up
+	_, err := s.repo.Update(ctx, current.ID, current.Version, values)
+	return err
+}
+
+func scheduleOf(workflow string, trigger *dsl.Trigger) (*models.Schedule, error) {
+	schedule := &models.Schedule{
+		ScheduleID:   ScheduleID(workflow, trigger.Event),
+		WorkflowName: workflow,
+		Cron:         trigger.Spec(),
+		Timezone:     trigger.Timezone,
+		Jitter:       trigger.Jitter,
+		Overlap:      trigger.OverlapPolicy(),
+		CatchUp:      trigger.CatchUpPolicy(),
+	}
+	if trigger.Payload != nil {
+		var err error
+		if schedule.Payload, err = json.Marshal(trigger.Payload); err != nil {
+			return nil, err
+		}
+	}
+	return schedule, nil
+}
+
+// NextRun returns the first activation of a schedule after t and when that
+// run fires: the activation delayed by a random part of the jitter. Times
+// are in UTC, as stored.
+func NextRun(schedule *models.Schedule, after time.Time) (time.Time, time.Time, error) {
+	sched, loc, err := specOf(schedule)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	next := sched.Next(after.In(loc))
+	if next.IsZero() {
+		return next, next, nil
+	}
+	next = next.UTC()
+	return next, FireTime(schedule, next), nil
+}
+
+// FireTime returns when the run of a schedule activated at the given time
+// starts: the activation delayed by a random part of the jitter.
+func FireTime(schedule *models.Schedule, at time.Time) time.Time {
+	if schedule.Jitter <= 0 {
+		return at
+	}
+	return at.Add(time.Duration(rand.Int63n(int64(schedule.Jitter))))
+}
+
+// DueRuns returns the activations of a schedule due at now, oldest first
+// and at most the latest max of them, and the activation after them, in
+// UTC.
+func DueRuns(schedule *models.Schedule, now time.Time, max int) ([]time.Time, time.Time, error) {
+	sched, loc, err := specOf(schedule)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var runs []time.Time
+	at := schedule.NextRunAt.In(loc)
+	for !at.IsZero() && !at.After(now) {
+		runs = append(runs, at)
+		if len(runs) > max {
+			runs = runs[1:]
+		}
+		at = sched.Next(at)
+	}
+	for i := range runs {
+		runs[i] = runs[i].UTC()
+	}
+	return runs, at.UTC(), nil
+}
+
+func specOf(schedule *models.Schedule) (cron.Schedule, *time.Location, error) {
+	sched, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc := time.UTC
+	if schedule.Timezone != "" {
+		if loc, err = time.LoadLocation(schedule.Timezone); err != nil {
+			return nil, nil, err
+		}
+	}
+	return sched, loc, nil
+}
+
+func (s *ScheduleService) ListSchedules(ctx context.Context) ([]models.Schedule, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *ScheduleService) GetSchedule(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	return s.repo.GetByScheduleID(ctx, scheduleID)
+}
+
+// ListDueSchedules returns up to limit schedules with a run due at now or
+// buffered.
+func (s *ScheduleService) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.Schedule, error) {
+	return s.repo.ListDue(ctx, now, limit)
+}
+
+// Advance applies values to a schedule unless it changed since it was read,
+// and reports whether it did.
+func (s *ScheduleService) Advance(ctx context.Context, schedule *models.Schedule, values map[string]interface{}) (bool, error) {
+	return s.repo.Update(ctx, schedule.ID, schedule.Version, values)
+}
+
+// Pause stops a schedule from firing until it is resumed. Buffered runs are
+// kept.
+func (s *ScheduleService) Pause(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	return s.update(ctx, scheduleID, func(schedule *models.Schedule) (map[string]interface{}, error) {
+		if schedule.Paused {
+			return nil, nil
+		}
+		return map[string]interface{}{"paused": true}, nil
+	})
+}
+
+// Resume lets a paused schedule fire again from its next activation after
+// now. Runs that fell due while it was paused are not made up for.
+func (s *ScheduleService) Resume(ctx context.Context, scheduleID string) (*models.Schedule, error) {
+	return s.update(ctx, scheduleID, func(schedule *models.Schedule) (map[string]interface{}, error) {
+		if !schedule.Paused {
+			return nil, nil
+		}
+		next, fireAt, err := NextRun(schedule, time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"paused":      false,
+			"next_run_at": next,
+			"fire_at":     fireAt,
+		}, nil
+	})
+}
+
+// RecordRun notes an execution started by a schedule outside its own
+// activations.
+func (s *ScheduleService) RecordRun(ctx context.Context, scheduleID, executionID string, at time.Time) (*models.Schedule, error) {
+	return s.update(ctx, scheduleID, func(schedule *models.Schedule) (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"last_run_at":       at,
+			"last_execution_id": executionID,
+		}, nil
+	})
+}
+
+// update applies the values change returns for the current schedule,
+// rereading it when it changed concurrently. No values leave it as is.
+func (s *ScheduleService) update(ctx context.Context, scheduleID string, change func(*models.Schedule) (map[string]interface{}, error)) (*models.Schedule, error) {
+	for attempt := 0; attempt < maxScheduleRetries; attempt++ {
+		schedule, err := s.repo.GetByScheduleID(ctx, scheduleID)
+		if err != nil {
+			return nil, err
+		}
+		values, err := change(schedule)
+		if err != nil || len(values) == 0 {
+			return schedule, err
+		}
+		updated, err := s.repo.Update(ctx, schedule.ID, schedule.Version, values)
+		if err != nil {
+			return nil, err
+		}
+		if updated {
+			return s.repo.GetByScheduleID(ctx, scheduleID)
+		}
+	}
+	return nil, ErrScheduleConflict
+}
+
+// AcquireLease takes or renews the scheduler lease for holder and reports
+// whether holder has it.
+func (s *ScheduleService) AcquireLease(ctx context.Context, holder string, ttl time.Duration) (bool, error) {
+	return s.leases.Acquire(ctx, ScheduleLease, holder, ttl)
+}
+
+// ReleaseLease hands the scheduler lease back so another replica can take
+// over without waiting for it to expire.
+func (s *ScheduleService) ReleaseLease(ctx context.Context, holder string) error {
+	return s.leases.Release(ctx, ScheduleLease, holder)
+}
+
+// ScheduleView is a schedule as returned by the API.
+type ScheduleView struct {
+	ScheduleID      string                 `json:"schedule_id"`
+	Workflow        string                 `json:"workflow"`
+	Cron            string                 `json:"cron"`
+	Timezone        string                 `json:"timezone"`
+	Jitter          string                 `json:"jitter"`
+	Overlap         string                 `json:"overlap"`
+	CatchUp         string                 `json:"catch_up"`
+	Payload         map[string]interface{} `json:"payload"`
+	Paused          bool                   `json:"paused"`
+	NextRunAt       *time.Time             `json:"next_run_at"`
+	Buffered        int                    `json:"buffered"`
+	LastRunAt       *time.Time             `json:"last_run_at"`
+	LastExecutionID string                 `json:"last_execution_id,omitempty"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+}
+
+func ViewOfSchedule(schedule *models.Schedule) ScheduleView {
+	view := ScheduleView{
+		ScheduleID:      schedule.ScheduleID,
+		Workflow:        schedule.WorkflowName,
+		Cron:            schedule.Cron,
+		Timezone:        schedule.Timezone,
+		Jitter:          schedule.Jitter.String(),
+		Overlap:         schedule.Overlap,
+		CatchUp:         schedule.CatchUp,
+		Payload:         decodeMap(schedule.Payload),
+		Paused:          schedule.Paused,
+		Buffered:        schedule.Buffered,
+		LastRunAt:       schedule.LastRunAt,
+		LastExecutionID: schedule.LastExecutionID,
+		UpdatedAt:       schedule.UpdatedAt,
+	}
+	if view.Timezone == "" {
+		view.Timezone = "UTC"
+	}
+	if !schedule.Paused && !schedule.NextRunAt.IsZero() {
+		next := schedule.NextRunAt
+		view.NextRunAt = &next
+	}
+	return view
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+)
+
+func utc(text string) time.Time {
+	at, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		panic(err)
+	}
+	return at.UTC()
+}
+
+func TestDueRuns(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule models.Schedule
+		now      string
+		max      int
+		want     []string
+		next     string
+	}{
+		{
+			name:     "not due",
+			schedule: models.Schedule{Cron: "*/10 * * * *", NextRunAt: utc("2026-10-19T10:40:00Z")},
+			now:      "2026-10-19T10:35:00Z",
+			max:      5,
+			next:     "2026-10-19T10:40:00Z",
+		},
+		{
+			name:     "due now",
+			schedule: models.Schedule{Cron: "*/10 * * * *", NextRunAt: utc("2026-10-19T10:40:00Z")},
+			now:      "2026-10-19T10:40:00Z",
+			max:      5,
+			want:     []string{"2026-10-19T10:40:00Z"},
+			next:     "2026-10-19T10:50:00Z",
+		},
+		{
+			name:     "missed runs",
+			schedule: models.Schedule{Cron: "*/10 * * * *", NextRunAt: utc("2026-10-19T10:00:00Z")},
+			now:      "2026-10-19T10:35:00Z",
+			max:      5,
+			want:     []string{"2026-10-19T10:00:00Z", "2026-10-19T10:10:00Z", "2026-10-19T10:20:00Z", "2026-10-19T10:30:00Z"},
+			next:     "2026-10-19T10:40:00Z",
+		},
+		{
+			name:     "keeps the latest",
+			schedule: models.Schedule{Cron: "*/10 * * * *", NextRunAt: utc("2026-10-19T10:00:00Z")},
+			now:      "2026-10-19T10:35:00Z",
+			max:      2,
+			want:     []string{"2026-10-19T10:20:00Z", "2026-10-19T10:30:00Z"},
+			next:     "2026-10-19T10:40:00Z",
+		},
+		{
+			name:     "interval",
+			schedule: models.Schedule{Cron: "@every 15m", NextRunAt: utc("2026-10-19T10:00:00Z")},
+			now:      "2026-10-19T10:20:00Z",
+			max:      5,
+			want:     []string{"2026-10-19T10:00:00Z", "2026-10-19T10:15:00Z"},
+			next:     "2026-10-19T10:30:00Z",
+		},
+		{
+			name:     "time zone across clocks going back",
+			schedule: models.Schedule{Cron: "30 1 * * *", Timezone: "America/New_York", NextRunAt: utc("2026-11-01T05:30:00Z")},
+			now:      "2026-11-02T12:00:00Z",
+			max:      5,
+			want:     []string{"2026-11-01T05:30:00Z", "2026-11-02T06:30:00Z"},
+			next:     "2026-11-03T06:30:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs, next, err := DueRuns(&tt.schedule, utc(tt.now), tt.max)
+			if err != nil {
+				t.Fatalf("DueRuns: %v", err)
+			}
+			if len(runs) != len(tt.want) {
+				t.Fatalf("DueRuns = %v, want %v", runs, tt.want)
+			}
+			for i, at := range runs {
+				if !at.Equal(utc(tt.want[i])) || at.Location() != time.UTC {
+					t.Errorf("run %d = %s, want %s", i, at, tt.want[i])
+				}
+			}
+			if !next.Equal(utc(tt.next)) || next.Location() != time.UTC {
+				t.Errorf("next = %s, want %s", next, tt.next)
+			}
+		})
+	}
+}
+
+func TestDueRunsInvalidSchedule(t *testing.T) {
+	for _, schedule := range []models.Schedule{
+		{Cron: "not cron", NextRunAt: utc("2026-10-19T10:00:00Z")},
+		{Cron: "@daily", Timezone: "Mars/Olympus_Mons", NextRunAt: utc("2026-10-19T10:00:00Z")},
+	} {
+		if _, _, err := DueRuns(&schedule, utc("2026-10-19T11:00:00Z"), 1); err == nil {
+			t.Errorf("DueRuns(%q in %q) succeeded, want an error", schedule.Cron, schedule.Timezone)
+		}
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	schedule := &models.Schedule{Cron: "0 3 * * *", Timezone: "Europe/Berlin"}
+	next, fireAt, err := NextRun(schedule, utc("2026-10-19T02:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := utc("2026-10-20T01:00:00Z"); !next.Equal(want) || !fireAt.Equal(want) {
+		t.Errorf("NextRun = %s, %s; want %s without jitter", next, fireAt, want)
+	}
+
+	never := &models.Schedule{Cron: "0 0 30 2 *"}
+	if next, fireAt, err := NextRun(never, utc("2026-10-19T02:00:00Z")); err != nil || !next.IsZero() || !fireAt.IsZero() {
+		t.Errorf("NextRun of an impossible date = %s, %s, %v; want zero times", next, fireAt, err)
+	}
+}
+
+func TestFireTime(t *testing.T) {
+	at := utc("2026-10-19T10:00:00Z")
+	if got := FireTime(&models.Schedule{}, at); !got.Equal(at) {
+		t.Errorf("FireTime without jitter = %s, want %s", got, at)
+	}
+
+	schedule := &models.Schedule{Jitter: 5 * time.Minute}
+	for range 100 {
+		got := FireTime(schedule, at)
+		if got.Before(at) || !got.Before(at.Add(schedule.Jitter)) {
+			t.Fatalf("FireTime = %s, want within %s of %s", got, schedule.Jitter, at)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+)
+
+// ErrReservedSignal is returned for signal names used internally, such as
+// those delivering approval decisions.
+var ErrReservedSignal = errors.New("signal name is reserved")
+
+// ErrPayloadRefs is returned for payloads from outside that hold claim
+// checks, which would let tasks load blobs of other executions.
+var ErrPayloadRefs = errors.New("payload must not contain $blob references")
+
+type SignalService struct {
+	repo *repositories.SignalRepository
+}
+
+func NewSignalService(repo *repositories.SignalRepository) *SignalService {
+	return &SignalService{repo: repo}
+}
+
+// Buffer stores a signal for an execution and records SIGNAL_RECEIVED. The
+// signal waits until a wait_for_signal task of the same name claims it.
+func (s *SignalService) Buffer(ctx context.Context, instance *models.WorkflowInstance, name string, payload map[string]interface{}) (*models.Signal, []models.HistoryEntry, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	signal := &models.Signal{
+		InstanceID: instance.ID,
+		Name:       name,
+		Payload:    raw,
+	}
+	entry, err := NewHistoryEntry(instance.ID, models.HistorySignalReceived, map[string]interface{}{
+		"name": name,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.repo.Create(ctx, signal, &entry); err != nil {
+		return nil, nil, err
+	}
+	return signal, []models.HistoryEntry{entry}, nil
+}
+
+// Claim hands the oldest waiting signal of the given name to a task, or the
+// one it claimed before. It returns gorm.ErrRecordNotFound when none waits.
+func (s *SignalService) Claim(ctx context.Context, instanceID uint, name, taskID string) (*models.Signal, error) {
+	return s.repo.Claim(ctx, instanceID, name, taskID)
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+)
+
+// StatusOptions selects the optional parts of an ExecutionStatus.
+type StatusOptions struct {
+	Variables bool
+	History   bool
+}
+
+// ParseStatusOptions reads the comma separated values of ?include=, such as
+// "variables,history".
+func ParseStatusOptions(include []string) (StatusOptions, error) {
+	var opts StatusOptions
+	for _, value := range include {
+		for _, part := range strings.Split(value, ",") {
+			switch strings.TrimSpace(part) {
+			case "":
+			case "variables":
+				opts.Variables = true
+			case "history":
+				opts.History = true
+			default:
+				return StatusOptions{}, fmt.Errorf("unknown include %s", part)
+			}
+		}
+	}
+	return opts, nil
+}
+
+// ExecutionStatus is the externally visible state of a workflow execution.
+type ExecutionStatus struct {
+	ExecutionID string                 `json:"execution_id"`
+	WorkflowID  uint                   `json:"workflow_id"`
+	Status      string                 `json:"status"`
+	CurrentStep uint8                  `json:"current_step"`
+	Output      map[string]interface{} `json:"output"`
+	Steps       []StepStatus           `json:"steps"`
+	// Tasks that undid completed steps after a failure, by step
+	Compensations []StepStatus           `json:"compensations,omitempty"`
+	LastError     *StepError             `json:"last_error"`
+	StartedAt     time.Time              `json:"started_at"`
+	FinishedAt    *time.Time             `json:"finished_at"`
+	DurationMs    int64                  `json:"duration_ms"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	History       []HistoryEvent         `json:"history,omitempty"`
+	// Set for executions started by a subworkflow state
+	ParentExecutionID string `json:"parent_execution_id,omitempty"`
+	// Executions started by subworkflow states of this one, with their own
+	// children
+	Children []ChildExecution `json:"children,omitempty"`
+}
+
+// ChildExecution is a node of the tree of executions below an execution.
+// TaskID is the subworkflow task of the parent that started it.
+type ChildExecution struct {
+	ExecutionID string           `json:"execution_id"`
+	WorkflowID  uint             `json:"workflow_id"`
+	TaskID      string           `json:"task_id"`
+	Status      string           `json:"status"`
+	CurrentStep uint8            `json:"current_step"`
+	StartedAt   time.Time        `json:"started_at"`
+	FinishedAt  *time.Time       `json:"finished_at"`
+	Children    []ChildExecution `json:"children,omitempty"`
+}
+
+// maxTreeDepth bounds how many levels of child executions a status shows.
+const maxTreeDepth = 8
+
+// StepStatus is the latest attempt of a step.
+type StepStatus struct {
+	Step       uint8                  `json:"step"`
+	TaskID     string                 `json:"task_id"`
+	TaskType   string                 `json:"task_type"`
+	Status     string                 `json:"status"`
+	Attempt    uint8                  `json:"attempt"`
+	Attempts   int                    `json:"attempts"`
+	Output     map[string]interface{} `json:"output"`
+	Error      string                 `json:"error,omitempty"`
+	StartedAt  time.Time              `json:"started_at"`
+	FinishedAt *time.Time             `json:"finished_at"`
+}
+
+// StepError is the most recent task failure of an execution.
+type StepError struct {
+	Message  string    `json:"message"`
+	Step     uint8     `json:"step"`
+	Attempt  uint8     `json:"attempt"`
+	TaskID   string    `json:"task_id"`
+	TaskType string    `json:"task_type"`
+	At       time.Time `json:"at"`
+}
+
+// HistoryEvent is a single transition of an execution. ID increases with
+// every event of an execution.
+type HistoryEvent struct {
+	ID        uint                   `json:"id"`
+	Event     string                 `json:"event"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+type StatusService struct {
+	instances *repositories.InstanceRepository
+	tasks     *repositories.TaskRepository
+}
+
+func NewStatusService(instances *repositories.InstanceRepository, tasks *repositories.TaskRepository) *StatusService {
+	return &StatusService{instances: instances, tasks: tasks}
+}
+
+// GetExecutionStatus assembles the status of an execution from its instance,
+// tasks and, if requested, history.
+func (s *StatusService) GetExecutionStatus(ctx context.Context, executionID string, opts StatusOptions) (*ExecutionStatus, error) {
+	instance, err := s.instances.GetByExecutionID(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.tasks.ListByInstance(ctx, instance.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ExecutionStatus{
+		ExecutionID: instance.ExecutionID,
+		WorkflowID:  instance.WorkflowID,
+		Status:      instance.Status,
+		CurrentStep: instance.CurrentStep,
+		Steps:       make([]StepStatus, 0, len(tasks)),
+		StartedAt:   instance.CreatedAt,
+		FinishedAt:  instance.FinishedAt,
+		CreatedAt:   instance.CreatedAt,
+		UpdatedAt:   instance.UpdatedAt,
+
+		ParentExecutionID: instance.ParentExecutionID,
+	}
+
+	if status.FinishedAt == nil && instance.IsTerminal() {
+		// Finished before finish times were recorded
+		status.FinishedAt = &instance.UpdatedAt
+	}
+	end := time.Now()
+	if status.FinishedAt != nil {
+		end = *status.FinishedAt
+	}
+	status.DurationMs = end.Sub(status.StartedAt).Milliseconds()
+
+	for i := range tasks {
+		task := &tasks[i]
+
+		if task.Status == models.TaskStatusFailed && (status.LastError == nil || !task.UpdatedAt.Before(status.LastError.At)) {
+			status.LastError = &StepError{
+				Message:  task.Error,
+				Step:     task.StepID,
+				Attempt:  task.Attempt,
+				TaskID:   task.TaskID,
+				TaskType: task.Type,
+				At:       task.UpdatedAt,
+			}
+		}
+
+		// Tasks are ordered by step and attempt, so the last one wins
+		step := StepStatus{
+			Step:      task.StepID,
+			TaskID:    task.TaskID,
+			TaskType:  task.Type,
+			Status:    task.Status,
+			Attempt:   task.Attempt,
+			Attempts:  1,
+			Output:    decodeMap(task.Output),
+			Error:     task.Error,
+			StartedAt: task.CreatedAt,
+		}
+		if task.Status == models.TaskStatusCompleted || task.Status == models.TaskStatusFailed || task.Status == models.TaskStatusCancelled {
+			finished := task.UpdatedAt
+			step.FinishedAt = &finished
+		}
+
+		if task.Compensation {
+			status.Compensations = append(status.Compensations, step)
+			continue
+		}
+		if n := len(status.Steps); n > 0 && status.Steps[n-1].Step == step.Step {
+			step.Attempts = status.Steps[n-1].Attempts + 1
+			status.Steps[n-1] = step
+		} else {
+			status.Steps = append(status.Steps, step)
+		}
+	}
+
+	if instance.Status == models.InstanceStatusCompleted && len(status.Steps) > 0 {
+		status.Output = status.Steps[len(status.Steps)-1].Output
+	}
+
+	status.Children, err = s.children(ctx, instance.ExecutionID, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Variables {
+		status.Variables = decodeMap(instance.Variables)
+		if status.Variables == nil {
+			status.Variables = map[string]interface{}{}
+		}
+	}
+
+	if opts.History {
+		entries, err := s.instances.ListHistory(ctx, instance.ID, 0)
+		if err != nil {
+			return nil, err
+		}
+		status.History = make([]HistoryEvent, 0, len(entries))
+		for _, entry := range entries {
+			status.History = append(status.History, newHistoryEvent(entry))
+		}
+	}
+
+	return status, nil
+}
+
+// children returns the tree of executions started by an execution.
+func (s *StatusService) children(ctx context.Context, executionID string, depth int) ([]ChildExecution, error) {
+	if depth > maxTreeDepth {
+		return nil, nil
+	}
+	instances, err := s.instances.ListChildren(ctx, executionID)
+	if err != nil || len(instances) == 0 {
+		return nil, err
+	}
+
+	children := make([]ChildExecution, 0, len(instances))
+	for _, instance := range instances {
+		child := ChildExecution{
+			ExecutionID: instance.ExecutionID,
+			WorkflowID:  instance.WorkflowID,
+			TaskID:      instance.ParentTaskID,
+			Status:      instance.Status,
+			CurrentStep: instance.CurrentStep,
+			StartedAt:   instance.CreatedAt,
+			FinishedAt:  instance.FinishedAt,
+		}
+		if child.Children, err = s.children(ctx, instance.ExecutionID, depth+1); err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+// HistorySince returns the history events of an execution after the event
+// with the given ID.
+func (s *StatusService) HistorySince(ctx context.Context, executionID string, afterID uint) ([]HistoryEvent, error) {
+	instance, err := s.instances.GetByExecutionID(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.instances.ListHistory(ctx, instance.ID, afterID)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]HistoryEvent, 0, len(entries))
+	for _, entry := range entries {
+		history = append(history, newHistoryEvent(entry))
+	}
+	return history, nil
+}
+
+func newHistoryEvent(entry models.HistoryEntry) HistoryEvent {
+	return HistoryEvent{
+		ID:        entry.ID,
+		Event:     entry.Event,
+		Timestamp: entry.Timestamp,
+		Data:      decodeMap(entry.Data),
+	}
+}
+
+// decodeMap decodes a JSON object column, returning nil for empty or
+// malformed values.
+func decodeMap(raw []byte) map[string]interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+	"gorm.io/gorm"
+)
+
+func TestParseStatusOptions(t *testing.T) {
+	tests := []struct {
+		include []string
+		want    StatusOptions
+	}{
+		{nil, StatusOptions{}},
+		{[]string{"variables"}, StatusOptions{Variables: true}},
+		{[]string{"variables,history"}, StatusOptions{Variables: true, History: true}},
+		{[]string{"history", " variables "}, StatusOptions{Variables: true, History: true}},
+		{[]string{"history,"}, StatusOptions{History: true}},
+	}
+	for _, tt := range tests {
+		got, err := ParseStatusOptions(tt.include)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseStatusOptions(%q) = %+v, %v; want %+v", tt.include, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseStatusOptions([]string{"variables,secrets"}); err == nil {
+		t.Error("ParseStatusOptions accepted an unknown include")
+	}
+}
+
+// seedStatus records a completed execution whose first step succeeded on its
+// second attempt.
+func seedStatus(t *testing.T, db *gorm.DB, started time.Time) *models.WorkflowInstance {
+	t.Helper()
+	workflow := &models.Workflow{Name: "narrate", Status: "active"}
+	if err := db.Create(workflow).Error; err != nil {
+		t.Fatal(err)
+	}
+	finished := started.Add(9 * time.Second)
+	instance := &models.WorkflowInstance{
+		WorkflowID:  workflow.ID,
+		ExecutionID: "exec-1",
+		Status:      models.InstanceStatusCompleted,
+		Variables:   []byte(`{"file_url": "s3://uploads/talk.mp3"}`),
+		CurrentStep: 2,
+		FinishedAt:  &finished,
+		CreatedAt:   started,
+		UpdatedAt:   finished,
+	}
+	if err := db.Create(instance).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(seconds int) time.Time { return started.Add(time.Duration(seconds) * time.Second) }
+	tasks := []models.Task{
+		{TaskID: "task-1a", StepID: 1, Attempt: 1, Type: "transcribe", Status: models.TaskStatusFailed, Error: "model overloaded", CreatedAt: at(0), UpdatedAt: at(2)},
+		{TaskID: "task-1b", StepID: 1, Attempt: 2, Type: "transcribe", Status: models.TaskStatusCompleted, Output: []byte(`{"text": "hello"}`), CreatedAt: at(3), UpdatedAt: at(6)},
+		{TaskID: "task-2", StepID: 2, Attempt: 1, Type: "speak", Status: models.TaskStatusCompleted, Output: []byte(`{"audio": "s3://out/hello.wav"}`), CreatedAt: at(6), UpdatedAt: at(9)},
+	}
+	for i := range tasks {
+		tasks[i].InstanceID = instance.ID
+		if err := db.Create(&tasks[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, event := range []string{models.HistoryTaskScheduled, models.HistoryStepCompleted, models.HistoryExecutionCompleted} {
+		entry := &models.HistoryEntry{InstanceID: instance.ID, Event: event, Timestamp: finished}
+		if err := db.Create(entry).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return instance
+}
+
+func newTestStatusService(db *gorm.DB) *StatusService {
+	return NewStatusService(repositories.NewInstanceRepository(db), repositories.NewTaskRepository(db))
+}
+
+func TestExecutionStatus(t *testing.T) {
+	db := databasetest.Open(t)
+	started := utc("2026-10-19T10:00:00Z")
+	seedStatus(t, db, started)
+
+	status, err := newTestStatusService(db).GetExecutionStatus(context.Background(), "exec-1", StatusOptions{})
+	if err != nil {
+		t.Fatalf("GetExecutionStatus: %v", err)
+	}
+
+	if len(status.Steps) != 2 {
+		t.Fatalf("steps = %+v, want one per step", status.Steps)
+	}
+	transcribe, speak := status.Steps[0], status.Steps[1]
+	if transcribe.TaskID != "task-1b" || transcribe.Attempt != 2 || transcribe.Attempts != 2 || transcribe.Error != "" {
+		t.Errorf("step 1 = %+v, want its second, successful attempt", transcribe)
+	}
+	if transcribe.Output["text"] != "hello" || speak.Output["audio"] != "s3://out/hello.wav" {
+		t.Errorf("step outputs = %v and %v, want the task outputs", transcribe.Output, speak.Output)
+	}
+	if !reflect.DeepEqual(status.Output, speak.Output) {
+		t.Errorf("output = %v, want the output of the last step", status.Output)
+	}
+
+	// Durations of the steps and of the execution
+	if !transcribe.StartedAt.Equal(started.Add(3*time.Second)) || transcribe.FinishedAt == nil || !transcribe.FinishedAt.Equal(started.Add(6*time.Second)) {
+		t.Errorf("step 1 ran from %s to %v, want its last attempt", transcribe.StartedAt, transcribe.FinishedAt)
+	}
+	if !status.StartedAt.Equal(started) || status.FinishedAt == nil || status.DurationMs != 9000 {
+		t.Errorf("execution ran from %s to %v (%dms), want 9s", status.StartedAt, status.FinishedAt, status.DurationMs)
+	}
+
+	// The failure stays visible after the retry succeeded
+	want := &StepError{Message: "model overloaded", Step: 1, Attempt: 1, TaskID: "task-1a", TaskType: "transcribe", At: started.Add(2 * time.Second)}
+	if last := status.LastError; last == nil || !last.At.Equal(want.At) {
+		t.Fatalf("last error = %+v, want %+v", last, want)
+	}
+	status.LastError.At = want.At
+	if !reflect.DeepEqual(status.LastError, want) {
+		t.Errorf("last error = %+v, want %+v", status.LastError, want)
+	}
+
+	if status.Variables != nil || status.History != nil {
+		t.Errorf("variables %v and history %v included without being asked for", status.Variables, status.History)
+	}
+}
+
+func TestExecutionStatusIncludes(t *testing.T) {
+	db := databasetest.Open(t)
+	seedStatus(t, db, utc("2026-10-19T10:00:00Z"))
+
+	status, err := newTestStatusService(db).GetExecutionStatus(context.Background(), "exec-1", StatusOptions{Variables: true, History: true})
+	if err != nil {
+		t.Fatalf("GetExecutionStatus: %v", err)
+	}
+	if status.Variables["file_url"] != "s3://uploads/talk.mp3" {
+		t.Errorf("variables = %v, want the trigger variables", status.Variables)
+	}
+	var events []string
+	for _, event := range status.History {
+		events = append(events, event.Event)
+	}
+	if want := []string{models.HistoryTaskScheduled, models.HistoryStepCompleted, models.HistoryExecutionCompleted}; !reflect.DeepEqual(events, want) {
+		t.Errorf("history = %v, want %v", events, want)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/google/uuid"
+)
+
+type TaskService struct {
+	repo *repositories.TaskRepository
+}
+
+func NewTaskService(repo *repositories.TaskRepository) *TaskService {
+	return &TaskService{repo: repo}
+}
+
+// TaskSpec describes the task dispatched for a step: its input, the
+// configuration of its workflow state and the scope templates in that
+// configuration are rendered against.
+type TaskSpec struct {
+	Step    uint8
+	Type    string
+	Input   map[string]interface{}
+	Params  map[string]interface{}
+	Context map[string]interface{}
+}
+
+// NewTask builds a pending task with a fresh task ID. It is not persisted.
+func NewTask(instanceID uint, spec TaskSpec) (*models.Task, error) {
+	payload, err := json.Marshal(spec.Input)
+	if err != nil {
+		return nil, err
+	}
+	task := &models.Task{
+		InstanceID: instanceID,
+		TaskID:     uuid.New().String(),
+		Attempt:    1,
+		StepID:     spec.Step,
+		Type:       spec.Type,
+		Payload:    payload,
+		Status:     models.TaskStatusPending,
+	}
+	if spec.Params != nil {
+		if task.Params, err = json.Marshal(spec.Params); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Context != nil {
+		if task.Context, err = json.Marshal(spec.Context); err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+// SpecOf restores the spec of a recorded task.
+func SpecOf(task *models.Task) (TaskSpec, error) {
+	spec := TaskSpec{Step: task.StepID, Type: task.Type}
+	for _, field := range []struct {
+		raw []byte
+		out *map[string]interface{}
+	}{
+		{task.Payload, &spec.Input},
+		{task.Params, &spec.Params},
+		{task.Context, &spec.Context},
+	} {
+		if len(field.raw) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(field.raw, field.out); err != nil {
+			return spec, err
+		}
+	}
+	return spec, nil
+}
+
+func (s *TaskService) CreateTask(ctx context.Context, instanceID uint, spec TaskSpec) (*models.Task, error) {
+	task, err := NewTask(instanceID, spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *TaskService) GetTaskByTaskID(ctx context.Context, taskID string) (*models.Task, error) {
+	return s.repo.GetByTaskID(ctx, taskID)
+}
+
+func (s *TaskService) GetPendingTaskForStep(ctx context.Context, instanceID uint, step uint8) (*models.Task, error) {
+	return s.repo.GetPendingForStep(ctx, instanceID, step)
+}
+
+// GetLatestTaskForStep returns the latest attempt of a step in any status.
+func (s *TaskService) GetLatestTaskForStep(ctx context.Context, instanceID uint, step uint8) (*models.Task, error) {
+	return s.repo.GetLatestForStep(ctx, instanceID, step)
+}
+
+// GetCompletedTaskForStep returns the task that completed a step.
+func (s *TaskService) GetCompletedTaskForStep(ctx context.Context, instanceID uint, step uint8) (*models.Task, error) {
+	return s.repo.GetCompletedForStep(ctx, instanceID, step)
+}
+
+// ListExpiredTasks returns dispatched tasks whose deadline has passed.
+func (s *TaskService) ListExpiredTasks(ctx context.Context, limit int) ([]models.Task, error) {
+	return s.repo.ListExpired(ctx, limit)
+}
+
+// ClearTaskDeadline stops the timers from expiring a task again.
+func (s *TaskService) ClearTaskDeadline(ctx context.Context, taskID string) error {
+	return s.repo.ClearDeadline(ctx, taskID)
+}
+
+func (s *TaskService) MarkTaskDispatched(ctx context.Context, taskID string) error {
+	return s.repo.MarkDispatched(ctx, taskID)
+}
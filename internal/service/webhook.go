@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/google/uuid"
+)
+
+// Webhook events subscriptions can filter on.
+const (
+	WebhookEventCompleted = "completed"
+	WebhookEventFailed    = "failed"
+	WebhookEventCancelled = "cancelled"
+	// A failed execution that undid its completed steps, or tried to
+	WebhookEventCompensated        = "compensated"
+	WebhookEventCompensationFailed = "compensation_failed"
+	// An approval waits for its assignees, or was escalated to more
+	WebhookEventApprovalRequested = "approval_requested"
+	WebhookEventApprovalEscalated = "approval_escalated"
+	WebhookEventStepCompleted     = "step_completed"
+	WebhookEventStepFailed        = "step_failed"
+)
+
+// webhookEvents maps history events to the webhook events they trigger.
+var webhookEvents = map[string]string{
+	models.HistoryExecutionCompleted: WebhookEventCompleted,
+	models.HistoryExecutionFailed:    WebhookEventFailed,
+	models.HistoryExecutionCancelled: WebhookEventCancelled,
+
+	models.HistoryExecutionCompensated:        WebhookEventCompensated,
+	models.HistoryExecutionCompensationFailed: WebhookEventCompensationFailed,
+	models.HistoryApprovalRequested:           WebhookEventApprovalRequested,
+	models.HistoryApprovalEscalated:           WebhookEventApprovalEscalated,
+	models.HistoryStepCompleted:               WebhookEventStepCompleted,
+	models.HistoryStepFailed:                  WebhookEventStepFailed,
+}
+
+// CreateWebhookRequest subscribes a URL to events of a workflow. A secret is
+// generated when none is given; an empty event list means every event.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// WebhookPayload is the JSON body POSTed to subscribers.
+type WebhookPayload struct {
+	DeliveryID  string                 `json:"delivery_id"`
+	Event       string                 `json:"event"`
+	ExecutionID string                 `json:"execution_id"`
+	WorkflowID  uint                   `json:"workflow_id"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	Output      map[string]interface{} `json:"output,omitempty"`
+}
+
+type WebhookService struct {
+	repo *repositories.WebhookRepository
+}
+
+func NewWebhookService(repo *repositories.WebhookRepository) *WebhookService {
+	return &WebhookService{repo: repo}
+}
+
+// CreateSubscription validates and stores a subscription. The returned
+// subscription is the only place its secret is exposed.
+func (s *WebhookService) CreateSubscription(ctx context.Context, workflowID uint, req CreateWebhookRequest) (*models.WebhookSubscription, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	events := make([]string, 0, len(req.Events))
+	for _, event := range req.Events {
+		event = strings.TrimSpace(event)
+		if !isWebhookEvent(event) {
+			return nil, fmt.Errorf("unknown webhook event %q", event)
+		}
+		events = append(events, event)
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(raw)
+	}
+
+	sub := &models.WebhookSubscription{
+		WorkflowID: workflowID,
+		URL:        req.URL,
+		Secret:     secret,
+		Events:     strings.Join(events, ","),
+		Active:     true,
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, workflowID, id uint) (*models.WebhookSubscription, error) {
+	return s.repo.GetSubscription(ctx, workflowID, id)
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context, workflowID uint) ([]models.WebhookSubscription, error) {
+	return s.repo.ListSubscriptions(ctx, workflowID)
+}
+
+func (s *WebhookService) SetSubscriptionActive(ctx context.Context, sub *models.WebhookSubscription, active bool) error {
+	sub.Active = active
+	return s.repo.UpdateSubscription(ctx, sub)
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, workflowID, id uint) error {
+	return s.repo.DeleteSubscription(ctx, workflowID, id)
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, subscriptionID uint, limit int) ([]models.WebhookDelivery, error) {
+	return s.repo.ListDeliveries(ctx, subscriptionID, limit)
+}
+
+func (s *WebhookService) Redeliver(ctx context.Context, subscriptionID, id uint) error {
+	return s.repo.Redeliver(ctx, subscriptionID, id)
+}
+
+// Deliveries builds the deliveries that history entries of an instance
+// trigger for the active subscriptions of its workflow. output is attached
+// to completed events. They are not persisted.
+func (s *WebhookService) Deliveries(ctx context.Context, instance *models.WorkflowInstance, entries []models.HistoryEntry, output map[string]interface{}) ([]models.WebhookDelivery, error) {
+	var subs []models.WebhookSubscription
+	var deliveries []models.WebhookDelivery
+
+	for _, entry := range entries {
+		event, ok := webhookEvents[entry.Event]
+		if !ok {
+			continue
+		}
+		if subs == nil {
+			var err error
+			if subs, err = s.repo.ListActiveSubscriptions(ctx, instance.WorkflowID); err != nil {
+				return nil, err
+			}
+			if len(subs) == 0 {
+				return nil, nil
+			}
+		}
+
+		for _, sub := range subs {
+			if !subscribed(&sub, event) {
+				continue
+			}
+			payload := WebhookPayload{
+				DeliveryID:  uuid.NewString(),
+				Event:       event,
+				ExecutionID: instance.ExecutionID,
+				WorkflowID:  instance.WorkflowID,
+				Timestamp:   entry.Timestamp,
+				Data:        decodeMap(entry.Data),
+			}
+			if event == WebhookEventCompleted {
+				payload.Output = output
+			}
+			body, err := json.Marshal(payload)
+			if err != nil {
+				return nil, err
+			}
+			deliveries = append(deliveries, models.WebhookDelivery{
+				SubscriptionID: sub.ID,
+				DeliveryID:     payload.DeliveryID,
+				ExecutionID:    instance.ExecutionID,
+				Event:          event,
+				Payload:        body,
+				Status:         models.WebhookDeliveryPending,
+				NextAttemptAt:  time.Now(),
+			})
+		}
+	}
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries leases due deliveries for a dispatcher together with
+// their subscriptions.
+func (s *WebhookService) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, map[uint]*models.WebhookSubscription, error) {
+	deliveries, err := s.repo.ClaimDue(ctx, limit, lease)
+	if err != nil || len(deliveries) == 0 {
+		return nil, nil, err
+	}
+
+	ids := make([]uint, 0, len(deliveries))
+	for _, d := range deliveries {
+		ids = append(ids, d.SubscriptionID)
+	}
+	subs, err := s.repo.GetSubscriptionsByID(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byID := make(map[uint]*models.WebhookSubscription, len(subs))
+	for i := range subs {
+		byID[subs[i].ID] = &subs[i]
+	}
+	return deliveries, byID, nil
+}
+
+func (s *WebhookService) RecordAttempt(ctx context.Context, id uint, status string, responseStatus int, lastError string, nextAttempt time.Time) error {
+	return s.repo.RecordAttempt(ctx, id, status, responseStatus, lastError, nextAttempt)
+}
+
+// SignWebhook returns the signature of a webhook body sent at timestamp:
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed
+// with the subscription secret. Receivers recompute it to verify deliveries.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func isWebhookEvent(event string) bool {
+	for _, e := range webhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func subscribed(sub *models.WebhookSubscription, event string) bool {
+	if sub.Events == "" {
+		return true
+	}
+	for _, e := range strings.Split(sub.Events, ",") {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+)
+
+func TestSignWebhook(t *testing.T) {
+	got := SignWebhook("whsec", utc("2026-10-06T00:00:00Z"), []byte(`{"event":"completed"}`))
+	want := "sha256=336bef9054a201387b6ff290f7cc9d346ceea9fcb32834af46a99a21cec25465"
+	if got != want {
+		t.Errorf("SignWebhook = %s, want %s", got, want)
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	tests := []struct {
+		events string
+		event  string
+		want   bool
+	}{
+		{"", WebhookEventCompleted, true},
+		{"", WebhookEventStepFailed, true},
+		{"completed,failed", WebhookEventFailed, true},
+		{"completed,failed", WebhookEventStepFailed, false},
+		{"step_completed", WebhookEventCompleted, false},
+	}
+	for _, tt := range tests {
+		sub := &models.WebhookSubscription{Events: tt.events}
+		if got := subscribed(sub, tt.event); got != tt.want {
+			t.Errorf("subscribed(%q, %q) = %v, want %v", tt.events, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestCreateSubscriptionRejectsInvalidRequests(t *testing.T) {
+	svc := NewWebhookService(nil)
+	for _, req := range []CreateWebhookRequest{
+		{URL: "ftp://example.com/hook"},
+		{URL: "/relative"},
+		{URL: "https://example.com/hook", Events: []string{"completed", "exploded"}},
+	} {
+		if _, err := svc.CreateSubscription(context.Background(), 1, req); err == nil {
+			t.Errorf("CreateSubscription(%+v) succeeded, want it rejected", req)
+		}
+	}
+}
+
+func TestDeliveriesFollowSubscribedEvents(t *testing.T) {
+	db := databasetest.Open(t)
+	ctx := context.Background()
+
+	workflow := &models.Workflow{Name: "hooks", Status: "active"}
+	if err := db.Create(workflow).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc := NewWebhookService(repositories.NewWebhookRepository(db))
+	all, err := svc.CreateSubscription(ctx, workflow.ID, CreateWebhookRequest{URL: "https://example.com/all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failures, err := svc.CreateSubscription(ctx, workflow.ID, CreateWebhookRequest{URL: "https://example.com/failures", Events: []string{WebhookEventFailed, WebhookEventStepFailed}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paused, err := svc.CreateSubscription(ctx, workflow.ID, CreateWebhookRequest{URL: "https://example.com/paused"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetSubscriptionActive(ctx, paused, false); err != nil {
+		t.Fatal(err)
+	}
+
+	instance := &models.WorkflowInstance{WorkflowID: workflow.ID, ExecutionID: "exec-1"}
+	entries := []models.HistoryEntry{
+		{Event: models.HistoryTaskScheduled, Timestamp: time.Now()},
+		{Event: models.HistoryStepCompleted, Timestamp: time.Now()},
+		{Event: models.HistoryExecutionCompleted, Timestamp: time.Now()},
+	}
+	deliveries, err := svc.Deliveries(ctx, instance, entries, map[string]interface{}{"url": "s3://out"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the subscription to every event is active and subscribed
+	if len(deliveries) != 2 {
+		t.Fatalf("got %d deliveries, want step_completed and completed", len(deliveries))
+	}
+	for i, event := range []string{WebhookEventStepCompleted, WebhookEventCompleted} {
+		delivery := deliveries[i]
+		if delivery.SubscriptionID != all.ID || delivery.Event != event || delivery.Status != models.WebhookDeliveryPending {
+			t.Errorf("delivery %d = %s to %d (%s), want %s to %d", i, delivery.Event, delivery.SubscriptionID, delivery.Status, event, all.ID)
+		}
+		var payload WebhookPayload
+		if err := json.Unmarshal(delivery.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.DeliveryID != delivery.DeliveryID || payload.ExecutionID != "exec-1" {
+			t.Errorf("payload = %+v, want the delivery and execution IDs", payload)
+		}
+		if (payload.Output != nil) != (event == WebhookEventCompleted) {
+			t.Errorf("%s payload output = %v, want the output on completed events only", event, payload.Output)
+		}
+	}
+
+	failed := []models.HistoryEntry{{Event: models.HistoryExecutionFailed, Timestamp: time.Now()}}
+	if deliveries, err = svc.Deliveries(ctx, instance, failed, nil); err != nil {
+		t.Fatal(err)
+	}
+	to := map[uint]bool{}
+	for _, delivery := range deliveries {
+		to[delivery.SubscriptionID] = true
+	}
+	if len(deliveries) != 2 || !to[all.ID] || !to[failures.ID] {
+		t.Errorf("got %d deliveries of a failure, want one to each active subscription", len(deliveries))
+	}
+}
@@ -5,14 +5,21 @@ import (
 
 	"github.com/Vighnesh-V-H/async/internal/models"
 	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
 )
 
+// defaultPipeline is used for workflows without a DSL definition.
+var defaultPipeline = []string{"extract_text", "generate_audio", "store_audio"}
+
 type WorkflowService struct {
-    repo *repositories.WorkflowRepository
+    repo        *repositories.WorkflowRepository
+    definitions *dsl.Registry
 }
 
-func NewWorkflowService(repo *repositories.WorkflowRepository) *WorkflowService {
-    return &WorkflowService{repo: repo}
+// NewWorkflowService creates the service. definitions may be nil, in which
+// case every workflow runs the default pipeline.
+func NewWorkflowService(repo *repositories.WorkflowRepository, definitions *dsl.Registry) *WorkflowService {
+    return &WorkflowService{repo: repo, definitions: definitions}
 }
 
 type CreateWorkflowRequest struct {
@@ -43,3 +50,115 @@ func (s *WorkflowService) CreateWorkflow(ctx context.Context, req CreateWorkflow
 func (s *WorkflowService) GetWorkflowByEvent(ctx context.Context, event string) (*models.Workflow, error) {
     return s.repo.GetByEvent(ctx, event)
 }
+
+// GetWorkflowByName retrieves an active workflow by name
+func (s *WorkflowService) GetWorkflowByName(ctx context.Context, name string) (*models.Workflow, error) {
+    return s.repo.GetByName(ctx, name)
+}
+
+func (s *WorkflowService) GetWorkflowByID(ctx context.Context, id uint) (*models.Workflow, error) {
+    return s.repo.GetByID(ctx, id)
+}
+
+// GetDefinition returns the DSL definition registered under the workflow's name.
+func (s *WorkflowService) GetDefinition(wf *models.Workflow) (*dsl.Workflow, bool) {
+    if s.definitions == nil {
+        return nil, false
+    }
+    return s.definitions.Get(wf.Name)
+}
+
+// StepTaskType returns the task type to dispatch for the 1-based step of wf,
+// or false once the workflow has no more steps.
+func (s *WorkflowService) StepTaskType(wf *models.Workflow, step uint8) (string, bool) {
+    if step == 0 {
+        return "", false
+    }
+
+    if _, ok := s.GetDefinition(wf); ok {
+        state, ok := s.StepState(wf, step)
+        if !ok {
+            return "", false
+        }
+        return state.TaskType(), true
+    }
+
+    if step > wf.Steps || int(step) > len(defaultPipeline) {
+        return "", false
+    }
+    return defaultPipeline[step-1], true
+}
+
+// StepState returns the DSL state behind the 1-based step of wf. Workflows
+// without a definition have no states.
+func (s *WorkflowService) StepState(wf *models.Workflow, step uint8) (*dsl.State, bool) {
+    def, ok := s.GetDefinition(wf)
+    if !ok || step == 0 {
+        return nil, false
+    }
+    pipeline := def.Pipeline()
+    if int(step) > len(pipeline) {
+        return nil, false
+    }
+    return pipeline[step-1], true
+}
+
+// NextStep returns the 1-based step that follows step of wf: the on_success
+// target of its state, or the on_failure target when it failed. Step 0 is
+// followed by the first step. Workflows without a definition run their
+// steps in order and end at the first failure.
+func (s *WorkflowService) NextStep(wf *models.Workflow, step uint8, failed bool) (uint8, bool) {
+    if step == 0 {
+        _, ok := s.StepTaskType(wf, 1)
+        return 1, ok
+    }
+
+    if def, ok := s.GetDefinition(wf); ok {
+        next, ok := def.Next(int(step)-1, failed)
+        if !ok {
+            return 0, false
+        }
+        return uint8(next + 1), true
+    }
+
+    if failed {
+        return 0, false
+    }
+    _, ok := s.StepTaskType(wf, step+1)
+    return step + 1, ok
+}
+
+// StepSpec returns the task to dispatch for the 1-based step of wf with the
+// given input and template context, or false once the workflow has no more
+// steps.
+func (s *WorkflowService) StepSpec(wf *models.Workflow, step uint8, input, context map[string]interface{}) (TaskSpec, bool) {
+    taskType, ok := s.StepTaskType(wf, step)
+    if !ok {
+        return TaskSpec{}, false
+    }
+
+    spec := TaskSpec{
+        Step:    step,
+        Type:    taskType,
+        Input:   input,
+        Context: context,
+    }
+    if state, ok := s.StepState(wf, step); ok {
+        spec.Params = state.Params()
+    }
+    return spec, true
+}
+
+// StepContext builds the scope of step templates: the trigger payload as
+// trigger and vars, the previous step as prev and the execution ID.
+func StepContext(executionID string, vars map[string]interface{}, prev map[string]interface{}) map[string]interface{} {
+    context := map[string]interface{}{
+        "execution_id": executionID,
+        "trigger":      vars,
+        "vars":         vars,
+    }
+    if prev != nil {
+        context["prev"] = prev
+    }
+    return context
+}
@@ -0,0 +1,132 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/pkg/cache"
+	"github.com/Vighnesh-V-H/async/pkg/database"
+	"github.com/Vighnesh-V-H/async/pkg/kafka"
+	"github.com/rs/zerolog"
+)
+
+const (
+	BackendKafka    = "kafka"
+	BackendRedis    = "redis"
+	BackendPostgres = "postgres"
+)
+
+// Transport bundles the Publisher and Subscriber of the configured backend so
+// the orchestrator and workers are wired the same way whichever one is used.
+type Transport struct {
+	Backend    string
+	Publisher  events.Publisher
+	Subscriber events.Subscriber
+	close      func()
+}
+
+// Open connects to the backend selected by cfg.Transport.Backend.
+func Open(ctx context.Context, cfg *config.Config, log zerolog.Logger, logCfg logger.Config) (*Transport, error) {
+	switch cfg.Transport.Backend {
+	case BackendKafka:
+		return openKafka(cfg, log, logCfg)
+	case BackendRedis:
+		return openRedis(ctx, cfg, log, logCfg)
+	case BackendPostgres:
+		return openPostgres(ctx, cfg, log, logCfg)
+	default:
+		return nil, fmt.Errorf("unknown transport backend %q", cfg.Transport.Backend)
+	}
+}
+
+// HandleInTransactions makes the postgres backend run handlers in the
+// transaction that acknowledges their message, so the state they change
+// commits together with the acknowledgement. The other backends cannot share
+// a transaction with the database and are left as they are.
+func (t *Transport) HandleInTransactions() {
+	if subscriber, ok := t.Subscriber.(*events.PostgresSubscriber); ok {
+		subscriber.SetTransactional(true)
+	}
+}
+
+// Close flushes and releases the backend connections.
+func (t *Transport) Close() {
+	if t.close != nil {
+		t.close()
+	}
+}
+
+func openKafka(cfg *config.Config, log zerolog.Logger, logCfg logger.Config) (*Transport, error) {
+	producer, err := kafka.InitProducer(&cfg.Kafka, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Kafka producer: %w", err)
+	}
+
+	consumer, err := kafka.InitConsumer(&cfg.Kafka, log)
+	if err != nil {
+		kafka.Close()
+		return nil, fmt.Errorf("failed to initialize Kafka consumer: %w", err)
+	}
+
+	return &Transport{
+		Backend:    BackendKafka,
+		Publisher:  events.NewKafkaPublisher(producer, logCfg),
+		Subscriber: events.NewKafkaSubscriber(consumer, cfg.Kafka.ConsumerConcurrency, logCfg),
+		close:      kafka.Close,
+	}, nil
+}
+
+func openRedis(ctx context.Context, cfg *config.Config, log zerolog.Logger, logCfg logger.Config) (*Transport, error) {
+	client, err := cache.InitRedis(ctx, &cfg.Redis, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Redis: %w", err)
+	}
+
+	return &Transport{
+		Backend:   BackendRedis,
+		Publisher: events.NewRedisStreamPublisher(client, cfg.Redis.StreamMaxLen, logCfg),
+		Subscriber: events.NewRedisStreamSubscriber(
+			client,
+			cfg.Kafka.ConsumerGroupID,
+			cfg.Transport.ConsumerName,
+			cfg.Kafka.ConsumerConcurrency,
+			cfg.Redis.StreamBlockTimeout,
+			cfg.Redis.StreamClaimMinIdle,
+			logCfg,
+		),
+		close: func() {
+			if err := cache.CloseRedis(); err != nil {
+				log.Error().Err(err).Msg("Failed to close Redis")
+			}
+		},
+	}, nil
+}
+
+func openPostgres(ctx context.Context, cfg *config.Config, log zerolog.Logger, logCfg logger.Config) (*Transport, error) {
+	db, err := database.InitDB(ctx, &cfg.Database, logCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	return &Transport{
+		Backend:   BackendPostgres,
+		Publisher: events.NewPostgresPublisher(db, logCfg),
+		Subscriber: events.NewPostgresSubscriber(
+			db,
+			cfg.Database.URL,
+			cfg.Transport.ConsumerName,
+			cfg.Kafka.ConsumerConcurrency,
+			cfg.Database.QueueVisibilityTimeout,
+			cfg.Database.QueuePollInterval,
+			logCfg,
+		),
+		close: func() {
+			if err := database.CloseDB(context.Background()); err != nil {
+				log.Error().Err(err).Msg("Failed to close database connection")
+			}
+		},
+	}, nil
+}
@@ -0,0 +1,188 @@
+package triggers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/orchestrator"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// messageNamespace derives the execution IDs of triggered executions from
+// their messages.
+var messageNamespace = uuid.MustParse("9e4b7d12-3c85-4f0a-b6e1-2d7a8c5f9b30")
+
+// Starter starts executions of workflows.
+type Starter interface {
+	Start(ctx context.Context, workflowName, executionID string, input map[string]interface{}) (*models.WorkflowInstance, error)
+}
+
+// subscription is a kafka trigger of a workflow.
+type subscription struct {
+	workflow string
+	trigger  *dsl.Trigger
+	filter   *dsl.Condition
+}
+
+// KafkaTriggers starts executions for the messages on the topics of the
+// kafka triggers of the workflow definitions. Its subscriber should consume
+// in a group of its own: every orchestrator replica may run it, and each
+// message is handled by one of them. Messages are acknowledged once every
+// workflow subscribed to their topic started; the execution ID of a message
+// derives from it, so redelivered messages start nothing new.
+type KafkaTriggers struct {
+	subscriber events.Subscriber
+	starter    Starter
+	renderer   *dsl.Renderer
+	byTopic    map[string][]subscription
+	logger     zerolog.Logger
+}
+
+func NewKafkaTriggers(subscriber events.Subscriber, starter Starter, definitions *dsl.Registry, logCfg logger.Config) (*KafkaTriggers, error) {
+	byTopic := make(map[string][]subscription)
+	for _, wf := range definitions.All() {
+		for _, trigger := range wf.Subscriptions() {
+			sub := subscription{workflow: wf.Name, trigger: trigger}
+			if trigger.Filter != "" {
+				filter, err := dsl.ParseCondition(trigger.Filter)
+				if err != nil {
+					return nil, fmt.Errorf("workflow %s: %w", wf.Name, err)
+				}
+				sub.filter = filter
+			}
+			topic := trigger.SubscribedTopic()
+			byTopic[topic] = append(byTopic[topic], sub)
+		}
+	}
+
+	return &KafkaTriggers{
+		subscriber: subscriber,
+		starter:    starter,
+		renderer:   dsl.NewRenderer(nil),
+		byTopic:    byTopic,
+		logger:     logger.New(logCfg),
+	}, nil
+}
+
+// Topics returns the subscribed topics, sorted.
+func (k *KafkaTriggers) Topics() []string {
+	topics := make([]string, 0, len(k.byTopic))
+	for topic := range k.byTopic {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Run consumes the subscribed topics until ctx is done. It returns right
+// away when no workflow has a kafka trigger.
+func (k *KafkaTriggers) Run(ctx context.Context) error {
+	if len(k.byTopic) == 0 {
+		return nil
+	}
+	return k.subscriber.Subscribe(ctx, k.Topics(), k.handle)
+}
+
+func (k *KafkaTriggers) handle(ctx context.Context, msg *events.Message) error {
+	scope := map[string]interface{}{"message": messageScope(msg)}
+
+	var errs []error
+	for _, sub := range k.byTopic[msg.Topic] {
+		if err := k.trigger(ctx, sub, msg, scope); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// trigger starts the execution of a workflow for a message that passes its
+// filter.
+func (k *KafkaTriggers) trigger(ctx context.Context, sub subscription, msg *events.Message, scope map[string]interface{}) error {
+	log := k.logger.With().
+		Str("workflow", sub.workflow).
+		Str("topic", msg.Topic).
+		Int32("partition", msg.Partition).
+		Int64("offset", msg.Offset).
+		Logger()
+
+	if sub.filter != nil && !sub.filter.Eval(scope) {
+		log.Debug().Str("filter", sub.filter.String()).Msg("Message filtered out")
+		return nil
+	}
+
+	input, err := k.payload(ctx, sub, scope)
+	if err != nil {
+		// Mapping the same message again fails the same way
+		log.Error().Err(err).Msg("Failed to map message to trigger payload, skipping it")
+		return nil
+	}
+
+	executionID := executionIDOf(sub, msg)
+	instance, err := k.starter.Start(ctx, sub.workflow, executionID, input)
+	if err != nil {
+		if errors.Is(err, orchestrator.ErrNotStartable) {
+			log.Error().Err(err).Msg("Skipping message for a workflow that cannot be started")
+			return nil
+		}
+		return err
+	}
+
+	log.Info().
+		Str("execution_id", instance.ExecutionID).
+		Msg("Started execution for message")
+	return nil
+}
+
+// payload renders the mapping of the trigger against the message, or takes
+// the message value when there is none.
+func (k *KafkaTriggers) payload(ctx context.Context, sub subscription, scope map[string]interface{}) (map[string]interface{}, error) {
+	if len(sub.trigger.Mapping) > 0 {
+		return k.renderer.RenderInputs(ctx, sub.trigger.Mapping, scope)
+	}
+	message := scope["message"].(map[string]interface{})
+	if value, ok := message["value"].(map[string]interface{}); ok {
+		return value, nil
+	}
+	return map[string]interface{}{"value": message["value"]}, nil
+}
+
+// messageScope exposes a message to filters and mappings. Values holding
+// JSON are decoded; others are kept as text.
+func messageScope(msg *events.Message) map[string]interface{} {
+	var value interface{}
+	if len(msg.Value) > 0 {
+		if err := json.Unmarshal(msg.Value, &value); err != nil {
+			value = string(msg.Value)
+		}
+	}
+	headers := make(map[string]interface{}, len(msg.Headers))
+	for name, v := range msg.Headers {
+		headers[name] = v
+	}
+	return map[string]interface{}{
+		"topic":     msg.Topic,
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+		"key":       string(msg.Key),
+		"value":     value,
+		"headers":   headers,
+	}
+}
+
+// executionIDOf derives the execution a message starts for a workflow from
+// its position in the topic or, with key idempotency, its key.
+func executionIDOf(sub subscription, msg *events.Message) string {
+	basis := fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Partition, msg.Offset)
+	if sub.trigger.IdempotencyPolicy() == dsl.IdempotencyKey && len(msg.Key) > 0 {
+		basis = msg.Topic + "#" + string(msg.Key)
+	}
+	return uuid.NewSHA1(messageNamespace, []byte(sub.workflow+"\n"+basis)).String()
+}
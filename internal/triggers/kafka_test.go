@@ -0,0 +1,301 @@
+package triggers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/orchestrator"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+)
+
+var testLog = logger.Config{Level: "error"}
+
+const transcodeWorkflow = `
+name: transcode
+triggers:
+  - type: kafka
+    event: file.uploaded
+    filter: "message.value.type == 'audio' && message.value.size > 0"
+    mapping: { file_url: "{{message.value.url}}", user: "{{message.key}}" }
+states:
+  - id: convert
+    type: task
+    action: convert_audio
+`
+
+const archiveWorkflow = `
+name: archive
+triggers:
+  - type: kafka
+    event: file.uploaded
+    idempotency: key
+states:
+  - id: store
+    type: task
+    action: store_file
+`
+
+func registryOf(t *testing.T, definitions ...string) *dsl.Registry {
+	t.Helper()
+	registry := dsl.NewRegistry()
+	for _, def := range definitions {
+		wf, err := dsl.Parse([]byte(def))
+		if err != nil {
+			t.Fatalf("parse workflow: %v", err)
+		}
+		if err := registry.Register(wf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return registry
+}
+
+type startCall struct {
+	workflow    string
+	executionID string
+	input       map[string]interface{}
+}
+
+// fakeStarter records the executions it is asked to start.
+type fakeStarter struct {
+	mu    sync.Mutex
+	calls []startCall
+	err   error
+}
+
+func (f *fakeStarter) Start(ctx context.Context, workflowName, executionID string, input map[string]interface{}) (*models.WorkflowInstance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, startCall{workflowName, executionID, input})
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &models.WorkflowInstance{ExecutionID: executionID}, nil
+}
+
+func (f *fakeStarter) started(workflow string) []startCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []startCall
+	for _, c := range f.calls {
+		if c.workflow == workflow {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func newTestTriggers(t *testing.T, starter Starter, definitions ...string) *KafkaTriggers {
+	t.Helper()
+	k, err := NewKafkaTriggers(nil, starter, registryOf(t, definitions...), testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func uploaded(offset int64, key, value string) *events.Message {
+	return &events.Message{Topic: "file.uploaded", Partition: 1, Offset: offset, Key: []byte(key), Value: []byte(value)}
+}
+
+func TestExecutionIDOf(t *testing.T) {
+	k := newTestTriggers(t, &fakeStarter{}, transcodeWorkflow, archiveWorkflow)
+	subs := map[string]subscription{}
+	for _, sub := range k.byTopic["file.uploaded"] {
+		subs[sub.workflow] = sub
+	}
+	transcode, archive := subs["transcode"], subs["archive"]
+
+	tests := []struct {
+		name   string
+		a, b   *events.Message
+		subA   subscription
+		subB   subscription
+		shared bool
+	}{
+		{"same offset", uploaded(7, "k", ""), uploaded(7, "k", ""), transcode, transcode, true},
+		{"other offset", uploaded(7, "k", ""), uploaded(8, "k", ""), transcode, transcode, false},
+		{"other partition", uploaded(7, "k", ""), &events.Message{Topic: "file.uploaded", Partition: 2, Offset: 7}, transcode, transcode, false},
+		{"other workflow", uploaded(7, "k", ""), uploaded(7, "k", ""), transcode, archive, false},
+		{"same key", uploaded(7, "k", ""), uploaded(8, "k", ""), archive, archive, true},
+		{"other key", uploaded(7, "k", ""), uploaded(7, "j", ""), archive, archive, false},
+		{"no key falls back to the offset", uploaded(7, "", ""), uploaded(8, "", ""), archive, archive, false},
+	}
+	for _, tt := range tests {
+		a, b := executionIDOf(tt.subA, tt.a), executionIDOf(tt.subB, tt.b)
+		if (a == b) != tt.shared {
+			t.Errorf("%s: execution IDs %s and %s, want shared %v", tt.name, a, b, tt.shared)
+		}
+	}
+}
+
+func TestHandleStartsSubscribedWorkflows(t *testing.T) {
+	starter := &fakeStarter{}
+	k := newTestTriggers(t, starter, transcodeWorkflow, archiveWorkflow)
+
+	msg := uploaded(7, "user-1", `{"type": "audio", "size": 10, "url": "s3://in/a.wav"}`)
+	if err := k.handle(context.Background(), msg); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	transcode := starter.started("transcode")
+	if len(transcode) != 1 {
+		t.Fatalf("transcode started %d times, want once", len(transcode))
+	}
+	if want := map[string]interface{}{"file_url": "s3://in/a.wav", "user": "user-1"}; !reflect.DeepEqual(transcode[0].input, want) {
+		t.Errorf("transcode input = %v, want the mapping %v", transcode[0].input, want)
+	}
+	if transcode[0].executionID != executionIDOf(subscription{workflow: "transcode", trigger: &dsl.Trigger{}}, msg) {
+		t.Errorf("transcode execution ID = %s, want it derived from the message", transcode[0].executionID)
+	}
+
+	archive := starter.started("archive")
+	if len(archive) != 1 {
+		t.Fatalf("archive started %d times, want once", len(archive))
+	}
+	if want := map[string]interface{}{"type": "audio", "size": 10.0, "url": "s3://in/a.wav"}; !reflect.DeepEqual(archive[0].input, want) {
+		t.Errorf("archive input = %v, want the message value %v", archive[0].input, want)
+	}
+}
+
+func TestHandleWrapsValuesThatAreNotObjects(t *testing.T) {
+	starter := &fakeStarter{}
+	k := newTestTriggers(t, starter, archiveWorkflow)
+
+	for value, want := range map[string]interface{}{
+		"plain text": "plain text",
+		"[1, 2]":     []interface{}{1.0, 2.0},
+		"42":         42.0,
+	} {
+		starter.calls = nil
+		if err := k.handle(context.Background(), uploaded(1, "k", value)); err != nil {
+			t.Fatal(err)
+		}
+		if got := starter.calls[0].input; !reflect.DeepEqual(got, map[string]interface{}{"value": want}) {
+			t.Errorf("input for %q = %v, want {value: %v}", value, got, want)
+		}
+	}
+}
+
+func TestHandleSkipsFilteredMessages(t *testing.T) {
+	starter := &fakeStarter{}
+	k := newTestTriggers(t, starter, transcodeWorkflow)
+
+	for _, value := range []string{
+		`{"type": "video", "size": 10}`,
+		`{"type": "audio", "size": 0}`,
+		`{"type": "audio"}`,
+		`not json`,
+	} {
+		if err := k.handle(context.Background(), uploaded(1, "k", value)); err != nil {
+			t.Errorf("handle(%s) = %v, want the message acknowledged", value, err)
+		}
+	}
+	if len(starter.calls) != 0 {
+		t.Errorf("started %v, want filtered messages skipped", starter.calls)
+	}
+}
+
+func TestHandleStartErrors(t *testing.T) {
+	msg := uploaded(1, "k", `{"type": "audio", "size": 10}`)
+
+	starter := &fakeStarter{err: errors.New("database unavailable")}
+	k := newTestTriggers(t, starter, transcodeWorkflow)
+	if err := k.handle(context.Background(), msg); err == nil {
+		t.Error("handle succeeded although the execution did not start, want it redelivered")
+	}
+
+	starter.err = fmt.Errorf("%w: workflow %q not found", orchestrator.ErrNotStartable, "transcode")
+	if err := k.handle(context.Background(), msg); err != nil {
+		t.Errorf("handle = %v, want a message that can never start acknowledged", err)
+	}
+}
+
+func TestRedeliveredMessageStartsOneExecution(t *testing.T) {
+	db := databasetest.Open(t)
+	registry := registryOf(t, transcodeWorkflow, archiveWorkflow)
+	for _, wf := range registry.All() {
+		if err := db.Create(&models.Workflow{Name: wf.Name, Status: "active"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	broker := events.NewMemoryBroker(1)
+	orch := orchestrator.NewOrchestrator(
+		service.NewWorkflowService(repositories.NewWorkflowRepository(db), registry),
+		service.NewInstanceService(repositories.NewInstanceRepository(db)),
+		service.NewTaskService(repositories.NewTaskRepository(db)),
+		events.NewEventProducer(broker, time.Second, testLog),
+		events.NewTopicRouter("tasks"),
+		nil,
+		testLog,
+	)
+	k, err := NewKafkaTriggers(nil, orch, registry, testLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same offset twice at once, then again, then the same key at
+	// another offset
+	value := `{"type": "audio", "size": 10, "url": "s3://in/a.wav"}`
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = k.handle(context.Background(), uploaded(7, "user-1", value))
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("handle: %v", err)
+		}
+	}
+	if err := k.handle(context.Background(), uploaded(7, "user-1", value)); err != nil {
+		t.Fatalf("handle redelivery: %v", err)
+	}
+	if err := k.handle(context.Background(), uploaded(8, "user-1", value)); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+
+	var counts []struct {
+		Name  string
+		Count int
+	}
+	err = db.Table("workflow_instances").
+		Select("workflows.name AS name, count(*) AS count").
+		Joins("JOIN workflows ON workflows.id = workflow_instances.workflow_id").
+		Group("workflows.name").
+		Order("workflows.name").
+		Scan(&counts).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		Name  string
+		Count int
+	}{{"archive", 1}, {"transcode", 2}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("executions per workflow = %v, want one per offset for transcode and one per key for archive", counts)
+	}
+
+	tasks := map[string]bool{}
+	for _, msg := range broker.Messages("tasks") {
+		tasks[msg.Headers[events.HeaderTaskID]] = true
+	}
+	if len(tasks) != 3 {
+		t.Errorf("dispatched %d distinct tasks, want the first task of each execution", len(tasks))
+	}
+}
@@ -0,0 +1,182 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/rs/zerolog"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderEvent     = "X-Async-Event"
+	HeaderDelivery  = "X-Async-Delivery"
+	HeaderTimestamp = "X-Async-Timestamp"
+	HeaderSignature = "X-Async-Signature"
+)
+
+// maxErrorBody bounds how much of a failed response is kept in the log.
+const maxErrorBody = 1024
+
+// Dispatcher delivers queued webhook events. Any number of dispatchers can
+// run side by side; each delivery is leased to one of them at a time.
+type Dispatcher struct {
+	svc    *service.WebhookService
+	client *http.Client
+	cfg    config.WebhooksConfig
+	logger zerolog.Logger
+}
+
+func NewDispatcher(svc *service.WebhookService, cfg config.WebhooksConfig, logCfg logger.Config) *Dispatcher {
+	return &Dispatcher{
+		svc:    svc,
+		client: &http.Client{Timeout: cfg.RequestTimeout},
+		cfg:    cfg,
+		logger: logger.New(logCfg),
+	}
+}
+
+// Run delivers due events until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	// Leases outlast a request so a delivery is never sent twice at once
+	lease := d.cfg.RequestTimeout + 30*time.Second
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		deliveries, subs, err := d.svc.ClaimDueDeliveries(ctx, d.cfg.BatchSize, lease)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			d.logger.Error().Err(err).Msg("Failed to claim webhook deliveries")
+		}
+
+		d.deliverAll(ctx, deliveries, subs)
+
+		// Keep draining while there is a backlog
+		if len(deliveries) == d.cfg.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(d.cfg.PollInterval)
+		}
+	}
+}
+
+func (d *Dispatcher) deliverAll(ctx context.Context, deliveries []models.WebhookDelivery, subs map[uint]*models.WebhookSubscription) {
+	sem := make(chan struct{}, d.cfg.Concurrency)
+	var wg sync.WaitGroup
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		sub, ok := subs[delivery.SubscriptionID]
+		if !ok {
+			continue // deleted meanwhile; the cascade removes the delivery
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			d.deliver(ctx, delivery, sub)
+		}()
+	}
+	wg.Wait()
+}
+
+// deliver makes one attempt and records its outcome.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery, sub *models.WebhookSubscription) {
+	status, err := d.send(ctx, delivery, sub)
+	if ctx.Err() != nil {
+		return // the lease runs out and the attempt is repeated
+	}
+
+	log := d.logger.With().
+		Str("delivery_id", delivery.DeliveryID).
+		Str("execution_id", delivery.ExecutionID).
+		Str("event", delivery.Event).
+		Uint("subscription_id", sub.ID).
+		Uint("attempt", delivery.Attempts).
+		Int("response_status", status).
+		Logger()
+
+	outcome := models.WebhookDeliveryDelivered
+	next := time.Now()
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+		if int(delivery.Attempts) >= d.cfg.MaxAttempts {
+			outcome = models.WebhookDeliveryFailed
+			log.Error().Err(err).Msg("Webhook delivery failed permanently")
+		} else {
+			outcome = models.WebhookDeliveryPending
+			next = next.Add(d.backoff(delivery.Attempts))
+			log.Warn().Err(err).Time("next_attempt_at", next).Msg("Webhook delivery failed, retrying")
+		}
+	} else {
+		log.Info().Msg("Webhook delivered")
+	}
+
+	if err := d.svc.RecordAttempt(ctx, delivery.ID, outcome, status, lastError, next); err != nil {
+		log.Error().Err(err).Msg("Failed to record webhook delivery attempt")
+	}
+}
+
+// send POSTs the payload and returns the response status.
+func (d *Dispatcher) send(ctx context.Context, delivery *models.WebhookDelivery, sub *models.WebhookSubscription) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "async-webhooks/1")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.DeliveryID)
+	req.Header.Set(HeaderTimestamp, fmt.Sprint(now.Unix()))
+	req.Header.Set(HeaderSignature, service.SignWebhook(sub.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles the delay after every attempt and adds up to 20% jitter so
+// failing receivers are not hit in bursts, capped at the maximum backoff.
+func (d *Dispatcher) backoff(attempts uint) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := uint(1); i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay += time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return min(delay, d.cfg.MaxBackoff)
+}
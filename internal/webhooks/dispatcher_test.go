@@ -0,0 +1,180 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "github.com/Vighnesh-V-H/async/configs"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/internal/models"
+	"github.com/Vighnesh-V-H/async/internal/repositories"
+	"github.com/Vighnesh-V-H/async/internal/service"
+	"github.com/Vighnesh-V-H/async/pkg/database/databasetest"
+	"gorm.io/gorm"
+)
+
+const testSecret = "whsec"
+
+var testConfig = config.WebhooksConfig{
+	MaxAttempts:    2,
+	InitialBackoff: time.Second,
+	MaxBackoff:     10 * time.Second,
+	RequestTimeout: time.Second,
+	PollInterval:   time.Second,
+	BatchSize:      10,
+	Concurrency:    2,
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{cfg: testConfig}
+	tests := []struct {
+		attempts uint
+		min, max time.Duration
+	}{
+		{1, time.Second, 1200 * time.Millisecond},
+		{2, 2 * time.Second, 2400 * time.Millisecond},
+		{3, 4 * time.Second, 4800 * time.Millisecond},
+		{5, 10 * time.Second, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := d.backoff(tt.attempts); got < tt.min || got > tt.max {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", tt.attempts, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+// newTestDispatcher queues a delivery for a subscription of receiver.
+func newTestDispatcher(t *testing.T, receiver http.HandlerFunc) (*Dispatcher, *gorm.DB, *models.WebhookDelivery) {
+	t.Helper()
+	db := databasetest.Open(t)
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	workflow := &models.Workflow{Name: "hooks", Status: "active"}
+	if err := db.Create(workflow).Error; err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewWebhookService(repositories.NewWebhookRepository(db))
+	sub, err := svc.CreateSubscription(context.Background(), workflow.ID, service.CreateWebhookRequest{URL: server.URL, Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: sub.ID,
+		DeliveryID:     "delivery-1",
+		ExecutionID:    "exec-1",
+		Event:          service.WebhookEventCompleted,
+		Payload:        []byte(`{"event":"completed"}`),
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  time.Now().Add(-time.Second),
+	}
+	if err := db.Create(delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return NewDispatcher(svc, testConfig, logger.Config{Level: "error"}), db, delivery
+}
+
+// dispatch runs one round of the dispatcher, first making the delivery due
+// again when it waits for a retry, and returns the delivery log.
+func dispatch(t *testing.T, d *Dispatcher, db *gorm.DB, delivery *models.WebhookDelivery) *models.WebhookDelivery {
+	t.Helper()
+	ctx := context.Background()
+	if err := db.Model(delivery).Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	deliveries, subs, err := d.svc.ClaimDueDeliveries(ctx, testConfig.BatchSize, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.deliverAll(ctx, deliveries, subs)
+
+	logged := &models.WebhookDelivery{}
+	if err := db.First(logged, delivery.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return logged
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	var received atomic.Int32
+	d, db, delivery := newTestDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		body, _ := io.ReadAll(r.Body)
+
+		// Verify the delivery as a receiver would
+		mac := hmac.New(sha256.New, []byte(testSecret))
+		mac.Write([]byte(r.Header.Get(HeaderTimestamp) + "." + string(body)))
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if got := r.Header.Get(HeaderSignature); !hmac.Equal([]byte(got), []byte(want)) {
+			t.Errorf("%s = %s, want %s", HeaderSignature, got, want)
+		}
+		if r.Header.Get(HeaderEvent) != service.WebhookEventCompleted || r.Header.Get(HeaderDelivery) != "delivery-1" {
+			t.Errorf("headers = %v, want the event and delivery ID", r.Header)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	logged := dispatch(t, d, db, delivery)
+	if received.Load() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", received.Load())
+	}
+	if logged.Status != models.WebhookDeliveryDelivered || logged.Attempts != 1 || logged.ResponseStatus != http.StatusNoContent || logged.DeliveredAt == nil {
+		t.Errorf("delivery = %s after %d attempts with status %d, want delivered on the first", logged.Status, logged.Attempts, logged.ResponseStatus)
+	}
+}
+
+func TestDispatcherRetriesFailedDeliveries(t *testing.T) {
+	var received atomic.Int32
+	d, db, delivery := newTestDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		if received.Add(1) == 1 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	before := time.Now()
+	logged := dispatch(t, d, db, delivery)
+	if logged.Status != models.WebhookDeliveryPending || logged.ResponseStatus != http.StatusServiceUnavailable || !strings.Contains(logged.LastError, "try later") {
+		t.Fatalf("delivery = %s with status %d (%q), want it pending with the failure logged", logged.Status, logged.ResponseStatus, logged.LastError)
+	}
+	if wait := logged.NextAttemptAt.Sub(before); wait < testConfig.InitialBackoff || wait > 2*testConfig.InitialBackoff {
+		t.Errorf("next attempt in %s, want after the initial backoff", wait)
+	}
+
+	logged = dispatch(t, d, db, delivery)
+	if logged.Status != models.WebhookDeliveryDelivered || logged.Attempts != 2 || logged.ResponseStatus != http.StatusOK {
+		t.Errorf("delivery = %s after %d attempts with status %d, want delivered on the second", logged.Status, logged.Attempts, logged.ResponseStatus)
+	}
+}
+
+func TestDispatcherGivesUpAfterMaxAttempts(t *testing.T) {
+	var received atomic.Int32
+	d, db, delivery := newTestDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		http.Error(w, "broken", http.StatusInternalServerError)
+	})
+
+	var logged *models.WebhookDelivery
+	for i := 0; i < testConfig.MaxAttempts+1; i++ {
+		logged = dispatch(t, d, db, delivery)
+	}
+	if received.Load() != int32(testConfig.MaxAttempts) {
+		t.Errorf("receiver got %d requests, want %d", received.Load(), testConfig.MaxAttempts)
+	}
+	if logged.Status != models.WebhookDeliveryFailed || logged.Attempts != uint(testConfig.MaxAttempts) || logged.ResponseStatus != http.StatusInternalServerError {
+		t.Errorf("delivery = %s after %d attempts with status %d, want failed after %d", logged.Status, logged.Attempts, logged.ResponseStatus, testConfig.MaxAttempts)
+	}
+}
@@ -0,0 +1,82 @@
+package workers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+)
+
+// ErrNoArtifactStore is returned by RegisterArtifact when the worker has no
+// blob store to keep artifacts in.
+var ErrNoArtifactStore = errors.New("artifact storage is not configured")
+
+var artifactNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,254}$`)
+
+type artifactsKey struct{}
+
+// artifactCollector gathers the artifacts registered while a task runs.
+type artifactCollector struct {
+	store       blob.Store
+	executionID string
+	taskID      string
+
+	mu        sync.Mutex
+	artifacts []events.Artifact
+}
+
+func withArtifacts(ctx context.Context, c *artifactCollector) context.Context {
+	return context.WithValue(ctx, artifactsKey{}, c)
+}
+
+// RegisterArtifact stores data as a named output of the running task. It is
+// called by executors with the context they were given; the artifact is
+// recorded for the execution once the task completes. Registering a name
+// again replaces the earlier artifact.
+func RegisterArtifact(ctx context.Context, name, contentType string, data []byte) (*events.Artifact, error) {
+	c, ok := ctx.Value(artifactsKey{}).(*artifactCollector)
+	if !ok || c.store == nil {
+		return nil, ErrNoArtifactStore
+	}
+	if !artifactNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid artifact name %q", name)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	sum := sha256.Sum256(data)
+	artifact := events.Artifact{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Key:         fmt.Sprintf("%s/artifacts/%s/%s", c.executionID, c.taskID, name),
+	}
+	if err := c.store.Put(ctx, artifact.Key, data, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store artifact %s: %w", name, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.artifacts {
+		if c.artifacts[i].Name == name {
+			c.artifacts[i] = artifact
+			return &artifact, nil
+		}
+	}
+	c.artifacts = append(c.artifacts, artifact)
+	return &artifact, nil
+}
+
+func (c *artifactCollector) list() []events.Artifact {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]events.Artifact(nil), c.artifacts...)
+}
@@ -0,0 +1,49 @@
+package workers
+
+import (
+	"context"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+)
+
+type rendererKey struct{}
+
+var plainRenderer = dsl.NewRenderer(nil)
+
+func withRenderer(ctx context.Context, r *dsl.Renderer) context.Context {
+	return context.WithValue(ctx, rendererKey{}, r)
+}
+
+func rendererFrom(ctx context.Context) *dsl.Renderer {
+	if r, ok := ctx.Value(rendererKey{}).(*dsl.Renderer); ok {
+		return r
+	}
+	return plainRenderer
+}
+
+// TemplateScope returns the scope templates in the params of task are
+// rendered against: the step context (trigger, vars, prev, execution_id)
+// plus the task input as inputs and the step context itself as context.
+func TemplateScope(task *events.TaskEvent) map[string]interface{} {
+	scope := make(map[string]interface{}, len(task.Context)+2)
+	for k, v := range task.Context {
+		scope[k] = v
+	}
+	scope["inputs"] = task.Input
+	scope["context"] = task.Context
+	return scope
+}
+
+// RenderTemplate renders a template from the params of task as text. It is
+// called by executors with the context they were given so that offloaded
+// values referenced by the template are loaded.
+func RenderTemplate(ctx context.Context, task *events.TaskEvent, tmpl string) (string, error) {
+	return rendererFrom(ctx).RenderString(ctx, tmpl, TemplateScope(task))
+}
+
+// RenderParam renders every template inside a param value, keeping the
+// shape of maps and lists.
+func RenderParam(ctx context.Context, task *events.TaskEvent, value interface{}) (interface{}, error) {
+	return rendererFrom(ctx).RenderValue(ctx, value, TemplateScope(task))
+}
@@ -0,0 +1,173 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/internal/events"
+	"github.com/Vighnesh-V-H/async/internal/logger"
+	"github.com/Vighnesh-V-H/async/pkg/blob"
+	"github.com/Vighnesh-V-H/async/pkg/dsl"
+	"github.com/rs/zerolog"
+)
+
+// Executor runs a single task type. The returned map becomes the output of the
+// step and the input of the next one.
+type Executor interface {
+	Execute(ctx context.Context, task *events.TaskEvent) (map[string]interface{}, error)
+}
+
+// ExecutorFunc adapts a plain function to Executor.
+type ExecutorFunc func(ctx context.Context, task *events.TaskEvent) (map[string]interface{}, error)
+
+func (f ExecutorFunc) Execute(ctx context.Context, task *events.TaskEvent) (map[string]interface{}, error) {
+	return f(ctx, task)
+}
+
+// Registry maps task types to their executors.
+type Registry struct {
+	mu        sync.RWMutex
+	executors map[string]Executor
+}
+
+func NewRegistry() *Registry {
+	return &Registry{executors: make(map[string]Executor)}
+}
+
+func (r *Registry) Register(taskType string, executor Executor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executors[taskType] = executor
+}
+
+func (r *Registry) Get(taskType string) (Executor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	executor, ok := r.executors[taskType]
+	return executor, ok
+}
+
+// TaskTypes returns the registered task types.
+func (r *Registry) TaskTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.executors))
+	for taskType := range r.executors {
+		types = append(types, taskType)
+	}
+	return types
+}
+
+// Worker executes task events and reports the outcome as completion events.
+type Worker struct {
+	registry         *Registry
+	producer         *events.EventProducer
+	completionsTopic string
+	claimCheck       *blob.ClaimCheck
+	renderer         *dsl.Renderer
+	logger           zerolog.Logger
+}
+
+func NewWorker(registry *Registry, producer *events.EventProducer, completionsTopic string, logCfg logger.Config) *Worker {
+	return &Worker{
+		registry:         registry,
+		producer:         producer,
+		completionsTopic: completionsTopic,
+		renderer:         plainRenderer,
+		logger:           logger.New(logCfg),
+	}
+}
+
+// SetClaimCheck makes the worker load offloaded inputs before executors run
+// and offload large outputs before they are published, so executors only
+// ever see plain values. Its store also keeps artifacts registered with
+// RegisterArtifact, and templates rendered with RenderTemplate load the
+// offloaded values they reference. Tasks only load values offloaded for
+// their own execution.
+func (w *Worker) SetClaimCheck(claimCheck *blob.ClaimCheck) {
+	w.claimCheck = claimCheck
+}
+
+// HandleTask is an events.TaskHandler. Executor failures are reported to the
+// orchestrator as failed completions; only a failure to publish the completion
+// is returned, so the task is redelivered.
+func (w *Worker) HandleTask(ctx context.Context, task *events.TaskEvent) error {
+	completion := &events.CompletionEvent{
+		ExecutionID: task.ExecutionID,
+		WorkflowID:  task.WorkflowID,
+		TaskID:      task.TaskID,
+		Attempt:     task.Attempt,
+		TaskType:    task.TaskType,
+		Step:        task.Step,
+		Status:      events.CompletionStatusCompleted,
+	}
+
+	artifacts := &artifactCollector{executionID: task.ExecutionID, taskID: task.TaskID}
+	claimCheck, renderer := w.claimCheck, w.renderer
+	if claimCheck != nil {
+		claimCheck = claimCheck.Scoped(task.ExecutionID)
+		renderer = dsl.NewRenderer(claimCheck.ResolveValue)
+		artifacts.store = claimCheck.Store()
+	}
+
+	started := time.Now()
+	output, err := w.execute(withRenderer(withArtifacts(ctx, artifacts), renderer), claimCheck, task)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		w.logger.Error().
+			Err(err).
+			Str("execution_id", task.ExecutionID).
+			Str("task_id", task.TaskID).
+			Str("task_type", task.TaskType).
+			Msg("Task failed")
+		completion.Status = events.CompletionStatusFailed
+		completion.Error = err.Error()
+	} else {
+		if claimCheck != nil {
+			if output, err = claimCheck.Offload(ctx, task.ExecutionID, output); err != nil {
+				// Leave the task for redelivery rather than fail it on a
+				// storage hiccup.
+				return err
+			}
+		}
+		completion.Output = output
+		completion.Artifacts = artifacts.list()
+		w.logger.Info().
+			Str("execution_id", task.ExecutionID).
+			Str("task_id", task.TaskID).
+			Str("task_type", task.TaskType).
+			Dur("duration", time.Since(started)).
+			Msg("Task completed")
+	}
+
+	return w.producer.PublishCompletion(ctx, w.completionsTopic, completion)
+}
+
+func (w *Worker) execute(ctx context.Context, claimCheck *blob.ClaimCheck, task *events.TaskEvent) (output map[string]interface{}, err error) {
+	executor, ok := w.registry.Get(task.TaskType)
+	if !ok {
+		return nil, fmt.Errorf("no executor registered for task type %s", task.TaskType)
+	}
+
+	if claimCheck != nil {
+		input, err := claimCheck.Resolve(ctx, task.Input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load task input: %w", err)
+		}
+		resolved := *task
+		resolved.Input = input
+		task = &resolved
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("executor %s panicked: %v", task.TaskType, r)
+		}
+	}()
+	return executor.Execute(ctx, task)
+}
@@ -0,0 +1,312 @@
+package blob
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RefField is the single key of the map that replaces an offloaded value.
+// The map form survives every event codec unchanged.
+const RefField = "$blob"
+
+// ErrOutOfScope is returned when resolving a reference outside the namespace
+// a claim check is scoped to.
+var ErrOutOfScope = errors.New("blob reference outside its namespace")
+
+const (
+	contentTypeText   = "text/plain; charset=utf-8"
+	contentTypeBinary = "application/octet-stream"
+)
+
+// Ref is the claim check left in place of an offloaded payload.
+type Ref struct {
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	SHA256      string `json:"sha256"`
+}
+
+// Value returns the representation of r stored in inputs and outputs.
+func (r *Ref) Value() map[string]interface{} {
+	return map[string]interface{}{
+		RefField: map[string]interface{}{
+			"key":          r.Key,
+			"size":         r.Size,
+			"content_type": r.ContentType,
+			"sha256":       r.SHA256,
+		},
+	}
+}
+
+// AsRef reports whether v is a claim check and returns it.
+func AsRef(v interface{}) (*Ref, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, false
+	}
+	inner, ok := m[RefField]
+	if !ok {
+		return nil, false
+	}
+	raw, err := json.Marshal(inner)
+	if err != nil {
+		return nil, false
+	}
+	var ref Ref
+	if err := json.Unmarshal(raw, &ref); err != nil || ref.Key == "" {
+		return nil, false
+	}
+	return &ref, true
+}
+
+// HasRefs reports whether v holds a claim check at any depth, or anything
+// posing as one. Payloads from outside, such as API requests, must not, or
+// they could make workers load blobs of other executions.
+func HasRefs(v interface{}) bool {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if _, ok := x[RefField]; ok {
+			return true
+		}
+		for _, item := range x {
+			if HasRefs(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range x {
+			if HasRefs(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ClaimCheck moves large values out of task inputs and outputs into a Store
+// and puts them back on the way in.
+type ClaimCheck struct {
+	store     Store
+	threshold int
+	scope     string
+}
+
+// NewClaimCheck offloads strings and byte slices longer than threshold bytes.
+func NewClaimCheck(store Store, threshold int) *ClaimCheck {
+	return &ClaimCheck{store: store, threshold: threshold}
+}
+
+// Scoped returns a claim check that only resolves references stored under
+// namespace, such as the execution a task belongs to.
+func (c *ClaimCheck) Scoped(namespace string) *ClaimCheck {
+	scoped := *c
+	scoped.scope = strings.Trim(namespace, "/")
+	return &scoped
+}
+
+// Store returns the underlying blob store.
+func (c *ClaimCheck) Store() Store {
+	return c.store
+}
+
+// Offload returns a copy of values with every large string or byte slice,
+// at any depth, stored under namespace and replaced by its Ref.
+func (c *ClaimCheck) Offload(ctx context.Context, namespace string, values map[string]interface{}) (map[string]interface{}, error) {
+	if values == nil {
+		return nil, nil
+	}
+	out, err := c.offload(ctx, namespace, values)
+	if err != nil {
+		return nil, err
+	}
+	return out.(map[string]interface{}), nil
+}
+
+func (c *ClaimCheck) offload(ctx context.Context, namespace string, v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		if len(x) > c.threshold {
+			return c.put(ctx, namespace, []byte(x), contentTypeText)
+		}
+		return x, nil
+	case []byte:
+		if len(x) > c.threshold {
+			return c.put(ctx, namespace, x, contentTypeBinary)
+		}
+		return x, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			converted, err := c.offload(ctx, namespace, item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = converted
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			converted, err := c.offload(ctx, namespace, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = converted
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func (c *ClaimCheck) put(ctx context.Context, namespace string, data []byte, contentType string) (map[string]interface{}, error) {
+	ref := &Ref{
+		Key:         strings.Trim(namespace, "/") + "/" + uuid.NewString(),
+		Size:        int64(len(data)),
+		ContentType: contentType,
+		SHA256:      sha256Hex(data),
+	}
+	if err := c.store.Put(ctx, ref.Key, data, contentType); err != nil {
+		return nil, fmt.Errorf("failed to offload payload: %w", err)
+	}
+	return ref.Value(), nil
+}
+
+// Resolve returns a copy of values with every Ref replaced by the stored
+// payload: a string for offloaded text, a byte slice otherwise.
+func (c *ClaimCheck) Resolve(ctx context.Context, values map[string]interface{}) (map[string]interface{}, error) {
+	if values == nil {
+		return nil, nil
+	}
+	out, err := c.ResolveValue(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+	return out.(map[string]interface{}), nil
+}
+
+// ResolveValue is Resolve for a single value of any shape.
+func (c *ClaimCheck) ResolveValue(ctx context.Context, v interface{}) (interface{}, error) {
+	if ref, ok := AsRef(v); ok {
+		data, err := c.Load(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if ref.ContentType == contentTypeText {
+			return string(data), nil
+		}
+		return data, nil
+	}
+
+	switch x := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			resolved, err := c.ResolveValue(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			resolved, err := c.ResolveValue(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// Load fetches the payload behind ref and verifies its checksum, which every
+// reference must carry. A scoped claim check only loads references under its
+// namespace.
+func (c *ClaimCheck) Load(ctx context.Context, ref *Ref) ([]byte, error) {
+	if c.scope != "" && !strings.HasPrefix(ref.Key, c.scope+"/") {
+		return nil, fmt.Errorf("%w: %s", ErrOutOfScope, ref.Key)
+	}
+	return c.load(ctx, ref)
+}
+
+func (c *ClaimCheck) load(ctx context.Context, ref *Ref) ([]byte, error) {
+	if ref.SHA256 == "" {
+		return nil, fmt.Errorf("blob %s has no checksum", ref.Key)
+	}
+	data, err := c.store.Get(ctx, ref.Key)
+	if err != nil {
+		return nil, err
+	}
+	if sha256Hex(data) != ref.SHA256 {
+		return nil, fmt.Errorf("blob %s does not match its checksum", ref.Key)
+	}
+	return data, nil
+}
+
+// Adopt returns a copy of values with every reference outside the namespace
+// of a scoped claim check copied into it, for values handed from one
+// execution to another such as the input and output of a subworkflow.
+// Copies are keyed by checksum, so adopting again stores nothing new.
+func (c *ClaimCheck) Adopt(ctx context.Context, values map[string]interface{}) (map[string]interface{}, error) {
+	if values == nil {
+		return nil, nil
+	}
+	out, err := c.adopt(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+	return out.(map[string]interface{}), nil
+}
+
+func (c *ClaimCheck) adopt(ctx context.Context, v interface{}) (interface{}, error) {
+	if ref, ok := AsRef(v); ok {
+		if strings.HasPrefix(ref.Key, c.scope+"/") {
+			return v, nil
+		}
+		data, err := c.load(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		adopted := *ref
+		adopted.Key = c.scope + "/" + ref.SHA256
+		if err := c.store.Put(ctx, adopted.Key, data, ref.ContentType); err != nil {
+			return nil, fmt.Errorf("failed to copy payload: %w", err)
+		}
+		return adopted.Value(), nil
+	}
+
+	switch x := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			adopted, err := c.adopt(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = adopted
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			adopted, err := c.adopt(ctx, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = adopted
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
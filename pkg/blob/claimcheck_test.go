@@ -0,0 +1,239 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestClaimCheck(t *testing.T) (*ClaimCheck, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewClaimCheck(store, 16), dir
+}
+
+func TestClaimCheckOffloadsLargeValues(t *testing.T) {
+	c, _ := newTestClaimCheck(t)
+	ctx := context.Background()
+
+	transcript := strings.Repeat("hello world ", 10)
+	audio := bytes.Repeat([]byte{0x52, 0x49, 0x46, 0x46}, 8)
+	values := map[string]interface{}{
+		"title":      "short",
+		"transcript": transcript,
+		"count":      3,
+		"nested": map[string]interface{}{
+			"audio": audio,
+			"parts": []interface{}{"tiny", transcript},
+		},
+	}
+
+	offloaded, err := c.Offload(ctx, "/executions/exec-1/", values)
+	if err != nil {
+		t.Fatalf("Offload: %v", err)
+	}
+
+	if offloaded["title"] != "short" || offloaded["count"] != 3 {
+		t.Errorf("small values changed: title %v, count %v", offloaded["title"], offloaded["count"])
+	}
+	ref, ok := AsRef(offloaded["transcript"])
+	if !ok {
+		t.Fatalf("transcript = %v, want a claim check", offloaded["transcript"])
+	}
+	if !strings.HasPrefix(ref.Key, "executions/exec-1/") {
+		t.Errorf("key = %q, want it under the trimmed namespace", ref.Key)
+	}
+	if ref.Size != int64(len(transcript)) || ref.ContentType != contentTypeText || ref.SHA256 != sha256Hex([]byte(transcript)) {
+		t.Errorf("ref = %+v, want the size, text content type and checksum of the transcript", ref)
+	}
+
+	nested := offloaded["nested"].(map[string]interface{})
+	if ref, ok := AsRef(nested["audio"]); !ok || ref.ContentType != contentTypeBinary {
+		t.Errorf("nested audio = %v, want a binary claim check", nested["audio"])
+	}
+	parts := nested["parts"].([]interface{})
+	if parts[0] != "tiny" {
+		t.Errorf("parts[0] = %v, want it kept inline", parts[0])
+	}
+	if _, ok := AsRef(parts[1]); !ok {
+		t.Errorf("parts[1] = %v, want a claim check", parts[1])
+	}
+
+	if values["transcript"] != transcript {
+		t.Error("Offload modified its input")
+	}
+
+	resolved, err := c.Resolve(ctx, offloaded)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if !reflect.DeepEqual(resolved, values) {
+		t.Errorf("Resolve = %v, want %v", resolved, values)
+	}
+}
+
+func TestClaimCheckResolveValue(t *testing.T) {
+	c, _ := newTestClaimCheck(t)
+	ctx := context.Background()
+
+	text := strings.Repeat("x", 32)
+	offloaded, err := c.Offload(ctx, "ns", map[string]interface{}{"text": text})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.ResolveValue(ctx, []interface{}{offloaded["text"], 1.5})
+	if err != nil {
+		t.Fatalf("ResolveValue: %v", err)
+	}
+	if want := []interface{}{text, 1.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveValue = %v, want %v", got, want)
+	}
+}
+
+func TestClaimCheckRejectsTamperedContent(t *testing.T) {
+	c, dir := newTestClaimCheck(t)
+	ctx := context.Background()
+
+	offloaded, err := c.Offload(ctx, "ns", map[string]interface{}{"text": strings.Repeat("a", 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := AsRef(offloaded["text"])
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(ref.Key)), []byte(strings.Repeat("b", 32)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Load(ctx, ref); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Load = %v, want a checksum error", err)
+	}
+	if _, err := c.Resolve(ctx, offloaded); err == nil {
+		t.Error("Resolve succeeded on tampered content")
+	}
+}
+
+func TestAsRef(t *testing.T) {
+	ref := &Ref{Key: "ns/1", Size: 4, ContentType: contentTypeText, SHA256: "abc"}
+	if got, ok := AsRef(ref.Value()); !ok || *got != *ref {
+		t.Errorf("AsRef(ref.Value()) = %+v, %v; want %+v", got, ok, ref)
+	}
+
+	for name, v := range map[string]interface{}{
+		"string":     "ns/1",
+		"extra keys": map[string]interface{}{RefField: map[string]interface{}{"key": "ns/1"}, "other": 1},
+		"no key":     map[string]interface{}{RefField: map[string]interface{}{"size": 4}},
+		"other map":  map[string]interface{}{"key": "ns/1"},
+	} {
+		if _, ok := AsRef(v); ok {
+			t.Errorf("AsRef(%s) = true, want false", name)
+		}
+	}
+}
+
+func TestClaimCheckRequiresChecksum(t *testing.T) {
+	c, _ := newTestClaimCheck(t)
+	ctx := context.Background()
+
+	offloaded, err := c.Offload(ctx, "ns", map[string]interface{}{"text": strings.Repeat("a", 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, _ := AsRef(offloaded["text"])
+	ref.SHA256 = ""
+	if _, err := c.Load(ctx, ref); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Load = %v, want a reference without checksum rejected", err)
+	}
+}
+
+func TestScopedClaimCheckOnlyResolvesItsNamespace(t *testing.T) {
+	c, _ := newTestClaimCheck(t)
+	ctx := context.Background()
+
+	own, err := c.Offload(ctx, "exec-1", map[string]interface{}{"text": strings.Repeat("a", 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := c.Offload(ctx, "exec-10", map[string]interface{}{"text": strings.Repeat("b", 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scoped := c.Scoped("exec-1")
+	if got, err := scoped.Resolve(ctx, own); err != nil || got["text"] != strings.Repeat("a", 32) {
+		t.Errorf("Resolve own = %v, %v; want the payload", got, err)
+	}
+	if _, err := scoped.Resolve(ctx, other); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("Resolve other = %v, want ErrOutOfScope", err)
+	}
+	if _, err := scoped.ResolveValue(ctx, []interface{}{other["text"]}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("ResolveValue nested = %v, want ErrOutOfScope", err)
+	}
+}
+
+func TestClaimCheckAdopt(t *testing.T) {
+	c, _ := newTestClaimCheck(t)
+	ctx := context.Background()
+
+	text := strings.Repeat("a", 32)
+	parent, err := c.Offload(ctx, "parent", map[string]interface{}{"text": text, "small": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	child := c.Scoped("child")
+	adopted, err := child.Adopt(ctx, parent)
+	if err != nil {
+		t.Fatalf("Adopt: %v", err)
+	}
+	ref, ok := AsRef(adopted["text"])
+	if !ok || !strings.HasPrefix(ref.Key, "child/") {
+		t.Fatalf("adopted = %v, want a reference under child/", adopted["text"])
+	}
+	if got, err := child.Resolve(ctx, adopted); err != nil || got["text"] != text || got["small"] != "x" {
+		t.Errorf("Resolve adopted = %v, %v; want the parent payload", got, err)
+	}
+
+	again, err := child.Adopt(ctx, parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, adopted) {
+		t.Errorf("Adopt again = %v, want the same copy %v", again, adopted)
+	}
+	if kept, err := child.Adopt(ctx, adopted); err != nil || !reflect.DeepEqual(kept, adopted) {
+		t.Errorf("Adopt own = %v, %v; want it unchanged", kept, err)
+	}
+
+	forged := map[string]interface{}{"text": (&Ref{Key: "parent/missing", ContentType: contentTypeText}).Value()}
+	if _, err := child.Adopt(ctx, forged); err == nil {
+		t.Error("Adopt copied a reference without checksum")
+	}
+}
+
+func TestHasRefs(t *testing.T) {
+	for name, tt := range map[string]struct {
+		v    interface{}
+		want bool
+	}{
+		"plain":      {map[string]interface{}{"a": "b", "n": []interface{}{1.0, "x"}}, false},
+		"ref":        {(&Ref{Key: "ns/1"}).Value(), true},
+		"nested":     {map[string]interface{}{"meta": map[string]interface{}{"x": (&Ref{Key: "ns/1"}).Value()}}, true},
+		"in a list":  {map[string]interface{}{"items": []interface{}{(&Ref{Key: "ns/1"}).Value()}}, true},
+		"posing":     {map[string]interface{}{"meta": map[string]interface{}{RefField: "ns/1", "other": 1}}, true},
+		"nil":        {nil, false},
+		"bare value": {"$blob", false},
+	} {
+		if got := HasRefs(tt.v); got != tt.want {
+			t.Errorf("HasRefs(%s) = %v, want %v", name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Deduper remembers processed message IDs in Redis for a limited time so
+// redelivered messages can be dropped before touching the database.
+type Deduper struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+func NewDeduper(client *redis.Client, prefix string, ttl time.Duration) *Deduper {
+	return &Deduper{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (d *Deduper) key(id string) string {
+	return d.prefix + ":" + id
+}
+
+// Seen reports whether id has already been marked as processed.
+func (d *Deduper) Seen(ctx context.Context, id string) (bool, error) {
+	n, err := d.client.Exists(ctx, d.key(id)).Result()
+	if err != nil {
+		return false, fmt.Errorf("dedupe lookup failed: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Mark records id as processed. It should only be called once processing has
+// been committed, otherwise a failed attempt would suppress its own retry.
+func (d *Deduper) Mark(ctx context.Context, id string) error {
+	if err := d.client.Set(ctx, d.key(id), 1, d.ttl).Err(); err != nil {
+		return fmt.Errorf("dedupe mark failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PubSub fans messages out to every subscriber of a channel across
+// processes using Redis pub/sub. Delivery is best effort: subscribers that
+// are not connected when a message is published never see it.
+type PubSub struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewPubSub(client *redis.Client, prefix string) *PubSub {
+	return &PubSub{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (p *PubSub) channel(name string) string {
+	return p.prefix + ":" + name
+}
+
+// Publish sends payload to the current subscribers of channel.
+func (p *PubSub) Publish(ctx context.Context, channel string, payload []byte) error {
+	if err := p.client.Publish(ctx, p.channel(channel), payload).Err(); err != nil {
+		return fmt.Errorf("publish to %s failed: %w", channel, err)
+	}
+	return nil
+}
+
+// Subscribe listens on channel until ctx is done or the returned close
+// function is called. The subscription is active when Subscribe returns, so
+// nothing published afterwards is missed.
+func (p *PubSub) Subscribe(ctx context.Context, channel string) (<-chan []byte, func() error, error) {
+	sub := p.client.Subscribe(ctx, p.channel(channel))
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, nil, fmt.Errorf("subscribe to %s failed: %w", channel, err)
+	}
+
+	out := make(chan []byte, 16)
+	messages := sub.Channel()
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, sub.Close, nil
+}
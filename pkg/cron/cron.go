@@ -0,0 +1,245 @@
+// Package cron parses cron expressions and computes when they next fire.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Time zones resolve even on hosts without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// Schedule yields the activation times of a cron expression or interval.
+type Schedule interface {
+	// Next returns the first activation strictly after t, in the location
+	// of t, or the zero time if there is none within five years.
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a standard five field cron expression (minute, hour, day of
+// month, month, day of week), one of the macros @yearly, @annually,
+// @monthly, @weekly, @daily, @midnight and @hourly, or "@every <duration>".
+// Fields accept *, values, ranges, lists and steps; months and weekdays
+// accept their three letter English names.
+//
+// Times are wall clock times in the location Next is given. Times skipped
+// when clocks go forward do not fire, and times repeated when they go back
+// fire once, unless the hour field is * and the schedule runs every hour.
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, errors.New("empty cron expression")
+	}
+
+	if strings.HasPrefix(expr, "@") {
+		if rest, ok := strings.CutPrefix(expr, "@every "); ok {
+			d, err := time.ParseDuration(strings.TrimSpace(rest))
+			if err != nil {
+				return nil, fmt.Errorf("invalid interval in %q: %w", expr, err)
+			}
+			return Every(d)
+		}
+		macro, ok := macros[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cron macro %q", expr)
+		}
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q has %d fields, expected 5", expr, len(fields))
+	}
+
+	var s spec
+	var err error
+	if s.minute, _, err = parseField(fields[0], minutes); err != nil {
+		return nil, err
+	}
+	if s.hour, s.hourStar, err = parseField(fields[1], hours); err != nil {
+		return nil, err
+	}
+	if s.dom, s.domStar, err = parseField(fields[2], daysOfMonth); err != nil {
+		return nil, err
+	}
+	if s.month, _, err = parseField(fields[3], months); err != nil {
+		return nil, err
+	}
+	if s.dow, s.dowStar, err = parseField(fields[4], daysOfWeek); err != nil {
+		return nil, err
+	}
+	// 7 is Sunday as well
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return &s, nil
+}
+
+// Every returns a schedule that fires every d, which must be at least a
+// second.
+func Every(d time.Duration) (Schedule, error) {
+	if d < time.Second {
+		return nil, fmt.Errorf("interval %s is shorter than a second", d)
+	}
+	return interval(d.Truncate(time.Second)), nil
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type interval time.Duration
+
+func (d interval) Next(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(time.Duration(d))
+}
+
+// spec holds the set of allowed values of every field as a bit mask.
+type spec struct {
+	minute, hour, dom, month, dow uint64
+	// Set when the day fields were *, which changes how they combine
+	domStar, dowStar bool
+	// Set when the hour field was *, so repeated hours fire again
+	hourStar bool
+}
+
+func (s *spec) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+
+	for t.Year() <= limit {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			if !next.After(t) {
+				// The hour repeats when clocks go back
+				next = t.Truncate(time.Hour).Add(time.Hour)
+			}
+			t = next
+		case s.minute&(1<<uint(t.Minute())) == 0 || (!s.hourStar && repeated(t)):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// repeated reports whether the wall clock time of t already passed earlier
+// that day, because clocks went back since.
+func repeated(t time.Time) bool {
+	_, offset := t.Zone()
+	// Clocks never go back by more than two hours
+	_, before := t.Add(-2 * time.Hour).Zone()
+	if before <= offset {
+		return false
+	}
+	earlier := t.Add(-time.Duration(before-offset) * time.Second)
+	return earlier.Day() == t.Day() && earlier.Hour() == t.Hour() && earlier.Minute() == t.Minute()
+}
+
+// dayMatches follows the cron convention: when both day fields are
+// restricted, a day matching either of them matches.
+func (s *spec) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+type bounds struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minutes     = bounds{name: "minute", min: 0, max: 59}
+	hours       = bounds{name: "hour", min: 0, max: 23}
+	daysOfMonth = bounds{name: "day of month", min: 1, max: 31}
+	months      = bounds{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	daysOfWeek = bounds{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// parseField returns the values a field allows and whether it was *.
+func parseField(field string, b bounds) (uint64, bool, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		lo, hi, step := b.min, b.max, 1
+
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, false, fmt.Errorf("invalid step %q in %s field %q", stepText, b.name, field)
+			}
+			step = n
+		}
+
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			from, to, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = b.value(from); err != nil {
+				return 0, false, err
+			}
+			if hi, err = b.value(to); err != nil {
+				return 0, false, err
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("empty range %q in %s field", rng, b.name)
+			}
+		default:
+			v, err := b.value(rng)
+			if err != nil {
+				return 0, false, err
+			}
+			lo = v
+			if !hasStep {
+				// A value with a step runs up to the maximum
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, field == "*" || field == "?", nil
+}
+
+func (b bounds) value(text string) (int, error) {
+	if v, ok := b.names[strings.ToLower(text)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", b.name, text)
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("%s %d out of range %d-%d", b.name, v, b.min, b.max)
+	}
+	return v, nil
+}
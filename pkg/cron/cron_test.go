@@ -0,0 +1,183 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		tz   string
+		from string
+		want []string
+	}{
+		{
+			name: "steps",
+			expr: "*/15 * * * *",
+			from: "2026-10-19T10:07:30Z",
+			want: []string{"2026-10-19T10:15:00Z", "2026-10-19T10:30:00Z", "2026-10-19T10:45:00Z", "2026-10-19T11:00:00Z"},
+		},
+		{
+			name: "value with step runs to the maximum",
+			expr: "5/20 * * * *",
+			from: "2026-10-19T10:00:00Z",
+			want: []string{"2026-10-19T10:05:00Z", "2026-10-19T10:25:00Z", "2026-10-19T10:45:00Z", "2026-10-19T11:05:00Z"},
+		},
+		{
+			name: "range with step",
+			expr: "0 8-18/5 * * *",
+			from: "2026-10-19T00:00:00Z",
+			want: []string{"2026-10-19T08:00:00Z", "2026-10-19T13:00:00Z", "2026-10-19T18:00:00Z", "2026-10-20T08:00:00Z"},
+		},
+		{
+			name: "next is strictly after",
+			expr: "0 9 * * *",
+			from: "2026-10-19T09:00:00Z",
+			want: []string{"2026-10-20T09:00:00Z"},
+		},
+		{
+			name: "weekdays",
+			expr: "0 9 * * mon-fri",
+			from: "2026-10-16T10:00:00Z",
+			want: []string{"2026-10-19T09:00:00Z", "2026-10-20T09:00:00Z"},
+		},
+		{
+			name: "7 is sunday",
+			expr: "0 0 * * 7",
+			from: "2026-10-19T00:00:00Z",
+			want: []string{"2026-10-25T00:00:00Z", "2026-11-01T00:00:00Z"},
+		},
+		{
+			name: "day of month or day of week",
+			expr: "0 0 13 * fri",
+			from: "2026-11-05T00:00:00Z",
+			want: []string{"2026-11-06T00:00:00Z", "2026-11-13T00:00:00Z", "2026-11-20T00:00:00Z", "2026-11-27T00:00:00Z", "2026-12-04T00:00:00Z", "2026-12-11T00:00:00Z", "2026-12-13T00:00:00Z"},
+		},
+		{
+			name: "day of month and any weekday",
+			expr: "0 0 31 * *",
+			from: "2026-10-31T12:00:00Z",
+			want: []string{"2026-12-31T00:00:00Z", "2027-01-31T00:00:00Z", "2027-03-31T00:00:00Z"},
+		},
+		{
+			name: "month names",
+			expr: "0 0 1 jan,jul *",
+			from: "2026-10-19T00:00:00Z",
+			want: []string{"2027-01-01T00:00:00Z", "2027-07-01T00:00:00Z"},
+		},
+		{
+			name: "macro",
+			expr: "@weekly",
+			from: "2026-10-19T00:00:00Z",
+			want: []string{"2026-10-25T00:00:00Z"},
+		},
+		{
+			name: "interval",
+			expr: "@every 90s",
+			from: "2026-10-19T10:00:00.5Z",
+			want: []string{"2026-10-19T10:01:30Z", "2026-10-19T10:03:00Z"},
+		},
+		{
+			name: "never",
+			expr: "0 0 30 2 *",
+			from: "2026-10-19T00:00:00Z",
+			want: []string{"0001-01-01T00:00:00Z"},
+		},
+		{
+			name: "time zone",
+			expr: "0 3 * * *",
+			tz:   "Europe/Berlin",
+			from: "2026-10-19T00:00:00Z",
+			want: []string{"2026-10-19T01:00:00Z", "2026-10-20T01:00:00Z"},
+		},
+		{
+			name: "repeated hour fires once",
+			expr: "30 1 * * *",
+			tz:   "America/New_York",
+			from: "2026-11-01T04:00:00Z",
+			want: []string{"2026-11-01T01:30:00-04:00", "2026-11-02T01:30:00-05:00"},
+		},
+		{
+			name: "repeated hour of an hourly schedule fires again",
+			expr: "*/30 * * * *",
+			tz:   "America/New_York",
+			from: "2026-11-01T04:45:00Z",
+			want: []string{"2026-11-01T01:00:00-04:00", "2026-11-01T01:30:00-04:00", "2026-11-01T01:00:00-05:00", "2026-11-01T01:30:00-05:00", "2026-11-01T02:00:00-05:00"},
+		},
+		{
+			name: "repeated hour of a schedule restricted to it",
+			expr: "*/30 1 * * *",
+			tz:   "America/New_York",
+			from: "2026-11-01T04:45:00Z",
+			want: []string{"2026-11-01T01:00:00-04:00", "2026-11-01T01:30:00-04:00", "2026-11-02T01:00:00-05:00"},
+		},
+		{
+			name: "skipped hour does not fire",
+			expr: "30 2 * * *",
+			tz:   "America/New_York",
+			from: "2026-03-08T05:00:00Z",
+			want: []string{"2026-03-09T02:30:00-04:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			loc := time.UTC
+			if tt.tz != "" {
+				if loc, err = time.LoadLocation(tt.tz); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			at := mustTime(t, tt.from).In(loc)
+			for _, w := range tt.want {
+				want := mustTime(t, w)
+				at = sched.Next(at)
+				if !at.Equal(want) {
+					t.Fatalf("Next = %s, want %s", at, want)
+				}
+				if !at.IsZero() && at.Location() != loc {
+					t.Fatalf("Next is in %s, want %s", at.Location(), loc)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@often",
+		"@every 500ms",
+		"@every soon",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func mustTime(t *testing.T, text string) time.Time {
+	t.Helper()
+	at, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return at
+}
@@ -0,0 +1,107 @@
+// Package databasetest provides migrated Postgres databases to tests.
+package databasetest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// EnvURL names the variable holding the postgres:// URL of the database
+// tests run against.
+const EnvURL = "ASYNC_TEST_DATABASE_URL"
+
+// Open returns a connection to a fresh schema of the database at EnvURL with
+// every migration applied. The schema is dropped when the test ends. Tests
+// are skipped when EnvURL is unset.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	rawURL := os.Getenv(EnvURL)
+	if rawURL == "" {
+		t.Skipf("%s is not set", EnvURL)
+	}
+
+	admin, err := gorm.Open(postgres.Open(rawURL), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	adminDB, err := admin.DB()
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { adminDB.Close() })
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatalf("generate schema name: %v", err)
+	}
+	schema := "test_" + hex.EncodeToString(suffix)
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	})
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse %s: %v", EnvURL, err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	db, err := gorm.Open(postgres.Open(u.String()), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("open schema: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open schema: %v", err)
+	}
+	// Registered after the drop, so it runs before it
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// migrate applies the up migrations of pkg/database/migrations in order.
+func migrate(db *gorm.DB) error {
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Join(filepath.Dir(file), "..", "migrations")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, path := range files {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		up, _, _ := strings.Cut(string(raw), "-- +goose Down")
+		if err := db.Exec(up).Error; err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Every dispatched task carries a unique task_id so duplicate completions can be detected
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS task_id VARCHAR(100);
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS attempt SMALLINT NOT NULL DEFAULT 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_task_id ON tasks(task_id);
+
+-- A step can only be dispatched once per attempt
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_instance_step_attempt ON tasks(instance_id, step_id, attempt);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_tasks_instance_step_attempt;
+DROP INDEX IF EXISTS idx_tasks_task_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS attempt;
+ALTER TABLE tasks DROP COLUMN IF EXISTS task_id;
+
+-- +goose StatementEnd
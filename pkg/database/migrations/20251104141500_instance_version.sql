@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Optimistic concurrency: every update must match and bump the version
+ALTER TABLE workflow_instances ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE workflow_instances DROP COLUMN IF EXISTS version;
+
+-- +goose StatementEnd
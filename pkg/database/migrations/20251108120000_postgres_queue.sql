@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Tasks double as queue entries for the postgres transport: message holds the
+-- encoded task event until a worker acknowledges it, timeout_at is the
+-- visibility deadline of the current claim
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS topic VARCHAR(255);
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS message_key BYTEA;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS message BYTEA;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS message_headers JSONB;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS deliveries INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS claimed_by VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_queue ON tasks(topic, timeout_at, id) WHERE message IS NOT NULL;
+
+-- Every other event (completions, dead letters, control) waits here
+CREATE TABLE IF NOT EXISTS queue_messages (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    message_key BYTEA,
+    message BYTEA NOT NULL,
+    message_headers JSONB,
+    deliveries INTEGER NOT NULL DEFAULT 0,
+    claimed_by VARCHAR(255),
+    visible_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_queue_messages_topic_visible ON queue_messages(topic, visible_at, id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS queue_messages;
+DROP INDEX IF EXISTS idx_tasks_queue;
+ALTER TABLE tasks DROP COLUMN IF EXISTS claimed_by;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deliveries;
+ALTER TABLE tasks DROP COLUMN IF EXISTS message_headers;
+ALTER TABLE tasks DROP COLUMN IF EXISTS message;
+ALTER TABLE tasks DROP COLUMN IF EXISTS message_key;
+ALTER TABLE tasks DROP COLUMN IF EXISTS topic;
+
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Error reported by the worker for failed tasks
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS error TEXT;
+
+-- Set once an instance reaches a terminal status
+ALTER TABLE workflow_instances ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_instance_step ON tasks(instance_id, step_id, attempt);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_tasks_instance_step;
+ALTER TABLE workflow_instances DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS error;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Configuration of the workflow state behind a task and the scope its
+-- templates are rendered against, kept so redispatched tasks are identical
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS params JSONB;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS context JSONB;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS context;
+ALTER TABLE tasks DROP COLUMN IF EXISTS params;
+
+-- +goose StatementEnd
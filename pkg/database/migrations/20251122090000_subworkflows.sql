@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Child executions started by subworkflow states point at the execution and
+-- the task waiting for them. A task starts at most one child.
+ALTER TABLE workflow_instances ADD COLUMN IF NOT EXISTS parent_execution_id VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE workflow_instances ADD COLUMN IF NOT EXISTS parent_task_id VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_workflow_instances_parent_execution_id
+    ON workflow_instances (parent_execution_id)
+    WHERE parent_execution_id <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workflow_instances_parent_task_id
+    ON workflow_instances (parent_task_id)
+    WHERE parent_task_id <> '';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_workflow_instances_parent_task_id;
+DROP INDEX IF EXISTS idx_workflow_instances_parent_execution_id;
+ALTER TABLE workflow_instances DROP COLUMN IF EXISTS parent_task_id;
+ALTER TABLE workflow_instances DROP COLUMN IF EXISTS parent_execution_id;
+
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Tasks run to undo a completed step after a later step failed
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS compensation BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS compensation;
+
+-- +goose StatementEnd
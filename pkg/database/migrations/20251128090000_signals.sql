@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Signals sent to executions, buffered until a wait_for_signal task claims
+-- them. A task claims at most one signal.
+CREATE TABLE IF NOT EXISTS signals (
+    id SERIAL PRIMARY KEY,
+    instance_id INTEGER NOT NULL REFERENCES workflow_instances(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    payload JSONB,
+    task_id VARCHAR(100) NOT NULL DEFAULT '',
+    consumed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_signals_pending
+    ON signals (instance_id, name, id)
+    WHERE task_id = '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signals_task_id
+    ON signals (task_id)
+    WHERE task_id <> '';
+
+-- Tasks the orchestrator waits on give up at their deadline
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS deadline_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_deadline_at
+    ON tasks (deadline_at)
+    WHERE deadline_at IS NOT NULL AND status = 'DISPATCHED';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_tasks_deadline_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deadline_at;
+DROP TABLE IF EXISTS signals;
+
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Decisions requested by approval states. A task requests at most one.
+CREATE TABLE IF NOT EXISTS approvals (
+    id SERIAL PRIMARY KEY,
+    approval_id VARCHAR(100) NOT NULL UNIQUE,
+    instance_id INTEGER NOT NULL REFERENCES workflow_instances(id) ON DELETE CASCADE,
+    execution_id VARCHAR(100) NOT NULL,
+    task_id VARCHAR(100) NOT NULL UNIQUE,
+    state VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(50) NOT NULL,
+    assignees JSONB,
+    context JSONB,
+    deadline_at TIMESTAMP,
+    escalated_at TIMESTAMP,
+    decided_by VARCHAR(255) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_approvals_instance_id ON approvals (instance_id);
+CREATE INDEX IF NOT EXISTS idx_approvals_status ON approvals (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_approvals_assignees ON approvals USING GIN (assignees);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS approvals;
+
+-- +goose StatementEnd
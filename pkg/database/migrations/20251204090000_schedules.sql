@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Schedule triggers of the workflow definitions, synced by the scheduler.
+CREATE TABLE IF NOT EXISTS schedules (
+    id SERIAL PRIMARY KEY,
+    schedule_id VARCHAR(255) NOT NULL UNIQUE,
+    workflow_name VARCHAR(255) NOT NULL,
+    cron VARCHAR(255) NOT NULL,
+    timezone VARCHAR(100) NOT NULL DEFAULT '',
+    jitter BIGINT NOT NULL DEFAULT 0,
+    overlap VARCHAR(20) NOT NULL,
+    catch_up VARCHAR(20) NOT NULL,
+    payload JSONB,
+    paused BOOLEAN NOT NULL DEFAULT FALSE,
+    next_run_at TIMESTAMP NOT NULL,
+    fire_at TIMESTAMP NOT NULL,
+    buffered INTEGER NOT NULL DEFAULT 0,
+    last_run_at TIMESTAMP,
+    last_execution_id VARCHAR(100) NOT NULL DEFAULT '',
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_workflow_name ON schedules (workflow_name);
+CREATE INDEX IF NOT EXISTS idx_schedules_fire_at ON schedules (fire_at) WHERE NOT paused;
+
+-- Leases held by one orchestrator replica at a time, such as the one of the
+-- replica firing schedules.
+CREATE TABLE IF NOT EXISTS leases (
+    name VARCHAR(100) PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS leases;
+DROP TABLE IF EXISTS schedules;
+
+-- +goose StatementEnd
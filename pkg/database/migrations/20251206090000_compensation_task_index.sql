@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A compensation task shares the step and attempt of the task it undoes
+DROP INDEX IF EXISTS idx_tasks_instance_step_attempt;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_instance_step_attempt ON tasks(instance_id, step_id, attempt, compensation);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_tasks_instance_step_attempt;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_instance_step_attempt ON tasks(instance_id, step_id, attempt);
+
+-- +goose StatementEnd
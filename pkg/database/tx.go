@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// Tx is a transaction carried in a context, with the work to do once it
+// commits.
+type Tx struct {
+	db *gorm.DB
+
+	mu          sync.Mutex
+	afterCommit []func()
+}
+
+// WithTx returns a context carrying tx, so repositories handed the context
+// run their statements in it. Call Committed on the returned Tx once tx
+// commits.
+func WithTx(ctx context.Context, tx *gorm.DB) (context.Context, *Tx) {
+	t := &Tx{db: tx}
+	return context.WithValue(ctx, txKey{}, t), t
+}
+
+// Conn returns the transaction ctx carries, or db otherwise, bound to ctx.
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if t, ok := ctx.Value(txKey{}).(*Tx); ok {
+		return t.db.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// AfterCommit runs fn once the transaction ctx carries commits, or right
+// away without one. It is for effects outside the database, which must not
+// outlive a transaction that rolls back.
+func AfterCommit(ctx context.Context, fn func()) {
+	t, ok := ctx.Value(txKey{}).(*Tx)
+	if !ok {
+		fn()
+		return
+	}
+	t.mu.Lock()
+	t.afterCommit = append(t.afterCommit, fn)
+	t.mu.Unlock()
+}
+
+// Committed runs the work queued with AfterCommit.
+func (t *Tx) Committed() {
+	t.mu.Lock()
+	fns := t.afterCommit
+	t.afterCommit = nil
+	t.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
@@ -0,0 +1,368 @@
+package dsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Condition is a boolean expression over a template scope, such as
+// message.value.size > 1024 && message.value.type == 'audio'. Operands are
+// dotted paths, single or double quoted strings, numbers, true, false and
+// null. Comparisons are ==, !=, <, <=, > and >=; conditions combine with &&,
+// || and ! and group with parentheses. A bare operand holds when it is set
+// and not false, zero or empty. The whole expression may be wrapped in
+// {{ }} like a placeholder.
+type Condition struct {
+	expr string
+	root node
+}
+
+// ParseCondition parses a condition.
+func ParseCondition(expr string) (*Condition, error) {
+	text := strings.TrimSpace(expr)
+	if inner, ok := strings.CutPrefix(text, "{{"); ok {
+		if inner, ok = strings.CutSuffix(inner, "}}"); ok {
+			text = strings.TrimSpace(inner)
+		}
+	}
+
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, err)
+	}
+	return &Condition{expr: expr, root: root}, nil
+}
+
+// Eval reports whether the condition holds in scope. Missing paths are null;
+// ordering comparisons between values that are not both numbers or both
+// strings do not hold.
+func (c *Condition) Eval(scope map[string]interface{}) bool {
+	return truthy(c.root.eval(scope))
+}
+
+func (c *Condition) String() string {
+	return c.expr
+}
+
+type node interface {
+	eval(scope map[string]interface{}) interface{}
+}
+
+type literal struct{ value interface{} }
+
+func (n literal) eval(map[string]interface{}) interface{} { return n.value }
+
+type path string
+
+func (n path) eval(scope map[string]interface{}) interface{} {
+	value, _ := Lookup(scope, string(n))
+	return value
+}
+
+type not struct{ operand node }
+
+func (n not) eval(scope map[string]interface{}) interface{} {
+	return !truthy(n.operand.eval(scope))
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (n binary) eval(scope map[string]interface{}) interface{} {
+	switch n.op {
+	case "&&":
+		return truthy(n.left.eval(scope)) && truthy(n.right.eval(scope))
+	case "||":
+		return truthy(n.left.eval(scope)) || truthy(n.right.eval(scope))
+	}
+
+	left, right := n.left.eval(scope), n.right.eval(scope)
+	switch n.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	}
+
+	cmp, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != ""
+	case map[string]interface{}:
+		return len(x) > 0
+	case []interface{}:
+		return len(x) > 0
+	}
+	if f, ok := number(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+func equal(a, b interface{}) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case string, bool:
+		return a == b
+	default:
+		return format(x) == format(b)
+	}
+}
+
+func compare(a, b interface{}) (int, bool) {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+	sa, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	sb, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(sa, sb), true
+}
+
+func number(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case uint:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case uint8:
+		return float64(x), true
+	}
+	return 0, false
+}
+
+type tokenKind int
+
+const (
+	tokenPath tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func tokenize(text string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(text); {
+		c := rune(text[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexRune(text[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{tokenString, text[i+1 : i+1+end]})
+			i += end + 2
+		case c == '-' || unicode.IsDigit(c):
+			j := i + 1
+			for j < len(text) && (unicode.IsDigit(rune(text[j])) || text[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, text[i:j]})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(text) && (text[j] == '_' || text[j] == '.' || unicode.IsLetter(rune(text[j])) || unicode.IsDigit(rune(text[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{tokenPath, text[i:j]})
+			i = j
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(text[i:], op) {
+					tokens = append(tokens, token{tokenOp, op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser; each method parses one precedence
+// level, loosest first.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOp && p.tokens[p.pos].text == op
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var right node
+		if right, err = p.and(); err == nil {
+			left = binary{op: "||", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var right node
+		if right, err = p.unary(); err == nil {
+			left = binary{op: "&&", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (node, error) {
+	if p.peek("!") {
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{operand: operand}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.peek(op) {
+			p.pos++
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return binary{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) operand() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case tokenString:
+		return literal{tok.text}, nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return literal{f}, nil
+	case tokenPath:
+		switch tok.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null", "nil":
+			return literal{nil}, nil
+		}
+		if !pathPattern.MatchString(tok.text) {
+			return nil, fmt.Errorf("invalid path %q", tok.text)
+		}
+		return path(tok.text), nil
+	}
+
+	if tok.text == "(" {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return inner, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
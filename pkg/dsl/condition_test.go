@@ -0,0 +1,129 @@
+package dsl
+
+import "testing"
+
+func TestConditionEval(t *testing.T) {
+	scope := map[string]interface{}{
+		"message": map[string]interface{}{
+			"key": "user-1",
+			"value": map[string]interface{}{
+				"size":   2048.0,
+				"type":   "audio",
+				"lang":   "en",
+				"urgent": true,
+				"empty":  "",
+				"zero":   0.0,
+				"tags":   []interface{}{"a"},
+			},
+		},
+		"count": 3,
+		"temp":  -5.5,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"message.value.size > 1024", true},
+		{"message.value.size >= 2048", true},
+		{"message.value.size < 2048", false},
+		{"message.value.size <= 2048.0", true},
+		{"message.value.type == 'audio'", true},
+		{`message.value.type == "audio"`, true},
+		{"message.value.type != 'audio'", false},
+		{"'audio' == message.value.type", true},
+		{`"it's" == 'it' || "it's" != "it's"`, false},
+		{`'say "hi"' == 'say "hi"'`, true},
+		{"'a && b' == 'a && b'", true},
+		{"count == 3", true},
+		{"count == '3'", false},
+		{"temp < -5", true},
+		{"temp == -5.5", true},
+		{"temp > -6 && temp < 0", true},
+
+		// Precedence: ! binds tighter than &&, which binds tighter than ||
+		{"true || false && false", true},
+		{"(true || false) && false", false},
+		{"false && false || true", true},
+		{"false && (false || true)", false},
+		{"!false && false", false},
+		{"!(false && false)", true},
+		{"!!message.value.urgent", true},
+		{"!message.value.size > 1024", false},
+		{"((message.value.type == 'audio'))", true},
+
+		// Bare operands
+		{"message.value.urgent", true},
+		{"message.value.empty", false},
+		{"message.value.zero", false},
+		{"message.value.tags", true},
+		{"message.value", true},
+		{"'x'", true},
+		{"0", false},
+
+		// Missing paths are null
+		{"message.value.missing", false},
+		{"!message.value.missing", true},
+		{"message.value.missing == null", true},
+		{"message.value.missing != nil", false},
+		{"message.value.missing > 0", false},
+		{"message.value.missing < 0", false},
+		{"message.value.size.bytes == null", true},
+
+		// Ordering only holds between numbers or between strings
+		{"message.value.lang < 'fr'", true},
+		{"message.value.type > 1", false},
+		{"message.value.type < 1", false},
+		{"message.value.size > '1'", false},
+		{"message.value.urgent > false", false},
+		{"message.value.urgent == true", true},
+
+		{"{{ message.key == 'user-1' }}", true},
+	}
+
+	for _, tt := range tests {
+		cond, err := ParseCondition(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := cond.Eval(scope); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"a ==",
+		"a - b",
+		"a -1",
+		"a = b",
+		"a & b",
+		"a && ",
+		"(a == 1",
+		"a == 1)",
+		"'unterminated",
+		"a == 'b",
+		"a b",
+		"!",
+		"1.2.3 == 1",
+		"a..b",
+		"a == 1 == 1",
+	} {
+		if _, err := ParseCondition(expr); err == nil {
+			t.Errorf("ParseCondition(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestConditionString(t *testing.T) {
+	cond, err := ParseCondition("{{ a > 1 }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cond.String() != "{{ a > 1 }}" {
+		t.Errorf("String = %q, want the expression as written", cond.String())
+	}
+}
@@ -0,0 +1,602 @@
+package dsl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vighnesh-V-H/async/pkg/cron"
+	"gopkg.in/yaml.v3"
+)
+
+// Workflow is a parsed workflow definition as found in workflows/*.yml.
+type Workflow struct {
+	Name     string    `yaml:"name"`
+	Version  string    `yaml:"version"`
+	Triggers []Trigger `yaml:"triggers"`
+	States   []State   `yaml:"states"`
+}
+
+type Trigger struct {
+	Type          string            `yaml:"type"`
+	Event         string            `yaml:"event"`
+	PayloadSchema map[string]string `yaml:"payload_schema"`
+
+	// schedule: Event names the schedule. Executions start on the Cron
+	// expression in Timezone, UTC by default, or Every interval, each run
+	// delayed by a random part of Jitter, with Payload as trigger payload.
+	// Overlap decides what a run due while the previous one still runs
+	// does, CatchUp which runs missed while no scheduler was running are
+	// made up for.
+	Cron     string                 `yaml:"cron"`
+	Every    time.Duration          `yaml:"every"`
+	Timezone string                 `yaml:"timezone"`
+	Jitter   time.Duration          `yaml:"jitter"`
+	Overlap  string                 `yaml:"overlap"`
+	CatchUp  string                 `yaml:"catch_up"`
+	Payload  map[string]interface{} `yaml:"payload"`
+
+	// kafka: executions start for messages on Topic, Event by default,
+	// that match Filter. Mapping renders the trigger payload from the
+	// message; without it the payload is the message value. Idempotency
+	// derives the execution of a message from its offset or its key.
+	Topic       string                 `yaml:"topic"`
+	Filter      string                 `yaml:"filter"`
+	Mapping     map[string]interface{} `yaml:"mapping"`
+	Idempotency string                 `yaml:"idempotency"`
+}
+
+// TriggerSchedule is the type of triggers that start executions on a cron
+// expression or a fixed interval.
+const TriggerSchedule = "schedule"
+
+// TriggerKafka is the type of triggers that start an execution for every
+// message on a Kafka topic.
+const TriggerKafka = "kafka"
+
+// Idempotency policies of kafka triggers: which messages start the same
+// execution.
+const (
+	// IdempotencyOffset starts one execution per topic, partition and
+	// offset, so redelivered messages do not start another; the default
+	IdempotencyOffset = "offset"
+	// IdempotencyKey starts one execution per message key, so messages
+	// published again with the same key do not start another. Messages
+	// without a key fall back to their offset.
+	IdempotencyKey = "key"
+)
+
+// Overlap policies of schedule triggers, for runs due while the previous run
+// of the schedule has not finished.
+const (
+	// OverlapSkip drops the run; the default
+	OverlapSkip = "skip"
+	// OverlapBuffer starts the run once the previous one finished
+	OverlapBuffer = "buffer"
+	// OverlapAllow starts the run anyway
+	OverlapAllow = "allow"
+)
+
+// Catch-up policies of schedule triggers, for runs that fell due while no
+// scheduler was running.
+const (
+	// CatchUpNone drops missed runs
+	CatchUpNone = "none"
+	// CatchUpLatest makes up for the most recent missed run only; the
+	// default
+	CatchUpLatest = "latest"
+	// CatchUpAll makes up for every missed run, oldest first
+	CatchUpAll = "all"
+)
+
+// Schedule returns when a schedule trigger fires and the time zone its cron
+// expression is read in.
+func (t *Trigger) Schedule() (cron.Schedule, *time.Location, error) {
+	loc := time.UTC
+	if t.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(t.Timezone); err != nil {
+			return nil, nil, fmt.Errorf("unknown timezone %q", t.Timezone)
+		}
+	}
+	if t.Every > 0 {
+		sched, err := cron.Every(t.Every)
+		return sched, loc, err
+	}
+	sched, err := cron.Parse(t.Cron)
+	return sched, loc, err
+}
+
+// Spec returns the cron expression of a schedule trigger, with intervals
+// written as @every.
+func (t *Trigger) Spec() string {
+	if t.Every > 0 {
+		return "@every " + t.Every.String()
+	}
+	return t.Cron
+}
+
+// OverlapPolicy returns the overlap policy of a schedule trigger.
+func (t *Trigger) OverlapPolicy() string {
+	if t.Overlap == "" {
+		return OverlapSkip
+	}
+	return t.Overlap
+}
+
+// CatchUpPolicy returns the catch-up policy of a schedule trigger.
+func (t *Trigger) CatchUpPolicy() string {
+	if t.CatchUp == "" {
+		return CatchUpLatest
+	}
+	return t.CatchUp
+}
+
+// SubscribedTopic returns the topic of a kafka trigger.
+func (t *Trigger) SubscribedTopic() string {
+	if t.Topic == "" {
+		return t.Event
+	}
+	return t.Topic
+}
+
+// IdempotencyPolicy returns the idempotency policy of a kafka trigger.
+func (t *Trigger) IdempotencyPolicy() string {
+	if t.Idempotency == "" {
+		return IdempotencyOffset
+	}
+	return t.Idempotency
+}
+
+// Schedules returns the schedule triggers of the workflow.
+func (w *Workflow) Schedules() []*Trigger {
+	return w.triggersOf(TriggerSchedule)
+}
+
+// Subscriptions returns the kafka triggers of the workflow.
+func (w *Workflow) Subscriptions() []*Trigger {
+	return w.triggersOf(TriggerKafka)
+}
+
+func (w *Workflow) triggersOf(typ string) []*Trigger {
+	var triggers []*Trigger
+	for i := range w.Triggers {
+		if w.Triggers[i].Type == typ {
+			triggers = append(triggers, &w.Triggers[i])
+		}
+	}
+	return triggers
+}
+
+// State is a single node of the workflow graph. Only the fields relevant to
+// its Type are set.
+type State struct {
+	ID   string `yaml:"id"`
+	Type string `yaml:"type"`
+
+	// ai_task
+	Model  string `yaml:"model"`
+	Prompt string `yaml:"prompt"`
+
+	// task
+	Action string `yaml:"action"`
+
+	// http_call
+	Method string                 `yaml:"method"`
+	URL    string                 `yaml:"url"`
+	Body   map[string]interface{} `yaml:"body"`
+
+	// notification
+	Channels []map[string]string `yaml:"channels"`
+	Message  string              `yaml:"message"`
+	Subject  string              `yaml:"subject"`
+
+	// subworkflow: the name of the workflow to run as a child execution
+	Workflow string `yaml:"workflow"`
+
+	// wait_for_signal: the name of the signal to wait for, until Timeout
+	// if set
+	Signal string `yaml:"signal"`
+
+	// approval: who may decide, and who it escalates to when nobody did
+	// within Timeout. The escalation lasts EscalationTimeout, Timeout by
+	// default.
+	Assignees         []string      `yaml:"assignees"`
+	EscalateTo        []string      `yaml:"escalate_to"`
+	EscalationTimeout time.Duration `yaml:"escalation_timeout"`
+
+	// decision
+	Condition string `yaml:"condition"`
+	True      string `yaml:"true"`
+	False     string `yaml:"false"`
+
+	// Compensate undoes the effect of this state when a later state fails.
+	// It is a state of its own, usually a task action or an http_call, whose
+	// templates see the output of this state as prev.output.
+	Compensate *State `yaml:"compensate"`
+
+	Inputs    map[string]interface{} `yaml:"inputs"`
+	OnSuccess string                 `yaml:"on_success"`
+	OnFailure string                 `yaml:"on_failure"`
+	Retries   int                    `yaml:"retries"`
+	Timeout   time.Duration          `yaml:"timeout"`
+
+	// Topic overrides the task topic for this state so heavy task types can
+	// be served by their own worker pool.
+	Topic string `yaml:"topic"`
+}
+
+// TypeSubworkflow is the type of states that run another workflow as a
+// child execution. The orchestrator handles them itself instead of
+// dispatching a task to workers.
+const TypeSubworkflow = "subworkflow"
+
+// TypeWaitForSignal is the type of states that block until a signal of the
+// given name is sent to the execution. The orchestrator completes them with
+// the signal payload, or fails them once their timeout passes.
+const TypeWaitForSignal = "wait_for_signal"
+
+// TypeApproval is the type of states that ask people for a decision and wait
+// for it. Approval continues with on_success; rejection and timeouts fail the
+// step, keeping the decision as its output, and continue with on_failure.
+const TypeApproval = "approval"
+
+// Waits reports whether the orchestrator completes tasks of the state itself
+// once something outside the workflow happens, instead of a worker.
+func (s *State) Waits() bool {
+	return s.Type == TypeWaitForSignal || s.Type == TypeApproval
+}
+
+// TaskType is the name workers register executors under: the action for
+// custom tasks, the state type otherwise.
+func (s *State) TaskType() string {
+	if s.Action != "" {
+		return s.Action
+	}
+	return s.Type
+}
+
+// Params returns the type specific configuration of the state, as handed to
+// the executor of its task. Templates in it are left unrendered.
+func (s *State) Params() map[string]interface{} {
+	params := map[string]interface{}{"state": s.ID}
+	set := func(key, value string) {
+		if value != "" {
+			params[key] = value
+		}
+	}
+
+	set("model", s.Model)
+	set("prompt", s.Prompt)
+	set("action", s.Action)
+	set("method", s.Method)
+	set("url", s.URL)
+	set("message", s.Message)
+	set("subject", s.Subject)
+	set("workflow", s.Workflow)
+	set("signal", s.Signal)
+	if s.Body != nil {
+		params["body"] = s.Body
+	}
+	if len(s.Assignees) > 0 {
+		params["assignees"] = stringList(s.Assignees)
+	}
+	if len(s.EscalateTo) > 0 {
+		params["escalate_to"] = stringList(s.EscalateTo)
+		timeout := s.EscalationTimeout
+		if timeout <= 0 {
+			timeout = s.Timeout
+		}
+		params["escalation_timeout"] = timeout.String()
+	}
+	if len(s.Channels) > 0 {
+		channels := make([]interface{}, 0, len(s.Channels))
+		for _, ch := range s.Channels {
+			entry := make(map[string]interface{}, len(ch))
+			for kind, target := range ch {
+				entry[kind] = target
+			}
+			channels = append(channels, entry)
+		}
+		params["channels"] = channels
+	}
+	return params
+}
+
+// stringList converts to the list type params have after a JSON round trip.
+func stringList(values []string) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	return list
+}
+
+// State returns the state with the given id.
+func (w *Workflow) State(id string) (*State, bool) {
+	for i := range w.States {
+		if w.States[i].ID == id {
+			return &w.States[i], true
+		}
+	}
+	return nil, false
+}
+
+// Pipeline returns the states a workflow can reach from its first state
+// through on_success and on_failure, in step order: every transition leads
+// to a later state, except those looping back to a state that may already
+// have run. The happy path comes first when no failure leads into it.
+func (w *Workflow) Pipeline() []*State {
+	if len(w.States) == 0 {
+		return nil
+	}
+
+	// Reverse postorder of a depth-first walk, visiting on_failure before
+	// on_success so a state is directly followed by its on_success chain
+	var post []*State
+	seen := make(map[string]bool)
+	var visit func(st *State)
+	visit = func(st *State) {
+		seen[st.ID] = true
+		for _, target := range []string{st.OnFailure, st.OnSuccess} {
+			if next, ok := w.State(target); ok && !seen[next.ID] {
+				visit(next)
+			}
+		}
+		post = append(post, st)
+	}
+	visit(&w.States[0])
+
+	path := make([]*State, len(post))
+	for i, st := range post {
+		path[len(post)-1-i] = st
+	}
+	return path
+}
+
+// Next returns the 0-based position in Pipeline of the state that follows
+// the one at position i, depending on whether it failed, or false when the
+// workflow ends there. Transitions looping back end it too.
+func (w *Workflow) Next(i int, failed bool) (int, bool) {
+	pipeline := w.Pipeline()
+	if i < 0 || i >= len(pipeline) {
+		return 0, false
+	}
+	target := pipeline[i].OnSuccess
+	if failed {
+		target = pipeline[i].OnFailure
+	}
+	if target == "" {
+		return 0, false
+	}
+	for j := i + 1; j < len(pipeline); j++ {
+		if pipeline[j].ID == target {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// Validate checks that the definition is complete and that every transition
+// points at an existing state.
+func (w *Workflow) Validate() error {
+	if w.Name == "" {
+		return errors.New("workflow name is required")
+	}
+	if len(w.States) == 0 {
+		return fmt.Errorf("workflow %s has no states", w.Name)
+	}
+
+	ids := make(map[string]bool, len(w.States))
+	for _, st := range w.States {
+		if st.ID == "" {
+			return fmt.Errorf("workflow %s has a state without id", w.Name)
+		}
+		if st.Type == "" {
+			return fmt.Errorf("state %s in workflow %s has no type", st.ID, w.Name)
+		}
+		if ids[st.ID] {
+			return fmt.Errorf("duplicate state %s in workflow %s", st.ID, w.Name)
+		}
+		ids[st.ID] = true
+
+		if st.Type == TypeSubworkflow && st.Workflow == "" {
+			return fmt.Errorf("subworkflow state %s in workflow %s has no workflow", st.ID, w.Name)
+		}
+		if st.Type == TypeWaitForSignal && st.Signal == "" {
+			return fmt.Errorf("wait_for_signal state %s in workflow %s has no signal", st.ID, w.Name)
+		}
+		if st.Type == TypeApproval && len(st.EscalateTo) > 0 && st.Timeout <= 0 {
+			return fmt.Errorf("approval state %s in workflow %s escalates without a timeout", st.ID, w.Name)
+		}
+		if c := st.Compensate; c != nil {
+			if c.Type == "" && c.Action == "" {
+				return fmt.Errorf("compensation of state %s in workflow %s has no type or action", st.ID, w.Name)
+			}
+			if c.Type == TypeSubworkflow || c.Waits() || c.Compensate != nil {
+				return fmt.Errorf("compensation of state %s in workflow %s must be a plain task", st.ID, w.Name)
+			}
+		}
+	}
+
+	if err := w.validateSchedules(); err != nil {
+		return err
+	}
+	if err := w.validateSubscriptions(); err != nil {
+		return err
+	}
+
+	// The first task is dispatched by the trigger, which cannot start
+	// child executions or wait
+	if first := w.States[0]; first.Type == TypeSubworkflow || first.Waits() {
+		return fmt.Errorf("workflow %s starts with %s state %s", w.Name, first.Type, first.ID)
+	}
+
+	for _, st := range w.States {
+		for _, target := range []string{st.OnSuccess, st.OnFailure, st.True, st.False} {
+			if target != "" && !ids[target] {
+				return fmt.Errorf("state %s in workflow %s references unknown state %s", st.ID, w.Name, target)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *Workflow) validateSchedules() error {
+	names := make(map[string]bool)
+	for _, t := range w.Schedules() {
+		if t.Event == "" {
+			return fmt.Errorf("schedule trigger in workflow %s has no event", w.Name)
+		}
+		if names[t.Event] {
+			return fmt.Errorf("duplicate schedule %s in workflow %s", t.Event, w.Name)
+		}
+		names[t.Event] = true
+
+		if (t.Cron == "") == (t.Every <= 0) {
+			return fmt.Errorf("schedule %s in workflow %s needs either cron or every", t.Event, w.Name)
+		}
+		sched, loc, err := t.Schedule()
+		if err != nil {
+			return fmt.Errorf("schedule %s in workflow %s: %w", t.Event, w.Name, err)
+		}
+		if sched.Next(time.Now().In(loc)).IsZero() {
+			return fmt.Errorf("schedule %s in workflow %s never fires", t.Event, w.Name)
+		}
+		if t.Jitter < 0 {
+			return fmt.Errorf("schedule %s in workflow %s has a negative jitter", t.Event, w.Name)
+		}
+		switch t.OverlapPolicy() {
+		case OverlapSkip, OverlapBuffer, OverlapAllow:
+		default:
+			return fmt.Errorf("schedule %s in workflow %s has unknown overlap policy %q", t.Event, w.Name, t.Overlap)
+		}
+		switch t.CatchUpPolicy() {
+		case CatchUpNone, CatchUpLatest, CatchUpAll:
+		default:
+			return fmt.Errorf("schedule %s in workflow %s has unknown catch_up policy %q", t.Event, w.Name, t.CatchUp)
+		}
+	}
+	return nil
+}
+
+func (w *Workflow) validateSubscriptions() error {
+	topics := make(map[string]bool)
+	for _, t := range w.Subscriptions() {
+		topic := t.SubscribedTopic()
+		if topic == "" {
+			return fmt.Errorf("kafka trigger in workflow %s has no topic", w.Name)
+		}
+		if topics[topic] {
+			return fmt.Errorf("workflow %s subscribes to topic %s twice", w.Name, topic)
+		}
+		topics[topic] = true
+		if t.Filter != "" {
+			if _, err := ParseCondition(t.Filter); err != nil {
+				return fmt.Errorf("kafka trigger on %s in workflow %s: %w", topic, w.Name, err)
+			}
+		}
+		switch t.IdempotencyPolicy() {
+		case IdempotencyOffset, IdempotencyKey:
+		default:
+			return fmt.Errorf("kafka trigger on %s in workflow %s has unknown idempotency %q", topic, w.Name, t.Idempotency)
+		}
+	}
+	return nil
+}
+
+// Parse decodes and validates a single workflow definition.
+func Parse(data []byte) (*Workflow, error) {
+	var wf Workflow
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow definition: %w", err)
+	}
+	if err := wf.Validate(); err != nil {
+		return nil, err
+	}
+	return &wf, nil
+}
+
+// ParseFile reads and parses a workflow definition from disk.
+func ParseFile(path string) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow definition: %w", err)
+	}
+	wf, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return wf, nil
+}
+
+// Registry holds the workflow definitions known to a service, keyed by name.
+type Registry struct {
+	mu        sync.RWMutex
+	workflows map[string]*Workflow
+}
+
+func NewRegistry() *Registry {
+	return &Registry{workflows: make(map[string]*Workflow)}
+}
+
+// LoadDir parses every .yml/.yaml file in dir into a new registry.
+func LoadDir(dir string) (*Registry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow directory: %w", err)
+	}
+
+	reg := NewRegistry()
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		wf, err := ParseFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := reg.Register(wf); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+func (r *Registry) Register(wf *Workflow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.workflows[wf.Name]; exists {
+		return fmt.Errorf("workflow %s is already registered", wf.Name)
+	}
+	r.workflows[wf.Name] = wf
+	return nil
+}
+
+func (r *Registry) Get(name string) (*Workflow, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wf, ok := r.workflows[name]
+	return wf, ok
+}
+
+// All returns the registered workflows sorted by name.
+func (r *Registry) All() []*Workflow {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]*Workflow, 0, len(r.workflows))
+	for _, wf := range r.workflows {
+		all = append(all, wf)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
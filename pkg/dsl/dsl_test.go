@@ -0,0 +1,80 @@
+package dsl
+
+import "testing"
+
+const branchingWorkflow = `
+name: branching
+states:
+  - id: fetch
+    type: task
+    on_success: review
+    on_failure: alert
+  - id: review
+    type: approval
+    on_success: publish
+    on_failure: discard
+  - id: publish
+    type: task
+    on_success: fetch
+  - id: discard
+    type: task
+    on_success: alert
+  - id: alert
+    type: notification
+    on_failure: fetch
+  - id: orphan
+    type: task
+`
+
+func TestPipeline(t *testing.T) {
+	wf, err := Parse([]byte(branchingWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, st := range wf.Pipeline() {
+		ids = append(ids, st.ID)
+	}
+	want := []string{"fetch", "review", "publish", "discard", "alert"}
+	if len(ids) != len(want) {
+		t.Fatalf("Pipeline = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("Pipeline = %v, want %v", ids, want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	wf, err := Parse([]byte(branchingWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		from   int
+		failed bool
+		want   int
+		ok     bool
+	}{
+		{"on_success", 0, false, 1, true},
+		{"on_failure", 0, true, 4, true},
+		{"approved", 1, false, 2, true},
+		{"rejected", 1, true, 3, true},
+		{"loop back ends", 2, false, 0, false},
+		{"no on_failure ends", 2, true, 0, false},
+		{"branches join", 3, false, 4, true},
+		{"no on_success ends", 4, false, 0, false},
+		{"failure loop back ends", 4, true, 0, false},
+		{"out of range", 5, false, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := wf.Next(tt.from, tt.failed)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s: Next(%d, %v) = %d, %v; want %d, %v", tt.name, tt.from, tt.failed, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package dsl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+	pathPattern        = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z0-9_]+)*$`)
+)
+
+// Resolver dereferences a value before it is interpolated into text, such as
+// a claim check standing in for a large payload.
+type Resolver func(ctx context.Context, value interface{}) (interface{}, error)
+
+// Renderer fills {{path.to.value}} placeholders from a scope such as
+// {"trigger": ..., "prev": {"output": ...}, "vars": ...}.
+//
+// A string that is a single placeholder renders to the value itself, keeping
+// its type, so references are passed along without being loaded. Placeholders
+// embedded in text are resolved and formatted. Missing paths render as nil or
+// the empty string; placeholders that are not plain paths, such as
+// conditions, are left as they are.
+type Renderer struct {
+	resolve Resolver
+}
+
+// NewRenderer creates a renderer. resolve may be nil.
+func NewRenderer(resolve Resolver) *Renderer {
+	return &Renderer{resolve: resolve}
+}
+
+// Render renders a single template string.
+func (r *Renderer) Render(ctx context.Context, tmpl string, scope map[string]interface{}) (interface{}, error) {
+	if m := placeholderPattern.FindStringSubmatchIndex(tmpl); m != nil && m[0] == 0 && m[1] == len(tmpl) {
+		path := tmpl[m[2]:m[3]]
+		if pathPattern.MatchString(path) {
+			value, _ := Lookup(scope, path)
+			return value, nil
+		}
+	}
+
+	var renderErr error
+	out := placeholderPattern.ReplaceAllStringFunc(tmpl, func(match string) string {
+		path := placeholderPattern.FindStringSubmatch(match)[1]
+		if !pathPattern.MatchString(path) || renderErr != nil {
+			return match
+		}
+
+		value, ok := Lookup(scope, path)
+		if !ok {
+			return ""
+		}
+		if r.resolve != nil {
+			resolved, err := r.resolve(ctx, value)
+			if err != nil {
+				renderErr = fmt.Errorf("resolving %s: %w", path, err)
+				return match
+			}
+			value = resolved
+		}
+		return format(value)
+	})
+	if renderErr != nil {
+		return nil, renderErr
+	}
+	return out, nil
+}
+
+// RenderString renders tmpl and formats the result as text.
+func (r *Renderer) RenderString(ctx context.Context, tmpl string, scope map[string]interface{}) (string, error) {
+	value, err := r.Render(ctx, tmpl, scope)
+	if err != nil {
+		return "", err
+	}
+	if r.resolve != nil {
+		if value, err = r.resolve(ctx, value); err != nil {
+			return "", err
+		}
+	}
+	return format(value), nil
+}
+
+// RenderValue renders every string inside v, walking maps and lists.
+func (r *Renderer) RenderValue(ctx context.Context, v interface{}, scope map[string]interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return r.Render(ctx, x, scope)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			rendered, err := r.RenderValue(ctx, item, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = rendered
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			rendered, err := r.RenderValue(ctx, item, scope)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// RenderInputs renders the inputs of a state.
+func (r *Renderer) RenderInputs(ctx context.Context, inputs map[string]interface{}, scope map[string]interface{}) (map[string]interface{}, error) {
+	if inputs == nil {
+		return nil, nil
+	}
+	out, err := r.RenderValue(ctx, inputs, scope)
+	if err != nil {
+		return nil, err
+	}
+	return out.(map[string]interface{}), nil
+}
+
+// Lookup walks a dotted path through nested maps; numeric segments index
+// lists.
+func Lookup(scope map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = scope
+	for _, part := range strings.Split(path, ".") {
+		switch x := current.(type) {
+		case map[string]interface{}:
+			next, ok := x[part]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			current = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func format(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(x)
+		if err != nil {
+			return fmt.Sprint(x)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(x)
+	}
+}
@@ -76,6 +76,8 @@ func InitProducer(cfg *config.KafkaConfig, log zerolog.Logger) (*kafka.Producer,
 	return producer, err
 }
 
+// InitConsumer creates the shared consumer. Subscribing is left to the
+// subscriber that polls it.
 func InitConsumer(cfg *config.KafkaConfig, log zerolog.Logger) (*kafka.Consumer, error) {
 	var err error
 	logg = log
@@ -88,25 +90,44 @@ func InitConsumer(cfg *config.KafkaConfig, log zerolog.Logger) (*kafka.Consumer,
 			"auto.offset.reset":  cfg.AutoOffsetReset,
 			"enable.auto.commit": cfg.EnableAutoCommit,
 			"session.timeout.ms": cfg.SessionTimeoutMs,
+			// Deliver assign/revoke as events from Poll so the consumer can
+			// drain and commit in-flight work before giving partitions up.
+			"go.application.rebalance.enable": true,
 		})
 		if e != nil {
 			err = e
 			logg.Error().Err(e).Msg("Failed to create Kafka consumer")
 			return
 		}
-		if err = c.SubscribeTopics([]string{cfg.ConsumerTopic}, nil); err != nil {
-			logg.Error().Err(err).Str("topic", cfg.ConsumerTopic).Msg("Failed to subscribe to topic")
-			return
-		}
 		consumer = c
 		logg.Info().
-			Str("topic", cfg.ConsumerTopic).
 			Str("group_id", cfg.ConsumerGroupID).
 			Msg("Kafka Consumer initialized")
 	})
 	return consumer, err
 }
 
+// NewConsumer creates a consumer of its own in groupID, for consumers that
+// must not share the group of the event transport. The caller closes it.
+func NewConsumer(cfg *config.KafkaConfig, groupID string, log zerolog.Logger) (*kafka.Consumer, error) {
+	c, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":               strings.Join(cfg.Brokers, ","),
+		"group.id":                        groupID,
+		"auto.offset.reset":               cfg.AutoOffsetReset,
+		"enable.auto.commit":              false,
+		"session.timeout.ms":              cfg.SessionTimeoutMs,
+		"go.application.rebalance.enable": true,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("group_id", groupID).Msg("Failed to create Kafka consumer")
+		return nil, err
+	}
+	log.Info().
+		Str("group_id", groupID).
+		Msg("Kafka Consumer initialized")
+	return c, nil
+}
+
 func GetProducer() *kafka.Producer {
 	if producer == nil {
 		panic("Kafka producer not initialized. Call InitProducer first.")